@@ -1,16 +1,30 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
+	"infoscope/internal/auth"
 	"infoscope/internal/config"
 	"infoscope/internal/database"
 	"infoscope/internal/favicon"
 	"infoscope/internal/feed"
+	"infoscope/internal/hooks"
+	"infoscope/internal/logging"
 	"infoscope/internal/server"
-	"log"
+	"infoscope/internal/statsd"
+	"infoscope/internal/storage"
+	"infoscope/internal/systemd"
+	"io"
+	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/kardianos/service"
 )
 
 var (
@@ -25,6 +39,22 @@ var (
 	prodMode          = flag.Bool("prod", false, "Enable production mode (HTTPS-only features including strict CSRF)")
 	noTemplateUpdates = flag.Bool("no-template-updates", false, "Disable automatic template updates")
 	webPath           = flag.String("web", "", "Path to web content directory (default: web or INFOSCOPE_WEB_PATH)")
+	readOnly          = flag.Bool("readonly", false, "Serve a read-only mirror: no admin, no writes, no feed fetching")
+	assetsInData      = flag.Bool("assets-in-data", false, "Store favicons and uploads in the data directory (or INFOSCOPE_ASSETS_IN_DATA)")
+	hookScript        = flag.String("hook-script", "", "Script run with a JSON payload on entry, fetch and click events (or INFOSCOPE_HOOK_SCRIPT)")
+	redisURL          = flag.String("redis-url", "", "Redis URL for sessions shared between instances (or INFOSCOPE_REDIS_URL)")
+	logLevel          = flag.String("log-level", "", "Log level: debug, info, warn or error (default: info or INFOSCOPE_LOG_LEVEL)")
+	logFormat         = flag.String("log-format", "", "Log format: text or json (default: text or INFOSCOPE_LOG_FORMAT)")
+	tlsCert           = flag.String("tls-cert", "", "TLS certificate file to serve HTTPS with (or INFOSCOPE_TLS_CERT)")
+	tlsKey            = flag.String("tls-key", "", "TLS private key file for -tls-cert (or INFOSCOPE_TLS_KEY)")
+	autocertDomains   = flag.String("autocert", "", "Comma separated domains to get Let's Encrypt certificates for (or INFOSCOPE_AUTOCERT_DOMAINS)")
+	autocertEmail     = flag.String("autocert-email", "", "Contact email for Let's Encrypt (or INFOSCOPE_AUTOCERT_EMAIL)")
+	httpRedirectPort  = flag.Int("http-redirect-port", 0, "Port redirecting HTTP to HTTPS (default: 80 with -autocert, off otherwise, or INFOSCOPE_HTTP_REDIRECT_PORT)")
+	serviceAction     = flag.String("service", "", "Manage infoscope as a system service: install, uninstall, start, stop or restart")
+	configFile        = flag.String("config", "", "File of INFOSCOPE_ variables, re-read on SIGHUP (or INFOSCOPE_CONFIG)")
+
+	// logLevelVar is the running log level, changed by a reload
+	logLevelVar = new(slog.LevelVar)
 )
 
 func main() {
@@ -37,11 +67,79 @@ func main() {
 		return
 	}
 
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "infoscope: %v\n", err)
+		os.Exit(2)
+	}
+
 	// Setup logging
-	logger := log.New(os.Stdout, "infoscope: ", log.LstdFlags|log.Lshortfile)
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "infoscope: %v\n", err)
+		os.Exit(2)
+	}
+	format, err := logging.ParseFormat(cfg.LogFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "infoscope: %v\n", err)
+		os.Exit(2)
+	}
+	logLevelVar.Set(level)
+
+	// Subcommands print their results, so they log to stderr
+	if flag.Arg(0) == "simulate-fetch" {
+		os.Exit(simulateFetch(cfg, logging.New(os.Stderr, logLevelVar, format), flag.Args()[1:]))
+	}
 
-	// Get base configuration from environment
-	cfg := config.GetConfig()
+	// Under a service manager without a console, log to a file
+	out := io.Writer(os.Stdout)
+	if underServiceManager() {
+		logFile, err := openServiceLog(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "infoscope: %v\n", err)
+			os.Exit(1)
+		}
+		defer logFile.Close()
+		out = logFile
+	}
+	logger := logging.New(out, logLevelVar, format)
+
+	// Install or control the system service, or run as one when started by
+	// the service manager
+	prg := &program{cfg: cfg, logger: logger}
+	svc, err := newService(prg, os.Args[1:])
+	if *serviceAction != "" {
+		if err == nil {
+			err = controlService(svc, *serviceAction)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "infoscope: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Infoscope service: %s done\n", *serviceAction)
+		return
+	}
+	if err == nil && !service.Interactive() {
+		if err := svc.Run(); err != nil {
+			fatal(logger, "Service error", "error", err)
+		}
+		return
+	}
+
+	run(cfg, logger, nil)
+}
+
+// loadConfig reads the configuration from the config file and environment,
+// with command line flags taking precedence
+func loadConfig() (config.Config, error) {
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("INFOSCOPE_CONFIG")
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return cfg, err
+	}
 
 	// Override with command line flags if provided
 	if *port > 0 {
@@ -63,23 +161,80 @@ func main() {
 	// Set production mode
 	cfg.ProductionMode = *prodMode
 
+	// Read-only mirror mode
+	if *readOnly {
+		cfg.ReadOnly = true
+	}
+	if *assetsInData {
+		cfg.AssetsInData = true
+	}
+	if *hookScript != "" {
+		cfg.HookScript = *hookScript
+	}
+	if *redisURL != "" {
+		cfg.RedisURL = *redisURL
+	}
+	if *logLevel != "" {
+		cfg.LogLevel = *logLevel
+	}
+	if *logFormat != "" {
+		cfg.LogFormat = *logFormat
+	}
+	if *tlsCert != "" {
+		cfg.TLSCert = *tlsCert
+	}
+	if *tlsKey != "" {
+		cfg.TLSKey = *tlsKey
+	}
+	if *autocertDomains != "" {
+		cfg.AutocertDomains = config.SplitList(*autocertDomains)
+	}
+	if *autocertEmail != "" {
+		cfg.AutocertEmail = *autocertEmail
+	}
+	if *httpRedirectPort > 0 {
+		cfg.HTTPRedirectPort = *httpRedirectPort
+	}
+	return cfg, nil
+}
+
+// run starts infoscope and serves until its listener is closed. listening,
+// when set, is handed the listener once it's open.
+func run(cfg config.Config, logger *slog.Logger, listening func(net.Listener)) {
 	// Log startup configuration
-	logger.Printf("Starting Infoscope v%s", Version)
-	logger.Printf("Port: %d", cfg.Port)
-	logger.Printf("Database: %s", cfg.DBPath)
-	logger.Printf("Data directory: %s", cfg.DataPath)
-	logger.Printf("Mode: %s", map[bool]string{true: "production", false: "development"}[cfg.ProductionMode])
+	logger.Info("Starting Infoscope",
+		"version", Version,
+		"port", cfg.Port,
+		"database", cfg.DBPath,
+		"data", cfg.DataPath,
+		"assets", cfg.AssetsPath(),
+		"mode", map[bool]string{true: "production", false: "development"}[cfg.ProductionMode])
+	if cfg.ReadOnly {
+		logger.Info("Read-only mirror: admin, writes and feed fetching are disabled")
+	}
+
+	// Under systemd socket activation the listening socket is passed in
+	listeners, err := systemd.Listeners()
+	if err != nil {
+		fatal(logger, "Failed to use sockets from systemd", "error", err)
+	}
+
+	// Catch environment problems before they surface as handler errors
+	if err := preflight(cfg, logger, len(listeners) > 0); err != nil {
+		fatal(logger, "Preflight checks failed", "error", err)
+	}
 
 	// Create database directory
 	if err := os.MkdirAll(filepath.Dir(cfg.DBPath), 0755); err != nil {
-		logger.Fatalf("Failed to create database directory: %v", err)
+		fatal(logger, "Failed to create database directory", "error", err)
 	}
 
 	// Initialize database
-	dbConfig := database.DefaultConfig()
+	dbConfig := poolConfig(cfg)
+	dbConfig.ReadOnly = cfg.ReadOnly
 	db, err := database.NewDB(cfg.DBPath, dbConfig)
 	if err != nil {
-		logger.Fatalf("Failed to initialize database: %v", err)
+		fatal(logger, "Failed to initialize database", "error", err)
 	}
 	defer db.Close()
 
@@ -93,35 +248,233 @@ func main() {
 	}
 	for _, dir := range requiredDirs {
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			logger.Fatalf("Failed to create directory %s: %v", dir, err)
+			fatal(logger, "Failed to create directory", "dir", dir, "error", err)
+		}
+	}
+
+	// Move favicons and uploads out of the web directory once assets are
+	// kept with the data
+	if cfg.AssetsInData && cfg.Storage == "local" && !cfg.ReadOnly {
+		if err := server.MigrateAssets(filepath.Join(cfg.WebPath, "static"), cfg.AssetsPath(), logger); err != nil {
+			fatal(logger, "Failed to move assets", "error", err)
 		}
 	}
 
-	// Initialize favicon service with configured path
-	faviconSvc, err := favicon.NewService(filepath.Join(cfg.WebPath, "static", "favicons"))
+	// Favicons and uploads stay on disk unless they're kept in a bucket
+	assets, err := assetStore(cfg)
 	if err != nil {
-		logger.Fatalf("Failed to initialize favicon service: %v", err)
+		fatal(logger, "Failed to set up asset storage", "error", err)
+	}
+	if cfg.Storage == "s3" {
+		logger.Info("Keeping assets in S3", "bucket", cfg.S3Bucket, "prefix", cfg.S3Prefix)
+	}
+	faviconSvc := favicon.NewService(storage.Sub(assets, "favicons"))
+
+	// Integrations hook into ingest, fetch and click events
+	hookRegistry := hooks.NewRegistry(logger.With("component", "hooks"))
+	if cfg.HookScript != "" {
+		hookRegistry.Register(hooks.NewExec(cfg.HookScript, logger.With("component", "hooks")))
+		logger.Info("Running hook script", "script", cfg.HookScript)
 	}
 
 	// Initialize feed service
-	feedService := feed.NewService(db.DB, logger, faviconSvc)
-	feedService.Start()
-	defer feedService.Stop()
+	feedService := feed.NewService(db.DB, logger.With("component", "feed"), faviconSvc)
+	feedService.ConfigureTransport(transportConfig(cfg))
+	feedService.SetHooks(hookRegistry)
+	if !cfg.ReadOnly {
+		feedService.Start()
+		defer feedService.Stop()
+		go db.EnforceRetention(context.Background(), logger.With("component", "retention"))
+	}
+
+	// Optional StatsD export
+	var statsdClient *statsd.Client
+	if cfg.StatsDAddr != "" {
+		statsdClient, err = statsd.Dial(cfg.StatsDAddr, cfg.StatsDPrefix, cfg.StatsDTags)
+		if err != nil {
+			fatal(logger, "Failed to set up StatsD", "error", err)
+		}
+		logger.Info("Sending metrics to StatsD", "addr", cfg.StatsDAddr)
+	}
+
+	// Sessions stay in the database unless instances share them in Redis
+	var sessions auth.SessionStore
+	if cfg.RedisURL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		redisStore, err := auth.NewRedisStore(ctx, cfg.RedisURL)
+		cancel()
+		if err != nil {
+			fatal(logger, "Failed to set up session store", "error", err)
+		}
+		defer redisStore.Close()
+		sessions = redisStore
+		logger.Info("Keeping sessions in Redis")
+	}
+
+	// Checked by preflight
+	trustedProxies, _ := cfg.TrustedProxyPrefixes()
 
 	// Initialize server with configuration
-	srv, err := server.NewServer(db.DB, logger, feedService, server.Config{
-		UseHTTPS:               cfg.ProductionMode,
+	// Serving HTTPS directly makes every cookie safe to mark secure
+	srv, err := server.NewServer(db.DB, logger.With("component", "server"), feedService, server.Config{
+		UseHTTPS:               cfg.ProductionMode || cfg.TLSEnabled(),
 		DisableTemplateUpdates: cfg.DisableTemplateUpdates,
 		WebPath:                cfg.WebPath,
+		DataPath:               cfg.DataPath,
+		AssetsPath:             cfg.AssetsPath(),
+		Assets:                 assets,
+		ReadOnly:               cfg.ReadOnly,
+		StatsD:                 statsdClient,
+		StatsDInterval:         time.Duration(cfg.StatsDInterval) * time.Second,
+		Hooks:                  hookRegistry,
+		Version:                Version,
+		Sessions:               sessions,
+		TrustedProxies:         trustedProxies,
+		LoginBurst:             cfg.LoginBurst,
+		LoginRefill:            time.Duration(cfg.LoginRefill) * time.Second,
+		TLS: server.TLSConfig{
+			CertFile:         cfg.TLSCert,
+			KeyFile:          cfg.TLSKey,
+			AutocertDomains:  cfg.AutocertDomains,
+			AutocertEmail:    cfg.AutocertEmail,
+			AutocertCacheDir: cfg.AutocertCacheDir(),
+			RedirectAddr:     cfg.RedirectAddress(),
+		},
 	})
 	if err != nil {
-		logger.Fatalf("Failed to initialize server: %v", err)
+		fatal(logger, "Failed to initialize server", "error", err)
 	}
 
 	// Start the server
-	addr := fmt.Sprintf(":%d", cfg.Port)
-	logger.Printf("Server listening on %s", addr)
-	if err := srv.Start(addr); err != nil {
-		logger.Fatalf("Server error: %v", err)
+	var ln net.Listener
+	if len(listeners) > 0 {
+		ln = listeners[0]
+		for _, extra := range listeners[1:] {
+			logger.Warn("Ignoring extra socket from systemd", "addr", extra.Addr().String())
+			extra.Close()
+		}
+	} else if ln, err = net.Listen("tcp", cfg.GetAddress()); err != nil {
+		fatal(logger, "Server error", "error", err)
+	}
+	addr := ln.Addr().String()
+	logger.Info("Server listening", "addr", addr, "tls", cfg.TLSEnabled())
+	if listening != nil {
+		listening(ln)
+	}
+
+	// Tell systemd we're up and keep its watchdog fed while the database
+	// answers
+	if _, err := systemd.Ready("Serving on " + addr); err != nil {
+		logger.Warn("Failed to notify systemd", "error", err)
+	}
+	if interval := systemd.WatchdogInterval(); interval > 0 {
+		go watchdog(db.DB, interval, logger)
+	}
+	go reloadOnHangup(srv, db, logger)
+
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, net.ErrClosed) {
+		fatal(logger, "Server error", "error", err)
+	}
+	logger.Info("Server stopped")
+}
+
+// watchdog pings the systemd watchdog every interval as long as the
+// database answers a query within it. A wedged process stops pinging, and
+// systemd restarts it.
+func watchdog(db *sql.DB, interval time.Duration, logger *slog.Logger) {
+	logger.Info("Pinging the systemd watchdog", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		var one int
+		err := db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+		cancel()
+		if err != nil {
+			logger.Warn("Skipping watchdog ping, database not answering", "error", err)
+			continue
+		}
+		if _, err := systemd.Notify("WATCHDOG=1"); err != nil {
+			logger.Warn("Failed to ping the systemd watchdog", "error", err)
+		}
+	}
+}
+
+// transportConfig applies the fetch tuning from cfg over the defaults.
+func transportConfig(cfg config.Config) feed.TransportConfig {
+	tc := feed.DefaultTransportConfig()
+	if cfg.FetchMaxIdleConns > 0 {
+		tc.MaxIdleConns = cfg.FetchMaxIdleConns
+	}
+	if cfg.FetchMaxIdleConnsPerHost > 0 {
+		tc.MaxIdleConnsPerHost = cfg.FetchMaxIdleConnsPerHost
+	}
+	if cfg.FetchMaxConnsPerHost > 0 {
+		tc.MaxConnsPerHost = cfg.FetchMaxConnsPerHost
+	}
+	if cfg.FetchIdleConnTimeout > 0 {
+		tc.IdleConnTimeout = time.Duration(cfg.FetchIdleConnTimeout) * time.Second
+	}
+	if cfg.FetchTLSSessionCache > 0 {
+		tc.TLSSessionCacheSize = cfg.FetchTLSSessionCache
+	}
+	tc.DisableHTTP2 = cfg.FetchDisableHTTP2
+	return tc
+}
+
+// poolConfig applies the database pool tuning from cfg over the defaults.
+func poolConfig(cfg config.Config) database.Config {
+	dc := database.DefaultConfig()
+	if cfg.DBMaxOpenConns > 0 {
+		dc.MaxOpenConns = cfg.DBMaxOpenConns
+	}
+	if cfg.DBMaxIdleConns > 0 {
+		dc.MaxIdleConns = cfg.DBMaxIdleConns
+	}
+	if dc.MaxIdleConns > dc.MaxOpenConns {
+		dc.MaxIdleConns = dc.MaxOpenConns
+	}
+	if cfg.DBConnMaxLifetime > 0 {
+		dc.ConnMaxLifetime = time.Duration(cfg.DBConnMaxLifetime) * time.Second
 	}
+	if cfg.DBConnMaxIdleTime > 0 {
+		dc.ConnMaxIdleTime = time.Duration(cfg.DBConnMaxIdleTime) * time.Second
+	}
+	return dc
+}
+
+// s3Config is the bucket described by cfg
+func s3Config(cfg config.Config) storage.S3Config {
+	return storage.S3Config{
+		Endpoint:  cfg.S3Endpoint,
+		Region:    cfg.S3Region,
+		Bucket:    cfg.S3Bucket,
+		Prefix:    cfg.S3Prefix,
+		AccessKey: cfg.S3AccessKey,
+		SecretKey: cfg.S3SecretKey,
+	}
+}
+
+// assetStore opens the storage cfg names, checking that a bucket answers
+// before anything is written to it
+func assetStore(cfg config.Config) (storage.Store, error) {
+	if cfg.Storage != "s3" {
+		return storage.NewDir(cfg.AssetsPath())
+	}
+	bucket, err := storage.NewS3(s3Config(cfg))
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := bucket.List(ctx, ""); err != nil {
+		return nil, fmt.Errorf("error reaching bucket: %w", err)
+	}
+	return bucket, nil
+}
+
+// fatal logs msg at error level and exits
+func fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
 }
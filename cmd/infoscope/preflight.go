@@ -0,0 +1,230 @@
+package main
+
+import (
+	"crypto/tls"
+	"database/sql"
+	"fmt"
+	"infoscope/internal/config"
+	"infoscope/internal/storage"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minSQLiteVersion is the oldest SQLite with the upserts the schema relies on
+const minSQLiteVersion = "3.24.0"
+
+// clockFloor is a date the system clock must be past; anything earlier
+// means an unset clock, which breaks TLS, sessions and feed scheduling
+var clockFloor = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// preflight checks the environment before anything is started, so a
+// misconfigured install fails with a fix rather than a handler error later.
+// Every problem found is logged before the error is returned. A socket
+// passed in by systemd replaces the listening port, so it isn't checked.
+func preflight(cfg config.Config, logger *slog.Logger, socketActivated bool) error {
+	var problems []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// Directories the server writes to
+	if !cfg.ReadOnly {
+		dirs := map[string]string{
+			"database directory": filepath.Dir(cfg.DBPath),
+			"data directory":     cfg.DataPath,
+		}
+		if cfg.Storage == "local" {
+			dirs["assets directory"] = cfg.AssetsPath()
+		}
+		for name, dir := range dirs {
+			if err := checkWritable(dir); err != nil {
+				fail("%s %s is not writable (%v); create it and give the user running infoscope write access, or point -data/-db elsewhere", name, dir, err)
+			}
+		}
+	}
+	if cfg.DisableTemplateUpdates {
+		if _, err := os.Stat(cfg.WebPath); err != nil {
+			fail("web directory %s is missing (%v); run once without -no-template-updates to extract the templates", cfg.WebPath, err)
+		}
+	} else if err := checkWritable(cfg.WebPath); err != nil {
+		fail("web directory %s is not writable (%v); templates are extracted there on startup, so grant write access or pass -no-template-updates with a populated directory", cfg.WebPath, err)
+	}
+
+	// SQLite build
+	if version, err := checkSQLite(); err != nil {
+		fail("%v", err)
+	} else if checkFTS5() {
+		logger.Info("SQLite with FTS5", "version", version)
+	} else {
+		logger.Info("SQLite without FTS5 (build with -tags sqlite_fts5 to enable it)", "version", version)
+	}
+	if !cfg.ReadOnly {
+		if err := checkWAL(filepath.Dir(cfg.DBPath)); err != nil {
+			fail("%v; keep the database on a local disk, not a network share", err)
+		}
+	}
+
+	// Listening port
+	if socketActivated {
+		logger.Info("Listening on a socket from systemd")
+	} else if ln, err := net.Listen("tcp", cfg.GetAddress()); err != nil {
+		fail("cannot listen on port %d (%v); stop whatever is using it or choose another with -port or INFOSCOPE_PORT", cfg.Port, err)
+	} else {
+		ln.Close()
+	}
+
+	// HTTPS
+	switch {
+	case (cfg.TLSCert == "") != (cfg.TLSKey == ""):
+		fail("a TLS certificate and key must be given together; set both -tls-cert and -tls-key")
+	case cfg.TLSCert != "" && len(cfg.AutocertDomains) > 0:
+		fail("-tls-cert and -autocert both configure HTTPS; use one or the other")
+	case cfg.TLSCert != "":
+		if _, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey); err != nil {
+			fail("cannot load the TLS certificate (%v); check that -tls-cert and -tls-key are readable PEM files that belong together", err)
+		}
+	case len(cfg.AutocertDomains) > 0:
+		if err := checkWritable(cfg.AutocertCacheDir()); err != nil {
+			fail("certificate cache %s is not writable (%v); Let's Encrypt certificates are kept there between restarts", cfg.AutocertCacheDir(), err)
+		}
+		if cfg.Port != 443 && cfg.RedirectAddress() != ":80" {
+			logger.Warn("Let's Encrypt validates domains on ports 443 and 80; forward one of them to infoscope", "port", cfg.Port)
+		}
+	}
+	if addr := cfg.RedirectAddress(); addr != "" {
+		if ln, err := net.Listen("tcp", addr); err != nil {
+			fail("cannot listen on %s for the HTTP redirect (%v); binding ports below 1024 needs root or CAP_NET_BIND_SERVICE, or choose another with -http-redirect-port", addr, err)
+		} else {
+			ln.Close()
+		}
+	}
+
+	// Asset storage; the bucket itself is reached once startup gets to it
+	switch cfg.Storage {
+	case "local":
+	case "s3":
+		if _, err := storage.NewS3(s3Config(cfg)); err != nil {
+			fail("%v; set INFOSCOPE_S3_BUCKET, INFOSCOPE_S3_ACCESS_KEY_ID and INFOSCOPE_S3_SECRET_ACCESS_KEY, and INFOSCOPE_S3_ENDPOINT for services other than AWS", err)
+		}
+	default:
+		fail("unknown storage %q; set INFOSCOPE_STORAGE to local or s3", cfg.Storage)
+	}
+
+	// Reverse proxies
+	if _, err := cfg.TrustedProxyPrefixes(); err != nil {
+		fail("%v; set INFOSCOPE_TRUSTED_PROXIES to a comma separated list such as 127.0.0.1,10.0.0.0/8", err)
+	}
+
+	// System clock
+	if now := time.Now(); now.Before(clockFloor) {
+		fail("system clock reads %s, which is in the past; sync it with NTP before starting", now.Format(time.RFC3339))
+	}
+
+	for _, problem := range problems {
+		logger.Error("Preflight check failed", "problem", problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d preflight checks failed", len(problems))
+	}
+	return nil
+}
+
+// checkWritable creates dir if needed and writes a file to it
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".preflight-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkSQLite verifies the linked SQLite is recent enough for the schema
+// and returns its version
+func checkSQLite() (string, error) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return "", fmt.Errorf("SQLite driver unavailable (%v); build infoscope with CGO_ENABLED=1", err)
+	}
+	defer db.Close()
+
+	var version string
+	if err := db.QueryRow("SELECT sqlite_version()").Scan(&version); err != nil {
+		return "", fmt.Errorf("SQLite is not usable (%v); build infoscope with CGO_ENABLED=1", err)
+	}
+	if compareVersions(version, minSQLiteVersion) < 0 {
+		return version, fmt.Errorf("SQLite %s is too old, %s or newer is required; rebuild against a newer SQLite", version, minSQLiteVersion)
+	}
+	return version, nil
+}
+
+// checkFTS5 reports whether the SQLite build includes full-text search
+func checkFTS5() bool {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return false
+	}
+	defer db.Close()
+	_, err = db.Exec("CREATE VIRTUAL TABLE preflight USING fts5(body)")
+	return err == nil
+}
+
+// checkWAL verifies a database in dir can use write-ahead logging, which
+// needs shared memory that some network filesystems do not provide
+func checkWAL(dir string) error {
+	f, err := os.CreateTemp(dir, ".preflight-*.db")
+	if err != nil {
+		return err
+	}
+	path := f.Name()
+	f.Close()
+	defer func() {
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			os.Remove(path + suffix)
+		}
+	}()
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var mode string
+	if err := db.QueryRow("PRAGMA journal_mode=WAL").Scan(&mode); err != nil {
+		return fmt.Errorf("WAL journaling failed in %s (%v)", dir, err)
+	}
+	if mode != "wal" {
+		return fmt.Errorf("WAL journaling is not supported in %s", dir)
+	}
+	return nil
+}
+
+// compareVersions compares dotted numeric versions, returning -1, 0 or 1
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,57 @@
+// cmd/infoscope/reload.go
+package main
+
+import (
+	"infoscope/internal/database"
+	"infoscope/internal/logging"
+	"infoscope/internal/server"
+	"infoscope/internal/systemd"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// reloadOnHangup reloads the configuration each time the process gets
+// SIGHUP, until it exits
+func reloadOnHangup(srv *server.Server, db *database.DB, logger *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		systemd.Notify("RELOADING=1")
+		reload(srv, db, logger)
+		systemd.Notify("READY=1")
+	}
+}
+
+// reload re-reads the config file and environment and applies the values
+// that are safe to change while running: the log level, the login limits
+// and the database pool. Everything else needs a restart. Feed fetching is not touched,
+// so a cycle in progress carries on; fetch settings kept in the database
+// are read afresh by each cycle anyway.
+func reload(srv *server.Server, db *database.DB, logger *slog.Logger) {
+	cfg, err := loadConfig()
+	if err != nil {
+		logger.Error("Failed to reload configuration", "error", err)
+		return
+	}
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		logger.Error("Failed to reload configuration", "error", err)
+		return
+	}
+	logLevelVar.Set(level)
+	refill := time.Duration(cfg.LoginRefill) * time.Second
+	srv.SetLoginLimits(cfg.LoginBurst, refill)
+	pool := poolConfig(cfg)
+	db.SetPool(pool)
+	logger.Info("Reloaded configuration",
+		"log_level", level,
+		"login_burst", cfg.LoginBurst,
+		"login_refill", refill,
+		"db_max_open_conns", pool.MaxOpenConns,
+		"db_max_idle_conns", pool.MaxIdleConns,
+		"db_conn_max_lifetime", pool.ConnMaxLifetime,
+		"db_conn_max_idle_time", pool.ConnMaxIdleTime)
+}
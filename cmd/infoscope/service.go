@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"infoscope/internal/config"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kardianos/service"
+)
+
+// serviceStopTimeout is how long a stop request waits for infoscope to
+// finish serving and close the database
+const serviceStopTimeout = 30 * time.Second
+
+// serviceEnv lists the INFOSCOPE_ variables an installed service is given.
+// Service definitions are often readable by every user, so credentials
+// such as the Redis URL and S3 keys are left out; they belong in the config
+// file, which the service reads from -config.
+var serviceEnv = map[string]bool{
+	"INFOSCOPE_ASSETS_IN_DATA":                true,
+	"INFOSCOPE_AUTOCERT_DOMAINS":              true,
+	"INFOSCOPE_AUTOCERT_EMAIL":                true,
+	"INFOSCOPE_CONFIG":                        true,
+	"INFOSCOPE_DATA_PATH":                     true,
+	"INFOSCOPE_DB_CONN_MAX_IDLE_TIME":         true,
+	"INFOSCOPE_DB_CONN_MAX_LIFETIME":          true,
+	"INFOSCOPE_DB_MAX_IDLE_CONNS":             true,
+	"INFOSCOPE_DB_MAX_OPEN_CONNS":             true,
+	"INFOSCOPE_DB_PATH":                       true,
+	"INFOSCOPE_FETCH_DISABLE_HTTP2":           true,
+	"INFOSCOPE_FETCH_IDLE_CONN_TIMEOUT":       true,
+	"INFOSCOPE_FETCH_MAX_CONNS_PER_HOST":      true,
+	"INFOSCOPE_FETCH_MAX_IDLE_CONNS":          true,
+	"INFOSCOPE_FETCH_MAX_IDLE_CONNS_PER_HOST": true,
+	"INFOSCOPE_FETCH_TLS_SESSION_CACHE":       true,
+	"INFOSCOPE_HOOK_SCRIPT":                   true,
+	"INFOSCOPE_HTTP_REDIRECT_PORT":            true,
+	"INFOSCOPE_LOGIN_BURST":                   true,
+	"INFOSCOPE_LOGIN_REFILL":                  true,
+	"INFOSCOPE_LOG_FORMAT":                    true,
+	"INFOSCOPE_LOG_LEVEL":                     true,
+	"INFOSCOPE_NO_TEMPLATE_UPDATES":           true,
+	"INFOSCOPE_PORT":                          true,
+	"INFOSCOPE_PRODUCTION":                    true,
+	"INFOSCOPE_READONLY":                      true,
+	"INFOSCOPE_S3_BUCKET":                     true,
+	"INFOSCOPE_S3_ENDPOINT":                   true,
+	"INFOSCOPE_S3_PREFIX":                     true,
+	"INFOSCOPE_S3_REGION":                     true,
+	"INFOSCOPE_STATSD_ADDR":                   true,
+	"INFOSCOPE_STATSD_INTERVAL":               true,
+	"INFOSCOPE_STATSD_PREFIX":                 true,
+	"INFOSCOPE_STATSD_TAGS":                   true,
+	"INFOSCOPE_STORAGE":                       true,
+	"INFOSCOPE_TLS_CERT":                      true,
+	"INFOSCOPE_TLS_KEY":                       true,
+	"INFOSCOPE_TRUSTED_PROXIES":               true,
+}
+
+// program runs infoscope under the platform's service manager: the
+// Windows service control manager, launchd or systemd
+type program struct {
+	cfg    config.Config
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	ln   net.Listener
+	done chan struct{}
+}
+
+func (p *program) Start(service.Service) error {
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		run(p.cfg, p.logger, p.listening)
+	}()
+	return nil
+}
+
+// listening remembers the listener so Stop can close it
+func (p *program) listening(ln net.Listener) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ln = ln
+}
+
+// Stop closes the listener, which ends run, and waits for it to clean up
+func (p *program) Stop(service.Service) error {
+	p.logger.Info("Stopping service")
+	p.mu.Lock()
+	if p.ln != nil {
+		p.ln.Close()
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+	case <-time.After(serviceStopTimeout):
+		p.logger.Warn("Service did not stop in time", "timeout", serviceStopTimeout)
+	}
+	return nil
+}
+
+// newService describes infoscope to the service manager. A service starts
+// in another directory and without the installing shell's environment, so
+// the data, database, web and config paths are made absolute and the
+// INFOSCOPE_ variables in serviceEnv are carried over, along with any other
+// flags given.
+func newService(p *program, args []string) (service.Service, error) {
+	dirs := map[string]string{
+		"-data": p.cfg.DataPath,
+		"-db":   p.cfg.DBPath,
+		"-web":  p.cfg.WebPath,
+	}
+	args = serviceArgs(args)
+	for _, name := range []string{"-data", "-db", "-web"} {
+		abs, err := filepath.Abs(dirs[name])
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, name, abs)
+	}
+	if *configFile != "" {
+		abs, err := filepath.Abs(*configFile)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "-config", abs)
+	}
+
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		name, value, ok := strings.Cut(kv, "=")
+		switch {
+		case !ok || !strings.HasPrefix(name, "INFOSCOPE_"):
+		case serviceEnv[name]:
+			env[name] = value
+		default:
+			p.logger.Warn("Not writing variable into the service definition, set it in the config file instead", "name", name)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	// Dependencies are unit lines for systemd but service names on Windows
+	var deps []string
+	if runtime.GOOS == "linux" {
+		deps = []string{"After=network-online.target", "Wants=network-online.target"}
+	}
+
+	return service.New(p, &service.Config{
+		Name:             "infoscope",
+		DisplayName:      "Infoscope",
+		Description:      "Infoscope RSS river and curation server",
+		Arguments:        args,
+		WorkingDirectory: wd,
+		EnvVars:          env,
+		Dependencies:     deps,
+		Option: service.KeyValue{
+			"RunAtLoad": true,
+			"Restart":   "on-failure",
+		},
+	})
+}
+
+// serviceArgs drops -service and its value from the command line, keeping
+// the flags the installed service should run with
+func serviceArgs(args []string) []string {
+	kept := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		name := strings.TrimLeft(args[i], "-")
+		switch {
+		case name == "service":
+			i++
+		case strings.HasPrefix(name, "service="):
+		default:
+			kept = append(kept, args[i])
+		}
+	}
+	return kept
+}
+
+// controlService installs, uninstalls, starts, stops or restarts the
+// installed service
+func controlService(svc service.Service, action string) error {
+	valid := false
+	for _, a := range service.ControlAction {
+		valid = valid || a == action
+	}
+	if !valid {
+		return fmt.Errorf("unknown -service action %q; use one of %s", action, strings.Join(service.ControlAction[:], ", "))
+	}
+	return service.Control(svc, action)
+}
+
+// underServiceManager reports whether a service manager without a console
+// started infoscope, so its output would be lost. systemd keeps it in the
+// journal, so only other platforms log to a file.
+func underServiceManager() bool {
+	return runtime.GOOS != "linux" && !service.Interactive()
+}
+
+// serviceLogPath is where infoscope logs when underServiceManager
+func serviceLogPath(cfg config.Config) string {
+	return filepath.Join(cfg.DataPath, "logs", "infoscope.log")
+}
+
+// openServiceLog opens the service log for appending
+func openServiceLog(cfg config.Config) (*os.File, error) {
+	path := serviceLogPath(cfg)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
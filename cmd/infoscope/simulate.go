@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"infoscope/internal/config"
+	"infoscope/internal/database"
+	"infoscope/internal/feed"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+)
+
+// simulateTimeout bounds a simulated fetch, download included
+const simulateTimeout = time.Minute
+
+// simulateFetch runs the simulate-fetch command: it puts a feed URL or file
+// through a fetch against the configured database without storing
+// anything, and prints each entry with what the fetch would do with it.
+// Without a database the feed is treated as a new subscription under the
+// default settings. It returns the exit code.
+func simulateFetch(cfg config.Config, logger *slog.Logger, args []string) int {
+	fs := flag.NewFlagSet("simulate-fetch", flag.ContinueOnError)
+	feedID := fs.Int64("feed", 0, "Check against this subscribed feed (default: the feed with the URL given, if any)")
+	asJSON := fs.Bool("json", false, "Print the result as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: infoscope [flags] simulate-fetch [-feed ID] [-json] <url-or-file>\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	db, cleanup, err := simulationDB(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "infoscope: %v\n", err)
+		return 1
+	}
+	defer cleanup()
+
+	feedService := feed.NewService(db.DB, logger.With("component", "feed"), nil)
+	feedService.ConfigureTransport(transportConfig(cfg))
+
+	ctx, cancel := context.WithTimeout(context.Background(), simulateTimeout)
+	defer cancel()
+	sim, err := feedService.Simulate(ctx, fs.Arg(0), *feedID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "infoscope: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(sim); err != nil {
+			fmt.Fprintf(os.Stderr, "infoscope: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	printSimulation(os.Stdout, sim)
+	return 0
+}
+
+// simulationDB opens the configured database read-only, or a scratch one
+// with the default settings when there is none yet
+func simulationDB(cfg config.Config) (*database.DB, func(), error) {
+	if _, err := os.Stat(cfg.DBPath); err == nil {
+		dbConfig := poolConfig(cfg)
+		dbConfig.ReadOnly = true
+		db, err := database.NewDB(cfg.DBPath, dbConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening database: %w", err)
+		}
+		return db, func() { db.Close() }, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "infoscope-simulate-")
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := database.NewDB(filepath.Join(dir, "simulate.db"), database.DefaultConfig())
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, fmt.Errorf("creating scratch database: %w", err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// printSimulation writes a simulation as a table with a count of each
+// decision
+func printSimulation(out io.Writer, sim *feed.Simulation) {
+	if sim.Feed.ID != 0 {
+		fmt.Fprintf(out, "Feed: %s (subscribed as feed %d)\n", sim.Title, sim.Feed.ID)
+	} else {
+		fmt.Fprintf(out, "Feed: %s (not subscribed)\n", sim.Title)
+	}
+	if sim.Language != "" {
+		fmt.Fprintf(out, "Language: %s\n", sim.Language)
+	}
+	fmt.Fprintln(out)
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DECISION\tPUBLISHED\tTITLE\tURL\tREASON")
+	counts := make(map[string]int)
+	var order []string
+	for _, e := range sim.Entries {
+		if counts[e.Decision] == 0 {
+			order = append(order, e.Decision)
+		}
+		counts[e.Decision]++
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Decision,
+			e.PublishedAt.UTC().Format("2006-01-02 15:04"), e.Title, e.URL, e.Reason)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(out, "\n%d items", len(sim.Entries))
+	for _, decision := range order {
+		fmt.Fprintf(out, ", %d %s", counts[decision], decision)
+	}
+	fmt.Fprintln(out)
+}
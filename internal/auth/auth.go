@@ -18,10 +18,13 @@ var (
 )
 
 type User struct {
-	ID           int64
-	Username     string
-	PasswordHash string
-	CreatedAt    time.Time
+	ID           int64      `json:"id"`
+	Username     string     `json:"username"`
+	PasswordHash string     `json:"-"`
+	Role         string     `json:"role"`
+	LastLogin    *time.Time `json:"lastLogin"`
+	LockedUntil  *time.Time `json:"lockedUntil,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
 }
 
 type Session struct {
@@ -31,20 +34,28 @@ type Session struct {
 	ExpiresAt time.Time
 }
 
-// CreateUser creates a new admin user with a hashed password
-func CreateUser(db *sql.DB, username, password string) error {
+// CreateUser creates an account with a hashed password and the given role,
+// returning its ID
+func CreateUser(db *sql.DB, username, password, role string) (int64, error) {
 	// Hash password with bcrypt
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Insert user into database
-	_, err = db.Exec(
-		"INSERT INTO admin_users (username, password_hash) VALUES (?, ?)",
-		username, string(hash),
+	res, err := db.Exec(
+		`INSERT INTO admin_users (username, password_hash, role) VALUES (?, ?, ?)
+         ON CONFLICT(username) DO NOTHING`,
+		username, string(hash), role,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, ErrUsernameTaken
+	}
+	return res.LastInsertId()
 }
 
 // Authenticate verifies username and password, returns a new session if successful
@@ -0,0 +1,71 @@
+// internal/auth/lockout.go
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// MaxLoginAttempts is how many wrong passwords in a row lock an account
+	MaxLoginAttempts = 5
+
+	// LockoutDuration is how long the first lockout lasts. Each further
+	// wrong password once it ends doubles it, up to MaxLockoutDuration.
+	LockoutDuration    = 15 * time.Minute
+	MaxLockoutDuration = 24 * time.Hour
+)
+
+var ErrAccountLocked = errors.New("account locked after repeated failed logins")
+
+// LockoutError is returned for an account locked after repeated failed
+// logins. It matches ErrAccountLocked.
+type LockoutError struct {
+	Until time.Time
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s until %s", ErrAccountLocked, e.Until.Format(time.RFC3339))
+}
+
+func (e *LockoutError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
+// lockoutFor is how long an account is locked after failures wrong
+// passwords in a row, or zero when it isn't
+func lockoutFor(failures int) time.Duration {
+	if failures < MaxLoginAttempts {
+		return 0
+	}
+	d := LockoutDuration
+	for i := MaxLoginAttempts; i < failures && d < MaxLockoutDuration; i++ {
+		d *= 2
+	}
+	return min(d, MaxLockoutDuration)
+}
+
+// recordLoginFailure counts a wrong password against an account, locking
+// it once there have been too many
+func recordLoginFailure(ctx context.Context, db *sql.DB, id int64, failures int) error {
+	failures++
+	var lockedUntil any
+	if d := lockoutFor(failures); d > 0 {
+		lockedUntil = time.Now().Add(d).UTC()
+	}
+	_, err := db.ExecContext(ctx, `
+        UPDATE admin_users SET login_attempts = ?, locked_until = COALESCE(?, locked_until)
+        WHERE id = ?`, failures, lockedUntil, id)
+	return err
+}
+
+// ClearLoginFailures unlocks an account and forgets its failed logins,
+// such as once its owner has signed in with a passkey
+func ClearLoginFailures(ctx context.Context, db *sql.DB, id int64) error {
+	_, err := db.ExecContext(ctx,
+		"UPDATE admin_users SET login_attempts = 0, locked_until = NULL WHERE id = ?", id)
+	return err
+}
@@ -0,0 +1,147 @@
+// internal/auth/passkeys.go
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+var ErrPasskeyNotFound = errors.New("passkey not found")
+
+// Passkey is a WebAuthn credential registered to an account
+type Passkey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// PasskeyUser is an account along with its passkeys, as the WebAuthn
+// ceremonies see it
+type PasskeyUser struct {
+	User
+	Credentials []webauthn.Credential
+}
+
+func (u *PasskeyUser) WebAuthnID() []byte                         { return PasskeyUserHandle(u.ID) }
+func (u *PasskeyUser) WebAuthnName() string                       { return u.Username }
+func (u *PasskeyUser) WebAuthnDisplayName() string                { return u.Username }
+func (u *PasskeyUser) WebAuthnIcon() string                       { return "" }
+func (u *PasskeyUser) WebAuthnCredentials() []webauthn.Credential { return u.Credentials }
+
+// PasskeyUserHandle is the opaque handle an authenticator stores for an
+// account and hands back when signing in without a username
+func PasskeyUserHandle(id int64) []byte {
+	return []byte(strconv.FormatInt(id, 10))
+}
+
+// GetPasskeyUser looks up an account and its passkeys by ID
+func GetPasskeyUser(ctx context.Context, db *sql.DB, id int64) (*PasskeyUser, error) {
+	user, err := GetUser(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx,
+		"SELECT credential FROM webauthn_credentials WHERE user_id = ? ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passkeyUser := &PasskeyUser{User: *user}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var cred webauthn.Credential
+		if err := json.Unmarshal([]byte(data), &cred); err != nil {
+			return nil, err
+		}
+		passkeyUser.Credentials = append(passkeyUser.Credentials, cred)
+	}
+	return passkeyUser, rows.Err()
+}
+
+// GetPasskeyUserByHandle looks up the account an authenticator's user
+// handle names
+func GetPasskeyUserByHandle(ctx context.Context, db *sql.DB, handle []byte) (*PasskeyUser, error) {
+	id, err := strconv.ParseInt(string(handle), 10, 64)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return GetPasskeyUser(ctx, db, id)
+}
+
+// ListPasskeys returns an account's passkeys, oldest first
+func ListPasskeys(ctx context.Context, db *sql.DB, userID int64) ([]Passkey, error) {
+	rows, err := db.QueryContext(ctx, `
+        SELECT id, name, created_at, last_used_at
+        FROM webauthn_credentials WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passkeys := make([]Passkey, 0)
+	for rows.Next() {
+		var p Passkey
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&p.ID, &p.Name, &p.CreatedAt, &lastUsed); err != nil {
+			return nil, err
+		}
+		if lastUsed.Valid {
+			p.LastUsedAt = &lastUsed.Time
+		}
+		passkeys = append(passkeys, p)
+	}
+	return passkeys, rows.Err()
+}
+
+// AddPasskey stores a newly registered credential for an account
+func AddPasskey(ctx context.Context, db *sql.DB, userID int64, name string, cred *webauthn.Credential) (int64, error) {
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.ExecContext(ctx, `
+        INSERT INTO webauthn_credentials (user_id, credential_id, name, credential)
+        VALUES (?, ?, ?, ?)`, userID, cred.ID, name, string(data))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UsePasskey records a sign-in with a credential, keeping its signature
+// counter current so a cloned authenticator can be spotted
+func UsePasskey(ctx context.Context, db *sql.DB, cred *webauthn.Credential) error {
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `
+        UPDATE webauthn_credentials SET credential = ?, last_used_at = CURRENT_TIMESTAMP
+        WHERE credential_id = ?`, string(data), cred.ID)
+	return err
+}
+
+// DeletePasskey removes one of an account's passkeys
+func DeletePasskey(ctx context.Context, db *sql.DB, userID, id int64) error {
+	res, err := db.ExecContext(ctx,
+		"DELETE FROM webauthn_credentials WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrPasskeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// internal/auth/redis.go
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix namespaces session keys in a shared Redis
+const redisKeyPrefix = "infoscope:session:"
+
+// redisUserPrefix names the set of an account's session IDs, so they can
+// all be revoked together
+const redisUserPrefix = "infoscope:user-sessions:"
+
+// RedisStore keeps sessions in Redis so several instances can share them.
+// Keys expire with their sessions, so nothing needs cleaning up.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore connects to the Redis at rawURL, such as
+// redis://:password@host:6379/0, and checks that it answers.
+func NewRedisStore(ctx context.Context, rawURL string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("error connecting to redis: %w", err)
+	}
+	return &RedisStore{client: client}, nil
+}
+
+func (s *RedisStore) Save(ctx context.Context, session *Session) error {
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	userKey := redisUserKey(session.UserID)
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisKeyPrefix+session.ID, data, ttl)
+		pipe.SAdd(ctx, userKey, session.ID)
+		// Sessions all last as long, so the newest outlives the rest
+		pipe.Expire(ctx, userKey, ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (*Session, error) {
+	data, err := s.client.Get(ctx, redisKeyPrefix+id).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	if session.IsExpired() {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	return s.client.Del(ctx, redisKeyPrefix+id).Err()
+}
+
+func (s *RedisStore) DeleteByUser(ctx context.Context, userID int64) error {
+	userKey := redisUserKey(userID)
+	ids, err := s.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{userKey}
+	for _, id := range ids {
+		keys = append(keys, redisKeyPrefix+id)
+	}
+	return s.client.Del(ctx, keys...).Err()
+}
+
+func redisUserKey(userID int64) string {
+	return redisUserPrefix + strconv.FormatInt(userID, 10)
+}
+
+// Close releases the connection pool
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -2,43 +2,68 @@
 package auth
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-type Service struct{}
+type Service struct {
+	store SessionStore
+}
 
-func NewService() *Service {
-	return &Service{}
+// NewService creates an auth service keeping its sessions in store
+func NewService(store SessionStore) *Service {
+	return &Service{store: store}
 }
 
-func (s *Service) Authenticate(db *sql.DB, username, password string) (*Session, error) {
+// Authenticate checks a username and password and signs the account in.
+// Too many wrong passwords in a row lock the account for a while, during
+// which it fails with a *LockoutError even for the right one.
+func (s *Service) Authenticate(ctx context.Context, db *sql.DB, username, password string) (*Session, error) {
 	var user struct {
 		id           int64
 		passwordHash string
+		failures     int
+		lockedUntil  sql.NullTime
 	}
 
-	err := db.QueryRow(
-		"SELECT id, password_hash FROM admin_users WHERE username = ?",
+	err := db.QueryRowContext(ctx,
+		"SELECT id, password_hash, COALESCE(login_attempts, 0), locked_until FROM admin_users WHERE username = ?",
 		username,
-	).Scan(&user.id, &user.passwordHash)
+	).Scan(&user.id, &user.passwordHash, &user.failures, &user.lockedUntil)
 
 	if err != nil {
 		return nil, ErrInvalidCredentials
 	}
+	if user.lockedUntil.Valid && user.lockedUntil.Time.After(time.Now()) {
+		return nil, &LockoutError{Until: user.lockedUntil.Time}
+	}
 
 	if err := bcrypt.CompareHashAndPassword(
 		[]byte(user.passwordHash),
 		[]byte(password),
 	); err != nil {
+		if err := recordLoginFailure(ctx, db, user.id, user.failures); err != nil {
+			return nil, err
+		}
 		return nil, ErrInvalidCredentials
 	}
 
-	// Create new session
+	if user.failures > 0 {
+		if err := ClearLoginFailures(ctx, db, user.id); err != nil {
+			return nil, err
+		}
+	}
+	return s.StartSession(ctx, user.id)
+}
+
+// StartSession signs an account in once it has proven who it is, such as
+// with a passkey
+func (s *Service) StartSession(ctx context.Context, userID int64) (*Session, error) {
 	session := &Session{
-		UserID:    user.id,
+		UserID:    userID,
 		CreatedAt: time.Now(),
 		ExpiresAt: time.Now().Add(24 * time.Hour),
 	}
@@ -50,38 +75,24 @@ func (s *Service) Authenticate(db *sql.DB, username, password string) (*Session,
 	}
 	session.ID = sessionID
 
-	// Save session to database
-	_, err = db.Exec(
-		"INSERT INTO sessions (id, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)",
-		session.ID, session.UserID, session.CreatedAt, session.ExpiresAt,
-	)
-	if err != nil {
+	// Save session to the store
+	if err := s.store.Save(ctx, session); err != nil {
 		return nil, err
 	}
 
 	return session, nil
 }
 
-func (s *Service) ValidateSession(db *sql.DB, sessionID string) (*Session, error) {
-	var session Session
-	err := db.QueryRow(
-		`SELECT id, user_id, created_at, expires_at 
-         FROM sessions 
-         WHERE id = ? AND expires_at > ?`,
-		sessionID, time.Now(),
-	).Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.ExpiresAt)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrSessionNotFound
-		}
-		return nil, err
-	}
+func (s *Service) ValidateSession(ctx context.Context, sessionID string) (*Session, error) {
+	return s.store.Get(ctx, sessionID)
+}
 
-	return &session, nil
+func (s *Service) InvalidateSession(ctx context.Context, sessionID string) error {
+	return s.store.Delete(ctx, sessionID)
 }
 
-func (s *Service) InvalidateSession(db *sql.DB, sessionID string) error {
-	_, err := db.Exec("DELETE FROM sessions WHERE id = ?", sessionID)
-	return err
+// InvalidateUserSessions signs an account out everywhere, as when its
+// password changes
+func (s *Service) InvalidateUserSessions(ctx context.Context, userID int64) error {
+	return s.store.DeleteByUser(ctx, userID)
 }
@@ -0,0 +1,67 @@
+// internal/auth/store.go
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SessionStore persists admin sessions. The SQLite store serves a single
+// instance; instances behind a load balancer share one in Redis.
+type SessionStore interface {
+	// Save stores a session until its ExpiresAt
+	Save(ctx context.Context, session *Session) error
+	// Get returns an unexpired session, or ErrSessionNotFound
+	Get(ctx context.Context, id string) (*Session, error)
+	// Delete removes a session; deleting a missing one is not an error
+	Delete(ctx context.Context, id string) error
+	// DeleteByUser removes every session of an account
+	DeleteByUser(ctx context.Context, userID int64) error
+}
+
+// SQLStore keeps sessions in the sessions table
+type SQLStore struct {
+	db *sql.DB
+}
+
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+func (s *SQLStore) Save(ctx context.Context, session *Session) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO sessions (id, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)",
+		session.ID, session.UserID, session.CreatedAt, session.ExpiresAt,
+	)
+	return err
+}
+
+func (s *SQLStore) Get(ctx context.Context, id string) (*Session, error) {
+	var session Session
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, user_id, created_at, expires_at 
+         FROM sessions 
+         WHERE id = ? AND expires_at > ?`,
+		id, time.Now(),
+	).Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.ExpiresAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (s *SQLStore) Delete(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", id)
+	return err
+}
+
+func (s *SQLStore) DeleteByUser(ctx context.Context, userID int64) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID)
+	return err
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"infoscope/internal/database"
+)
+
+func TestSQLStoreDeleteByUser(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "test.db"), database.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("INSERT INTO admin_users (id, username, password_hash) VALUES (1, 'a', 'x'), (2, 'b', 'x')"); err != nil {
+		t.Fatalf("Failed to seed users: %v", err)
+	}
+
+	ctx := context.Background()
+	service := NewService(NewSQLStore(db.DB))
+	var sessions []*Session
+	for _, userID := range []int64{1, 1, 2} {
+		session, err := service.StartSession(ctx, userID)
+		if err != nil {
+			t.Fatalf("StartSession failed: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := service.InvalidateUserSessions(ctx, 1); err != nil {
+		t.Fatalf("InvalidateUserSessions failed: %v", err)
+	}
+	for _, session := range sessions[:2] {
+		if _, err := service.ValidateSession(ctx, session.ID); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Session of the changed account still valid: %v", err)
+		}
+	}
+	if _, err := service.ValidateSession(ctx, sessions[2].ID); err != nil {
+		t.Errorf("Session of another account revoked: %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+// internal/auth/users.go
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Roles an account can hold. Each role can do everything the roles before
+// it can: viewers see the admin pages, editors also manage feeds and
+// entries, and admins also manage settings and accounts.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Roles lists the roles from least to most privileged
+var Roles = []string{RoleViewer, RoleEditor, RoleAdmin}
+
+// MinPasswordLength is the shortest password an account may have
+const MinPasswordLength = 8
+
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUsernameTaken = errors.New("username already taken")
+	ErrLastAdmin     = errors.New("the last admin can't be removed or demoted")
+)
+
+// roleRank orders the roles for RoleAllows
+var roleRank = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleAdmin: 3}
+
+// ValidRole reports whether role is one of Roles
+func ValidRole(role string) bool {
+	return roleRank[role] > 0
+}
+
+// RoleAllows reports whether an account with role may do what required
+// permits. Unknown roles are allowed nothing.
+func RoleAllows(role, required string) bool {
+	rank := roleRank[role]
+	return rank > 0 && rank >= roleRank[required]
+}
+
+// userColumns are the account columns scanUser reads
+const userColumns = "id, username, role, last_login, locked_until, created_at"
+
+// scanUser reads an account selected with userColumns. LockedUntil is
+// only set while the lockout lasts.
+func scanUser(row interface{ Scan(...any) error }) (*User, error) {
+	var user User
+	var lastLogin, lockedUntil sql.NullTime
+	if err := row.Scan(&user.ID, &user.Username, &user.Role, &lastLogin, &lockedUntil, &user.CreatedAt); err != nil {
+		return nil, err
+	}
+	if lastLogin.Valid {
+		user.LastLogin = &lastLogin.Time
+	}
+	if lockedUntil.Valid && lockedUntil.Time.After(time.Now()) {
+		user.LockedUntil = &lockedUntil.Time
+	}
+	return &user, nil
+}
+
+// GetUser looks up an account by ID
+func GetUser(ctx context.Context, db *sql.DB, id int64) (*User, error) {
+	user, err := scanUser(db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM admin_users WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// ListUsers returns every account, ordered by username
+func ListUsers(ctx context.Context, db *sql.DB) ([]User, error) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT "+userColumns+" FROM admin_users ORDER BY username COLLATE NOCASE")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// SetRole changes an account's role. The last admin keeps theirs, so the
+// instance can always be administered.
+func SetRole(ctx context.Context, db *sql.DB, id int64, role string) error {
+	return changeAdmins(ctx, db, id, role != RoleAdmin, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx,
+			"UPDATE admin_users SET role = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", role, id)
+	})
+}
+
+// DeleteUser removes an account along with its sessions. The last admin
+// can't be deleted.
+func DeleteUser(ctx context.Context, db *sql.DB, id int64) error {
+	return changeAdmins(ctx, db, id, true, func(tx *sql.Tx) (sql.Result, error) {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", id); err != nil {
+			return nil, err
+		}
+		return tx.ExecContext(ctx, "DELETE FROM admin_users WHERE id = ?", id)
+	})
+}
+
+// changeAdmins runs change against account id in a transaction, refusing
+// with ErrLastAdmin when it would take away the only admin.
+func changeAdmins(ctx context.Context, db *sql.DB, id int64, removesAdmin bool, change func(*sql.Tx) (sql.Result, error)) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if removesAdmin {
+		var role string
+		var admins int
+		err := tx.QueryRowContext(ctx, `
+            SELECT role, (SELECT COUNT(*) FROM admin_users WHERE role = ?)
+            FROM admin_users WHERE id = ?`, RoleAdmin, id).Scan(&role, &admins)
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if role == RoleAdmin && admins <= 1 {
+			return ErrLastAdmin
+		}
+	}
+
+	res, err := change(tx)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	return tx.Commit()
+}
+
+// SetPassword replaces an account's password, lifting any lockout from
+// failed logins
+func SetPassword(ctx context.Context, db *sql.DB, id int64, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	res, err := db.ExecContext(ctx, `
+        UPDATE admin_users SET password_hash = ?, login_attempts = 0, locked_until = NULL,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = ?`,
+		string(hash), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// CheckPassword verifies an account's current password
+func CheckPassword(ctx context.Context, db *sql.DB, id int64, password string) error {
+	var hash string
+	err := db.QueryRowContext(ctx, "SELECT password_hash FROM admin_users WHERE id = ?", id).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
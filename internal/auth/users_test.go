@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"infoscope/internal/database"
+)
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role, required string
+		want           bool
+	}{
+		{RoleViewer, RoleViewer, true},
+		{RoleViewer, RoleEditor, false},
+		{RoleViewer, RoleAdmin, false},
+		{RoleEditor, RoleViewer, true},
+		{RoleEditor, RoleEditor, true},
+		{RoleEditor, RoleAdmin, false},
+		{RoleAdmin, RoleViewer, true},
+		{RoleAdmin, RoleEditor, true},
+		{RoleAdmin, RoleAdmin, true},
+		{"", RoleViewer, false},
+		{"superuser", RoleViewer, false},
+	}
+	for _, tt := range tests {
+		if got := RoleAllows(tt.role, tt.required); got != tt.want {
+			t.Errorf("RoleAllows(%q, %q) = %v, want %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestLastAdminGuard(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "test.db"), database.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO admin_users (id, username, password_hash, role)
+        VALUES (1, 'admin', 'x', 'admin'), (2, 'editor', 'x', 'editor')`); err != nil {
+		t.Fatalf("Failed to seed users: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := SetRole(ctx, db.DB, 1, RoleEditor); !errors.Is(err, ErrLastAdmin) {
+		t.Errorf("Demoting the only admin: err = %v, want ErrLastAdmin", err)
+	}
+	if err := DeleteUser(ctx, db.DB, 1); !errors.Is(err, ErrLastAdmin) {
+		t.Errorf("Deleting the only admin: err = %v, want ErrLastAdmin", err)
+	}
+	if user, err := GetUser(ctx, db.DB, 1); err != nil || user.Role != RoleAdmin {
+		t.Fatalf("Only admin is now %+v (%v), want it untouched", user, err)
+	}
+
+	// With a second admin either may go
+	if err := SetRole(ctx, db.DB, 2, RoleAdmin); err != nil {
+		t.Fatalf("Promoting the editor failed: %v", err)
+	}
+	if err := SetRole(ctx, db.DB, 1, RoleViewer); err != nil {
+		t.Errorf("Demoting one of two admins failed: %v", err)
+	}
+	if err := DeleteUser(ctx, db.DB, 2); !errors.Is(err, ErrLastAdmin) {
+		t.Errorf("Deleting the remaining admin: err = %v, want ErrLastAdmin", err)
+	}
+	if err := DeleteUser(ctx, db.DB, 1); err != nil {
+		t.Errorf("Deleting a viewer failed: %v", err)
+	}
+	if err := SetRole(ctx, db.DB, 99, RoleViewer); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Changing a missing account: err = %v, want ErrUserNotFound", err)
+	}
+}
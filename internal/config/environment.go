@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -13,9 +16,98 @@ type Config struct {
 	WebPath                string
 	ProductionMode         bool
 	DisableTemplateUpdates bool
+	ReadOnly               bool
+	AssetsInData           bool
+
+	// Feed fetcher transport tuning; zero values keep the built-in defaults
+	FetchMaxIdleConns        int
+	FetchMaxIdleConnsPerHost int
+	FetchMaxConnsPerHost     int
+	FetchIdleConnTimeout     int // seconds
+	FetchTLSSessionCache     int
+	FetchDisableHTTP2        bool
+
+	// Database connection pool; zero values keep the built-in defaults
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime int // seconds
+	DBConnMaxIdleTime int // seconds
+
+	// StatsD export; disabled while StatsDAddr is empty
+	StatsDAddr     string
+	StatsDPrefix   string
+	StatsDTags     []string
+	StatsDInterval int // seconds
+
+	// HookScript, when set, is run with a JSON payload on every ingest,
+	// fetch and click event
+	HookScript string
+
+	// RedisURL, when set, keeps admin sessions in Redis so instances behind
+	// a load balancer share them
+	RedisURL string
+
+	// LogLevel is debug, info, warn or error; LogFormat is text or json
+	LogLevel  string
+	LogFormat string
+	// HTTPS served directly, from TLSCert and TLSKey or with certificates
+	// for AutocertDomains from Let's Encrypt. HTTPRedirectPort, when set,
+	// redirects plain HTTP to HTTPS; autocert defaults it to 80.
+	TLSCert          string
+	TLSKey           string
+	AutocertDomains  []string
+	AutocertEmail    string
+	HTTPRedirectPort int
+
+	// TrustedProxies are the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For header names the real client
+	TrustedProxies []string
+
+	// LoginBurst sign-in attempts are allowed per client address, then one
+	// more every LoginRefill seconds
+	LoginBurst  int
+	LoginRefill int
+
+	// Storage keeps favicons and uploaded images on the local disk at
+	// AssetsPath ("local") or in the S3-compatible bucket below ("s3")
+	Storage     string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3Prefix    string
+	S3AccessKey string
+	S3SecretKey string
 }
 
+// GetConfig reads the configuration from the environment
 func GetConfig() Config {
+	return fromLookup(os.LookupEnv)
+}
+
+// Load reads the configuration from the environment and, when path is set,
+// a file of INFOSCOPE_ variables. The environment wins over the file.
+func Load(path string) (Config, error) {
+	if path == "" {
+		return GetConfig(), nil
+	}
+	file, err := readEnvFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	return fromLookup(func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		value, ok := file[name]
+		return value, ok
+	}), nil
+}
+
+func fromLookup(lookup func(string) (string, bool)) Config {
+	getenv := func(name string) string {
+		value, _ := lookup(name)
+		return value
+	}
 	config := Config{
 		Port:                   8080,
 		DBPath:                 "data/infoscope.db",
@@ -23,33 +115,192 @@ func GetConfig() Config {
 		WebPath:                "web",
 		ProductionMode:         false,
 		DisableTemplateUpdates: false,
+		StatsDPrefix:           "infoscope.",
+		StatsDInterval:         10,
+		LogLevel:               "info",
+		LogFormat:              "text",
+		LoginBurst:             10,
+		LoginRefill:            60,
+		Storage:                "local",
 	}
 
 	// Override with environment variables if present
-	if port := os.Getenv("INFOSCOPE_PORT"); port != "" {
+	if port := getenv("INFOSCOPE_PORT"); port != "" {
 		if p, err := strconv.Atoi(port); err == nil {
 			config.Port = p
 		}
 	}
-	if dbPath := os.Getenv("INFOSCOPE_DB_PATH"); dbPath != "" {
+	if dbPath := getenv("INFOSCOPE_DB_PATH"); dbPath != "" {
 		config.DBPath = dbPath
 	}
-	if dataPath := os.Getenv("INFOSCOPE_DATA_PATH"); dataPath != "" {
+	if dataPath := getenv("INFOSCOPE_DATA_PATH"); dataPath != "" {
 		config.DataPath = dataPath
 	}
-	if webPath := os.Getenv("INFOSCOPE_WEB_PATH"); webPath != "" {
+	if webPath := getenv("INFOSCOPE_WEB_PATH"); webPath != "" {
 		config.WebPath = webPath
 	}
-	if prodMode := os.Getenv("INFOSCOPE_PRODUCTION"); prodMode == "true" {
+	if prodMode := getenv("INFOSCOPE_PRODUCTION"); prodMode == "true" {
 		config.ProductionMode = true
 	}
-	if noUpdates := os.Getenv("INFOSCOPE_NO_TEMPLATE_UPDATES"); noUpdates == "true" {
+	if noUpdates := getenv("INFOSCOPE_NO_TEMPLATE_UPDATES"); noUpdates == "true" {
 		config.DisableTemplateUpdates = true
 	}
 
+	if readOnly := getenv("INFOSCOPE_READONLY"); readOnly == "true" {
+		config.ReadOnly = true
+	}
+	if assetsInData := getenv("INFOSCOPE_ASSETS_IN_DATA"); assetsInData == "true" {
+		config.AssetsInData = true
+	}
+
+	// Fetcher transport and database pool tuning
+	intVars := map[string]*int{
+		"INFOSCOPE_FETCH_MAX_IDLE_CONNS":          &config.FetchMaxIdleConns,
+		"INFOSCOPE_FETCH_MAX_IDLE_CONNS_PER_HOST": &config.FetchMaxIdleConnsPerHost,
+		"INFOSCOPE_FETCH_MAX_CONNS_PER_HOST":      &config.FetchMaxConnsPerHost,
+		"INFOSCOPE_FETCH_IDLE_CONN_TIMEOUT":       &config.FetchIdleConnTimeout,
+		"INFOSCOPE_FETCH_TLS_SESSION_CACHE":       &config.FetchTLSSessionCache,
+		"INFOSCOPE_DB_MAX_OPEN_CONNS":             &config.DBMaxOpenConns,
+		"INFOSCOPE_DB_MAX_IDLE_CONNS":             &config.DBMaxIdleConns,
+		"INFOSCOPE_DB_CONN_MAX_LIFETIME":          &config.DBConnMaxLifetime,
+		"INFOSCOPE_DB_CONN_MAX_IDLE_TIME":         &config.DBConnMaxIdleTime,
+	}
+	for name, target := range intVars {
+		if value := getenv(name); value != "" {
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				*target = n
+			}
+		}
+	}
+	if disableHTTP2 := getenv("INFOSCOPE_FETCH_DISABLE_HTTP2"); disableHTTP2 == "true" {
+		config.FetchDisableHTTP2 = true
+	}
+
+	// StatsD export
+	config.StatsDAddr = getenv("INFOSCOPE_STATSD_ADDR")
+	if prefix, ok := lookup("INFOSCOPE_STATSD_PREFIX"); ok {
+		config.StatsDPrefix = prefix
+	}
+	config.StatsDTags = SplitList(getenv("INFOSCOPE_STATSD_TAGS"))
+	if interval := getenv("INFOSCOPE_STATSD_INTERVAL"); interval != "" {
+		if n, err := strconv.Atoi(interval); err == nil && n > 0 {
+			config.StatsDInterval = n
+		}
+	}
+
+	config.HookScript = getenv("INFOSCOPE_HOOK_SCRIPT")
+	config.RedisURL = getenv("INFOSCOPE_REDIS_URL")
+
+	if level := getenv("INFOSCOPE_LOG_LEVEL"); level != "" {
+		config.LogLevel = level
+	}
+	if format := getenv("INFOSCOPE_LOG_FORMAT"); format != "" {
+		config.LogFormat = format
+	}
+
+	config.TLSCert = getenv("INFOSCOPE_TLS_CERT")
+	config.TLSKey = getenv("INFOSCOPE_TLS_KEY")
+	config.AutocertDomains = SplitList(getenv("INFOSCOPE_AUTOCERT_DOMAINS"))
+	config.AutocertEmail = getenv("INFOSCOPE_AUTOCERT_EMAIL")
+	if port := getenv("INFOSCOPE_HTTP_REDIRECT_PORT"); port != "" {
+		if p, err := strconv.Atoi(port); err == nil && p > 0 {
+			config.HTTPRedirectPort = p
+		}
+	}
+
+	config.TrustedProxies = SplitList(getenv("INFOSCOPE_TRUSTED_PROXIES"))
+	if burst := getenv("INFOSCOPE_LOGIN_BURST"); burst != "" {
+		if n, err := strconv.Atoi(burst); err == nil && n > 0 {
+			config.LoginBurst = n
+		}
+	}
+	if refill := getenv("INFOSCOPE_LOGIN_REFILL"); refill != "" {
+		if n, err := strconv.Atoi(refill); err == nil && n > 0 {
+			config.LoginRefill = n
+		}
+	}
+
+	// Asset storage; the standard AWS variables supply missing credentials
+	if storage := getenv("INFOSCOPE_STORAGE"); storage != "" {
+		config.Storage = strings.ToLower(storage)
+	}
+	config.S3Endpoint = getenv("INFOSCOPE_S3_ENDPOINT")
+	config.S3Region = getenv("INFOSCOPE_S3_REGION")
+	config.S3Bucket = getenv("INFOSCOPE_S3_BUCKET")
+	config.S3Prefix = getenv("INFOSCOPE_S3_PREFIX")
+	config.S3AccessKey = getenv("INFOSCOPE_S3_ACCESS_KEY_ID")
+	config.S3SecretKey = getenv("INFOSCOPE_S3_SECRET_ACCESS_KEY")
+	if config.S3AccessKey == "" && config.S3SecretKey == "" {
+		config.S3AccessKey = getenv("AWS_ACCESS_KEY_ID")
+		config.S3SecretKey = getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if config.S3Region == "" {
+		config.S3Region = getenv("AWS_REGION")
+	}
+
 	return config
 }
 
+// TrustedProxyPrefixes parses TrustedProxies, taking a bare address as a
+// range of one
+func (c Config) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, item := range c.TrustedProxies {
+		if addr, err := netip.ParseAddr(item); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q is neither an address nor a CIDR range", item)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// AssetsPath is where fetched favicons and uploaded images are stored
+func (c Config) AssetsPath() string {
+	if c.AssetsInData {
+		return filepath.Join(c.DataPath, "assets")
+	}
+	return filepath.Join(c.WebPath, "static")
+}
+
 func (c Config) GetAddress() string {
 	return fmt.Sprintf(":%d", c.Port)
 }
+
+// TLSEnabled reports whether infoscope serves HTTPS itself
+func (c Config) TLSEnabled() bool {
+	return c.TLSCert != "" || len(c.AutocertDomains) > 0
+}
+
+// AutocertCacheDir is where certificates from Let's Encrypt are kept
+func (c Config) AutocertCacheDir() string {
+	return filepath.Join(c.DataPath, "autocert")
+}
+
+// RedirectAddress is the plain HTTP listener redirecting to HTTPS, or
+// empty for none
+func (c Config) RedirectAddress() string {
+	port := c.HTTPRedirectPort
+	if port == 0 && len(c.AutocertDomains) > 0 {
+		port = 80
+	}
+	if port == 0 || !c.TLSEnabled() {
+		return ""
+	}
+	return fmt.Sprintf(":%d", port)
+}
+
+// SplitList splits a comma separated list, dropping blank items
+func SplitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "infoscope.env")
+	content := `# infoscope settings
+INFOSCOPE_LOG_LEVEL=debug
+export INFOSCOPE_LOGIN_BURST = 3
+INFOSCOPE_STATSD_PREFIX="site."
+INFOSCOPE_DB_MAX_OPEN_CONNS=8
+INFOSCOPE_STORAGE=S3
+INFOSCOPE_S3_BUCKET=assets
+
+INFOSCOPE_PORT=9000
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// The environment wins over the file
+	t.Setenv("INFOSCOPE_PORT", "9100")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.LogLevel != "debug" || cfg.LoginBurst != 3 || cfg.StatsDPrefix != "site." {
+		t.Errorf("file values = %q, %d, %q; want debug, 3, site.", cfg.LogLevel, cfg.LoginBurst, cfg.StatsDPrefix)
+	}
+	if cfg.DBMaxOpenConns != 8 {
+		t.Errorf("DBMaxOpenConns = %d, want 8", cfg.DBMaxOpenConns)
+	}
+	if cfg.Storage != "s3" || cfg.S3Bucket != "assets" {
+		t.Errorf("Storage = %q in %q, want s3 in assets", cfg.Storage, cfg.S3Bucket)
+	}
+	if cfg.Port != 9100 {
+		t.Errorf("Port = %d, want 9100 from the environment", cfg.Port)
+	}
+	if cfg.LoginRefill != 60 {
+		t.Errorf("LoginRefill = %d, want the default 60", cfg.LoginRefill)
+	}
+}
+
+func TestLoadBadLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "infoscope.env")
+	if err := os.WriteFile(path, []byte("INFOSCOPE_PORT\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Load accepted a line without =")
+	}
+}
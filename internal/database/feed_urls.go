@@ -0,0 +1,100 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"infoscope/internal/feedurl"
+)
+
+// feedChildTables hold rows that belong to a feed and move with it when
+// duplicates are merged. Daily stats are keyed by day as well, so a
+// duplicate's days that clash with the kept feed's are dropped.
+var feedChildTables = []string{"entries", "fetch_log", "notifications", "feed_fetch_stats", "feed_bandwidth"}
+
+// keyFeedURLs fills in feeds.url_key for feeds that lack it, merging a feed
+// into one already subscribed under another form of the same address, such
+// as http and https or with and without a trailing slash. Feeds are only
+// missing a key when added before keys existed, so after the first run
+// this finds nothing to do.
+func keyFeedURLs(db *sql.DB) error {
+	rows, err := db.Query("SELECT id, url FROM feeds WHERE url_key IS NULL ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("error reading feed URLs: %w", err)
+	}
+	type unkeyed struct {
+		id  int64
+		url string
+	}
+	var feeds []unkeyed
+	for rows.Next() {
+		var f unkeyed
+		if err := rows.Scan(&f.id, &f.url); err != nil {
+			rows.Close()
+			return fmt.Errorf("error reading feed URLs: %w", err)
+		}
+		feeds = append(feeds, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading feed URLs: %w", err)
+	}
+
+	for _, f := range feeds {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err := keyFeedURL(tx, f.id, f.url); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error keying feed %d: %w", f.id, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyFeedURL sets one feed's key, or merges it into the feed that already
+// has that key. The merged feed keeps the older feed's ID and settings,
+// filling in a category and tags it lacks, and takes the merged address
+// when feedurl.Prefer picks it.
+func keyFeedURL(tx *sql.Tx, id int64, url string) error {
+	key := feedurl.Key(url)
+	var keptID int64
+	var keptURL string
+	err := tx.QueryRow("SELECT id, url FROM feeds WHERE url_key = ?", key).Scan(&keptID, &keptURL)
+	if err == sql.ErrNoRows {
+		_, err = tx.Exec("UPDATE feeds SET url_key = ? WHERE id = ?", key, id)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, table := range feedChildTables {
+		if _, err := tx.Exec(fmt.Sprintf("UPDATE OR IGNORE %s SET feed_id = ? WHERE feed_id = ?", table), keptID, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE feed_id = ?", table), id); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`
+        UPDATE feeds SET
+            category = COALESCE(category, (SELECT category FROM feeds WHERE id = ?)),
+            tags = COALESCE(tags, (SELECT tags FROM feeds WHERE id = ?))
+        WHERE id = ?`, id, id, keptID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM feeds WHERE id = ?", id); err != nil {
+		return err
+	}
+	if normalized, err := feedurl.Normalize(url); err == nil && feedurl.Prefer(normalized, keptURL) {
+		if _, err := tx.Exec("UPDATE feeds SET url = ? WHERE id = ?", normalized, keptID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
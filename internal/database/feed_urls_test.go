@@ -0,0 +1,67 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyFeedURLsMergesDuplicates(t *testing.T) {
+	db := setupTestDB(t)
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Feeds added before keys existed, one of them three times over
+	first := insertEntries(t, db, "http://Example.com/feed", 2, published)
+	insertEntries(t, db, "https://example.com/feed/", 1, published)
+	insertEntries(t, db, "https://other.example/rss", 1, published)
+	if _, err := db.Exec("INSERT INTO feeds (url, title, category) VALUES ('https://example.com:443/feed', 't', 'News')"); err != nil {
+		t.Fatalf("Failed to insert feed: %v", err)
+	}
+
+	if err := keyFeedURLs(db.DB); err != nil {
+		t.Fatalf("keyFeedURLs failed: %v", err)
+	}
+
+	rows, err := db.Query("SELECT id, url, url_key, COALESCE(category, '') FROM feeds ORDER BY id")
+	if err != nil {
+		t.Fatalf("Failed to query feeds: %v", err)
+	}
+	defer rows.Close()
+	type feedRow struct {
+		id                 int64
+		url, key, category string
+	}
+	var feeds []feedRow
+	for rows.Next() {
+		var f feedRow
+		if err := rows.Scan(&f.id, &f.url, &f.key, &f.category); err != nil {
+			t.Fatalf("Failed to scan feed: %v", err)
+		}
+		feeds = append(feeds, f)
+	}
+	if len(feeds) != 2 {
+		t.Fatalf("Got %d feeds after merging, want 2: %+v", len(feeds), feeds)
+	}
+
+	// The oldest feed is kept, at the https address, with the category of a
+	// duplicate and every duplicate's entries
+	var feedID int64
+	if err := db.QueryRow("SELECT feed_id FROM entries WHERE id = ?", first[0]).Scan(&feedID); err != nil {
+		t.Fatalf("Failed to read entry: %v", err)
+	}
+	kept := feeds[0]
+	if kept.id != feedID || kept.url != "https://example.com/feed" || kept.key != "example.com/feed" || kept.category != "News" {
+		t.Errorf("Kept feed = %+v, want ID %d at https://example.com/feed filed under News", kept, feedID)
+	}
+	var entries int
+	if err := db.QueryRow("SELECT COUNT(*) FROM entries WHERE feed_id = ?", kept.id).Scan(&entries); err != nil {
+		t.Fatalf("Failed to count entries: %v", err)
+	}
+	if entries != 3 {
+		t.Errorf("Kept feed has %d entries, want 3", entries)
+	}
+
+	// A feed added later under a known address is refused by the key
+	if _, err := db.Exec("INSERT INTO feeds (url, url_key) VALUES ('http://example.com/feed/', 'example.com/feed')"); err == nil {
+		t.Error("Inserted a second feed with the same key")
+	}
+}
@@ -127,7 +127,8 @@ func (db *DB) GetRecentEntries(ctx context.Context, limit int) ([]Entry, error)
                e.favicon_url, f.title as feed_title
         FROM entries e
         JOIN feeds f ON e.feed_id = f.id
-        ORDER BY e.published_at DESC
+        WHERE f.snoozed_until IS NULL OR f.snoozed_until <= datetime('now')
+        ORDER BY e.published_at DESC, e.id DESC
         LIMIT ?`,
 		limit,
 	)
@@ -158,6 +159,7 @@ func (db *DB) GetActiveFeeds(ctx context.Context) ([]Feed, error) {
 		        status, error_count, last_error, created_at, updated_at
 		FROM feeds
 		WHERE status = 'active'
+		  AND (snoozed_until IS NULL OR snoozed_until <= datetime('now'))
 		ORDER BY title`,
 	)
 	if err != nil {
@@ -184,37 +186,29 @@ func (db *DB) GetActiveFeeds(ctx context.Context) ([]Feed, error) {
 
 // IncrementClicks atomically updates click counts
 func (db *DB) IncrementClicks(ctx context.Context, entryID int64) error {
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	// Update entry clicks
-	_, err = tx.ExecContext(ctx,
-		`INSERT INTO clicks (entry_id, click_count, last_clicked)
-		VALUES (?, 1, CURRENT_TIMESTAMP)
-		ON CONFLICT(entry_id) DO UPDATE SET
-		click_count = click_count + 1,
-		last_clicked = CURRENT_TIMESTAMP`,
-		entryID,
-	)
-	if err != nil {
-		return err
-	}
+	return WithTx(ctx, db.DB, func(tx *sql.Tx) error {
+		// Update entry clicks
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO clicks (entry_id, click_count, last_clicked)
+			VALUES (?, 1, CURRENT_TIMESTAMP)
+			ON CONFLICT(entry_id) DO UPDATE SET
+			click_count = click_count + 1,
+			last_clicked = CURRENT_TIMESTAMP`,
+			entryID,
+		)
+		if err != nil {
+			return err
+		}
 
-	// Update total clicks atomically
-	_, err = tx.ExecContext(ctx,
-		`UPDATE click_stats 
-		SET value = value + 1,
-		    updated_at = CURRENT_TIMESTAMP
-		WHERE key = 'total_clicks'`,
-	)
-	if err != nil {
+		// Update total clicks atomically
+		_, err = tx.ExecContext(ctx,
+			`UPDATE click_stats 
+			SET value = value + 1,
+			    updated_at = CURRENT_TIMESTAMP
+			WHERE key = 'total_clicks'`,
+		)
 		return err
-	}
-
-	return tx.Commit()
+	})
 }
 
 // GetClickStats retrieves optimized click statistics
@@ -306,21 +300,25 @@ func (db *DB) UpdateFeedStatus(ctx context.Context, feedID int64, status string,
 	return err
 }
 
-// CleanupOldEntries removes old entries beyond the retention limit
+// CleanupOldEntries removes old entries beyond the retention limit: a
+// feed's own max_entries_per_feed, or maxPosts for feeds without one.
+// Starred entries are kept and don't count towards the limit.
 func (db *DB) CleanupOldEntries(ctx context.Context, maxPosts int) error {
 	_, err := db.ExecContext(ctx,
 		`DELETE FROM entries 
 		WHERE id IN (
-			SELECT e.id 
-			FROM entries e
-			JOIN feeds f ON e.feed_id = f.id
-			WHERE e.id NOT IN (
-				SELECT e2.id
-				FROM entries e2
-				WHERE e2.feed_id = f.id
-				ORDER BY published_at DESC
-				LIMIT ?
+			SELECT id FROM (
+				SELECT e.id,
+				       ROW_NUMBER() OVER (
+				           PARTITION BY e.feed_id
+				           ORDER BY e.published_at DESC, e.id DESC
+				       ) AS position,
+				       COALESCE(NULLIF(f.max_entries_per_feed, 0), ?) AS keep
+				FROM entries e
+				JOIN feeds f ON e.feed_id = f.id
+				WHERE e.starred_at IS NULL
 			)
+			WHERE position > keep
 		)`,
 		maxPosts,
 	)
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func setupTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"), DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// insertEntries adds count entries to a new feed, all published at the same
+// moment, and returns their IDs in insertion order
+func insertEntries(t *testing.T, db *DB, feedURL string, count int, published time.Time) []int64 {
+	t.Helper()
+	res, err := db.Exec("INSERT INTO feeds (url, title) VALUES (?, ?)", feedURL, feedURL)
+	if err != nil {
+		t.Fatalf("Failed to insert feed: %v", err)
+	}
+	feedID, _ := res.LastInsertId()
+
+	var ids []int64
+	for i := 0; i < count; i++ {
+		res, err := db.Exec(
+			"INSERT INTO entries (feed_id, title, url, published_at, favicon_url) VALUES (?, ?, ?, ?, '')",
+			feedID, fmt.Sprintf("Entry %d", i), fmt.Sprintf("%s/%d", feedURL, i), published,
+		)
+		if err != nil {
+			t.Fatalf("Failed to insert entry: %v", err)
+		}
+		id, _ := res.LastInsertId()
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestGetRecentEntriesTieBreak(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ids := insertEntries(t, db, "https://example.com/feed", 5, published)
+
+	for run := 0; run < 3; run++ {
+		entries, err := db.GetRecentEntries(ctx, 3)
+		if err != nil {
+			t.Fatalf("GetRecentEntries failed: %v", err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("Expected 3 entries, got %d", len(entries))
+		}
+		// Identical timestamps fall back to newest ID first
+		for i, e := range entries {
+			if want := ids[len(ids)-1-i]; e.ID != want {
+				t.Errorf("Run %d: entry %d has ID %d, want %d", run, i, e.ID, want)
+			}
+		}
+	}
+}
+
+func TestCleanupOldEntriesTieBreak(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	first := insertEntries(t, db, "https://example.com/a", 4, published)
+	second := insertEntries(t, db, "https://example.com/b", 2, published)
+
+	if err := db.CleanupOldEntries(ctx, 2); err != nil {
+		t.Fatalf("CleanupOldEntries failed: %v", err)
+	}
+
+	rows, err := db.Query("SELECT id FROM entries ORDER BY id")
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+	defer rows.Close()
+
+	var kept []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("Failed to scan entry: %v", err)
+		}
+		kept = append(kept, id)
+	}
+
+	// Each feed keeps its newest entries by ID when timestamps tie
+	want := []int64{first[2], first[3], second[0], second[1]}
+	if fmt.Sprint(kept) != fmt.Sprint(want) {
+		t.Errorf("Kept entries %v, want %v", kept, want)
+	}
+}
+
+func TestCleanupOldEntriesPerFeedLimit(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	limits := map[string]any{
+		"https://example.com/archive": 4,
+		"https://example.com/noisy":   1,
+		"https://example.com/plain":   nil, // follows maxPosts
+	}
+	for feedURL, limit := range limits {
+		insertEntries(t, db, feedURL, 5, published)
+		if _, err := db.Exec("UPDATE feeds SET max_entries_per_feed = ? WHERE url = ?", limit, feedURL); err != nil {
+			t.Fatalf("Failed to set feed limit: %v", err)
+		}
+	}
+
+	if err := db.CleanupOldEntries(ctx, 2); err != nil {
+		t.Fatalf("CleanupOldEntries failed: %v", err)
+	}
+
+	want := map[string]int{"https://example.com/archive": 4, "https://example.com/noisy": 1, "https://example.com/plain": 2}
+	for feedURL, n := range want {
+		var kept int
+		if err := db.QueryRow(
+			"SELECT COUNT(*) FROM entries e JOIN feeds f ON f.id = e.feed_id WHERE f.url = ?", feedURL,
+		).Scan(&kept); err != nil {
+			t.Fatalf("Failed to count entries: %v", err)
+		}
+		if kept != n {
+			t.Errorf("%s kept %d entries, want %d", feedURL, kept, n)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"infoscope/internal/schedule"
+)
+
+const (
+	// retentionInterval is how often entries past their retention window
+	// are deleted
+	retentionInterval = time.Hour
+
+	// MaxRetentionDays caps a retention window at a hundred years
+	MaxRetentionDays = 36500
+)
+
+// RetentionDays returns the retention_days setting, the age in days past
+// which entries are deleted; 0 keeps entries regardless of age
+func (db *DB) RetentionDays(ctx context.Context) int {
+	var value string
+	if err := db.QueryRowContext(ctx,
+		"SELECT value FROM settings WHERE key = 'retention_days'").Scan(&value); err != nil {
+		return 0
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return 0
+	}
+	return days
+}
+
+// DeleteExpiredEntries removes entries published more than a feed's
+// retention_days before now, or days for feeds without their own window.
+// A window of 0 keeps the feed's entries, and starred entries are always
+// kept. It returns the entries deleted.
+func (db *DB) DeleteExpiredEntries(ctx context.Context, now time.Time, days int) (int64, error) {
+	res, err := db.ExecContext(ctx, `
+		DELETE FROM entries
+		WHERE id IN (
+			SELECT e.id
+			FROM entries e
+			JOIN feeds f ON e.feed_id = f.id
+			WHERE e.starred_at IS NULL
+			  AND COALESCE(NULLIF(f.retention_days, 0), ?) > 0
+			  AND datetime(e.published_at) < datetime(?, '-' || COALESCE(NULLIF(f.retention_days, 0), ?) || ' days')
+		)`,
+		days, now.UTC().Format("2006-01-02 15:04:05"), days,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ArchiveMode reports whether the archive_mode setting is on, in which
+// case no entries are deleted
+func (db *DB) ArchiveMode(ctx context.Context) bool {
+	var value string
+	err := db.QueryRowContext(ctx, "SELECT value FROM settings WHERE key = 'archive_mode'").Scan(&value)
+	return err == nil && value == "true"
+}
+
+// EnforceRetention deletes expired entries every retentionInterval until
+// ctx is cancelled, except in archive mode. Count limits are applied as
+// feeds are fetched; age limits need a job of their own, since a feed that
+// stops posting is never trimmed on fetch.
+func (db *DB) EnforceRetention(ctx context.Context, logger *slog.Logger) {
+	schedule.Run(ctx, func() schedule.Spec {
+		if db.ArchiveMode(ctx) {
+			return schedule.Spec{}
+		}
+		return schedule.Interval(retentionInterval)
+	}, func(ctx context.Context) {
+		deleted, err := db.DeleteExpiredEntries(ctx, time.Now(), db.RetentionDays(ctx))
+		if err != nil {
+			logger.ErrorContext(ctx, "Error deleting expired entries", "error", err)
+			return
+		}
+		if deleted > 0 {
+			logger.InfoContext(ctx, "Deleted expired entries", "count", deleted)
+		}
+	})
+}
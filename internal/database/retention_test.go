@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDeleteExpiredEntries(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// Each feed has an entry 10 days old and one 100 days old
+	feeds := map[string]any{
+		"https://example.com/site":    nil, // follows the site window
+		"https://example.com/short":   5,
+		"https://example.com/archive": 365,
+	}
+	for feedURL, days := range feeds {
+		insertEntries(t, db, feedURL, 1, now.AddDate(0, 0, -10))
+		if _, err := db.Exec(`
+			INSERT INTO entries (feed_id, title, url, published_at, favicon_url)
+			SELECT id, 'Old', url || '/old', ?, '' FROM feeds WHERE url = ?`,
+			now.AddDate(0, 0, -100), feedURL); err != nil {
+			t.Fatalf("Failed to insert entry: %v", err)
+		}
+		if _, err := db.Exec("UPDATE feeds SET retention_days = ? WHERE url = ?", days, feedURL); err != nil {
+			t.Fatalf("Failed to set feed retention: %v", err)
+		}
+	}
+
+	deleted, err := db.DeleteExpiredEntries(ctx, now, 30)
+	if err != nil {
+		t.Fatalf("DeleteExpiredEntries failed: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("Deleted %d entries, want 3", deleted)
+	}
+
+	want := map[string]int{"https://example.com/site": 1, "https://example.com/short": 0, "https://example.com/archive": 2}
+	for feedURL, n := range want {
+		var kept int
+		if err := db.QueryRow(
+			"SELECT COUNT(*) FROM entries e JOIN feeds f ON f.id = e.feed_id WHERE f.url = ?", feedURL,
+		).Scan(&kept); err != nil {
+			t.Fatalf("Failed to count entries: %v", err)
+		}
+		if kept != n {
+			t.Errorf("%s kept %d entries, want %d", feedURL, kept, n)
+		}
+	}
+
+	// Without a site window only feeds with their own are trimmed
+	if deleted, err := db.DeleteExpiredEntries(ctx, now.AddDate(1, 0, 0), 0); err != nil || deleted != 2 {
+		t.Errorf("DeleteExpiredEntries without a site window = %d, %v; want 2 from the archive feed", deleted, err)
+	}
+}
+
+func TestStarredEntriesAreKept(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	ids := insertEntries(t, db, "https://example.com/a", 3, now.AddDate(0, 0, -100))
+	if _, err := db.Exec("UPDATE entries SET starred_at = ? WHERE id = ?", now, ids[0]); err != nil {
+		t.Fatalf("Failed to star entry: %v", err)
+	}
+
+	// The starred entry doesn't take one of the two places kept
+	if err := db.CleanupOldEntries(ctx, 2); err != nil {
+		t.Fatalf("CleanupOldEntries failed: %v", err)
+	}
+	var kept int
+	if err := db.QueryRow("SELECT COUNT(*) FROM entries").Scan(&kept); err != nil {
+		t.Fatalf("Failed to count entries: %v", err)
+	}
+	if kept != 3 {
+		t.Errorf("CleanupOldEntries kept %d entries, want 3", kept)
+	}
+
+	if deleted, err := db.DeleteExpiredEntries(ctx, now, 30); err != nil || deleted != 2 {
+		t.Errorf("DeleteExpiredEntries = %d, %v; want the 2 unstarred entries", deleted, err)
+	}
+	var id int64
+	if err := db.QueryRow("SELECT id FROM entries").Scan(&id); err != nil || id != ids[0] {
+		t.Errorf("Remaining entry = %d, %v; want the starred entry %d", id, err, ids[0])
+	}
+}
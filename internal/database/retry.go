@@ -0,0 +1,91 @@
+// internal/database/retry.go
+package database
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Retry policy for write transactions that hit SQLite lock contention. The
+// connection's busy_timeout already waits inside SQLite; this covers the
+// cases it does not, such as a deferred transaction upgrading to a writer.
+const (
+	txMaxAttempts  = 5
+	txInitialDelay = 50 * time.Millisecond
+	txMaxDelay     = time.Second
+)
+
+// Contention counters, published with the other expvar metrics
+var (
+	busyRetries  = expvar.NewInt("db_busy_retries")
+	busyFailures = expvar.NewInt("db_busy_failures")
+)
+
+// ContentionStats reports how often writes ran into a locked database
+type ContentionStats struct {
+	Retries  int64 `json:"retries"`
+	Failures int64 `json:"failures"`
+}
+
+// GetContentionStats returns the lock contention counters since startup.
+func GetContentionStats() ContentionStats {
+	return ContentionStats{
+		Retries:  busyRetries.Value(),
+		Failures: busyFailures.Value(),
+	}
+}
+
+// IsBusy reports whether err is SQLite refusing a write because another
+// connection holds the lock.
+func IsBusy(err error) bool {
+	if busy, ok := sqliteBusy(err); ok {
+		return busy
+	}
+	return err != nil && strings.Contains(err.Error(), "database is locked")
+}
+
+// WithTx runs fn inside a transaction and commits it. When SQLite reports
+// the database busy or locked, the whole transaction is rolled back and
+// retried with exponential backoff, so fn must be safe to run more than once.
+func WithTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	delay := txInitialDelay
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		err = runTx(ctx, db, fn)
+		if err == nil || !IsBusy(err) {
+			return err
+		}
+		if attempt == txMaxAttempts {
+			busyFailures.Add(1)
+			return err
+		}
+		busyRetries.Add(1)
+
+		// Jitter keeps competing writers from retrying in lockstep
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		delay = min(delay*2, txMaxDelay)
+	}
+}
+
+func runTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,20 @@
+// internal/database/retry_cgo.go
+//go:build cgo
+
+package database
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteBusy checks the driver's error code; ok is false when err did not
+// come from SQLite.
+func sqliteBusy(err error) (busy, ok bool) {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false, false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked, true
+}
@@ -0,0 +1,10 @@
+// internal/database/retry_nocgo.go
+//go:build !cgo
+
+package database
+
+// sqliteBusy has no error codes to go on without cgo, where the driver is a
+// stub that only fails to open; IsBusy falls back to the message.
+func sqliteBusy(err error) (busy, ok bool) {
+	return false, false
+}
@@ -41,6 +41,7 @@ CREATE TABLE IF NOT EXISTS entries (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     feed_id INTEGER NOT NULL,
     title TEXT NOT NULL,
+    raw_title TEXT,
     url TEXT NOT NULL UNIQUE,
     content TEXT,
     guid TEXT,
@@ -56,8 +57,10 @@ CREATE TABLE IF NOT EXISTS admin_users (
     username TEXT UNIQUE NOT NULL,
     password_hash TEXT NOT NULL,
     last_login TIMESTAMP,
+    previous_login TIMESTAMP,
     login_attempts INTEGER DEFAULT 0,
     locked_until TIMESTAMP,
+    role TEXT NOT NULL DEFAULT 'admin',
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
@@ -73,6 +76,30 @@ CREATE TABLE IF NOT EXISTS sessions (
     FOREIGN KEY (user_id) REFERENCES admin_users(id) ON DELETE CASCADE
 );
 
+-- Passkeys (WebAuthn credentials) accounts can sign in with instead of a
+-- password; credential holds the public key and signature counter as JSON
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    credential_id BLOB NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    credential TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES admin_users(id) ON DELETE CASCADE
+);
+
+-- API tokens for the /api/v1 JSON API; only a hash of each token is kept
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    prefix TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
 -- Click tracking table
 CREATE TABLE IF NOT EXISTS clicks (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -84,6 +111,153 @@ CREATE TABLE IF NOT EXISTS clicks (
     UNIQUE(entry_id)
 );
 
+-- Clicks per entry and UTC day, for windowed and decayed rankings
+CREATE TABLE IF NOT EXISTS click_daily (
+    entry_id INTEGER NOT NULL,
+    day DATE NOT NULL,
+    clicks INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (entry_id, day),
+    FOREIGN KEY (entry_id) REFERENCES entries(id) ON DELETE CASCADE
+);
+
+-- Entry revisions table (previous versions of upstream-edited entries)
+CREATE TABLE IF NOT EXISTS entry_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entry_id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (entry_id) REFERENCES entries(id) ON DELETE CASCADE
+);
+
+-- Record the previous version whenever a re-fetch changes an entry.
+-- Replacing the feed's summary with the extracted article is not an edit.
+CREATE TRIGGER IF NOT EXISTS entries_revision_trigger
+AFTER UPDATE OF title, content ON entries
+FOR EACH ROW
+WHEN (OLD.title IS NOT NEW.title OR OLD.content IS NOT NEW.content)
+    AND NEW.content_extracted IS OLD.content_extracted
+BEGIN
+    INSERT INTO entry_revisions (entry_id, title, content)
+    VALUES (OLD.id, OLD.title, OLD.content);
+END;
+
+-- Fetch cycle history
+CREATE TABLE IF NOT EXISTS fetch_cycles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    started_at TIMESTAMP NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    feed_count INTEGER NOT NULL DEFAULT 0,
+    entry_count INTEGER NOT NULL DEFAULT 0,
+    error_count INTEGER NOT NULL DEFAULT 0
+);
+
+-- Anomalies spotted in fetch cycles, shown on the dashboard until dismissed
+CREATE TABLE IF NOT EXISTS fetch_alerts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    message TEXT NOT NULL,
+    occurrences INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    dismissed_at TIMESTAMP
+);
+
+-- Daily fetch outcomes per feed, for the feed health report. latency_ms
+-- sums the response times of fetches that got a response.
+CREATE TABLE IF NOT EXISTS feed_fetch_stats (
+    feed_id INTEGER NOT NULL,
+    day DATE NOT NULL,
+    fetches INTEGER NOT NULL DEFAULT 0,
+    ok INTEGER NOT NULL DEFAULT 0,
+    not_modified INTEGER NOT NULL DEFAULT 0,
+    client_errors INTEGER NOT NULL DEFAULT 0,
+    server_errors INTEGER NOT NULL DEFAULT 0,
+    network_errors INTEGER NOT NULL DEFAULT 0,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    new_entries INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (feed_id, day),
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- Events for the admin, such as feeds disabled after repeated errors
+CREATE TABLE IF NOT EXISTS notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    feed_id INTEGER,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMP,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- Fetch log (per-feed fetch failures, used for error digests)
+CREATE TABLE IF NOT EXISTS fetch_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    feed_id INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    error_class TEXT,
+    message TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- Daily downloaded bytes per feed
+CREATE TABLE IF NOT EXISTS feed_bandwidth (
+    feed_id INTEGER NOT NULL,
+    day DATE NOT NULL,
+    bytes INTEGER NOT NULL DEFAULT 0,
+    requests INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (feed_id, day),
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- Weekly roundups of the most clicked entries
+CREATE TABLE IF NOT EXISTS roundups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    week_start DATE UNIQUE NOT NULL,
+    title TEXT NOT NULL,
+    items TEXT NOT NULL,
+    published_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Fetch priority attached to feed tags
+CREATE TABLE IF NOT EXISTS tag_priorities (
+    tag TEXT PRIMARY KEY,
+    priority INTEGER NOT NULL DEFAULT 0,
+    interval_minutes INTEGER NOT NULL DEFAULT 0
+);
+
+-- Rules that tag feeds whose URL, host or title matches a pattern
+CREATE TABLE IF NOT EXISTS tag_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    field TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (field, pattern, tag)
+);
+
+-- Translations fetched on demand, kept until the entry changes
+CREATE TABLE IF NOT EXISTS entry_translations (
+    entry_id INTEGER NOT NULL,
+    language TEXT NOT NULL,
+    source_title TEXT NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (entry_id, language),
+    FOREIGN KEY (entry_id) REFERENCES entries(id) ON DELETE CASCADE
+);
+
+-- Internal application state (not user settings, not exported)
+CREATE TABLE IF NOT EXISTS app_state (
+    key TEXT PRIMARY KEY,
+    value TEXT,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Click stats table
 CREATE TABLE IF NOT EXISTS click_stats (
     key TEXT PRIMARY KEY,
@@ -95,30 +269,59 @@ const Indexes = `
 -- Feed indexes
 CREATE INDEX IF NOT EXISTS idx_feeds_status ON feeds(status, last_fetched);
 CREATE INDEX IF NOT EXISTS idx_feeds_error ON feeds(error_count) WHERE error_count > 0;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_feeds_url_key ON feeds(url_key);
 
 -- Entry indexes
 CREATE INDEX IF NOT EXISTS idx_entries_feed_date ON entries(feed_id, published_at DESC);
 CREATE INDEX IF NOT EXISTS idx_entries_published ON entries(published_at DESC);
+CREATE INDEX IF NOT EXISTS idx_entries_feed_guid ON entries(feed_id, guid);
+CREATE INDEX IF NOT EXISTS idx_entries_canonical ON entries(canonical_url);
+CREATE INDEX IF NOT EXISTS idx_entries_title_hash ON entries(title_hash, published_at) WHERE title_hash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_entries_duplicate ON entries(duplicate_of) WHERE duplicate_of IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_entries_starred ON entries(starred_at DESC) WHERE starred_at IS NOT NULL;
+
+-- Entry revision indexes
+CREATE INDEX IF NOT EXISTS idx_entry_revisions_entry ON entry_revisions(entry_id, created_at DESC);
 
 -- Click tracking indexes
 CREATE INDEX IF NOT EXISTS idx_clicks_entry ON clicks(entry_id);
 CREATE INDEX IF NOT EXISTS idx_clicks_count ON clicks(click_count DESC);
 CREATE INDEX IF NOT EXISTS idx_clicks_date ON clicks(last_clicked DESC);
+CREATE INDEX IF NOT EXISTS idx_click_daily_day ON click_daily(day);
+
+-- Fetch log index
+CREATE INDEX IF NOT EXISTS idx_fetch_log_feed_date ON fetch_log(feed_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_fetch_log_date ON fetch_log(created_at DESC);
+
+-- Fetch cycle index
+CREATE INDEX IF NOT EXISTS idx_fetch_cycles_started ON fetch_cycles(started_at DESC);
 
 -- Session index
-CREATE INDEX IF NOT EXISTS idx_sessions_expiry ON sessions(expires_at);`
+CREATE INDEX IF NOT EXISTS idx_sessions_expiry ON sessions(expires_at);
+
+-- Passkey index
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user ON webauthn_credentials(user_id);`
 
 // DB represents our database connection and operations
 type DB struct {
 	*sql.DB
 }
 
-// Configuration for the database
+// Configuration for the database. SQLite allows one writer at a time, and
+// writes wait out the busy timeout rather than run in parallel, so more
+// than a few dozen open connections only adds memory; reads in WAL mode do
+// run concurrently, so a handful is enough for most sites. Keep
+// MaxIdleConns at or below MaxOpenConns and lifetimes in minutes, not
+// seconds, as each new connection re-reads the schema.
 type Config struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
 	ConnMaxIdleTime time.Duration
+
+	// ReadOnly opens the database with writes refused by SQLite and skips
+	// schema creation, for mirrors serving a replicated copy
+	ReadOnly bool
 }
 
 // DefaultConfig returns the default database configuration
@@ -136,6 +339,9 @@ func NewDB(dbPath string, cfg Config) (*DB, error) {
 	// Add query parameters to optimize SQLite performance
 	dsn := fmt.Sprintf("%s?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=ON&_synchronous=NORMAL",
 		dbPath)
+	if cfg.ReadOnly {
+		dsn = fmt.Sprintf("%s?_busy_timeout=5000&_query_only=true", dbPath)
+	}
 
 	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
@@ -143,10 +349,7 @@ func NewDB(dbPath string, cfg Config) (*DB, error) {
 	}
 
 	// Configure connection pool
-	db.SetMaxOpenConns(cfg.MaxOpenConns)
-	db.SetMaxIdleConns(cfg.MaxIdleConns)
-	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
-	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	(&DB{db}).SetPool(cfg)
 
 	// Verify connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -157,7 +360,10 @@ func NewDB(dbPath string, cfg Config) (*DB, error) {
 		return nil, fmt.Errorf("error connecting to database: %w", err)
 	}
 
-	// Create schema
+	// Create schema; a read-only mirror relies on the primary having done so
+	if cfg.ReadOnly {
+		return &DB{db}, nil
+	}
 	if err := createSchema(db); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("error creating schema: %w", err)
@@ -166,6 +372,16 @@ func NewDB(dbPath string, cfg Config) (*DB, error) {
 	return &DB{db}, nil
 }
 
+// SetPool applies the connection pool settings of cfg. It may be called
+// while the database is in use; connections over the new limits are closed
+// as they are released.
+func (db *DB) SetPool(cfg Config) {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
 func createSchema(db *sql.DB) error {
 	// Keep existing pragma optimizations
 	if _, err := db.Exec(`
@@ -201,9 +417,38 @@ func createSchema(db *sql.DB) error {
 	}{
 		{"entries", "content", "TEXT"},
 		{"entries", "guid", "TEXT"},
+		{"entries", "raw_title", "TEXT"},
 		{"feeds", "status", "TEXT DEFAULT 'pending'"},
 		{"feeds", "error_count", "INTEGER DEFAULT 0"},
 		{"feeds", "last_error", "TEXT"},
+		{"feeds", "next_retry_at", "TIMESTAMP"},
+		{"feeds", "priority", "INTEGER DEFAULT 0"},
+		{"feeds", "language", "TEXT"},
+		{"feeds", "region", "TEXT"},
+		{"feeds", "locale_manual", "INTEGER DEFAULT 0"},
+		{"feeds", "category", "TEXT"},
+		{"feeds", "tags", "TEXT"},
+		{"feeds", "site_url", "TEXT"},
+		{"entries", "archive_status", "TEXT"},
+		{"entries", "archive_url", "TEXT"},
+		{"entries", "archive_attempts", "INTEGER DEFAULT 0"},
+		{"entries", "canonical_url", "TEXT"},
+		{"entries", "title_hash", "INTEGER"},
+		{"entries", "duplicate_of", "INTEGER"},
+		{"feeds", "snoozed_until", "TIMESTAMP"},
+		{"feeds", "fetch_interval_seconds", "INTEGER"},
+		{"feeds", "avg_post_interval_seconds", "INTEGER"},
+		{"feeds", "last_post_at", "TIMESTAMP"},
+		{"feeds", "full_content", "INTEGER DEFAULT 0"},
+		{"feeds", "max_entries_per_feed", "INTEGER"},
+		{"feeds", "retention_days", "INTEGER"},
+		{"feeds", "url_key", "TEXT"},
+		{"entries", "content_extracted", "INTEGER DEFAULT 0"},
+		{"entries", "starred_at", "TIMESTAMP"},
+		{"entries", "pinned", "INTEGER DEFAULT 0"},
+		{"entries", "hidden_at", "TIMESTAMP"},
+		{"admin_users", "previous_login", "TIMESTAMP"},
+		{"admin_users", "role", "TEXT NOT NULL DEFAULT 'admin'"},
 		{"settings", "timezone", "TEXT DEFAULT 'UTC'"},
 		{"settings", "favicon_url", "TEXT DEFAULT 'favicon.ico'"},
 	}
@@ -246,11 +491,20 @@ func performMigrations(db *sql.DB) error {
 		return err
 	}
 
+	// One feed under several forms of its address is merged into one
+	if err := keyFeedURLs(db); err != nil {
+		return err
+	}
+
 	// Migrate 'settings' table
 	if err := migrateSettingsTable(db); err != nil {
 		return err
 	}
 
+	if err := seedClickDaily(db); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -349,21 +603,59 @@ func columnExists(db *sql.DB, tableName, columnName string) (bool, error) {
 
 func insertDefaultSettings(db *sql.DB) error {
 	defaultSettings := map[string]string{
-		"site_title":          "infoscope_",
-		"max_posts":           "100",
-		"update_interval":     "900",
-		"header_link_text":    "infoscope_",
-		"header_link_url":     "/",
-		"footer_link_text":    "infoscope_",
-		"footer_link_url":     "/",
-		"footer_image_url":    "",
-		"footer_image_height": "50px",
-		"tracking_code":       "",
-		"timezone":            "UTC",
-		"favicon_url":         "favicon.ico",
-		"meta_description":    "A minimalist RSS river reader",
-		"meta_image_url":      "",
-		"site_url":            "",
+		"site_title":                "infoscope_",
+		"max_posts":                 "100",
+		"retention_days":            "0",
+		"update_interval":           "900",
+		"header_link_text":          "infoscope_",
+		"header_link_url":           "/",
+		"footer_link_text":          "infoscope_",
+		"footer_link_url":           "/",
+		"footer_image_url":          "",
+		"footer_image_height":       "50px",
+		"tracking_code":             "",
+		"timezone":                  "UTC",
+		"favicon_url":               "favicon.ico",
+		"meta_description":          "A minimalist RSS river reader",
+		"meta_image_url":            "",
+		"site_url":                  "",
+		"honor_dnt":                 "false",
+		"compact_mode":              "false",
+		"semantic_markup":           "false",
+		"clean_titles":              "false",
+		"dedup_key":                 "url",
+		"entry_update_mode":         "newer",
+		"visitor_muting":            "false",
+		"river_mode":                "chronological",
+		"stats_api_token":           "",
+		"host_concurrency":          "2",
+		"host_delay_ms":             "1000",
+		"daily_bandwidth_mb":        "0",
+		"backup_schedule":           "",
+		"cache_index_ttl":           "60",
+		"cache_static_ttl":          "86400",
+		"cache_api_max_age":         "0",
+		"weekly_roundup":            "false",
+		"roundup_size":              "10",
+		"river_layout":              "stream",
+		"share_links":               "false",
+		"mastodon_instance":         "mastodon.social",
+		"wayback_archive":           "false",
+		"archive_mode":              "false",
+		"public_api":                "true",
+		"local_dates":               "false",
+		"cross_feed_dedup":          "off",
+		"translation_backend":       "",
+		"translation_url":           "",
+		"translation_api_key":       "",
+		"translation_language":      "en",
+		"alert_cycle_minutes":       "10",
+		"update_check_hours":        "0",
+		"adaptive_polling":          "false",
+		"adaptive_poll_min_minutes": "15",
+		"adaptive_poll_max_minutes": "1440",
+		"dead_feed_errors":          "50",
+		"click_half_life_days":      "0",
 	}
 
 	tx, err := db.Begin()
@@ -413,6 +705,20 @@ func insertDefaultSettings(db *sql.DB) error {
 	return tx.Commit()
 }
 
+// seedClickDaily gives clicks recorded before click_daily existed a day to
+// count towards. Only their total is known, so each entry's clicks are
+// placed on the day it was last clicked.
+func seedClickDaily(db *sql.DB) error {
+	_, err := db.Exec(`
+        INSERT INTO click_daily (entry_id, day, clicks)
+        SELECT entry_id, date(last_clicked), click_count FROM clicks
+        WHERE NOT EXISTS (SELECT 1 FROM click_daily)`)
+	if err != nil {
+		return fmt.Errorf("error seeding daily clicks: %w", err)
+	}
+	return nil
+}
+
 func migrateSettingsTable(db *sql.DB) error {
 	expectedColumns := []struct {
 		name         string
@@ -0,0 +1,372 @@
+// Package extract pulls the main article out of a web page, after the
+// manner of Readability: paragraphs score the elements that contain them,
+// the best scoring element is taken as the article, and its markup is cut
+// down to a small set of tags safe to store and show.
+package extract
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrNoArticle is returned for pages without enough text to call an article
+var ErrNoArticle = errors.New("no article found")
+
+const (
+	// minArticleText is the least text, in bytes, an article may have
+	minArticleText = 250
+	// minParagraphText keeps captions and bylines from scoring
+	minParagraphText = 25
+)
+
+var (
+	// unlikely and likely match class and id values of page furniture and
+	// of content containers
+	unlikely = regexp.MustCompile(`(?i)banner|breadcrumb|comment|community|cookie|disqus|footer|header|menu|modal|nav|newsletter|pagination|popup|promo|related|share|sidebar|social|sponsor|subscribe|widget|\bads?\b|advert`)
+	likely   = regexp.MustCompile(`(?i)article|body|content|entry|main|post|story|text`)
+)
+
+// junk elements never hold article text
+var junk = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true,
+	atom.Form: true, atom.Nav: true, atom.Aside: true, atom.Footer: true,
+	atom.Header: true, atom.Button: true, atom.Input: true, atom.Select: true,
+	atom.Textarea: true, atom.Svg: true, atom.Canvas: true, atom.Object: true,
+	atom.Embed: true, atom.Link: true, atom.Meta: true, atom.Template: true,
+	atom.Dialog: true,
+}
+
+// kept are the elements left in the extracted HTML. Others are replaced
+// by their content.
+var kept = map[atom.Atom]bool{
+	atom.P: true, atom.A: true, atom.Em: true, atom.Strong: true, atom.B: true,
+	atom.I: true, atom.U: true, atom.S: true, atom.Sub: true, atom.Sup: true,
+	atom.Br: true, atom.Hr: true, atom.Ul: true, atom.Ol: true, atom.Li: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Blockquote: true,
+	atom.Pre: true, atom.Code: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.H5: true, atom.H6: true, atom.Img: true, atom.Figure: true,
+	atom.Figcaption: true, atom.Table: true, atom.Thead: true, atom.Tbody: true,
+	atom.Tr: true, atom.Th: true, atom.Td: true,
+}
+
+// Article returns the cleaned HTML of the main article in an HTML page.
+// Links and images are made absolute against base; scripts, styles,
+// attributes other than href, src and alt, and page furniture such as
+// navigation and comments are dropped.
+func Article(r io.Reader, base *url.URL) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+	body := find(doc, atom.Body)
+	if body == nil {
+		return "", ErrNoArticle
+	}
+	prune(body)
+
+	top := bestCandidate(body)
+	if top == nil {
+		return "", ErrNoArticle
+	}
+
+	// Paragraphs beside the best element often belong to the article, as
+	// when a page splits it around an image or a pull quote
+	var parts []*html.Node
+	for c := top.Parent.FirstChild; c != nil; c = c.NextSibling {
+		if c == top || isTrailingParagraph(c) {
+			parts = append(parts, c)
+		}
+	}
+
+	textLen := 0
+	var b strings.Builder
+	for _, n := range parts {
+		textLen += len(strings.TrimSpace(textOf(n)))
+		render(&b, n, base)
+	}
+	if textLen < minArticleText {
+		return "", ErrNoArticle
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// prune removes junk elements, hidden ones and those whose class or id
+// marks them as page furniture
+func prune(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && unwanted(c)) {
+			n.RemoveChild(c)
+		} else {
+			prune(c)
+		}
+		c = next
+	}
+}
+
+func unwanted(n *html.Node) bool {
+	if junk[n.DataAtom] {
+		return true
+	}
+	if _, hidden := attr(n, "hidden"); hidden || attrValue(n, "aria-hidden") == "true" {
+		return true
+	}
+	if style := strings.ReplaceAll(strings.ToLower(attrValue(n, "style")), " ", ""); strings.Contains(style, "display:none") {
+		return true
+	}
+	if n.DataAtom == atom.Article || n.DataAtom == atom.Main || n.DataAtom == atom.Body {
+		return false
+	}
+	names := attrValue(n, "class") + " " + attrValue(n, "id")
+	return unlikely.MatchString(names) && !likely.MatchString(names)
+}
+
+// bestCandidate scores every element by the paragraphs it holds and
+// returns the highest, discounted by how much of its text is links
+func bestCandidate(body *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+		}
+		scores[n] += score
+	}
+
+	walk(body, func(n *html.Node) {
+		if !isParagraph(n) {
+			return
+		}
+		text := strings.TrimSpace(textOf(n))
+		if len(text) < minParagraphText {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text)/100), 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+	})
+
+	var top *html.Node
+	best := 0.0
+	for n, score := range scores {
+		score *= 1 - linkDensity(n)
+		if score > best || (score == best && top != nil && depth(n) > depth(top)) {
+			top, best = n, score
+		}
+	}
+	return top
+}
+
+// initialScore favours elements that usually wrap articles
+func initialScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.DataAtom {
+	case atom.Article:
+		score = 10
+	case atom.Div, atom.Main, atom.Section:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score = 3
+	case atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Address:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+	names := attrValue(n, "class") + " " + attrValue(n, "id")
+	if likely.MatchString(names) {
+		score += 25
+	}
+	if unlikely.MatchString(names) {
+		score -= 25
+	}
+	return score
+}
+
+// isParagraph reports whether n holds a run of text: a p, pre or td, or a
+// div with no block elements inside, which some sites use instead of p
+func isParagraph(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td:
+		return true
+	case atom.Div:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.DataAtom {
+			case atom.Div, atom.P, atom.Table, atom.Ul, atom.Ol, atom.Pre, atom.Blockquote, atom.Section, atom.Article:
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isTrailingParagraph reports whether a sibling of the best element reads
+// like article text rather than a teaser or a list of links
+func isTrailingParagraph(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.DataAtom != atom.P {
+		return false
+	}
+	text := strings.TrimSpace(textOf(n))
+	return len(text) > 80 && linkDensity(n) < 0.25
+}
+
+// linkDensity is the share of n's text inside links
+func linkDensity(n *html.Node) float64 {
+	total := len(textOf(n))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	walk(n, func(c *html.Node) {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			linked += len(textOf(c))
+		}
+	})
+	return min(float64(linked)/float64(total), 1)
+}
+
+// render writes n as cleaned HTML
+func render(b *strings.Builder, n *html.Node, base *url.URL) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	tag := n.DataAtom
+	if tag == atom.H1 {
+		tag = atom.H2
+	}
+	if !kept[tag] {
+		renderChildren(b, n, base)
+		return
+	}
+
+	var attrs string
+	switch tag {
+	case atom.A:
+		href, ok := absolute(base, attrValue(n, "href"))
+		if !ok {
+			renderChildren(b, n, base)
+			return
+		}
+		attrs = ` href="` + html.EscapeString(href) + `"`
+	case atom.Img:
+		src := attrValue(n, "src")
+		if lazy := attrValue(n, "data-src"); lazy != "" {
+			src = lazy
+		}
+		src, ok := absolute(base, src)
+		if !ok {
+			return
+		}
+		attrs = ` src="` + html.EscapeString(src) + `"`
+		if alt := attrValue(n, "alt"); alt != "" {
+			attrs += ` alt="` + html.EscapeString(alt) + `"`
+		}
+	case atom.P:
+		if strings.TrimSpace(textOf(n)) == "" && find(n, atom.Img) == nil {
+			return
+		}
+	}
+
+	b.WriteString("<" + tag.String() + attrs + ">")
+	if tag == atom.Img || tag == atom.Br || tag == atom.Hr {
+		return
+	}
+	renderChildren(b, n, base)
+	b.WriteString("</" + tag.String() + ">")
+}
+
+func renderChildren(b *strings.Builder, n *html.Node, base *url.URL) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		render(b, c, base)
+	}
+}
+
+// absolute resolves a link against base, keeping only web addresses
+func absolute(base *url.URL, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", false
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// textOf is the text inside n
+func textOf(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(c *html.Node) {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	})
+	return b.String()
+}
+
+// walk calls fn for n and everything inside it, in document order
+func walk(n *html.Node, fn func(*html.Node)) {
+	fn(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+// find returns the first element of the given kind inside n
+func find(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(c *html.Node) {
+		if found == nil && c.Type == html.ElementNode && c.DataAtom == a {
+			found = c
+		}
+	})
+	return found
+}
+
+func depth(n *html.Node) int {
+	d := 0
+	for ; n != nil; n = n.Parent {
+		d++
+	}
+	return d
+}
+
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func attrValue(n *html.Node, key string) string {
+	v, _ := attr(n, key)
+	return v
+}
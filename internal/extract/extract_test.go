@@ -0,0 +1,72 @@
+package extract
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const page = `<!DOCTYPE html>
+<html><head><title>Story</title><script>track()</script></head>
+<body>
+<header class="site-header"><nav><a href="/">Home</a> <a href="/news">News</a></nav></header>
+<div class="layout">
+  <div class="sidebar"><p>Trending: a long list of other stories, with commas, that should never be part of the article text.</p></div>
+  <div class="article-body" onclick="evil()">
+    <h1>Council approves the new bridge</h1>
+    <p>The city council voted on Tuesday to approve the new bridge, ending a debate that ran for more than three years.</p>
+    <img data-src="/img/bridge.jpg" src="data:image/gif;base64,R0lGOD" alt="The bridge" class="lazy">
+    <p>Supporters said the crossing would cut commuting times, ease traffic in the old town, and open the east bank to new housing.</p>
+    <p>Opponents, who filled the public gallery, argued that the cost had doubled since the first estimate and <a href="/budget">the budget</a> could not carry it.</p>
+    <p style="display: none">Hidden tracking text that should not appear anywhere.</p>
+    <div class="share-buttons"><a href="https://social.example/share">Share</a></div>
+  </div>
+  <div class="comments"><p>First! This comment is long enough to look like a paragraph, but it sits in the comments.</p></div>
+</div>
+<footer>Copyright</footer>
+</body></html>`
+
+func TestArticle(t *testing.T) {
+	base, _ := url.Parse("https://news.example/2024/bridge")
+	got, err := Article(strings.NewReader(page), base)
+	if err != nil {
+		t.Fatalf("Article: %v", err)
+	}
+
+	for _, want := range []string{
+		"<h2>Council approves the new bridge</h2>",
+		"ending a debate that ran for more than three years",
+		`<img src="https://news.example/img/bridge.jpg" alt="The bridge">`,
+		`<a href="https://news.example/budget">the budget</a>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("article lacks %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"Trending", "First!", "Home", "Share", "Hidden", "track()", "onclick", "class=", "Copyright"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("article contains %q:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestArticleTooShort(t *testing.T) {
+	_, err := Article(strings.NewReader(`<html><body><p>Just a line of text.</p></body></html>`), nil)
+	if !errors.Is(err, ErrNoArticle) {
+		t.Errorf("Article of a short page = %v, want ErrNoArticle", err)
+	}
+}
+
+func TestArticleDropsUnsafeLinks(t *testing.T) {
+	body := `<html><body><div class="content">` +
+		strings.Repeat(`<p>A paragraph of article text, long enough to count, with a <a href="javascript:alert(1)">bad link</a> inside.</p>`, 4) +
+		`</div></body></html>`
+	got, err := Article(strings.NewReader(body), nil)
+	if err != nil {
+		t.Fatalf("Article: %v", err)
+	}
+	if strings.Contains(got, "javascript:") || strings.Contains(got, "<a") {
+		t.Errorf("unsafe link kept:\n%s", got)
+	}
+}
@@ -0,0 +1,267 @@
+// internal/favicon/normalize.go
+package favicon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/draw"
+)
+
+// IconSize is the edge length, in pixels, of normalized favicons
+const IconSize = 32
+
+const (
+	maxSVGBytes   = 256 << 10
+	maxIconPixels = 1024
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// normalizeIcon decodes an ICO, SVG, PNG, GIF or JPEG favicon and re-encodes
+// it as an IconSize square PNG. It also returns the detected source format.
+func normalizeIcon(data []byte) ([]byte, string, error) {
+	var img image.Image
+	var format string
+	var err error
+
+	switch {
+	case isICO(data):
+		format = "ico"
+		img, err = decodeICO(data)
+	case isSVG(data):
+		format = "svg"
+		img, err = rasterizeSVG(data, IconSize)
+	default:
+		img, format, err = decodeImage(data)
+	}
+	if err != nil {
+		return nil, format, err
+	}
+
+	b := img.Bounds()
+	if err := checkIconSize(b.Dx(), b.Dy()); err != nil {
+		return nil, format, err
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, IconSize, IconSize))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, dst); err != nil {
+		return nil, format, err
+	}
+	return out.Bytes(), format, nil
+}
+
+// decodeImage decodes a PNG, GIF or JPEG icon. The size the header
+// declares is checked first, so a small file claiming huge dimensions is
+// refused before its pixels are allocated.
+func decodeImage(data []byte) (image.Image, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, format, err
+	}
+	if err := checkIconSize(cfg.Width, cfg.Height); err != nil {
+		return nil, format, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, format, err
+}
+
+func checkIconSize(width, height int) error {
+	if width <= 0 || height <= 0 || width > maxIconPixels || height > maxIconPixels {
+		return fmt.Errorf("unsupported icon size %dx%d", width, height)
+	}
+	return nil
+}
+
+func isICO(data []byte) bool {
+	return len(data) >= 6 && data[0] == 0 && data[1] == 0 &&
+		(data[2] == 1 || data[2] == 2) && data[3] == 0
+}
+
+func isSVG(data []byte) bool {
+	head := data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	return bytes.Contains(bytes.ToLower(head), []byte("<svg"))
+}
+
+// rasterizeSVG draws an SVG icon into a size x size image. Large documents
+// are rejected rather than parsed.
+func rasterizeSVG(data []byte, size int) (image.Image, error) {
+	if len(data) > maxSVGBytes {
+		return nil, fmt.Errorf("svg too large (%d bytes)", len(data))
+	}
+
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(data), oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing svg: %w", err)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	icon.SetTarget(0, 0, float64(size), float64(size))
+	scanner := rasterx.NewScannerGV(size, size, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(size, size, scanner), 1)
+	return img, nil
+}
+
+// decodeICO picks the frame closest to IconSize from an ICO container and
+// decodes it. Frames may be embedded PNGs or uncompressed DIBs.
+func decodeICO(data []byte) (image.Image, error) {
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	if count == 0 || len(data) < 6+count*16 {
+		return nil, errors.New("truncated ico directory")
+	}
+
+	best, bestWidth, bestBPP := -1, 0, 0
+	for i := 0; i < count; i++ {
+		entry := data[6+i*16:]
+		width := int(entry[0])
+		if width == 0 {
+			width = 256
+		}
+		bpp := int(binary.LittleEndian.Uint16(entry[6:8]))
+
+		better := best < 0
+		switch {
+		case better:
+		case width >= IconSize && (bestWidth < IconSize || width < bestWidth):
+			// Smallest frame that is at least IconSize wide
+			better = true
+		case width < IconSize && bestWidth < IconSize && width > bestWidth:
+			better = true
+		case width == bestWidth && bpp > bestBPP:
+			better = true
+		}
+		if better {
+			best, bestWidth, bestBPP = i, width, bpp
+		}
+	}
+
+	entry := data[6+best*16:]
+	size := int(binary.LittleEndian.Uint32(entry[8:12]))
+	offset := int(binary.LittleEndian.Uint32(entry[12:16]))
+	if offset < 0 || size <= 0 || offset+size > len(data) {
+		return nil, errors.New("ico frame out of range")
+	}
+	frame := data[offset : offset+size]
+
+	if bytes.HasPrefix(frame, pngSignature) {
+		cfg, err := png.DecodeConfig(bytes.NewReader(frame))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkIconSize(cfg.Width, cfg.Height); err != nil {
+			return nil, err
+		}
+		return png.Decode(bytes.NewReader(frame))
+	}
+	return decodeDIB(frame)
+}
+
+// decodeDIB decodes the BITMAPINFOHEADER-based bitmap stored in ICO frames,
+// including the trailing 1-bit transparency mask
+func decodeDIB(frame []byte) (image.Image, error) {
+	if len(frame) < 40 || binary.LittleEndian.Uint32(frame[0:4]) < 40 {
+		return nil, errors.New("unsupported ico bitmap header")
+	}
+	headerSize := int(binary.LittleEndian.Uint32(frame[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(frame[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(frame[8:12]))) / 2 // XOR + AND masks
+	bpp := int(binary.LittleEndian.Uint16(frame[14:16]))
+	compression := binary.LittleEndian.Uint32(frame[16:20])
+	colorsUsed := int(binary.LittleEndian.Uint32(frame[32:36]))
+
+	if width <= 0 || height <= 0 || width > maxIconPixels || height > maxIconPixels {
+		return nil, fmt.Errorf("unsupported ico bitmap size %dx%d", width, height)
+	}
+	if compression != 0 {
+		return nil, errors.New("compressed ico bitmaps are not supported")
+	}
+
+	pos := headerSize
+	var palette []color.NRGBA
+	switch bpp {
+	case 1, 4, 8:
+		if colorsUsed == 0 {
+			colorsUsed = 1 << bpp
+		}
+		if pos+colorsUsed*4 > len(frame) {
+			return nil, errors.New("truncated ico palette")
+		}
+		for i := 0; i < colorsUsed; i++ {
+			p := frame[pos+i*4:]
+			palette = append(palette, color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xff})
+		}
+		pos += colorsUsed * 4
+	case 24, 32:
+	default:
+		return nil, fmt.Errorf("unsupported ico bit depth %d", bpp)
+	}
+
+	stride := ((width*bpp + 31) / 32) * 4
+	maskStride := ((width + 31) / 32) * 4
+	if pos+stride*height > len(frame) {
+		return nil, errors.New("truncated ico bitmap")
+	}
+	pixels := frame[pos : pos+stride*height]
+	var mask []byte
+	if end := pos + stride*height + maskStride*height; end <= len(frame) {
+		mask = frame[pos+stride*height : end]
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		row := pixels[(height-1-y)*stride:] // rows are stored bottom-up
+		for x := 0; x < width; x++ {
+			var c color.NRGBA
+			switch bpp {
+			case 32:
+				p := row[x*4:]
+				c = color.NRGBA{R: p[2], G: p[1], B: p[0], A: p[3]}
+				hasAlpha = hasAlpha || p[3] != 0
+			case 24:
+				p := row[x*3:]
+				c = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xff}
+			default:
+				bit := x * bpp
+				idx := int(row[bit/8]>>(8-bpp-bit%8)) & (1<<bpp - 1)
+				if idx < len(palette) {
+					c = palette[idx]
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	// Apply the AND mask unless the bitmap carries its own alpha channel
+	if mask != nil && !hasAlpha {
+		for y := 0; y < height; y++ {
+			row := mask[(height-1-y)*maskStride:]
+			for x := 0; x < width; x++ {
+				if row[x/8]&(0x80>>(x%8)) != 0 {
+					img.SetNRGBA(x, y, color.NRGBA{})
+				} else if bpp == 32 {
+					c := img.NRGBAAt(x, y)
+					c.A = 0xff
+					img.SetNRGBA(x, y, c)
+				}
+			}
+		}
+	}
+
+	return img, nil
+}
@@ -0,0 +1,49 @@
+package favicon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// pngDeclaring encodes a 1x1 PNG and rewrites its header to claim the
+// given size, as a hostile favicon could
+func pngDeclaring(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	data := buf.Bytes()
+	// The IHDR chunk follows the signature: length, type, then the data
+	ihdr := data[len(pngSignature)+8:]
+	binary.BigEndian.PutUint32(ihdr[0:4], width)
+	binary.BigEndian.PutUint32(ihdr[4:8], height)
+	crc := crc32.ChecksumIEEE(data[len(pngSignature)+4 : len(pngSignature)+8+13])
+	binary.BigEndian.PutUint32(data[len(pngSignature)+8+13:], crc)
+	return data
+}
+
+// icoWrapping puts a PNG frame in an ICO container
+func icoWrapping(frame []byte) []byte {
+	header := make([]byte, 6+16)
+	binary.LittleEndian.PutUint16(header[2:4], 1)
+	binary.LittleEndian.PutUint16(header[4:6], 1)
+	binary.LittleEndian.PutUint32(header[6+8:6+12], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(header[6+12:6+16], uint32(len(header)))
+	return append(header, frame...)
+}
+
+func TestNormalizeIconRejectsDeclaredSize(t *testing.T) {
+	huge := pngDeclaring(t, 60000, 60000)
+	for name, data := range map[string][]byte{"png": huge, "ico": icoWrapping(huge)} {
+		_, _, err := normalizeIcon(data)
+		if err == nil || !strings.Contains(err.Error(), "unsupported icon size") {
+			t.Errorf("%s declaring 60000x60000: err = %v, want it refused for its size", name, err)
+		}
+	}
+}
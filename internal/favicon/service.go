@@ -2,40 +2,88 @@
 package favicon
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"infoscope/internal/security/netutil"
+	"infoscope/internal/storage"
+
 	"golang.org/x/net/html"
 )
 
+const (
+	// maxIconSize caps a downloaded icon; real favicons are a few KB
+	maxIconSize = 1 << 20
+	// maxPageSize caps how much of a site's HTML is read looking for icons
+	maxPageSize = 2 << 20
+	// maxRedirects is how many redirects a single request may follow
+	maxRedirects = 5
+	// fetchTimeout bounds the whole search for one site's icon
+	fetchTimeout = 20 * time.Second
+	// failureTTL is how long a host that had no usable icon is left alone
+	failureTTL = 6 * time.Hour
+)
+
+var errTooLarge = errors.New("response exceeds size limit")
+
+// Service fetches site icons and keeps them in a store, normalized PNGs at
+// the top level and the downloads they came from under originals/
 type Service struct {
 	client      *http.Client
-	storageDir  string
-	failedHosts sync.Map // Add this line to track failed hosts
+	store       storage.Store
+	failedHosts sync.Map // host -> time of the last failed fetch
 }
 
-func NewService(storageDir string) (*Service, error) {
-	// Ensure storage directory exists
-	if err := os.MkdirAll(storageDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create favicon storage directory: %w", err)
-	}
-
+func NewService(store storage.Store) *Service {
+	// Icon URLs come from the sites themselves, so requests may only reach
+	// public addresses, checked again on every redirect
+	dialer := netutil.SafeDialer(&net.Dialer{Timeout: 10 * time.Second})
 	return &Service{
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 10 * time.Second},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				return netutil.CheckURL(req.URL)
+			},
 		},
-		storageDir:  storageDir,
-		failedHosts: sync.Map{}, // Initialize the map
-	}, nil
+		store:       store,
+		failedHosts: sync.Map{},
+	}
+}
+
+// recentlyFailed reports whether fetching host's icon failed within failureTTL
+func (s *Service) recentlyFailed(host string) bool {
+	v, ok := s.failedHosts.Load(host)
+	if !ok {
+		return false
+	}
+	if time.Since(v.(time.Time)) > failureTTL {
+		s.failedHosts.Delete(host)
+		return false
+	}
+	return true
+}
+
+// ForgetFailures clears every host's recent failure, so the next fetch of
+// each tries its icon again instead of waiting out failureTTL
+func (s *Service) ForgetFailures() {
+	s.failedHosts.Range(func(host, _ any) bool {
+		s.failedHosts.Delete(host)
+		return true
+	})
 }
 
 func (s *Service) GetFavicon(siteURL string) (string, error) {
@@ -44,59 +92,171 @@ func (s *Service) GetFavicon(siteURL string) (string, error) {
 		return "default.ico", nil
 	}
 
-	// Check if this host has failed before
-	if _, failed := s.failedHosts.Load(u.Host); failed {
+	// Check if this host has failed recently
+	if s.recentlyFailed(u.Host) {
 		return "default.ico", nil
 	}
 
 	// Generate a consistent filename based on the domain
-	hash := sha256.Sum256([]byte(u.Host))
-	filename := hex.EncodeToString(hash[:8]) + ".ico"
-	filepath := filepath.Join(s.storageDir, filename)
+	base := iconBase(u.Host)
 
 	// Check if we already have this favicon
-	if _, err := os.Stat(filepath); err == nil {
-		// Favicon exists
-		return filename, nil
+	ctx := context.Background()
+	if _, err := s.store.Stat(ctx, base+".png"); err == nil {
+		return base + ".png", nil
+	}
+
+	// Icons stored before normalization existed are converted in place
+	if data, _, err := s.store.Get(ctx, base+".ico"); err == nil {
+		if _, _, err := normalizeIcon(data); err != nil {
+			return base + ".ico", nil
+		}
+		if filename, err := s.storeIcon(base, data); err == nil {
+			return filename, nil
+		}
+		return base + ".ico", nil
+	}
+
+	faviconData, err := s.fetchIcon(siteURL)
+	if err != nil {
+		// Mark this host as failed
+		s.failedHosts.Store(u.Host, time.Now())
+		return "default.ico", err
+	}
+
+	// Save the favicon
+	filename, err := s.storeIcon(base, faviconData)
+	if err != nil {
+		s.failedHosts.Store(u.Host, time.Now())
+		return "default.ico", err
+	}
+
+	return filename, nil
+}
+
+// Refresh downloads the favicon for siteURL again and replaces the stored
+// copy, clearing any earlier failure for the host. When the download fails
+// the stored original is normalized again instead, so a changed icon format
+// still reaches hosts that are unreachable.
+func (s *Service) Refresh(siteURL string) (string, error) {
+	u, err := url.Parse(siteURL)
+	if err != nil || u.Host == "" {
+		return "default.ico", fmt.Errorf("invalid site URL %q", siteURL)
+	}
+	s.failedHosts.Delete(u.Host)
+	base := iconBase(u.Host)
+
+	ctx := context.Background()
+	data, fetchErr := s.fetchIcon(siteURL)
+	if fetchErr != nil {
+		original := s.findOriginal(ctx, base)
+		if original == "" {
+			s.failedHosts.Store(u.Host, time.Now())
+			return "default.ico", fetchErr
+		}
+		if data, _, err = s.store.Get(ctx, original); err != nil {
+			return "default.ico", fmt.Errorf("failed to read original favicon: %w", err)
+		}
+	}
+
+	filename, err := s.storeIcon(base, data)
+	if err != nil {
+		return "default.ico", err
+	}
+
+	// Drop whichever of the PNG and legacy ICO copies is now stale
+	for _, name := range []string{base + ".png", base + ".ico"} {
+		if name != filename {
+			s.store.Delete(ctx, name)
+		}
 	}
+	return filename, nil
+}
+
+// findOriginal returns the stored download for base, whatever its format,
+// or "" when there is none
+func (s *Service) findOriginal(ctx context.Context, base string) string {
+	originals, err := s.store.List(ctx, "originals")
+	if err != nil {
+		return ""
+	}
+	for _, obj := range originals {
+		if strings.HasPrefix(obj.Name, base+".") {
+			return "originals/" + obj.Name
+		}
+	}
+	return ""
+}
+
+// iconBase derives a stable file name from a site's host
+func iconBase(host string) string {
+	hash := sha256.Sum256([]byte(host))
+	return hex.EncodeToString(hash[:8])
+}
+
+// fetchIcon tries each way of finding a site's favicon in turn
+func (s *Service) fetchIcon(siteURL string) ([]byte, error) {
+	u, err := url.Parse(siteURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := netutil.CheckURL(u); err != nil {
+		return nil, fmt.Errorf("refusing to fetch favicon for %s: %w", siteURL, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
 
-	// Try different methods to get the favicon
-	var faviconData []byte
-	methods := []func(string) ([]byte, error){
+	methods := []func(context.Context, string) ([]byte, error){
 		s.getFaviconFromHTML,
 		s.getFaviconFromRoot,
 	}
 
 	var lastError error
 	for _, method := range methods {
-		if data, err := method(siteURL); err == nil && len(data) > 0 {
-			faviconData = data
-			break
+		if data, err := method(ctx, siteURL); err == nil && len(data) > 0 {
+			return data, nil
 		} else {
 			lastError = err
 		}
 	}
 
-	if len(faviconData) == 0 {
-		// Mark this host as failed
-		s.failedHosts.Store(u.Host, true)
-		if lastError != nil {
-			return "default.ico", fmt.Errorf("failed to fetch favicon for %s: %w", siteURL, lastError)
-		}
-		return "default.ico", fmt.Errorf("no favicon found for %s", siteURL)
+	if lastError != nil {
+		return nil, fmt.Errorf("failed to fetch favicon for %s: %w", siteURL, lastError)
 	}
+	return nil, fmt.Errorf("no favicon found for %s", siteURL)
+}
 
-	// Save the favicon
-	if err := os.WriteFile(filepath, faviconData, 0644); err != nil {
-		s.failedHosts.Store(u.Host, true)
-		return "default.ico", fmt.Errorf("failed to save favicon: %w", err)
+// storeIcon keeps the original download under originals/ and writes a
+// normalized PNG next to it. Icons that cannot be decoded are stored as-is.
+func (s *Service) storeIcon(base string, data []byte) (string, error) {
+	normalized, format, normErr := normalizeIcon(data)
+	if format == "" {
+		format = "bin"
 	}
 
+	ctx := context.Background()
+	if err := s.store.Put(ctx, "originals/"+base+"."+format, data); err != nil {
+		return "", fmt.Errorf("failed to save original favicon: %w", err)
+	}
+
+	if normErr != nil {
+		filename := base + ".ico"
+		if err := s.store.Put(ctx, filename, data); err != nil {
+			return "", fmt.Errorf("failed to save favicon: %w", err)
+		}
+		return filename, nil
+	}
+
+	filename := base + ".png"
+	if err := s.store.Put(ctx, filename, normalized); err != nil {
+		return "", fmt.Errorf("failed to save favicon: %w", err)
+	}
 	return filename, nil
 }
 
-func (s *Service) getFaviconFromHTML(siteURL string) ([]byte, error) {
-	resp, err := s.client.Get(siteURL)
+func (s *Service) getFaviconFromHTML(ctx context.Context, siteURL string) ([]byte, error) {
+	resp, err := s.get(ctx, siteURL)
 	if err != nil {
 		return nil, err
 	}
@@ -106,7 +266,8 @@ func (s *Service) getFaviconFromHTML(siteURL string) ([]byte, error) {
 		return nil, fmt.Errorf("got status %d", resp.StatusCode)
 	}
 
-	doc, err := html.Parse(resp.Body)
+	// The icon link sits in the head, so a truncated page is fine
+	doc, err := html.Parse(io.LimitReader(resp.Body, maxPageSize))
 	if err != nil {
 		return nil, err
 	}
@@ -149,21 +310,21 @@ func (s *Service) getFaviconFromHTML(siteURL string) ([]byte, error) {
 		return nil, err
 	}
 
-	return s.downloadFavicon(resolved.String())
+	return s.downloadFavicon(ctx, resolved.String())
 }
 
-func (s *Service) getFaviconFromRoot(siteURL string) ([]byte, error) {
+func (s *Service) getFaviconFromRoot(ctx context.Context, siteURL string) ([]byte, error) {
 	u, err := url.Parse(siteURL)
 	if err != nil {
 		return nil, err
 	}
 
 	faviconURL := fmt.Sprintf("%s://%s/favicon.ico", u.Scheme, u.Host)
-	return s.downloadFavicon(faviconURL)
+	return s.downloadFavicon(ctx, faviconURL)
 }
 
-func (s *Service) downloadFavicon(url string) ([]byte, error) {
-	resp, err := s.client.Get(url)
+func (s *Service) downloadFavicon(ctx context.Context, url string) ([]byte, error) {
+	resp, err := s.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -172,6 +333,32 @@ func (s *Service) downloadFavicon(url string) ([]byte, error) {
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("got status %d", resp.StatusCode)
 	}
+	if resp.ContentLength > maxIconSize {
+		return nil, errTooLarge
+	}
 
-	return io.ReadAll(resp.Body)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIconSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxIconSize {
+		return nil, errTooLarge
+	}
+	return data, nil
+}
+
+// get requests rawURL after checking it is safe to fetch
+func (s *Service) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := netutil.CheckURL(u); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.client.Do(req)
 }
@@ -0,0 +1,18 @@
+package favicon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestForgetFailures(t *testing.T) {
+	s := &Service{}
+	s.failedHosts.Store("example.com", time.Now())
+	if !s.recentlyFailed("example.com") {
+		t.Fatal("Host not remembered as failed")
+	}
+	s.ForgetFailures()
+	if s.recentlyFailed("example.com") {
+		t.Error("Host still failed after ForgetFailures")
+	}
+}
@@ -0,0 +1,81 @@
+// internal/feed/adaptive.go
+package feed
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// updatePostStatsSQL refreshes a feed's posting statistics from its stored
+// entries: the average gap between posts and the newest post. It takes the
+// feed ID three times.
+const updatePostStatsSQL = `
+    UPDATE feeds SET
+        avg_post_interval_seconds = (
+            SELECT CASE WHEN COUNT(*) > 1
+                THEN CAST((julianday(MAX(published_at)) - julianday(MIN(published_at))) * 86400 / (COUNT(*) - 1) AS INTEGER)
+                ELSE 0 END
+            FROM entries WHERE feed_id = ?),
+        last_post_at = (SELECT MAX(published_at) FROM entries WHERE feed_id = ?)
+    WHERE id = ?`
+
+// adaptivePolling holds the bounds from the adaptive_poll_* settings
+type adaptivePolling struct {
+	min, max time.Duration
+}
+
+// loadAdaptivePolling reads the adaptive polling settings. The second
+// result is false when adaptive polling is off.
+func (f *Fetcher) loadAdaptivePolling(ctx context.Context) (adaptivePolling, bool) {
+	if f.getSetting(ctx, "adaptive_polling", "false") != "true" {
+		return adaptivePolling{}, false
+	}
+	minMinutes, _ := strconv.Atoi(f.getSetting(ctx, "adaptive_poll_min_minutes", "15"))
+	maxMinutes, _ := strconv.Atoi(f.getSetting(ctx, "adaptive_poll_max_minutes", "1440"))
+
+	p := adaptivePolling{
+		min: max(time.Duration(minMinutes)*time.Minute, MinFetchInterval),
+		max: min(time.Duration(maxMinutes)*time.Minute, MaxFetchInterval),
+	}
+	if p.max < p.min {
+		p.max = p.min
+	}
+	return p, true
+}
+
+// interval picks how often to poll a feed from how often it posts: about
+// twice per expected post, where a silence longer than the usual gap
+// stretches the expectation. Feeds with no posting history get 0 and keep
+// the regular schedule.
+func (p adaptivePolling) interval(feed Feed, now time.Time) time.Duration {
+	gap := feed.AvgPostInterval
+	if !feed.LastPost.IsZero() {
+		if since := now.Sub(feed.LastPost); since > gap {
+			gap = since
+		}
+	}
+	if gap <= 0 {
+		return 0
+	}
+	return min(max(gap/2, p.min), p.max)
+}
+
+// applyAdaptivePolling gives feeds without their own interval one derived
+// from their posting rate. A tag interval still caps it, so tagged feeds
+// are never polled less often than their tag asks.
+func applyAdaptivePolling(feeds []Feed, p adaptivePolling, now time.Time) {
+	for i := range feeds {
+		if feeds[i].FetchInterval > 0 {
+			continue
+		}
+		adaptive := p.interval(feeds[i], now)
+		if adaptive == 0 {
+			continue
+		}
+		if feeds[i].Interval == 0 || adaptive < feeds[i].Interval {
+			feeds[i].Interval = adaptive
+		}
+		feeds[i].Adaptive = true
+	}
+}
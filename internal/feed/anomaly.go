@@ -0,0 +1,102 @@
+// internal/feed/anomaly.go
+package feed
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Alert kinds recorded in fetch_alerts
+const (
+	AlertEntryDrop = "entry_drop"
+	AlertErrors    = "errors"
+	AlertSlowCycle = "slow_cycle"
+)
+
+const (
+	// anomalyWindow is how many earlier cycles form the trailing average
+	anomalyWindow = 10
+	// entryDropRatio flags cycles yielding less than this share of the
+	// trailing entries per feed
+	entryDropRatio = 0.25
+	// minBaselineEntries keeps quiet installs from alerting on noise
+	minBaselineEntries = 20
+	// minErrorFeeds is the smallest cycle checked for widespread errors
+	minErrorFeeds = 3
+)
+
+// cycleStats summarizes a finished fetch cycle
+type cycleStats struct {
+	id       int64
+	feeds    int
+	entries  int
+	errors   int
+	duration time.Duration
+}
+
+// checkCycle compares a cycle with the ones before it and raises an alert
+// for each symptom of a stalled pipeline it shows.
+func (f *Fetcher) checkCycle(ctx context.Context, c cycleStats) {
+	if c.feeds == 0 {
+		return
+	}
+
+	// Entries per feed keeps short tag-interval cycles comparable with
+	// full ones
+	var baseFeeds, baseEntries int
+	err := f.db.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(feed_count), 0), COALESCE(SUM(entry_count), 0)
+        FROM (SELECT feed_count, entry_count FROM fetch_cycles
+              WHERE id < ? AND feed_count > 0
+              ORDER BY id DESC LIMIT ?)`,
+		c.id, anomalyWindow).Scan(&baseFeeds, &baseEntries)
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error reading fetch cycle history", "error", err)
+	} else if baseFeeds > 0 && baseEntries >= minBaselineEntries {
+		average := float64(baseEntries) / float64(baseFeeds)
+		current := float64(c.entries) / float64(c.feeds)
+		if current < average*entryDropRatio {
+			f.raiseAlert(ctx, AlertEntryDrop, fmt.Sprintf(
+				"Fetch cycle returned %.1f entries per feed against a recent average of %.1f",
+				current, average))
+		}
+	}
+
+	if c.feeds >= minErrorFeeds && c.errors*2 > c.feeds {
+		f.raiseAlert(ctx, AlertErrors, fmt.Sprintf(
+			"%d of %d feeds failed in one fetch cycle", c.errors, c.feeds))
+	}
+
+	limit, _ := strconv.Atoi(f.getSetting(ctx, "alert_cycle_minutes", "10"))
+	if limit > 0 && c.duration > time.Duration(limit)*time.Minute {
+		f.raiseAlert(ctx, AlertSlowCycle, fmt.Sprintf(
+			"Fetch cycle took %s, longer than the %d minute limit",
+			c.duration.Round(time.Second), limit))
+	}
+}
+
+// raiseAlert logs an anomaly and records it for the dashboard. A kind that
+// is already showing is updated in place rather than repeated.
+func (f *Fetcher) raiseAlert(ctx context.Context, kind, message string) {
+	f.logger.WarnContext(ctx, "Fetch alert", "kind", kind, "message", message)
+
+	var id int64
+	err := f.db.QueryRowContext(ctx,
+		"SELECT id FROM fetch_alerts WHERE kind = ? AND dismissed_at IS NULL", kind).Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = f.db.ExecContext(ctx,
+			"INSERT INTO fetch_alerts (kind, message) VALUES (?, ?)", kind, message)
+	case err == nil:
+		_, err = f.db.ExecContext(ctx, `
+            UPDATE fetch_alerts
+            SET message = ?, occurrences = occurrences + 1, last_seen_at = CURRENT_TIMESTAMP
+            WHERE id = ?`, message, id)
+	}
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error recording fetch alert", "error", err)
+	}
+}
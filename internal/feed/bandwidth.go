@@ -0,0 +1,68 @@
+// internal/feed/bandwidth.go
+package feed
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// bandwidthBudget tracks bytes downloaded today against an optional daily limit
+type bandwidthBudget struct {
+	limit int64 // 0 means unlimited
+	used  atomic.Int64
+}
+
+// exhausted reports whether non-priority fetches should be deferred
+func (b *bandwidthBudget) exhausted() bool {
+	return b.limit > 0 && b.used.Load() >= b.limit
+}
+
+// loadBandwidthBudget reads today's usage and the configured daily budget
+func (f *Fetcher) loadBandwidthBudget(ctx context.Context) *bandwidthBudget {
+	budget := &bandwidthBudget{}
+
+	var limitMB int64
+	if err := f.db.QueryRowContext(ctx,
+		"SELECT CAST(value AS INTEGER) FROM settings WHERE key = 'daily_bandwidth_mb'",
+	).Scan(&limitMB); err == nil && limitMB > 0 {
+		budget.limit = limitMB * 1024 * 1024
+	}
+
+	var used int64
+	if err := f.db.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(bytes), 0) FROM feed_bandwidth WHERE day = date('now')",
+	).Scan(&used); err != nil {
+		f.logger.ErrorContext(ctx, "Error reading bandwidth usage", "error", err)
+	}
+	budget.used.Store(used)
+
+	return budget
+}
+
+// recordBandwidth adds one request and its downloaded bytes to today's totals
+func (f *Fetcher) recordBandwidth(ctx context.Context, feedID, bytes int64) {
+	_, err := f.db.ExecContext(ctx, `
+        INSERT INTO feed_bandwidth (feed_id, day, bytes, requests)
+        VALUES (?, date('now'), ?, 1)
+        ON CONFLICT(feed_id, day) DO UPDATE SET
+            bytes = bytes + excluded.bytes,
+            requests = requests + 1`,
+		feedID, bytes,
+	)
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error recording bandwidth for feed", "feed_id", feedID, "error", err)
+	}
+}
@@ -0,0 +1,134 @@
+// internal/feed/conditional.go
+package feed
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// conditionalRequestHeaders and conditionalResponseHeaders are the headers
+// kept from a feed's last exchange, as the ones that decide whether a 304
+// comes back
+var (
+	conditionalRequestHeaders  = []string{"If-None-Match", "If-Modified-Since"}
+	conditionalResponseHeaders = []string{
+		"ETag", "Last-Modified", "Cache-Control", "Expires", "Age", "Date",
+		"Vary", "Content-Type", "Content-Length", "Content-Encoding", "Via",
+	}
+)
+
+// ConditionalGet shows whether conditional GETs work for one feed: the
+// validators kept from its last full response, what its last request sent
+// and got back, and counts since the server started. Like the validators
+// it is kept in memory only.
+type ConditionalGet struct {
+	FeedID int64 `json:"feedId"`
+
+	// ETag and LastModified are the validators the next request will send
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	StoredAt     time.Time `json:"storedAt,omitempty"`
+
+	RequestedAt     time.Time         `json:"requestedAt"`
+	RequestHeaders  map[string]string `json:"requestHeaders"`
+	StatusCode      int               `json:"statusCode"`
+	ResponseHeaders map[string]string `json:"responseHeaders"`
+
+	// BodySHA256 is the hash of the last full body; BodyUnchanged is set
+	// when it matched the one before
+	BodySHA256    string `json:"bodySha256,omitempty"`
+	BodyUnchanged bool   `json:"bodyUnchanged"`
+
+	NotModified     int `json:"notModified"`
+	FullResponses   int `json:"fullResponses"`
+	UnchangedBodies int `json:"unchangedBodies"`
+
+	Verdict string `json:"verdict"`
+}
+
+// conditionalLog keeps each feed's last exchange. The zero value is ready
+// to use.
+type conditionalLog struct {
+	mu    sync.Mutex
+	feeds map[int64]*ConditionalGet
+}
+
+// record notes a response to a feed request. bodyHash is empty when the
+// body wasn't read, as for a 304 or an error status.
+func (l *conditionalLog) record(feedID int64, req *http.Request, resp *http.Response, bodyHash string, validators cacheEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.feeds == nil {
+		l.feeds = make(map[int64]*ConditionalGet)
+	}
+	c, ok := l.feeds[feedID]
+	if !ok {
+		c = &ConditionalGet{FeedID: feedID}
+		l.feeds[feedID] = c
+	}
+
+	c.ETag, c.LastModified, c.StoredAt = validators.etag, validators.lastModified, validators.timestamp
+	c.RequestedAt = time.Now()
+	c.RequestHeaders = pickHeaders(req.Header, conditionalRequestHeaders)
+	c.StatusCode = resp.StatusCode
+	c.ResponseHeaders = pickHeaders(resp.Header, conditionalResponseHeaders)
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		c.NotModified++
+	case bodyHash != "":
+		c.FullResponses++
+		c.BodyUnchanged = bodyHash == c.BodySHA256
+		if c.BodyUnchanged {
+			c.UnchangedBodies++
+		}
+		c.BodySHA256 = bodyHash
+	}
+	c.Verdict = c.verdict()
+}
+
+// get returns a copy of a feed's last exchange
+func (l *conditionalLog) get(feedID int64) (ConditionalGet, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, ok := l.feeds[feedID]
+	if !ok {
+		return ConditionalGet{}, false
+	}
+	return *c, true
+}
+
+// verdict sums up whether the last exchange shows conditional GET working
+func (c *ConditionalGet) verdict() string {
+	sentValidators := len(c.RequestHeaders) > 0
+	gotValidators := c.ResponseHeaders["ETag"] != "" || c.ResponseHeaders["Last-Modified"] != ""
+	switch {
+	case c.StatusCode == http.StatusNotModified:
+		return "Working: the last request was answered with 304 Not Modified"
+	case c.StatusCode >= 400:
+		return fmt.Sprintf("Unknown: the last request failed with HTTP %d", c.StatusCode)
+	case !gotValidators:
+		return "Not supported: the server sends neither ETag nor Last-Modified, so every fetch downloads the whole feed"
+	case !sentValidators:
+		return "Pending: validators are stored and the next fetch will be conditional"
+	case c.BodyUnchanged && c.RequestHeaders["If-None-Match"] != "" && c.ResponseHeaders["ETag"] != c.RequestHeaders["If-None-Match"]:
+		return "Not honored: the feed came back unchanged in full with a new ETag, so the ETag never matches"
+	case c.BodyUnchanged:
+		return "Not honored: the feed came back unchanged in full despite the validators"
+	default:
+		return "Working: the feed changed since the last fetch"
+	}
+}
+
+// pickHeaders copies the named headers that are present
+func pickHeaders(h http.Header, names []string) map[string]string {
+	picked := make(map[string]string)
+	for _, name := range names {
+		if v := h.Get(name); v != "" {
+			picked[name] = v
+		}
+	}
+	return picked
+}
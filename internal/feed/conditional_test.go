@@ -0,0 +1,55 @@
+package feed
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestConditionalLog(t *testing.T) {
+	var log conditionalLog
+	exchange := func(sent map[string]string, status int, etag, bodyHash string) ConditionalGet {
+		req, _ := http.NewRequest("GET", "https://example.com/feed", nil)
+		for name, value := range sent {
+			req.Header.Set(name, value)
+		}
+		resp := &http.Response{StatusCode: status, Header: http.Header{}}
+		if etag != "" {
+			resp.Header.Set("ETag", etag)
+		}
+		log.record(1, req, resp, bodyHash, cacheEntry{etag: etag})
+		c, _ := log.get(1)
+		return c
+	}
+	validators := map[string]string{"If-None-Match": `"v1"`}
+
+	steps := []struct {
+		name    string
+		got     ConditionalGet
+		verdict string
+	}{
+		{"first fetch", exchange(nil, 200, `"v1"`, "aaa"), "Pending"},
+		{"ignored validators", exchange(validators, 200, `"v1"`, "aaa"), "Not honored"},
+		{"new ETag each time", exchange(validators, 200, `"v2"`, "aaa"), "Not honored: the feed came back unchanged in full with a new ETag"},
+		{"changed", exchange(validators, 200, `"v1"`, "bbb"), "Working: the feed changed"},
+		{"not modified", exchange(validators, 304, "", ""), "Working: the last request was answered with 304"},
+		{"no validators", exchange(nil, 200, "", "ccc"), "Not supported"},
+	}
+	for _, step := range steps {
+		if !strings.HasPrefix(step.got.Verdict, step.verdict) {
+			t.Errorf("%s: verdict %q, want %q", step.name, step.got.Verdict, step.verdict)
+		}
+	}
+
+	final, ok := log.get(1)
+	if !ok {
+		t.Fatal("no diagnostics kept for feed 1")
+	}
+	if final.NotModified != 1 || final.FullResponses != 5 || final.UnchangedBodies != 2 {
+		t.Errorf("counts = %d 304s, %d full, %d unchanged; want 1, 5, 2",
+			final.NotModified, final.FullResponses, final.UnchangedBodies)
+	}
+	if _, ok := log.get(2); ok {
+		t.Error("diagnostics reported for a feed never fetched")
+	}
+}
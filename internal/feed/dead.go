@@ -0,0 +1,53 @@
+// internal/feed/dead.go
+package feed
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// StatusDead marks a feed disabled after too many consecutive fetch errors.
+// It is not fetched again until an admin revives it.
+const StatusDead = "dead"
+
+// NotifyFeedDead is the notification kind queued when a feed is disabled
+const NotifyFeedDead = "feed_dead"
+
+// markDeadIfFailing disables a feed whose consecutive error count has
+// reached the dead_feed_errors setting, and queues a notification for the
+// admin. A setting of 0 never disables feeds.
+func (f *Fetcher) markDeadIfFailing(ctx context.Context, feedID int64, fetchErr error) {
+	limit, _ := strconv.Atoi(f.getSetting(ctx, "dead_feed_errors", "50"))
+	if limit <= 0 {
+		return
+	}
+
+	res, err := f.db.ExecContext(ctx, `
+        UPDATE feeds SET status = ?
+        WHERE id = ? AND error_count >= ? AND COALESCE(status, '') != ?`,
+		StatusDead, feedID, limit, StatusDead)
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error disabling feed", "feed_id", feedID, "error", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return
+	}
+
+	var title string
+	if err := f.db.QueryRowContext(ctx,
+		"SELECT COALESCE(NULLIF(title, ''), url) FROM feeds WHERE id = ?", feedID,
+	).Scan(&title); err != nil {
+		title = fmt.Sprintf("Feed %d", feedID)
+	}
+	f.logger.WarnContext(ctx, "Disabled feed after consecutive errors", "feed_id", feedID, "limit", limit)
+
+	_, err = f.db.ExecContext(ctx,
+		"INSERT INTO notifications (kind, feed_id, message) VALUES (?, ?, ?)",
+		NotifyFeedDead, feedID,
+		fmt.Sprintf("%s was disabled after %d consecutive fetch errors. Last error: %v", title, limit, fetchErr))
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error queueing notification for feed", "feed_id", feedID, "error", err)
+	}
+}
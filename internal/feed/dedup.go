@@ -0,0 +1,218 @@
+// internal/feed/dedup.go
+package feed
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"infoscope/internal/database"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Cross-feed dedup modes, stored in the cross_feed_dedup setting. Collapse
+// keeps later copies of a story as duplicates of the first, which the river
+// shows once with each source; first drops later copies.
+const (
+	CrossFeedDedupOff      = "off"
+	CrossFeedDedupCollapse = "collapse"
+	CrossFeedDedupFirst    = "first"
+)
+
+const (
+	// dedupWindow is how far apart two feeds may publish a story for a
+	// matching title to count as the same story
+	dedupWindow = 48 * time.Hour
+
+	// minTitleWords keeps short, generic titles such as "Weekly links"
+	// from matching on title alone
+	minTitleWords = 4
+)
+
+// trackingParams are query parameters that only record where a click came
+// from, so they are dropped when comparing URLs
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "mc_cid": true, "mc_eid": true,
+	"ref": true, "ref_src": true, "source": true, "cmpid": true,
+}
+
+// NormalizeURL reduces an article URL to a form shared by its syndicated
+// copies: scheme, "www.", fragment, trailing slash and tracking parameters
+// are dropped and the remaining query is sorted. An unparseable URL is
+// returned as is.
+func NormalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	query := u.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var q strings.Builder
+	for _, key := range keys {
+		for _, value := range query[key] {
+			if q.Len() > 0 {
+				q.WriteByte('&')
+			}
+			q.WriteString(url.QueryEscape(key) + "=" + url.QueryEscape(value))
+		}
+	}
+
+	normalized := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if q.Len() > 0 {
+		normalized += "?" + q.String()
+	}
+	return normalized
+}
+
+// canonicalLink is the address an item stands for. Feeds relayed through
+// FeedBurner link to a redirect and give the original in origLink.
+func canonicalLink(item *gofeed.Item) string {
+	for _, ext := range item.Extensions {
+		if links := ext["origLink"]; len(links) > 0 && strings.TrimSpace(links[0].Value) != "" {
+			return strings.TrimSpace(links[0].Value)
+		}
+	}
+	return item.Link
+}
+
+// TitleHash fingerprints a title by the set of words that carry its
+// meaning, so copies differing in case, punctuation, word order or small
+// words such as "the" hash the same. Titles with too few such words to
+// tell apart from unrelated ones report false.
+func TitleHash(title string) (uint64, bool) {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	seen := make(map[string]bool, len(words))
+	kept := words[:0]
+	for _, word := range words {
+		if !titleStopwords[word] && !seen[word] {
+			seen[word] = true
+			kept = append(kept, word)
+		}
+	}
+	if len(kept) < minTitleWords {
+		return 0, false
+	}
+	sort.Strings(kept)
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(kept, " ")))
+	return h.Sum64(), true
+}
+
+// titleStopwords are left out of title hashes, as outlets add or drop them
+// freely when rewording a headline
+var titleStopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "of": true,
+	"to": true, "in": true, "on": true, "at": true, "for": true, "with": true,
+	"by": true, "from": true, "as": true, "its": true, "is": true, "are": true,
+	"was": true, "be": true, "this": true, "that": true,
+}
+
+// findDuplicate looks for a story another feed already brought in: first by
+// canonical URL, then by title hash among entries published within
+// dedupWindow. It returns the original's ID, or 0 when the entry is new.
+func findDuplicate(ctx context.Context, tx *sql.Tx, entry Entry) (int64, error) {
+	var id int64
+	err := tx.QueryRowContext(ctx, `
+        SELECT id FROM entries
+        WHERE canonical_url = ? AND feed_id != ? AND duplicate_of IS NULL
+        ORDER BY id LIMIT 1`, entry.CanonicalURL, entry.FeedID).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	hash, ok := TitleHash(entry.Title)
+	if !ok {
+		return 0, nil
+	}
+	const layout = "2006-01-02 15:04:05"
+	err = tx.QueryRowContext(ctx, `
+        SELECT id FROM entries
+        WHERE title_hash = ? AND feed_id != ? AND duplicate_of IS NULL
+          AND published_at BETWEEN ? AND ?
+        ORDER BY published_at, id LIMIT 1`,
+		int64(hash), entry.FeedID,
+		entry.PublishedAt.Add(-dedupWindow).UTC().Format(layout),
+		entry.PublishedAt.Add(dedupWindow).UTC().Format(layout)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// titleHashValue is an entry's title hash as stored, or NULL for titles
+// too short to hash
+func titleHashValue(title string) any {
+	if hash, ok := TitleHash(title); ok {
+		return int64(hash)
+	}
+	return nil
+}
+
+// backfillDedupKeys fills in the canonical URL and title hash of entries
+// stored before cross-feed dedup, so new entries can match them
+func (f *Fetcher) backfillDedupKeys(ctx context.Context) error {
+	rows, err := f.db.QueryContext(ctx, "SELECT id, url, title FROM entries WHERE canonical_url IS NULL")
+	if err != nil {
+		return err
+	}
+	type key struct {
+		id         int64
+		url, title string
+	}
+	var keys []key
+	for rows.Next() {
+		var k key
+		if err := rows.Scan(&k.id, &k.url, &k.title); err != nil {
+			rows.Close()
+			return err
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(keys) == 0 {
+		return err
+	}
+
+	err = database.WithTx(ctx, f.db, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, "UPDATE entries SET canonical_url = ?, title_hash = ? WHERE id = ?")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, k := range keys {
+			if _, err := stmt.ExecContext(ctx, NormalizeURL(k.url), titleHashValue(k.title), k.id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		f.logger.InfoContext(ctx, "Filled in dedup keys for existing entries", "count", len(keys))
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package feed
+
+import (
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"https://www.example.com/story/", "http://example.com/story", true},
+		{"https://example.com/story?utm_source=rss&utm_medium=feed", "https://example.com/story", true},
+		{"https://example.com/story#comments", "https://example.com/story", true},
+		{"https://example.com/story?b=2&a=1", "https://example.com/story?a=1&b=2&fbclid=x", true},
+		{"https://example.com:443/story", "https://example.com/story", true},
+		{"https://example.com/story?id=1", "https://example.com/story?id=2", false},
+		{"https://example.com/a", "https://example.org/a", false},
+	}
+	for _, tt := range tests {
+		if got := NormalizeURL(tt.a) == NormalizeURL(tt.b); got != tt.same {
+			t.Errorf("NormalizeURL(%q) == NormalizeURL(%q) is %v, want %v (%q, %q)",
+				tt.a, tt.b, got, tt.same, NormalizeURL(tt.a), NormalizeURL(tt.b))
+		}
+	}
+}
+
+func TestTitleHash(t *testing.T) {
+	same := [][2]string{
+		{"Rust 1.80 released with lazy cell and exclusive ranges", "Rust 1.80 Released With Lazy Cell and Exclusive Ranges!"},
+		{"Apple unveils new iPhone 16 at September event", "Apple unveils the new iPhone 16 at its September event"},
+	}
+	for _, p := range same {
+		a, ok := TitleHash(p[0])
+		b, _ := TitleHash(p[1])
+		if !ok || a != b {
+			t.Errorf("TitleHash(%q) != TitleHash(%q)", p[0], p[1])
+		}
+	}
+
+	a, _ := TitleHash("OpenAI announces new model for developers")
+	b, _ := TitleHash("Google announces new model for developers")
+	if a == b {
+		t.Error("titles naming different companies hash the same")
+	}
+	if _, ok := TitleHash("The weekly links"); ok {
+		t.Error("short title hashed")
+	}
+}
+
+func TestCanonicalLink(t *testing.T) {
+	item := &gofeed.Item{
+		Link: "https://feedproxy.google.com/~r/example/~3/abc/",
+		Extensions: ext.Extensions{
+			"feedburner": {"origLink": {{Value: "https://example.com/story"}}},
+		},
+	}
+	if got := canonicalLink(item); got != "https://example.com/story" {
+		t.Errorf("canonicalLink = %q, want the origLink", got)
+	}
+	item.Extensions = nil
+	if got := canonicalLink(item); got != item.Link {
+		t.Errorf("canonicalLink without origLink = %q, want the link", got)
+	}
+}
@@ -0,0 +1,183 @@
+// internal/feed/errors.go
+package feed
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fetch error classes recorded in the fetch log
+const (
+	ErrorClassDNS        = "dns"
+	ErrorClassTimeout    = "timeout"
+	ErrorClassTLS        = "tls"
+	ErrorClassConnection = "connection"
+	ErrorClassNotFound   = "not_found"
+	ErrorClassGone       = "gone"
+	ErrorClassForbidden  = "forbidden"
+	ErrorClassServer     = "server_error"
+	ErrorClassRateLimit  = "rate_limited"
+	ErrorClassHTTP       = "http_error"
+	ErrorClassRedirect   = "redirect"
+	ErrorClassParse      = "parse"
+	ErrorClassOther      = "other"
+)
+
+// StatusError is returned when a feed responds with an unexpected HTTP status.
+// RetryAfter is set when the server asked us to back off.
+type StatusError struct {
+	StatusCode int
+	FinalURL   string
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("unexpected status %d %s, retry after %s",
+			e.StatusCode, http.StatusText(e.StatusCode), e.RetryAfter)
+	}
+	return fmt.Sprintf("unexpected status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// maxRetryAfter caps how long a Retry-After header can defer a feed
+const maxRetryAfter = 7 * 24 * time.Hour
+
+// parseRetryAfter interprets a Retry-After header given either as delay
+// seconds or as an HTTP date. It returns zero when the header is absent or
+// unusable.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	var delay time.Duration
+	if secs, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		delay = at.Sub(now)
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay
+}
+
+// ParseError wraps a failure to parse a fetched document as a feed. FinalURL is
+// set when the request was redirected to a different location.
+type ParseError struct {
+	FinalURL string
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("error parsing feed: %v", e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyFetchError maps a fetch error onto one of the ErrorClass constants
+func ClassifyFetchError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusNotFound:
+			return ErrorClassNotFound
+		case statusErr.StatusCode == http.StatusGone:
+			return ErrorClassGone
+		case statusErr.StatusCode == http.StatusUnauthorized, statusErr.StatusCode == http.StatusForbidden:
+			return ErrorClassForbidden
+		case statusErr.StatusCode == http.StatusTooManyRequests, statusErr.RetryAfter > 0:
+			return ErrorClassRateLimit
+		case statusErr.StatusCode >= 500:
+			return ErrorClassServer
+		default:
+			return ErrorClassHTTP
+		}
+	}
+
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		if parseErr.FinalURL != "" {
+			return ErrorClassRedirect
+		}
+		return ErrorClassParse
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrorClassDNS
+	}
+
+	var certErr *tls.CertificateVerificationError
+	var unknownAuth x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	if errors.As(err, &certErr) || errors.As(err, &unknownAuth) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidCert) || errors.As(err, &recordErr) {
+		return ErrorClassTLS
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClassTimeout
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return ErrorClassConnection
+	}
+
+	if strings.Contains(err.Error(), "error parsing feed") {
+		return ErrorClassParse
+	}
+	return ErrorClassOther
+}
+
+// SuggestedAction returns a short maintenance hint for an error class
+func SuggestedAction(class string) string {
+	switch class {
+	case ErrorClassDNS:
+		return "DNS lookup failed: the domain may have expired or moved. Check the site and remove the feed if it is gone."
+	case ErrorClassTimeout:
+		return "The server is slow or unreachable. Usually transient; investigate if it persists for days."
+	case ErrorClassTLS:
+		return "Certificate problem on the remote site. Check whether the site still serves a valid HTTPS certificate."
+	case ErrorClassConnection:
+		return "Connection refused or reset. The host may be down or blocking requests."
+	case ErrorClassNotFound:
+		return "The feed URL returns 404. Look for a new feed URL on the site and re-add it."
+	case ErrorClassGone:
+		return "The feed was permanently removed (410). Delete the subscription."
+	case ErrorClassForbidden:
+		return "Access denied. The site may block bots or require authentication."
+	case ErrorClassServer:
+		return "The remote server is failing (5xx). Usually transient."
+	case ErrorClassRateLimit:
+		return "The site is rate limiting us. Fetches are deferred as asked; consider lowering per-host concurrency."
+	case ErrorClassRedirect:
+		return "Redirect detected to a document that is not a feed. Update the feed URL to the redirect target's feed."
+	case ErrorClassParse:
+		return "The response is not a valid feed. The URL may now point to an HTML page; re-validate it."
+	default:
+		return "Check the last error message for details."
+	}
+}
@@ -0,0 +1,107 @@
+// internal/feed/favicons.go
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FaviconProgress reports how far a favicon refresh has got. Sites are
+// counted once however many feeds share them.
+type FaviconProgress struct {
+	Total  int `json:"total"`
+	Done   int `json:"done"`
+	Failed int `json:"failed"`
+}
+
+// RefreshFavicons downloads every site's favicon again, regenerating the
+// normalized icons, and points existing entries at the new files. progress
+// is called once the sites are known and after each one.
+func (s *Service) RefreshFavicons(ctx context.Context, progress func(FaviconProgress)) error {
+	return s.RefreshFeedFavicons(ctx, nil, progress)
+}
+
+// RefreshFeedFavicons is RefreshFavicons for the sites of the given feeds
+// only, or of every feed when feedIDs is empty.
+func (s *Service) RefreshFeedFavicons(ctx context.Context, feedIDs []int64, progress func(FaviconProgress)) error {
+	cond := ""
+	args := make([]any, 0, len(feedIDs))
+	if len(feedIDs) > 0 {
+		cond = " AND id IN (" + strings.TrimSuffix(strings.Repeat("?,", len(feedIDs)), ",") + ")"
+		for _, id := range feedIDs {
+			args = append(args, id)
+		}
+	}
+
+	// Feeds fetched before site URLs were recorded fall back to the feed URL
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, COALESCE(NULLIF(site_url, ''), url)
+        FROM feeds
+        WHERE status != 'deleted'`+cond+`
+        ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("listing feeds: %w", err)
+	}
+
+	var sites []string
+	feedsBySite := make(map[string][]int64)
+	for rows.Next() {
+		var id int64
+		var site string
+		if err := rows.Scan(&id, &site); err != nil {
+			rows.Close()
+			return err
+		}
+		u, err := url.Parse(site)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		// Icons are stored per host, so each host is fetched once
+		key := u.Scheme + "://" + u.Host
+		if _, ok := feedsBySite[key]; !ok {
+			sites = append(sites, key)
+		}
+		feedsBySite[key] = append(feedsBySite[key], id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	p := FaviconProgress{Total: len(sites)}
+	progress(p)
+
+	// A version parameter makes browsers drop icons cached under the old URL
+	version := time.Now().Unix()
+	for _, site := range sites {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		filename, err := s.faviconSvc.Refresh(site)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "Error refreshing favicon", "site", site, "error", err)
+			p.Failed++
+		} else {
+			ids := feedsBySite[site]
+			args := []any{fmt.Sprintf("/static/favicons/%s?v=%d", filename, version)}
+			for _, id := range ids {
+				args = append(args, id)
+			}
+			placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+			if _, err := s.db.ExecContext(ctx,
+				"UPDATE entries SET favicon_url = ? WHERE feed_id IN ("+placeholders+")",
+				args...); err != nil {
+				s.logger.ErrorContext(ctx, "Error updating favicons", "site", site, "error", err)
+				p.Failed++
+			}
+		}
+
+		p.Done++
+		progress(p)
+	}
+	return nil
+}
@@ -5,68 +5,52 @@ package feed
 import (
 	"context"
 	"database/sql"
-	"log"
+	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"infoscope/internal/database"
 	"infoscope/internal/favicon"
+	"infoscope/internal/logging"
+	"infoscope/internal/storage"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// newTestDB returns a database with the full schema in a temporary directory
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "test.db"), database.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db.DB
+}
+
 type testEnv struct {
 	db         *sql.DB
-	logger     *log.Logger
+	logger     *slog.Logger
 	faviconSvc *favicon.Service
 	service    *Service
 	fetcher    *Fetcher
 }
 
 func setupTest(t *testing.T) *testEnv {
-	// Create test database
-	db, err := sql.Open("sqlite3", ":memory:")
-	if err != nil {
-		t.Fatalf("Failed to open test database: %v", err)
-	}
-
-	// Create tables
-	_, err = db.Exec(`
-        CREATE TABLE feeds (
-            id INTEGER PRIMARY KEY,
-            url TEXT UNIQUE NOT NULL,
-            title TEXT,
-            last_fetched TIMESTAMP
-        );
-
-        CREATE TABLE entries (
-            id INTEGER PRIMARY KEY,
-            feed_id INTEGER NOT NULL,
-            title TEXT NOT NULL,
-            url TEXT UNIQUE NOT NULL,
-            published_at TIMESTAMP NOT NULL,
-            favicon_url TEXT,
-            FOREIGN KEY (feed_id) REFERENCES feeds(id)
-        );
-
-        CREATE TABLE settings (
-            key TEXT PRIMARY KEY,
-            value TEXT NOT NULL
-        );
-    `)
-	if err != nil {
-		t.Fatalf("Failed to create test tables: %v", err)
-	}
+	db := newTestDB(t)
 
 	// Create test logger
-	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
 	// Create temporary favicon directory
-	tempDir := t.TempDir()
-	faviconSvc, err := favicon.NewService(tempDir)
+	store, err := storage.NewDir(t.TempDir())
 	if err != nil {
-		t.Fatalf("Failed to create favicon service: %v", err)
+		t.Fatalf("Failed to create favicon directory: %v", err)
 	}
+	faviconSvc := favicon.NewService(store)
 
 	// Create service and fetcher
 	service := NewService(db, logger, faviconSvc)
@@ -87,7 +71,7 @@ func TestService(t *testing.T) {
 
 	// Test: Add feed
 	t.Run("Add feed", func(t *testing.T) {
-		err := env.service.AddFeed("https://blog.golang.org/feed.atom")
+		_, err := env.service.AddFeed("https://blog.golang.org/feed.atom")
 		if err != nil {
 			t.Fatalf("Failed to add feed: %v", err)
 		}
@@ -213,3 +197,170 @@ func TestFetcher(t *testing.T) {
 		}
 	})
 }
+
+func TestUpdateEntryByGUID(t *testing.T) {
+	db := newTestDB(t)
+	for _, stmt := range []string{
+		"INSERT INTO feeds (id, url, title) VALUES (1, 'https://example.com/feed', 'Example')",
+		"INSERT INTO entries (feed_id, guid, title, url, published_at, favicon_url) VALUES (1, 'g1', 'Draft', 'https://example.com/a', '2024-01-01 08:00:00', '')",
+		"INSERT INTO entries (feed_id, guid, title, url, published_at, favicon_url) VALUES (1, 'g2', 'Other', 'https://example.com/b', '2024-01-01 08:00:00', '')",
+		"INSERT OR REPLACE INTO settings (key, value, type) VALUES ('dedup_key', 'guid', 'string')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed database: %v", err)
+		}
+	}
+
+	// Same-day edits are newer and applied; g2 moving onto g1's old URL
+	// keeps its own
+	fetcher := NewFetcher(db, logging.Discard(), nil)
+	edited := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	_, err := fetcher.saveFeedEntries(context.Background(), FetchResult{
+		Feed: Feed{ID: 1},
+		Entries: []Entry{
+			{FeedID: 1, GUID: "g1", Title: "Final", URL: "https://example.com/a2", PublishedAt: edited},
+			{FeedID: 1, GUID: "g2", Title: "Other, edited", URL: "https://example.com/a2", PublishedAt: edited},
+		},
+	})
+	if err != nil {
+		t.Fatalf("saveFeedEntries failed: %v", err)
+	}
+
+	for guid, want := range map[string][2]string{
+		"g1": {"Final", "https://example.com/a2"},
+		"g2": {"Other, edited", "https://example.com/b"},
+	} {
+		var title, url string
+		if err := db.QueryRow("SELECT title, url FROM entries WHERE guid = ?", guid).Scan(&title, &url); err != nil {
+			t.Fatalf("Failed to read entry %s: %v", guid, err)
+		}
+		if title != want[0] || url != want[1] {
+			t.Errorf("Entry %s is %q at %s, want %q at %s", guid, title, url, want[0], want[1])
+		}
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		title, site, want string
+	}{
+		{"Budget passes — The Example Times", "The Example Times", "Budget passes"},
+		{"Budget passes | Example Times", "The Example Times", "Budget passes"},
+		{"  Spaced   out\n title ", "Example", "Spaced out title"},
+		{"Tom &amp; Jerry &#8211; a review", "", "Tom & Jerry – a review"},
+		{"Left - Right", "Other Site", "Left - Right"},
+		{"Example Times", "Example Times", "Example Times"},
+	}
+
+	for _, tt := range tests {
+		if got := CleanTitle(tt.title, tt.site); got != tt.want {
+			t.Errorf("CleanTitle(%q, %q) = %q, want %q", tt.title, tt.site, got, tt.want)
+		}
+	}
+}
+
+func TestHostLimiter(t *testing.T) {
+	limiter := newHostLimiter(1, 50*time.Millisecond)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := limiter.Acquire(ctx, "https://Example.com/feed")
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Same-host requests were not spaced out: %v", elapsed)
+	}
+
+	// Other hosts are not held up by the first one
+	start = time.Now()
+	release, err := limiter.Acquire(ctx, "https://other.example.org/rss")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	release()
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("Different host was delayed: %v", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"-5", 0},
+		{"soon", 0},
+		{"Mon, 01 Jan 2024 13:00:00 GMT", time.Hour},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0},
+		{"99999999", maxRetryAfter},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		tag, language, region string
+	}{
+		{"en-us", "en", "US"},
+		{"pt_BR", "pt", "BR"},
+		{"zh-Hant-TW", "zh", "TW"},
+		{"es-419", "es", "419"},
+		{"FR", "fr", ""},
+		{"english", "", ""},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		language, region := ParseLocale(tt.tag)
+		if language != tt.language || region != tt.region {
+			t.Errorf("ParseLocale(%q) = %q, %q, want %q, %q", tt.tag, language, region, tt.language, tt.region)
+		}
+	}
+}
+
+func TestTagPriorities(t *testing.T) {
+	feeds := []Feed{
+		{ID: 1, Tags: "world"},
+		{ID: 2, Tags: "breaking, world"},
+		{ID: 3},
+		{ID: 4, Tags: "Breaking,tech"},
+	}
+	applyTagPriorities(feeds, map[string]tagPriority{
+		"breaking": {priority: 10, interval: 5 * time.Minute},
+		"world":    {priority: 1, interval: 30 * time.Minute},
+	})
+
+	wantRank := []int{1, 10, 0, 10}
+	wantInterval := []time.Duration{30 * time.Minute, 5 * time.Minute, 0, 5 * time.Minute}
+	for i, feed := range feeds {
+		if feed.Rank != wantRank[i] || feed.Interval != wantInterval[i] {
+			t.Errorf("feed %d: rank %d interval %s, want %d %s",
+				feed.ID, feed.Rank, feed.Interval, wantRank[i], wantInterval[i])
+		}
+	}
+
+	var order [][]int64
+	for _, tier := range rankTiers(feeds) {
+		var ids []int64
+		for _, feed := range tier {
+			ids = append(ids, feed.ID)
+		}
+		order = append(order, ids)
+	}
+	if fmt.Sprint(order) != "[[2 4] [1] [3]]" {
+		t.Errorf("rankTiers = %v, want [[2 4] [1] [3]]", order)
+	}
+}
@@ -3,35 +3,57 @@ package feed
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"infoscope/internal/database"
 	"infoscope/internal/favicon"
+	"infoscope/internal/hooks"
 
 	"github.com/mmcdole/gofeed"
 )
 
 type Fetcher struct {
 	db         *sql.DB
-	logger     *log.Logger
+	logger     *slog.Logger
 	parser     *gofeed.Parser
 	client     *http.Client
 	faviconSvc *favicon.Service
 	cache      *sync.Map // Add in-memory cache
+
+	// articleClient downloads linked articles for full-content feeds
+	articleClient *http.Client
+
+	// running keeps regular cycles and tag interval passes from overlapping
+	running sync.Mutex
+
+	// hooks hears about fetched feeds and new entries; nil drops them
+	hooks *hooks.Registry
+
+	// conditional keeps each feed's last exchange for diagnosing 304s
+	conditional conditionalLog
 }
 
-func NewFetcher(db *sql.DB, logger *log.Logger, faviconSvc *favicon.Service) *Fetcher {
+func NewFetcher(db *sql.DB, logger *slog.Logger, faviconSvc *favicon.Service) *Fetcher {
 	return &Fetcher{
 		db:         db,
 		logger:     logger,
 		parser:     gofeed.NewParser(),
-		client:     &http.Client{Timeout: 30 * time.Second}, // Increased timeout
+		client:     newHTTPClient(DefaultTransportConfig()),
 		faviconSvc: faviconSvc,
 		cache:      &sync.Map{},
+
+		articleClient: newArticleClient(),
 	}
 }
 
@@ -42,45 +64,164 @@ type cacheEntry struct {
 }
 
 func (f *Fetcher) UpdateFeeds(ctx context.Context) error {
-	f.logger.Printf("Starting feed update...")
+	f.running.Lock()
+	defer f.running.Unlock()
+
+	f.logger.DebugContext(ctx, "Starting feed update")
+	startedAt := time.Now()
+
+	feeds, err := f.loadFeeds(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Feeds with their own or an adaptive interval wait until it has elapsed
+	due := feeds[:0]
+	for _, feed := range feeds {
+		if (feed.FetchInterval == 0 && !feed.Adaptive) || feed.due(startedAt) {
+			due = append(due, feed)
+		}
+	}
+	feeds = due
+	f.logger.DebugContext(ctx, "Found feeds to update", "count", len(feeds))
+
+	return f.fetchFeeds(ctx, feeds, startedAt)
+}
+
+// UpdateDueFeeds fetches only the feeds whose tag or own interval has
+// elapsed since they were last fetched. It does nothing while a regular
+// cycle runs.
+func (f *Fetcher) UpdateDueFeeds(ctx context.Context) error {
+	if !f.running.TryLock() {
+		return nil
+	}
+	defer f.running.Unlock()
+
+	startedAt := time.Now()
+	feeds, err := f.loadFeeds(ctx)
+	if err != nil {
+		return err
+	}
+
+	var due []Feed
+	for _, feed := range feeds {
+		if feed.Interval > 0 && feed.due(startedAt) {
+			due = append(due, feed)
+		}
+	}
+	if len(due) == 0 {
+		return nil
+	}
+	f.logger.DebugContext(ctx, "Fetching feeds due by interval", "count", len(due))
 
-	// Get all feeds from database
-	rows, err := f.db.QueryContext(ctx, "SELECT id, url, title FROM feeds")
+	return f.fetchFeeds(ctx, due, startedAt)
+}
+
+// loadFeeds returns the feeds that may be fetched now, skipping snoozed and
+// dead feeds and those a server asked us to leave alone, with their tag
+// priorities and own intervals applied.
+func (f *Fetcher) loadFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := f.db.QueryContext(ctx, `
+        SELECT id, url, title, COALESCE(priority, 0), COALESCE(tags, ''),
+               COALESCE(datetime(last_fetched), ''), COALESCE(fetch_interval_seconds, 0),
+               COALESCE(avg_post_interval_seconds, 0), COALESCE(datetime(last_post_at), ''),
+               COALESCE(full_content, 0), COALESCE(max_entries_per_feed, 0),
+               COALESCE(retention_days, 0)
+        FROM feeds
+        WHERE (next_retry_at IS NULL OR next_retry_at <= datetime('now'))
+          AND (snoozed_until IS NULL OR snoozed_until <= datetime('now'))
+          AND COALESCE(status, '') != 'dead'`)
 	if err != nil {
-		return fmt.Errorf("error querying feeds: %w", err)
+		return nil, fmt.Errorf("error querying feeds: %w", err)
 	}
 	defer rows.Close()
 
 	var feeds []Feed
 	for rows.Next() {
 		var feed Feed
-		if err := rows.Scan(&feed.ID, &feed.URL, &feed.Title); err != nil {
-			f.logger.Printf("Error scanning feed: %v", err)
+		var lastFetched, lastPost string
+		var intervalSeconds, avgPostSeconds int
+		if err := rows.Scan(&feed.ID, &feed.URL, &feed.Title, &feed.Priority, &feed.Tags, &lastFetched, &intervalSeconds,
+			&avgPostSeconds, &lastPost, &feed.FullContent, &feed.MaxEntries, &feed.RetentionDays); err != nil {
+			f.logger.ErrorContext(ctx, "Error scanning feed", "error", err)
 			continue
 		}
+		if t, err := time.Parse("2006-01-02 15:04:05", lastFetched); err == nil {
+			feed.LastFetched = t
+		}
+		if t, err := time.Parse("2006-01-02 15:04:05", lastPost); err == nil {
+			feed.LastPost = t
+		}
+		feed.FetchInterval = time.Duration(intervalSeconds) * time.Second
+		feed.AvgPostInterval = time.Duration(avgPostSeconds) * time.Second
 		feeds = append(feeds, feed)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading feeds: %w", err)
+	}
+
+	applyTagPriorities(feeds, f.loadTagPriorities(ctx))
+	for i := range feeds {
+		if feeds[i].FetchInterval > 0 {
+			feeds[i].Interval = feeds[i].FetchInterval
+		}
+	}
+	if adaptive, ok := f.loadAdaptivePolling(ctx); ok {
+		applyAdaptivePolling(feeds, adaptive, time.Now().UTC())
+	}
+	return feeds, nil
+}
+
+// fetchFeeds fetches and stores the given feeds as one recorded cycle.
+func (f *Fetcher) fetchFeeds(ctx context.Context, feeds []Feed, startedAt time.Time) error {
 
-	f.logger.Printf("Found %d feeds to update", len(feeds))
+	// Limit how hard we hit any single host
+	hostConcurrency, _ := strconv.Atoi(f.getSetting(ctx, "host_concurrency", "2"))
+	hostDelayMS, _ := strconv.Atoi(f.getSetting(ctx, "host_delay_ms", "1000"))
+	limiter := newHostLimiter(hostConcurrency, time.Duration(hostDelayMS)*time.Millisecond)
+
+	// Once the daily bandwidth budget is spent only priority feeds are fetched
+	budget := f.loadBandwidthBudget(ctx)
+	var deferred atomic.Int32
 
 	// Create a channel for results
 	results := make(chan FetchResult, len(feeds))
 	var wg sync.WaitGroup
 
-	// Fetch feeds concurrently
-	for _, feed := range feeds {
-		wg.Add(1)
-		go func(feed Feed) {
-			defer wg.Done()
-			f.logger.Printf("Fetching feed: %s", feed.URL)
-			result := f.fetchFeed(ctx, feed)
-			if result.Error != nil {
-				f.logger.Printf("Error fetching feed %s: %v", feed.URL, result.Error)
-			} else {
-				f.logger.Printf("Successfully fetched %d entries from %s", len(result.Entries), feed.URL)
-			}
-			results <- result
-		}(feed)
+	// Fetch feeds concurrently. Feeds in a higher tag priority tier get
+	// their host slots before the next tier is started.
+	for _, tier := range rankTiers(feeds) {
+		var started sync.WaitGroup
+		for _, feed := range tier {
+			wg.Add(1)
+			started.Add(1)
+			go func(feed Feed) {
+				defer wg.Done()
+				if !feed.Priority && budget.exhausted() {
+					started.Done()
+					deferred.Add(1)
+					return
+				}
+				release, err := limiter.Acquire(ctx, feed.URL)
+				started.Done()
+				if err != nil {
+					results <- FetchResult{Feed: feed, Error: err}
+					return
+				}
+				defer release()
+
+				f.logger.DebugContext(ctx, "Fetching feed", "feed_url", feed.URL)
+				result := f.fetchFeed(ctx, feed)
+				budget.used.Add(result.Bytes)
+				if result.Error != nil {
+					f.logger.ErrorContext(ctx, "Error fetching feed", "feed_url", feed.URL, "error", result.Error)
+				} else {
+					f.logger.DebugContext(ctx, "Successfully fetched entries", "count", len(result.Entries), "feed_url", feed.URL)
+				}
+				results <- result
+			}(feed)
+		}
+		started.Wait()
 	}
 
 	// Wait for all fetches to complete
@@ -90,23 +231,148 @@ func (f *Fetcher) UpdateFeeds(ctx context.Context) error {
 	}()
 
 	// Process results
+	var entryCount, errorCount int
 	for result := range results {
+		if result.Requested {
+			f.recordBandwidth(ctx, result.Feed.ID, result.Bytes)
+			f.recordFetchStats(ctx, result)
+		}
+
+		var statusErr *StatusError
+		if errors.As(result.Error, &statusErr) && statusErr.RetryAfter > 0 {
+			f.logger.WarnContext(ctx, "Feed asked us to back off", "feed_url", result.Feed.URL, "retry_after", statusErr.RetryAfter)
+			f.deferFeed(ctx, result.Feed.ID, statusErr)
+			continue
+		}
+		fetched := hooks.FeedFetch{FeedID: result.Feed.ID, URL: result.Feed.URL}
 		if result.Error != nil {
-			f.logger.Printf("Error fetching feed %s: %v", result.Feed.URL, result.Error)
+			f.logger.ErrorContext(ctx, "Error fetching feed", "feed_url", result.Feed.URL, "error", result.Error)
+			errorCount++
+			f.recordFetchError(ctx, result.Feed.ID, result.Error)
+			fetched.Error = result.Error.Error()
+			f.hooks.FeedFetched(ctx, fetched)
 			continue
 		}
 
-		if err := f.saveFeedEntries(ctx, result); err != nil {
-			f.logger.Printf("Error saving entries for feed %s: %v", result.Feed.URL, err)
+		added, err := f.saveFeedEntries(ctx, result)
+		if err != nil {
+			f.logger.ErrorContext(ctx, "Error saving entries for feed", "feed_url", result.Feed.URL, "error", err)
+			errorCount++
+			f.recordFetchError(ctx, result.Feed.ID, err)
+			fetched.Error = err.Error()
+			f.hooks.FeedFetched(ctx, fetched)
+			continue
 		}
+		entryCount += len(result.Entries)
+		f.clearFetchError(ctx, result.Feed.ID)
+		if added > 0 {
+			f.recordNewEntries(ctx, result.Feed.ID, added)
+		}
+		fetched.NewEntries = added
+		f.hooks.FeedFetched(ctx, fetched)
+	}
+
+	if n := deferred.Load(); n > 0 {
+		f.logger.WarnContext(ctx, "Daily bandwidth budget spent, deferred non-priority feeds", "deferred", n)
 	}
 
-	f.logger.Printf("Feed update completed")
+	// Record cycle statistics
+	cycle := cycleStats{
+		feeds:    len(feeds),
+		entries:  entryCount,
+		errors:   errorCount,
+		duration: time.Since(startedAt),
+	}
+	res, err := f.db.ExecContext(ctx, `
+        INSERT INTO fetch_cycles (started_at, duration_ms, feed_count, entry_count, error_count)
+        VALUES (DATETIME(?), ?, ?, ?, ?)`,
+		startedAt.UTC().Format("2006-01-02 15:04:05"), cycle.duration.Milliseconds(),
+		cycle.feeds, cycle.entries, cycle.errors,
+	)
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error recording fetch cycle", "error", err)
+	} else if cycle.id, err = res.LastInsertId(); err == nil {
+		f.checkCycle(ctx, cycle)
+	}
+
+	// Keep the fetch log bounded
+	if _, err := f.db.ExecContext(ctx,
+		"DELETE FROM fetch_log WHERE created_at < datetime('now', '-90 days')",
+	); err != nil {
+		f.logger.ErrorContext(ctx, "Error pruning fetch log", "error", err)
+	}
+	if _, err := f.db.ExecContext(ctx,
+		"DELETE FROM feed_bandwidth WHERE day < date('now', '-90 days')",
+	); err != nil {
+		f.logger.ErrorContext(ctx, "Error pruning bandwidth stats", "error", err)
+	}
+	if _, err := f.db.ExecContext(ctx,
+		"DELETE FROM feed_fetch_stats WHERE day < date('now', '-90 days')",
+	); err != nil {
+		f.logger.ErrorContext(ctx, "Error pruning fetch stats", "error", err)
+	}
+
+	f.logger.DebugContext(ctx, "Feed update completed")
 	return nil
 }
 
-func (f *Fetcher) fetchFeed(ctx context.Context, feed Feed) FetchResult {
-	result := FetchResult{Feed: feed}
+// recordFetchError increments a feed's error count and stores the last
+// error, disabling the feed once the errors have gone on too long
+func (f *Fetcher) recordFetchError(ctx context.Context, feedID int64, fetchErr error) {
+	_, err := f.db.ExecContext(ctx,
+		"UPDATE feeds SET error_count = COALESCE(error_count, 0) + 1, last_error = ? WHERE id = ?",
+		fetchErr.Error(), feedID,
+	)
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error recording fetch error for feed", "feed_id", feedID, "error", err)
+	}
+
+	_, err = f.db.ExecContext(ctx,
+		"INSERT INTO fetch_log (feed_id, status, error_class, message) VALUES (?, 'error', ?, ?)",
+		feedID, ClassifyFetchError(fetchErr), fetchErr.Error(),
+	)
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error writing fetch log for feed", "feed_id", feedID, "error", err)
+	}
+
+	f.markDeadIfFailing(ctx, feedID, fetchErr)
+}
+
+// deferFeed postpones a feed's next fetch until the server's Retry-After
+// has elapsed. This is not counted as a failure.
+func (f *Fetcher) deferFeed(ctx context.Context, feedID int64, statusErr *StatusError) {
+	retryAt := time.Now().Add(statusErr.RetryAfter).UTC()
+	_, err := f.db.ExecContext(ctx,
+		"UPDATE feeds SET next_retry_at = DATETIME(?), last_error = ? WHERE id = ?",
+		retryAt.Format("2006-01-02 15:04:05"), statusErr.Error(), feedID,
+	)
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error deferring feed", "feed_id", feedID, "error", err)
+	}
+
+	_, err = f.db.ExecContext(ctx,
+		"INSERT INTO fetch_log (feed_id, status, error_class, message) VALUES (?, 'deferred', ?, ?)",
+		feedID, ErrorClassRateLimit, statusErr.Error(),
+	)
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error writing fetch log for feed", "feed_id", feedID, "error", err)
+	}
+}
+
+// clearFetchError resets a feed's error state after a successful fetch
+func (f *Fetcher) clearFetchError(ctx context.Context, feedID int64) {
+	_, err := f.db.ExecContext(ctx, `
+        UPDATE feeds SET error_count = 0, last_error = NULL, next_retry_at = NULL
+        WHERE id = ? AND (error_count > 0 OR next_retry_at IS NOT NULL)`,
+		feedID,
+	)
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error clearing fetch error for feed", "feed_id", feedID, "error", err)
+	}
+}
+
+func (f *Fetcher) fetchFeed(ctx context.Context, feed Feed) (result FetchResult) {
+	result = FetchResult{Feed: feed}
 
 	// Check cache
 	cacheKey := fmt.Sprintf("feed_%d", feed.ID)
@@ -129,16 +395,50 @@ func (f *Fetcher) fetchFeed(ctx context.Context, feed Feed) FetchResult {
 		}
 	}
 
+	result.Requested = true
+	requestedAt := time.Now()
 	resp, err := f.client.Do(req)
 	if err != nil {
 		result.Error = fmt.Errorf("error fetching feed: %w", err)
 		return result
 	}
 	defer resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+	result.Latency = time.Since(requestedAt)
+
+	// Account for everything read from the body, whichever way we return
+	body := &countingReader{r: resp.Body}
+	defer func() { result.Bytes += body.n }()
+
+	// Keep the exchange and the validators it left for the diagnostics
+	var bodyHash string
+	defer func() {
+		validators, _ := f.cache.Load(cacheKey)
+		stored, _ := validators.(cacheEntry)
+		f.conditional.record(feed.ID, req, resp, bodyHash, stored)
+	}()
 
 	// Handle 304 Not Modified
 	if resp.StatusCode == http.StatusNotModified {
-		f.logger.Printf("Feed %s not modified since last fetch", feed.URL)
+		f.logger.DebugContext(ctx, "Feed not modified since last fetch", "feed_url", feed.URL)
+		if feed.FullContent {
+			f.fullContent(ctx, &result)
+		}
+		return result
+	}
+
+	// Note redirects so parse failures can be attributed to them
+	finalURL := ""
+	if resp.Request != nil && resp.Request.URL.String() != feed.URL {
+		finalURL = resp.Request.URL.String()
+	}
+
+	if resp.StatusCode >= 400 {
+		statusErr := &StatusError{StatusCode: resp.StatusCode, FinalURL: finalURL}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			statusErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+		result.Error = statusErr
 		return result
 	}
 
@@ -149,153 +449,408 @@ func (f *Fetcher) fetchFeed(ctx context.Context, feed Feed) FetchResult {
 		timestamp:    time.Now(),
 	})
 
-	// Parse feed
-	parsedFeed, err := f.parser.Parse(resp.Body)
+	// Parse feed, hashing the body to tell whether it really changed. The
+	// parser may stop short of the end, so the rest is read into the hash.
+	hash := sha256.New()
+	parsedFeed, err := f.parser.Parse(io.TeeReader(body, hash))
+	io.Copy(hash, body)
+	bodyHash = hex.EncodeToString(hash.Sum(nil))
 	if err != nil {
-		result.Error = fmt.Errorf("error parsing feed: %w", err)
+		result.Error = &ParseError{FinalURL: finalURL, Err: err}
 		return result
 	}
 
+	result.Language = parsedFeed.Language
+	result.SiteURL = parsedFeed.Link
+
 	// Get latest entry timestamp from database
-	var latestTimestampStr sql.NullString
-	err = f.db.QueryRowContext(ctx,
-		`SELECT COALESCE(MAX(published_at), '') FROM entries WHERE feed_id = ?`,
-		feed.ID,
-	).Scan(&latestTimestampStr)
+	latestTimestamp := f.latestPublished(ctx, feed)
 
-	var latestTimestamp time.Time
-	if err != nil && err != sql.ErrNoRows {
-		f.logger.Printf("Warning: error getting latest timestamp for feed %s: %v", feed.URL, err)
-	} else if latestTimestampStr.Valid && latestTimestampStr.String != "" {
-		latestTimestamp, err = time.Parse("2006-01-02 15:04:05", latestTimestampStr.String)
-		if err != nil {
-			f.logger.Printf("Warning: error parsing timestamp %s for feed %s: %v",
-				latestTimestampStr.String, feed.URL, err)
-		}
-	}
+	// Check whether titles should be normalized at ingest
+	cleanTitles := f.getSetting(ctx, "clean_titles", "false") == "true"
 
 	// Process entries
 	var newEntries []Entry
 	for _, item := range parsedFeed.Items {
-		pubDate := item.PublishedParsed
-		if pubDate == nil {
-			pubDate = item.UpdatedParsed
-		}
-		if pubDate == nil {
-			now := time.Now()
-			pubDate = &now
-		}
+		entry := newEntry(feed, parsedFeed, item, cleanTitles)
 
 		// Skip entries older than latest timestamp if we have one
-		if !latestTimestamp.IsZero() && pubDate.Before(latestTimestamp) {
+		if !latestTimestamp.IsZero() && entry.PublishedAt.Before(latestTimestamp) {
 			continue
 		}
 
 		// Get or create favicon
 		faviconFile, err := f.faviconSvc.GetFavicon(parsedFeed.Link)
 		if err != nil {
-			f.logger.Printf("Error getting favicon for %s: %v", parsedFeed.Link, err)
+			f.logger.ErrorContext(ctx, "Error getting favicon", "link", parsedFeed.Link, "error", err)
 			faviconFile = "default.ico"
 		}
-
-		entry := Entry{
-			FeedID:      feed.ID,
-			Title:       item.Title,
-			URL:         item.Link,
-			Content:     item.Description,
-			GUID:        item.GUID,
-			PublishedAt: *pubDate,
-			FaviconURL:  "/static/favicons/" + faviconFile,
-		}
+		entry.FaviconURL = "/static/favicons/" + faviconFile
 		newEntries = append(newEntries, entry)
 	}
 
 	result.Entries = newEntries
+	if feed.FullContent {
+		f.fullContent(ctx, &result)
+	}
 	return result
 }
 
-func (f *Fetcher) saveFeedEntries(ctx context.Context, result FetchResult) error {
+// latestPublished returns when the feed's newest stored entry was
+// published, or the zero time when it has none. Older items are not taken
+// again.
+func (f *Fetcher) latestPublished(ctx context.Context, feed Feed) time.Time {
+	var latest string
+	err := f.db.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(published_at), '') FROM entries WHERE feed_id = ?`,
+		feed.ID,
+	).Scan(&latest)
+	if err != nil {
+		f.logger.WarnContext(ctx, "Error getting latest timestamp for feed", "feed_url", feed.URL, "error", err)
+		return time.Time{}
+	}
+	if latest == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006-01-02 15:04:05", latest)
+	if err != nil {
+		f.logger.WarnContext(ctx, "Error parsing timestamp for feed", "timestamp", latest, "feed_url", feed.URL, "error", err)
+	}
+	return t
+}
+
+// newEntry makes the entry stored for a parsed item, without its favicon.
+// Items without a date are dated now.
+func newEntry(feed Feed, parsed *gofeed.Feed, item *gofeed.Item, cleanTitles bool) Entry {
+	pubDate := item.PublishedParsed
+	if pubDate == nil {
+		pubDate = item.UpdatedParsed
+	}
+	if pubDate == nil {
+		now := time.Now()
+		pubDate = &now
+	}
+
+	title := item.Title
+	if cleanTitles {
+		title = CleanTitle(item.Title, parsed.Title)
+	}
+
+	return Entry{
+		FeedID:      feed.ID,
+		Title:       title,
+		RawTitle:    item.Title,
+		URL:         item.Link,
+		Content:     item.Description,
+		GUID:        item.GUID,
+		PublishedAt: *pubDate,
+
+		CanonicalURL: NormalizeURL(canonicalLink(item)),
+	}
+}
+
+// saveFeedEntries stores a fetch's entries and returns how many were new.
+// Hooks hear about the new ones once they are committed.
+func (f *Fetcher) saveFeedEntries(ctx context.Context, result FetchResult) (int, error) {
+	f.saveExtracted(ctx, result.Extracted)
+	if result.Language != "" {
+		f.updateFeedLocale(ctx, result.Feed.ID, result.Language)
+	}
+	if result.SiteURL != "" {
+		if _, err := f.db.ExecContext(ctx,
+			"UPDATE feeds SET site_url = ? WHERE id = ? AND COALESCE(site_url, '') != ?",
+			result.SiteURL, result.Feed.ID, result.SiteURL); err != nil {
+			f.logger.ErrorContext(ctx, "Error updating site URL for feed", "feed_id", result.Feed.ID, "error", err)
+		}
+	}
+
 	if len(result.Entries) == 0 {
 		// Update last_fetched time even if no new entries
 		_, err := f.db.ExecContext(ctx,
 			"UPDATE feeds SET last_fetched = DATETIME(?) WHERE id = ?",
 			time.Now().UTC().Format("2006-01-02 15:04:05"), result.Feed.ID,
 		)
-		return err
+		if err != nil {
+			return 0, err
+		}
+		// Feeds that have been unchanged since posting statistics were
+		// added still need them once
+		if result.Feed.LastPost.IsZero() {
+			_, err = f.db.ExecContext(ctx, updatePostStatsSQL, result.Feed.ID, result.Feed.ID, result.Feed.ID)
+		}
+		return 0, err
 	}
 
-	tx, err := f.db.BeginTx(ctx, nil)
-	if err != nil {
+	// Determine how duplicates are detected and how upstream edits are applied
+	dedupKey := f.getSetting(ctx, "dedup_key", DedupByURL)
+	updateMode := f.getSetting(ctx, "entry_update_mode", UpdateIfNewer)
+	crossFeedDedup := f.getSetting(ctx, "cross_feed_dedup", CrossFeedDedupOff)
+
+	keep := f.retention(ctx, result.Feed)
+	maxPosts, expired, archive := keep.maxPosts, keep.expired, keep.archive
+
+	var added []hooks.Entry
+	err := database.WithTx(ctx, f.db, func(tx *sql.Tx) error {
+		added = added[:0]
+
+		// Update feed last_fetched time
+		_, err := tx.ExecContext(ctx,
+			"UPDATE feeds SET last_fetched = DATETIME(?) WHERE id = ?",
+			time.Now().UTC().Format("2006-01-02 15:04:05"), result.Feed.ID,
+		)
+		if err != nil {
+			return err
+		}
+
+		// Prepare statement for inserting entries
+		stmt, err := tx.PrepareContext(ctx, `
+    INSERT INTO entries (
+        feed_id, title, raw_title, url, content, guid, 
+        published_at, favicon_url, canonical_url, title_hash, duplicate_of,
+        content_extracted
+    )
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `+upsertClause(updateMode))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		// Insert entries
+		for _, entry := range result.Entries {
+			if !expired.IsZero() && entry.PublishedAt.Before(expired) {
+				continue
+			}
+			if dedupKey == DedupByGUID && entry.GUID != "" {
+				updated, err := f.updateEntryByGUID(ctx, tx, entry, updateMode)
+				if err != nil {
+					f.logger.ErrorContext(ctx, "Error updating entry by GUID", "guid", entry.GUID, "error", err)
+					continue
+				}
+				if updated {
+					continue
+				}
+			}
+
+			// URLs are unique, so an entry seen before is an update
+			var exists bool
+			if err := tx.QueryRowContext(ctx,
+				"SELECT EXISTS (SELECT 1 FROM entries WHERE url = ?)", entry.URL,
+			).Scan(&exists); err != nil {
+				f.logger.ErrorContext(ctx, "Error checking entry", "entry_url", entry.URL, "error", err)
+				continue
+			}
+
+			// A story another feed already brought in is collapsed into
+			// that entry or dropped
+			var duplicateOf any
+			if !exists && crossFeedDedup != CrossFeedDedupOff {
+				original, err := findDuplicate(ctx, tx, entry)
+				if err != nil {
+					f.logger.ErrorContext(ctx, "Error looking for duplicate entry", "entry_url", entry.URL, "error", err)
+				} else if original != 0 {
+					if crossFeedDedup == CrossFeedDedupFirst {
+						f.logger.DebugContext(ctx, "Dropping duplicate entry", "entry_url", entry.URL, "original", original)
+						continue
+					}
+					duplicateOf = original
+				}
+			}
+
+			res, err := stmt.ExecContext(ctx,
+				entry.FeedID,
+				entry.Title,
+				entry.RawTitle,
+				entry.URL,
+				entry.Content,
+				entry.GUID,
+				entry.PublishedAt.UTC().Format("2006-01-02 15:04:05"),
+				entry.FaviconURL,
+				entry.CanonicalURL,
+				titleHashValue(entry.Title),
+				duplicateOf,
+				entry.ContentExtracted,
+			)
+			if err != nil {
+				f.logger.ErrorContext(ctx, "Error inserting entry", "entry_url", entry.URL, "error", err)
+				continue
+			}
+			if !exists && duplicateOf == nil {
+				id, _ := res.LastInsertId()
+				added = append(added, hooks.Entry{
+					ID:          id,
+					FeedID:      entry.FeedID,
+					Title:       entry.Title,
+					URL:         entry.URL,
+					GUID:        entry.GUID,
+					PublishedAt: entry.PublishedAt,
+				})
+			}
+		}
+
+		// Delete old entries more efficiently, keeping starred ones
+		if !archive {
+			_, err = tx.ExecContext(ctx, `
+        DELETE FROM entries 
+        WHERE id IN (
+            SELECT id FROM entries 
+            WHERE feed_id = ? AND starred_at IS NULL
+            ORDER BY published_at DESC, id DESC
+            LIMIT -1 OFFSET ?
+        )
+    `, result.Feed.ID, maxPosts)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Keep the posting rate used by adaptive polling current
+		_, err = tx.ExecContext(ctx, updatePostStatsSQL, result.Feed.ID, result.Feed.ID, result.Feed.ID)
 		return err
+	})
+	if err != nil {
+		return 0, err
 	}
-	defer tx.Rollback()
 
-	// Update feed last_fetched time
-	_, err = tx.ExecContext(ctx,
-		"UPDATE feeds SET last_fetched = DATETIME(?) WHERE id = ?",
-		time.Now().UTC().Format("2006-01-02 15:04:05"), result.Feed.ID,
-	)
+	for _, e := range added {
+		f.hooks.EntryIngested(ctx, e)
+	}
+	return len(added), nil
+}
+
+// retentionLimits are how much of a feed is kept
+type retentionLimits struct {
+	// maxPosts is how many entries the feed keeps
+	maxPosts int
+	// expired is the publication time before which entries are not
+	// stored; zero keeps every age
+	expired time.Time
+	// archive keeps everything, and maxPosts is not applied
+	archive bool
+}
+
+// retention reads the feed's retention limits. Entries already past the
+// retention window are not stored, or they would be deleted and ingested
+// again as new on every fetch. Archive mode keeps everything.
+func (f *Fetcher) retention(ctx context.Context, feed Feed) retentionLimits {
+	var keep retentionLimits
+
+	// Number of entries kept per feed, unless the feed sets its own
+	err := f.db.QueryRowContext(ctx,
+		"SELECT COALESCE(CAST(value AS INTEGER), 33) FROM settings WHERE key = 'max_posts'",
+	).Scan(&keep.maxPosts)
 	if err != nil {
-		return err
+		keep.maxPosts = 33 // Default value
+	}
+	if feed.MaxEntries > 0 {
+		keep.maxPosts = feed.MaxEntries
 	}
 
-	// Prepare statement for inserting entries
-	stmt, err := tx.PrepareContext(ctx, `
-    INSERT INTO entries (
-        feed_id, title, url, content, guid, 
-        published_at, favicon_url
-    )
-    VALUES (?, ?, ?, ?, ?, ?, ?)
-    ON CONFLICT(url) DO UPDATE SET
+	keep.archive = f.getSetting(ctx, "archive_mode", "false") == "true"
+	retentionDays := feed.RetentionDays
+	if retentionDays == 0 {
+		retentionDays, _ = strconv.Atoi(f.getSetting(ctx, "retention_days", "0"))
+	}
+	if retentionDays > 0 && !keep.archive {
+		keep.expired = time.Now().AddDate(0, 0, -retentionDays)
+	}
+	return keep
+}
+
+// Entry dedup keys and update modes, stored in the dedup_key and
+// entry_update_mode settings
+const (
+	DedupByURL  = "url"
+	DedupByGUID = "guid"
+
+	UpdateIfNewer = "newer"
+	UpdateAlways  = "always"
+	UpdateNever   = "ignore"
+)
+
+// upsertClause returns the ON CONFLICT clause matching the entry update mode
+func upsertClause(mode string) string {
+	switch mode {
+	case UpdateNever:
+		return "ON CONFLICT(url) DO NOTHING"
+	case UpdateAlways:
+		return `ON CONFLICT(url) DO UPDATE SET
         title = excluded.title,
+        raw_title = excluded.raw_title,
         content = excluded.content,
+        content_extracted = excluded.content_extracted,
+        published_at = excluded.published_at`
+	default:
+		return `ON CONFLICT(url) DO UPDATE SET
+        title = excluded.title,
+        raw_title = excluded.raw_title,
+        content = excluded.content,
+        content_extracted = excluded.content_extracted,
         published_at = excluded.published_at
-        WHERE excluded.published_at > published_at
-`)
+        WHERE excluded.published_at > published_at`
+	}
+}
+
+// updateEntryByGUID applies an upstream item to an existing entry with the same
+// GUID, following its URL if it changed. It reports whether a match was found.
+// A new URL another entry already has is not followed, as URLs are unique; the
+// rest of the item is still applied.
+func (f *Fetcher) updateEntryByGUID(ctx context.Context, tx *sql.Tx, entry Entry, mode string) (bool, error) {
+	var existingID int64
+	var existingURL string
+	var existingPublished time.Time
+	err := tx.QueryRowContext(ctx,
+		"SELECT id, url, published_at FROM entries WHERE feed_id = ? AND guid = ?",
+		entry.FeedID, entry.GUID,
+	).Scan(&existingID, &existingURL, &existingPublished)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
 	if err != nil {
-		return err
+		return false, err
 	}
-	defer stmt.Close()
-
-	// Insert entries
-	for _, entry := range result.Entries {
-		_, err = stmt.ExecContext(ctx,
-			entry.FeedID,
-			entry.Title,
-			entry.URL,
-			entry.Content,
-			entry.GUID,
-			entry.PublishedAt.UTC().Format("2006-01-02 15:04:05"),
-			entry.FaviconURL,
-		)
-		if err != nil {
-			f.logger.Printf("Error inserting entry %s: %v", entry.URL, err)
-			continue
-		}
+
+	if mode == UpdateNever || (mode != UpdateAlways && !entry.PublishedAt.After(existingPublished)) {
+		return true, nil
 	}
 
-	// Clean old entries
-	var maxPosts int
-	err = f.db.QueryRowContext(ctx,
-		"SELECT COALESCE(CAST(value AS INTEGER), 33) FROM settings WHERE key = 'max_posts'",
-	).Scan(&maxPosts)
-	if err != nil {
-		maxPosts = 33 // Default value
+	url := entry.URL
+	if url != existingURL {
+		var taken bool
+		if err := tx.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM entries WHERE url = ? AND id != ?)", url, existingID,
+		).Scan(&taken); err != nil {
+			return true, err
+		}
+		if taken {
+			f.logger.DebugContext(ctx, "Keeping entry URL already used by another entry",
+				"guid", entry.GUID, "entry_url", existingURL, "new_url", url)
+			url = existingURL
+		}
 	}
 
-	// Delete old entries more efficiently
 	_, err = tx.ExecContext(ctx, `
-        DELETE FROM entries 
-        WHERE id IN (
-            SELECT id FROM entries 
-            WHERE feed_id = ? 
-            ORDER BY published_at DESC
-            LIMIT -1 OFFSET ?
-        )
-    `, result.Feed.ID, maxPosts)
+        UPDATE entries SET
+            url = ?, title = ?, raw_title = ?, content = ?, content_extracted = ?, published_at = ?
+        WHERE id = ?`,
+		url, entry.Title, entry.RawTitle, entry.Content, entry.ContentExtracted,
+		entry.PublishedAt.UTC().Format("2006-01-02 15:04:05"), existingID,
+	)
+	return true, err
+}
+
+// getSetting reads a setting value, returning fallback when it is missing
+func (f *Fetcher) getSetting(ctx context.Context, key, fallback string) string {
+	var value string
+	err := f.db.QueryRowContext(ctx, "SELECT value FROM settings WHERE key = ?", key).Scan(&value)
 	if err != nil {
-		return err
+		if err != sql.ErrNoRows {
+			f.logger.WarnContext(ctx, "Error reading setting", "key", key, "error", err)
+		}
+		return fallback
 	}
-
-	return tx.Commit()
+	if value == "" {
+		return fallback
+	}
+	return value
 }
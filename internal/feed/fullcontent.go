@@ -0,0 +1,238 @@
+// internal/feed/fullcontent.go
+package feed
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"infoscope/internal/extract"
+	"infoscope/internal/security/netutil"
+
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// maxArticlesPerFetch caps the articles downloaded on one fetch of a
+	// feed, so switching a busy feed to full content doesn't pull its whole
+	// backlog at once. Downloads left over go to stored entries still
+	// carrying the feed's summary.
+	maxArticlesPerFetch = 10
+
+	// fullContentRetryAge is how long after publication an entry whose
+	// article couldn't be extracted is tried again
+	fullContentRetryAge = 7 * 24 * time.Hour
+
+	// maxArticleBytes caps the page read for one article
+	maxArticleBytes = 5 << 20
+
+	// articleDelay spaces out article downloads, which usually go to the
+	// same site as the feed
+	articleDelay = time.Second
+
+	// maxArticleRedirects is how many redirects an article link may take
+	maxArticleRedirects = 5
+)
+
+// newArticleClient returns the client for article downloads. Entry links
+// come from the feeds rather than the admin, so like favicon requests they
+// may only reach public addresses, checked again on every redirect.
+func newArticleClient() *http.Client {
+	dialer := netutil.SafeDialer(&net.Dialer{Timeout: 10 * time.Second})
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 10 * time.Second},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxArticleRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxArticleRedirects)
+			}
+			return netutil.CheckURL(req.URL)
+		},
+	}
+}
+
+// fullContent fills a full-content feed's fetch result with articles: the
+// new entries' first, then, with the downloads left, those of stored
+// entries that still carry the feed's summary because their article
+// couldn't be extracted before or didn't fit in an earlier fetch.
+func (f *Fetcher) fullContent(ctx context.Context, result *FetchResult) {
+	stored, downloaded, fetched := f.fetchFullContent(ctx, result.Entries)
+	result.Extracted = stored
+	result.Bytes += downloaded
+	if fetched < maxArticlesPerFetch && ctx.Err() == nil {
+		extracted, downloaded := f.retryFullContent(ctx, result.Feed, result.Entries, maxArticlesPerFetch-fetched)
+		result.Extracted = append(result.Extracted, extracted...)
+		result.Bytes += downloaded
+	}
+}
+
+// fetchFullContent replaces the summaries of a full-content feed's entries
+// with the articles they link to. Entries extracted before reuse what was
+// stored; those whose article can't be fetched or holds no article keep
+// the feed's summary, and retryFullContent tries them again on later
+// fetches. Articles of entries already stored are also returned with their
+// IDs, since the update mode only lets the feed's item overwrite a stored
+// entry when it is newer. It returns those entries, the bytes downloaded
+// and the articles requested.
+func (f *Fetcher) fetchFullContent(ctx context.Context, entries []Entry) ([]Entry, int64, int) {
+	var stored []Entry
+	var downloaded int64
+	fetched := 0
+	for i := range entries {
+		entry := &entries[i]
+
+		var id int64
+		var content string
+		var extracted bool
+		err := f.db.QueryRowContext(ctx,
+			"SELECT id, COALESCE(content, ''), COALESCE(content_extracted, 0) FROM entries WHERE url = ?", entry.URL,
+		).Scan(&id, &content, &extracted)
+		if err == nil && extracted {
+			entry.Content, entry.ContentExtracted = content, true
+			continue
+		}
+		if err != nil && err != sql.ErrNoRows {
+			f.logger.ErrorContext(ctx, "Error checking extracted content", "entry_url", entry.URL, "error", err)
+			continue
+		}
+
+		if fetched == maxArticlesPerFetch {
+			continue
+		}
+		if fetched > 0 && !sleepCtx(ctx, articleDelay) {
+			return stored, downloaded, fetched
+		}
+		fetched++
+
+		article, n, err := f.fetchArticle(ctx, entry.URL)
+		downloaded += n
+		if err != nil {
+			f.logger.DebugContext(ctx, "Keeping feed summary, article extraction failed", "entry_url", entry.URL, "error", err)
+			continue
+		}
+		entry.Content, entry.ContentExtracted = article, true
+		if id != 0 {
+			stored = append(stored, Entry{ID: id, URL: entry.URL, Content: article, ContentExtracted: true})
+		}
+	}
+	return stored, downloaded, fetched
+}
+
+// retryFullContent downloads, newest first and at most limit of them, the
+// articles of the feed's stored entries that still carry its summary and
+// were published within fullContentRetryAge. The entries in tried were
+// just attempted and are left out. It returns the entries extracted, with
+// their IDs, and the bytes downloaded; saveFeedEntries stores them.
+func (f *Fetcher) retryFullContent(ctx context.Context, feed Feed, tried []Entry, limit int) ([]Entry, int64) {
+	rows, err := f.db.QueryContext(ctx, `
+        SELECT id, url FROM entries
+        WHERE feed_id = ? AND COALESCE(content_extracted, 0) = 0 AND published_at >= ?
+        ORDER BY published_at DESC, id DESC
+        LIMIT ?`,
+		feed.ID, time.Now().Add(-fullContentRetryAge).UTC().Format("2006-01-02 15:04:05"), limit+len(tried))
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error listing entries to extract", "feed_url", feed.URL, "error", err)
+		return nil, 0
+	}
+	skip := make(map[string]bool, len(tried))
+	for _, e := range tried {
+		skip[e.URL] = true
+	}
+	var pending []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.URL); err != nil {
+			f.logger.ErrorContext(ctx, "Error scanning entry to extract", "error", err)
+			continue
+		}
+		if !skip[e.URL] && len(pending) < limit {
+			pending = append(pending, e)
+		}
+	}
+	rows.Close()
+
+	var extracted []Entry
+	var downloaded int64
+	for _, e := range pending {
+		// The feed itself was just requested from the same site
+		if !sleepCtx(ctx, articleDelay) {
+			break
+		}
+		content, n, err := f.fetchArticle(ctx, e.URL)
+		downloaded += n
+		if err != nil {
+			f.logger.DebugContext(ctx, "Article extraction failed again", "entry_url", e.URL, "error", err)
+			continue
+		}
+		e.Content, e.ContentExtracted = content, true
+		extracted = append(extracted, e)
+	}
+	return extracted, downloaded
+}
+
+// saveExtracted stores the articles extracted for entries already stored
+func (f *Fetcher) saveExtracted(ctx context.Context, entries []Entry) {
+	for _, e := range entries {
+		if _, err := f.db.ExecContext(ctx,
+			"UPDATE entries SET content = ?, content_extracted = 1 WHERE id = ?", e.Content, e.ID,
+		); err != nil {
+			f.logger.ErrorContext(ctx, "Error storing extracted article", "entry_url", e.URL, "error", err)
+		}
+	}
+}
+
+// sleepCtx waits for d, reporting false if ctx ends first
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// fetchArticle downloads the page at link and extracts its article,
+// returning the cleaned HTML and the bytes read
+func (f *Fetcher) fetchArticle(ctx context.Context, link string) (string, int64, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := netutil.CheckURL(u); err != nil {
+		return "", 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	resp, err := f.articleClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	body := &countingReader{r: io.LimitReader(resp.Body, maxArticleBytes)}
+	if resp.StatusCode >= 400 {
+		return "", body.n, &StatusError{StatusCode: resp.StatusCode}
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" && !strings.Contains(contentType, "html") {
+		return "", body.n, fmt.Errorf("not an HTML page: %s", contentType)
+	}
+
+	// Pages aren't always UTF-8; the header or a meta tag says otherwise
+	page, err := charset.NewReader(body, contentType)
+	if err != nil {
+		return "", body.n, err
+	}
+	content, err := extract.Article(page, resp.Request.URL)
+	return content, body.n, err
+}
@@ -0,0 +1,100 @@
+package feed
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"infoscope/internal/logging"
+)
+
+func TestFullContentKeptAcrossFetches(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.Exec("INSERT INTO feeds (id, url, title, full_content) VALUES (1, 'https://example.com/feed', 'Example', 1)"); err != nil {
+		t.Fatalf("Failed to seed feed: %v", err)
+	}
+
+	// The article can't be had on the first fetch, and can afterwards
+	paragraph := strings.Repeat("The story goes on at some length about the matter. ", 20)
+	var requests atomic.Int32
+	articles := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body><article><p>" + paragraph + "</p></article></body></html>"))
+	}))
+	defer articles.Close()
+
+	f := NewFetcher(db, logging.Discard(), nil)
+	// Every article host is the test server
+	f.articleClient = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, articles.Listener.Addr().String())
+		},
+	}}
+
+	// The feed is unchanged between fetches
+	feed := Feed{ID: 1, URL: "https://example.com/feed", FullContent: true}
+	item := Entry{
+		FeedID:      1,
+		Title:       "Story",
+		URL:         "http://news.example.com/story",
+		Content:     "Summary",
+		PublishedAt: time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC),
+	}
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		result := FetchResult{Feed: feed, Entries: []Entry{item}}
+		f.fullContent(ctx, &result)
+		if _, err := f.saveFeedEntries(ctx, result); err != nil {
+			t.Fatalf("Fetch %d: saveFeedEntries failed: %v", i+1, err)
+		}
+	}
+
+	var content string
+	var extracted bool
+	if err := db.QueryRow("SELECT content, content_extracted FROM entries WHERE url = ?", item.URL).Scan(&content, &extracted); err != nil {
+		t.Fatalf("Failed to read entry: %v", err)
+	}
+	if !extracted || !strings.Contains(content, "The story goes on") {
+		t.Errorf("Stored content %q (extracted %v), want the article", content, extracted)
+	}
+	// Once stored, the article isn't downloaded again
+	if n := requests.Load(); n != 2 {
+		t.Errorf("Article requested %d times, want 2", n)
+	}
+}
+
+func TestSaveExtractedIsNotARevision(t *testing.T) {
+	db := newTestDB(t)
+	for _, stmt := range []string{
+		"INSERT INTO feeds (id, url, title) VALUES (1, 'https://example.com/feed', 'Example')",
+		"INSERT INTO entries (id, feed_id, title, url, content, published_at, favicon_url) VALUES (1, 1, 'Story', 'https://example.com/a', 'Summary', CURRENT_TIMESTAMP, '')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed database: %v", err)
+		}
+	}
+
+	f := NewFetcher(db, logging.Discard(), nil)
+	f.saveExtracted(context.Background(), []Entry{{ID: 1, Content: "<p>Article</p>", ContentExtracted: true}})
+	var revisions int
+	if err := db.QueryRow("SELECT COUNT(*) FROM entry_revisions").Scan(&revisions); err != nil || revisions != 0 {
+		t.Errorf("Found %d revisions after extraction (%v), want none", revisions, err)
+	}
+
+	// An edit upstream still is one
+	if _, err := db.Exec("UPDATE entries SET title = 'Story, corrected' WHERE id = 1"); err != nil {
+		t.Fatalf("Failed to edit entry: %v", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM entry_revisions").Scan(&revisions); err != nil || revisions != 1 {
+		t.Errorf("Found %d revisions after an edit (%v), want 1", revisions, err)
+	}
+}
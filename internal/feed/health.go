@@ -0,0 +1,55 @@
+// internal/feed/health.go
+package feed
+
+import (
+	"context"
+	"net/http"
+)
+
+// recordFetchStats adds one fetch to the feed's daily health totals: its
+// HTTP outcome and how long the server took to respond.
+func (f *Fetcher) recordFetchStats(ctx context.Context, result FetchResult) {
+	var ok, notModified, clientErrors, serverErrors, networkErrors int
+	switch code := result.StatusCode; {
+	case code == 0:
+		networkErrors = 1
+	case code == http.StatusNotModified:
+		notModified = 1
+	case code >= 500:
+		serverErrors = 1
+	case code >= 400:
+		clientErrors = 1
+	default:
+		ok = 1
+	}
+
+	_, err := f.db.ExecContext(ctx, `
+        INSERT INTO feed_fetch_stats (feed_id, day, fetches, ok, not_modified,
+                                      client_errors, server_errors, network_errors, latency_ms)
+        VALUES (?, date('now'), 1, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT(feed_id, day) DO UPDATE SET
+            fetches = fetches + 1,
+            ok = ok + excluded.ok,
+            not_modified = not_modified + excluded.not_modified,
+            client_errors = client_errors + excluded.client_errors,
+            server_errors = server_errors + excluded.server_errors,
+            network_errors = network_errors + excluded.network_errors,
+            latency_ms = latency_ms + excluded.latency_ms`,
+		result.Feed.ID, ok, notModified, clientErrors, serverErrors, networkErrors,
+		result.Latency.Milliseconds(),
+	)
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error recording fetch stats for feed", "feed_id", result.Feed.ID, "error", err)
+	}
+}
+
+// recordNewEntries adds entries that arrived to the feed's totals for today
+func (f *Fetcher) recordNewEntries(ctx context.Context, feedID int64, added int) {
+	_, err := f.db.ExecContext(ctx,
+		"UPDATE feed_fetch_stats SET new_entries = new_entries + ? WHERE feed_id = ? AND day = date('now')",
+		added, feedID,
+	)
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error recording new entries for feed", "feed_id", feedID, "error", err)
+	}
+}
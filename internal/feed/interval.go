@@ -0,0 +1,39 @@
+// internal/feed/interval.go
+package feed
+
+import (
+	"context"
+	"time"
+)
+
+// Bounds for a feed's own fetch interval, stored in
+// feeds.fetch_interval_seconds
+const (
+	MinFetchInterval = time.Minute
+	MaxFetchInterval = 7 * 24 * time.Hour
+)
+
+// due reports whether a feed's interval has elapsed at now. A tenth of the
+// interval is allowed as slack, since last_fetched is stamped a little after
+// the pass that fetched the feed started.
+func (feed Feed) due(now time.Time) bool {
+	if feed.Interval <= 0 || feed.LastFetched.IsZero() {
+		return true
+	}
+	return now.Sub(feed.LastFetched) >= feed.Interval-feed.Interval/10
+}
+
+// shortestFetchInterval returns the shortest fetch interval set on a feed
+// itself, or 0 when no feed has one.
+func (s *Service) shortestFetchInterval(ctx context.Context) time.Duration {
+	var seconds int
+	err := s.db.QueryRowContext(ctx, `
+        SELECT COALESCE(MIN(fetch_interval_seconds), 0)
+        FROM feeds
+        WHERE fetch_interval_seconds > 0`).Scan(&seconds)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error reading feed fetch intervals", "error", err)
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,66 @@
+// internal/feed/locale.go
+package feed
+
+import (
+	"context"
+	"strings"
+)
+
+// ParseLocale splits a language tag such as "en-us", "pt_BR" or
+// "zh-Hant-TW" into a lowercase language and an uppercase region. Parts
+// that don't look like a language or region are dropped.
+func ParseLocale(tag string) (language, region string) {
+	parts := strings.FieldsFunc(strings.TrimSpace(tag), func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	if len(parts) == 0 || !isAlpha(parts[0]) || len(parts[0]) < 2 || len(parts[0]) > 3 {
+		return "", ""
+	}
+	language = strings.ToLower(parts[0])
+
+	for _, part := range parts[1:] {
+		switch {
+		case len(part) == 4 && isAlpha(part):
+			// Script subtag, e.g. Hant
+			continue
+		case len(part) == 2 && isAlpha(part), len(part) == 3 && isDigits(part):
+			region = strings.ToUpper(part)
+		}
+		break
+	}
+	return language, region
+}
+
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// updateFeedLocale stores the language a feed declares, unless an admin
+// has set it by hand.
+func (f *Fetcher) updateFeedLocale(ctx context.Context, feedID int64, tag string) {
+	language, region := ParseLocale(tag)
+	if language == "" {
+		return
+	}
+	_, err := f.db.ExecContext(ctx, `
+        UPDATE feeds SET language = ?, region = ?
+        WHERE id = ? AND COALESCE(locale_manual, 0) = 0`,
+		language, region, feedID)
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error updating language for feed", "feed_id", feedID, "error", err)
+	}
+}
@@ -0,0 +1,97 @@
+// internal/feed/politeness.go
+package feed
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostGate limits concurrent requests to a single host and spaces them apart
+type hostGate struct {
+	slots chan struct{}
+	mu    sync.Mutex
+	next  time.Time
+}
+
+// hostLimiter hands out per-host gates for a single fetch cycle
+type hostLimiter struct {
+	concurrency int
+	delay       time.Duration
+	mu          sync.Mutex
+	gates       map[string]*hostGate
+}
+
+func newHostLimiter(concurrency int, delay time.Duration) *hostLimiter {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return &hostLimiter{
+		concurrency: concurrency,
+		delay:       delay,
+		gates:       make(map[string]*hostGate),
+	}
+}
+
+func (l *hostLimiter) gate(host string) *hostGate {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	g, ok := l.gates[host]
+	if !ok {
+		g = &hostGate{slots: make(chan struct{}, l.concurrency)}
+		l.gates[host] = g
+	}
+	return g
+}
+
+// Acquire blocks until a request to rawURL's host may start. The returned
+// function must be called once the request has finished.
+func (l *hostLimiter) Acquire(ctx context.Context, rawURL string) (func(), error) {
+	g := l.gate(feedHost(rawURL))
+
+	select {
+	case g.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-g.slots }
+
+	// Reserve the next start time for this host
+	g.mu.Lock()
+	now := time.Now()
+	start := g.next
+	if start.Before(now) {
+		start = now
+	}
+	g.next = start.Add(l.delay)
+	g.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
+
+// feedHost returns the lowercased host of a feed URL, or the URL itself
+// when it cannot be parsed
+func feedHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -5,21 +5,26 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
 	"time"
 
+	"infoscope/internal/database"
 	"infoscope/internal/favicon"
+	"infoscope/internal/feedurl"
+	"infoscope/internal/hooks"
+	"infoscope/internal/schedule"
 )
 
 type Service struct {
 	db         *sql.DB
-	logger     *log.Logger
+	logger     *slog.Logger
 	fetcher    *Fetcher
 	faviconSvc *favicon.Service
 	done       chan struct{}
 }
 
-func NewService(db *sql.DB, logger *log.Logger, faviconSvc *favicon.Service) *Service {
+func NewService(db *sql.DB, logger *slog.Logger, faviconSvc *favicon.Service) *Service {
 	s := &Service{
 		db:         db,
 		logger:     logger,
@@ -30,6 +35,17 @@ func NewService(db *sql.DB, logger *log.Logger, faviconSvc *favicon.Service) *Se
 	return s
 }
 
+// SetHooks routes fetch and ingest events to registry. Call it before Start.
+func (s *Service) SetHooks(registry *hooks.Registry) {
+	s.fetcher.hooks = registry
+}
+
+// ConfigureTransport replaces the fetch client's transport settings. Call it
+// before Start.
+func (s *Service) ConfigureTransport(cfg TransportConfig) {
+	s.fetcher.client = newHTTPClient(cfg)
+}
+
 func (s *Service) Start() {
 	go s.updateLoop()
 }
@@ -43,14 +59,14 @@ func (s *Service) getUpdateInterval() time.Duration {
 	var intervalStr string
 	err := s.db.QueryRow("SELECT value FROM settings WHERE key = 'update_interval'").Scan(&intervalStr)
 	if err != nil {
-		s.logger.Printf("Error getting update interval, using default: %v", err)
+		s.logger.Error("Error getting update interval, using default", "error", err)
 		return 15 * time.Minute
 	}
 
 	// Convert string to integer seconds
 	interval, err := time.ParseDuration(intervalStr + "s")
 	if err != nil {
-		s.logger.Printf("Error parsing update interval, using default: %v", err)
+		s.logger.Error("Error parsing update interval, using default", "error", err)
 		return 15 * time.Minute
 	}
 
@@ -63,66 +79,112 @@ func (s *Service) getUpdateInterval() time.Duration {
 }
 
 func (s *Service) updateLoop() {
-	s.logger.Printf("Starting feed service update loop")
+	s.logger.Info("Starting feed service update loop")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.done
+		s.logger.Info("Feed service shutting down")
+		cancel()
+	}()
+
+	// Entries from before cross-feed dedup need its keys to be matched
+	if err := s.fetcher.backfillDedupKeys(ctx); err != nil {
+		s.logger.Error("Failed to fill in dedup keys", "error", err)
+	}
 
 	// Do initial update
-	if err := s.UpdateFeeds(context.Background()); err != nil {
-		s.logger.Printf("Initial feed update failed: %v", err)
-	}
-
-	// Create ticker with initial interval
-	interval := s.getUpdateInterval()
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			s.logger.Printf("Starting scheduled feed update")
-			// Get current interval in case it was changed
-			newInterval := s.getUpdateInterval()
-			if newInterval != interval {
-				s.logger.Printf("Update interval changed from %v to %v", interval, newInterval)
-				ticker.Reset(newInterval)
-				interval = newInterval
-			}
-
-			if err := s.UpdateFeeds(context.Background()); err != nil {
-				s.logger.Printf("Scheduled feed update failed: %v", err)
-			}
-
-		case <-s.done:
-			s.logger.Printf("Feed service shutting down")
-			return
-		}
+	if err := s.UpdateFeeds(ctx); err != nil {
+		s.logger.Error("Initial feed update failed", "error", err)
 	}
+
+	// Feeds with tag, own or adaptive intervals are also fetched between
+	// regular cycles
+	go schedule.Run(ctx, func() schedule.Spec {
+		interval := s.ShortestTagInterval(ctx)
+		if own := s.shortestFetchInterval(ctx); own > 0 && (interval == 0 || own < interval) {
+			interval = own
+		}
+		if adaptive, ok := s.fetcher.loadAdaptivePolling(ctx); ok && (interval == 0 || adaptive.min < interval) {
+			interval = adaptive.min
+		}
+		return schedule.Interval(interval)
+	}, func(ctx context.Context) {
+		if err := s.fetcher.UpdateDueFeeds(ctx); err != nil {
+			s.logger.Error("Interval feed update failed", "error", err)
+		}
+	})
+
+	// The interval is re-read before every wait, so changes apply without a restart
+	schedule.Run(ctx, func() schedule.Spec {
+		return schedule.Interval(s.getUpdateInterval())
+	}, func(ctx context.Context) {
+		s.logger.Debug("Starting scheduled feed update")
+		if err := s.UpdateFeeds(ctx); err != nil {
+			s.logger.Error("Scheduled feed update failed", "error", err)
+		}
+	})
 }
 
 func (s *Service) UpdateFeeds(ctx context.Context) error {
 	return s.fetcher.UpdateFeeds(ctx)
 }
 
-func (s *Service) AddFeed(url string) error {
-	// Validate the feed first
-	validationResult, err := ValidateFeedURL(url)
+// ConditionalGet reports a feed's last exchange, or false when it hasn't
+// been fetched since the server started
+func (s *Service) ConditionalGet(feedID int64) (ConditionalGet, bool) {
+	return s.fetcher.conditional.get(feedID)
+}
+
+// AddFeed subscribes to the feed at url and fetches it, returning the new
+// feed's ID. The address is normalized first, and an http address is
+// stored as https when the feed is served there too. A feed already
+// subscribed under another form of the address is refused.
+func (s *Service) AddFeed(url string) (int64, error) {
+	url, err := feedurl.Normalize(url)
 	if err != nil {
-		return fmt.Errorf("feed validation failed: %w", err)
+		return 0, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	var existing string
+	err = s.db.QueryRow("SELECT url FROM feeds WHERE url_key = ?", feedurl.Key(url)).Scan(&existing)
+	if err == nil {
+		return 0, fmt.Errorf("already subscribed as %s", existing)
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	// Validate the feed first, preferring https when it works
+	var validationResult *FeedValidationResult
+	if secure, ok := strings.CutPrefix(url, "http://"); ok {
+		if result, err := ValidateFeedURL("https://" + secure); err == nil {
+			url, validationResult = "https://"+secure, result
+		}
+	}
+	if validationResult == nil {
+		if validationResult, err = ValidateFeedURL(url); err != nil {
+			return 0, fmt.Errorf("feed validation failed: %w", err)
+		}
 	}
 	// Insert the feed with active status
 	result, err := s.db.Exec(
-		"INSERT INTO feeds (url, title, status) VALUES (?, ?, 'active')", // Add status
-		url, validationResult.Title,
+		"INSERT INTO feeds (url, url_key, title, status) VALUES (?, ?, ?, 'active')",
+		url, feedurl.Key(url), validationResult.Title,
 	)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Get the inserted feed ID
 	feedID, err := result.LastInsertId()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
+	// A new feed's site may have failed before, for another feed or while
+	// it was down; its icon is worth trying again now
+	s.faviconSvc.ForgetFailures()
+
 	// Immediately fetch the feed
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -135,31 +197,39 @@ func (s *Service) AddFeed(url string) error {
 
 	fetchResult := s.fetcher.fetchFeed(ctx, feedObj)
 	if fetchResult.Error != nil {
-		s.logger.Printf("Error fetching new feed %s: %v", url, fetchResult.Error)
-		return nil // Don't fail the add operation if initial fetch fails
+		s.logger.Error("Error fetching new feed", "feed_url", url, "error", fetchResult.Error)
+		return feedID, nil // Don't fail the add operation if initial fetch fails
 	}
 
-	return s.fetcher.saveFeedEntries(ctx, fetchResult)
+	_, err = s.fetcher.saveFeedEntries(ctx, fetchResult)
+	return feedID, err
 }
 
 func (s *Service) DeleteFeed(id int64) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
+	var entries, feeds int64
+	err := database.WithTx(context.Background(), s.db, func(tx *sql.Tx) error {
+		// Delete entries first
+		res, err := tx.Exec("DELETE FROM entries WHERE feed_id = ?", id)
+		if err != nil {
+			return err
+		}
+		entries, _ = res.RowsAffected()
 
-	// Delete entries first
-	_, err = tx.Exec("DELETE FROM entries WHERE feed_id = ?", id)
+		// Delete feed
+		res, err = tx.Exec("DELETE FROM feeds WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		feeds, _ = res.RowsAffected()
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-
-	// Delete feed
-	_, err = tx.Exec("DELETE FROM feeds WHERE id = ?", id)
-	if err != nil {
-		return err
+	if feeds == 0 {
+		s.logger.Warn("Deleted feed did not exist", "feed_id", id)
+	} else {
+		s.logger.Info("Deleted feed", "feed_id", id, "entries", entries)
 	}
-
-	return tx.Commit()
+	return nil
 }
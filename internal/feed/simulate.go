@@ -0,0 +1,276 @@
+// internal/feed/simulate.go
+package feed
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Decisions a simulated fetch reports for each item of the feed
+const (
+	// DecisionNew is stored as a new entry
+	DecisionNew = "new"
+	// DecisionUpdate replaces a stored entry with the same URL or GUID
+	DecisionUpdate = "update"
+	// DecisionUnchanged matches a stored entry the update mode leaves alone
+	DecisionUnchanged = "unchanged"
+	// DecisionOlder is skipped as older than the feed's newest entry
+	DecisionOlder = "skip-older"
+	// DecisionExpired is skipped as past the retention window
+	DecisionExpired = "skip-expired"
+	// DecisionDuplicate is stored but collapsed into another feed's copy
+	DecisionDuplicate = "duplicate"
+	// DecisionDropped is another feed's story, dropped by cross-feed dedup
+	DecisionDropped = "drop-duplicate"
+	// DecisionPruned is stored new but over the feed's entry limit, so it
+	// is deleted again in the same fetch
+	DecisionPruned = "pruned"
+)
+
+// SimulatedEntry is an item of a simulated fetch and what the fetch would
+// do with it
+type SimulatedEntry struct {
+	Entry
+	Decision string `json:"decision"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Simulation is the outcome of a simulated fetch
+type Simulation struct {
+	// Feed is the subscribed feed the source was checked against; its ID
+	// is 0 when the source isn't subscribed
+	Feed     Feed             `json:"feed"`
+	Title    string           `json:"title"`
+	Language string           `json:"language,omitempty"`
+	Entries  []SimulatedEntry `json:"entries"`
+}
+
+// Simulate runs source, a feed URL or a local file, through a fetch as far
+// as storing it: parsing, title cleaning and every rule deciding which
+// items are stored, against the entries and settings in the database.
+// Nothing is written and no favicons or articles are downloaded. feedID
+// names the subscribed feed to check against; 0 looks a URL up among the
+// subscriptions and treats anything else as a new feed.
+func (f *Fetcher) Simulate(ctx context.Context, source string, feedID int64) (*Simulation, error) {
+	feed, err := f.simulatedFeed(ctx, source, feedID)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := f.openSource(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	parsed, err := f.parser.Parse(body)
+	if err != nil {
+		return nil, &ParseError{Err: err}
+	}
+
+	sim := &Simulation{Feed: feed, Title: parsed.Title, Language: parsed.Language}
+	latest := f.latestPublished(ctx, feed)
+	cleanTitles := f.getSetting(ctx, "clean_titles", "false") == "true"
+	keep := f.retention(ctx, feed)
+	dedupKey := f.getSetting(ctx, "dedup_key", DedupByURL)
+	updateMode := f.getSetting(ctx, "entry_update_mode", UpdateIfNewer)
+	crossFeedDedup := f.getSetting(ctx, "cross_feed_dedup", CrossFeedDedupOff)
+
+	// A transaction that is never committed gives the dedup lookups the
+	// same view a fetch has
+	tx, err := f.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, item := range parsed.Items {
+		e := SimulatedEntry{Entry: newEntry(feed, parsed, item, cleanTitles)}
+		switch {
+		case !latest.IsZero() && e.PublishedAt.Before(latest):
+			e.Decision = DecisionOlder
+			e.Reason = "published before the newest stored entry, " + latest.Format(time.RFC3339)
+		case !keep.expired.IsZero() && e.PublishedAt.Before(keep.expired):
+			e.Decision = DecisionExpired
+			e.Reason = "published before the retention window starts, " + keep.expired.UTC().Format(time.RFC3339)
+		default:
+			if err := simulateStore(ctx, tx, &e, dedupKey, updateMode, crossFeedDedup); err != nil {
+				return nil, err
+			}
+		}
+		sim.Entries = append(sim.Entries, e)
+	}
+
+	if !keep.archive {
+		if err := simulatePrune(ctx, tx, sim.Entries, feed.ID, keep.maxPosts); err != nil {
+			return nil, err
+		}
+	}
+	return sim, nil
+}
+
+// Simulate runs a simulated fetch with the service's fetcher, as
+// Fetcher.Simulate does
+func (s *Service) Simulate(ctx context.Context, source string, feedID int64) (*Simulation, error) {
+	return s.fetcher.Simulate(ctx, source, feedID)
+}
+
+// simulatedFeed finds the subscribed feed a simulation is checked against
+func (f *Fetcher) simulatedFeed(ctx context.Context, source string, feedID int64) (Feed, error) {
+	query := `
+        SELECT id, url, title, COALESCE(full_content, 0), COALESCE(max_entries_per_feed, 0),
+               COALESCE(retention_days, 0)
+        FROM feeds WHERE `
+	var arg any
+	switch {
+	case feedID != 0:
+		query, arg = query+"id = ?", feedID
+	case isURL(source):
+		query, arg = query+"url = ?", source
+	default:
+		return Feed{URL: source}, nil
+	}
+
+	var feed Feed
+	err := f.db.QueryRowContext(ctx, query, arg).Scan(
+		&feed.ID, &feed.URL, &feed.Title, &feed.FullContent, &feed.MaxEntries, &feed.RetentionDays)
+	if err == sql.ErrNoRows {
+		if feedID != 0 {
+			return Feed{}, fmt.Errorf("feed %d not found", feedID)
+		}
+		return Feed{URL: source}, nil
+	}
+	return feed, err
+}
+
+// openSource opens a feed URL or a local file
+func (f *Fetcher) openSource(ctx context.Context, source string) (io.ReadCloser, error) {
+	if !isURL(source) {
+		return os.Open(source)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching feed: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+	return resp.Body, nil
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// simulateStore decides an item the way saveFeedEntries stores it
+func simulateStore(ctx context.Context, tx *sql.Tx, e *SimulatedEntry, dedupKey, updateMode, crossFeedDedup string) error {
+	matched := func(by string, stored time.Time) {
+		switch {
+		case updateMode == UpdateNever:
+			e.Decision = DecisionUnchanged
+			e.Reason = "same " + by + " as a stored entry, and updates are ignored"
+		case updateMode != UpdateAlways && !e.PublishedAt.After(stored):
+			e.Decision = DecisionUnchanged
+			e.Reason = "same " + by + " as a stored entry that is as new"
+		default:
+			e.Decision = DecisionUpdate
+			e.Reason = "same " + by + " as a stored entry"
+		}
+	}
+
+	if dedupKey == DedupByGUID && e.GUID != "" && e.FeedID != 0 {
+		var stored time.Time
+		err := tx.QueryRowContext(ctx,
+			"SELECT published_at FROM entries WHERE feed_id = ? AND guid = ?", e.FeedID, e.GUID,
+		).Scan(&stored)
+		if err == nil {
+			matched("GUID", stored)
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	var stored time.Time
+	err := tx.QueryRowContext(ctx, "SELECT published_at FROM entries WHERE url = ?", e.URL).Scan(&stored)
+	if err == nil {
+		matched("URL", stored)
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	e.Decision = DecisionNew
+	if crossFeedDedup == CrossFeedDedupOff {
+		return nil
+	}
+	original, err := findDuplicate(ctx, tx, e.Entry)
+	if err != nil || original == 0 {
+		return err
+	}
+	if crossFeedDedup == CrossFeedDedupFirst {
+		e.Decision = DecisionDropped
+	} else {
+		e.Decision = DecisionDuplicate
+	}
+	e.Reason = fmt.Sprintf("same story as entry %d", original)
+	return nil
+}
+
+// simulatePrune marks the new entries the feed's entry limit would delete
+// straight after storing them. Starred entries are never pruned.
+func simulatePrune(ctx context.Context, tx *sql.Tx, entries []SimulatedEntry, feedID int64, maxPosts int) error {
+	var kept []time.Time
+	if feedID != 0 {
+		rows, err := tx.QueryContext(ctx,
+			"SELECT published_at FROM entries WHERE feed_id = ? AND starred_at IS NULL", feedID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var published time.Time
+			if err := rows.Scan(&published); err != nil {
+				rows.Close()
+				return err
+			}
+			kept = append(kept, published)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	var added []*SimulatedEntry
+	for i := range entries {
+		switch entries[i].Decision {
+		case DecisionNew, DecisionDuplicate:
+			added = append(added, &entries[i])
+			kept = append(kept, entries[i].PublishedAt)
+		}
+	}
+	if len(kept) <= maxPosts {
+		return nil
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].After(kept[j]) })
+	for _, e := range added {
+		if maxPosts <= 0 || e.PublishedAt.Before(kept[maxPosts-1]) {
+			e.Decision = DecisionPruned
+			e.Reason = fmt.Sprintf("beyond the feed's %d newest entries", maxPosts)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package feed
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"infoscope/internal/database"
+	"infoscope/internal/logging"
+)
+
+const simulatedFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Example</title><link>https://example.com/</link>
+<item><title>Newest story</title><link>https://example.com/a</link><pubDate>Fri, 16 Oct 2026 10:00:00 GMT</pubDate></item>
+<item><title>Second story</title><link>https://example.com/d</link><pubDate>Thu, 15 Oct 2026 12:00:00 GMT</pubDate></item>
+<item><title>Stored story</title><link>https://example.com/b</link><pubDate>Thu, 15 Oct 2026 10:00:00 GMT</pubDate></item>
+<item><title>Old story</title><link>https://example.com/c</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate></item>
+</channel></rss>`
+
+func TestSimulate(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.NewDB(filepath.Join(dir, "test.db"), database.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"INSERT INTO feeds (id, url, title) VALUES (1, 'https://example.com/feed', 'Example')",
+		"INSERT INTO entries (feed_id, title, url, published_at, favicon_url) VALUES (1, 'Stored story', 'https://example.com/b', '2026-10-15 09:00:00', '')",
+		"INSERT OR REPLACE INTO settings (key, value, type) VALUES ('max_posts', '1', 'int')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed database: %v", err)
+		}
+	}
+	path := filepath.Join(dir, "feed.xml")
+	if err := os.WriteFile(path, []byte(simulatedFeed), 0644); err != nil {
+		t.Fatalf("Failed to write feed: %v", err)
+	}
+
+	fetcher := NewFetcher(db.DB, logging.Discard(), nil)
+	sim, err := fetcher.Simulate(context.Background(), path, 1)
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+	if sim.Feed.ID != 1 || sim.Title != "Example" {
+		t.Errorf("Simulated feed %d titled %q, want feed 1 titled Example", sim.Feed.ID, sim.Title)
+	}
+
+	// The feed keeps one entry, so the older of the two new ones goes
+	// straight away
+	want := []string{DecisionNew, DecisionPruned, DecisionUpdate, DecisionOlder}
+	if len(sim.Entries) != len(want) {
+		t.Fatalf("Got %d entries, want %d", len(sim.Entries), len(want))
+	}
+	for i, e := range sim.Entries {
+		if e.Decision != want[i] {
+			t.Errorf("%s: decision %q, want %q", e.Title, e.Decision, want[i])
+		}
+	}
+
+	// Nothing was stored
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM entries").Scan(&count); err != nil || count != 1 {
+		t.Errorf("Found %d entries after simulating (%v), want the 1 stored before", count, err)
+	}
+
+	// A file that isn't subscribed is checked as a new feed
+	sim, err = fetcher.Simulate(context.Background(), path, 0)
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+	if sim.Feed.ID != 0 {
+		t.Errorf("Unsubscribed file matched feed %d", sim.Feed.ID)
+	}
+}
@@ -0,0 +1,99 @@
+// internal/feed/tag_priority.go
+package feed
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tagPriority is the fetch treatment attached to a tag. Feeds carrying the
+// tag are started ahead of lower priority feeds, and when interval is set
+// they are also fetched on that shorter interval between regular cycles.
+type tagPriority struct {
+	priority int
+	interval time.Duration
+}
+
+// loadTagPriorities reads the configured tag priorities.
+func (f *Fetcher) loadTagPriorities(ctx context.Context) map[string]tagPriority {
+	priorities := make(map[string]tagPriority)
+	rows, err := f.db.QueryContext(ctx,
+		"SELECT tag, priority, interval_minutes FROM tag_priorities")
+	if err != nil {
+		f.logger.ErrorContext(ctx, "Error loading tag priorities", "error", err)
+		return priorities
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag string
+		var priority, minutes int
+		if err := rows.Scan(&tag, &priority, &minutes); err != nil {
+			f.logger.ErrorContext(ctx, "Error scanning tag priority", "error", err)
+			continue
+		}
+		priorities[strings.ToLower(tag)] = tagPriority{
+			priority: priority,
+			interval: time.Duration(minutes) * time.Minute,
+		}
+	}
+	return priorities
+}
+
+// applyTagPriorities sets each feed's rank to the highest priority among
+// its tags and its interval to the shortest tag interval.
+func applyTagPriorities(feeds []Feed, priorities map[string]tagPriority) {
+	if len(priorities) == 0 {
+		return
+	}
+	for i := range feeds {
+		for _, tag := range strings.Split(feeds[i].Tags, ",") {
+			p, ok := priorities[strings.ToLower(strings.TrimSpace(tag))]
+			if !ok {
+				continue
+			}
+			feeds[i].Rank = max(feeds[i].Rank, p.priority)
+			if p.interval > 0 && (feeds[i].Interval == 0 || p.interval < feeds[i].Interval) {
+				feeds[i].Interval = p.interval
+			}
+		}
+	}
+}
+
+// rankTiers groups feeds by rank, highest first, keeping their order
+// within a tier.
+func rankTiers(feeds []Feed) [][]Feed {
+	sorted := append([]Feed(nil), feeds...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Rank > sorted[j].Rank
+	})
+
+	var tiers [][]Feed
+	for i, feed := range sorted {
+		if i == 0 || feed.Rank != sorted[i-1].Rank {
+			tiers = append(tiers, nil)
+		}
+		tiers[len(tiers)-1] = append(tiers[len(tiers)-1], feed)
+	}
+	return tiers
+}
+
+// ShortestTagInterval returns the shortest fetch interval configured on a
+// tag that some feed carries, or 0 when there is none.
+func (s *Service) ShortestTagInterval(ctx context.Context) time.Duration {
+	var minutes int
+	err := s.db.QueryRowContext(ctx, `
+        SELECT COALESCE(MIN(p.interval_minutes), 0)
+        FROM tag_priorities p
+        WHERE p.interval_minutes > 0 AND EXISTS (
+            SELECT 1 FROM feeds f
+            WHERE ',' || REPLACE(LOWER(COALESCE(f.tags, '')), ', ', ',') || ',' LIKE '%,' || LOWER(p.tag) || ',%'
+        )`).Scan(&minutes)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error reading tag intervals", "error", err)
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
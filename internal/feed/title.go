@@ -0,0 +1,39 @@
+// internal/feed/title.go
+package feed
+
+import (
+	"html"
+	"strings"
+)
+
+// titleSeparators are the delimiters commonly used to append a site name to a title
+var titleSeparators = []string{" — ", " – ", " - ", " | ", " :: ", " · ", " » "}
+
+// CleanTitle decodes HTML entities, collapses whitespace and strips a trailing
+// site name (e.g. "Story — The Example Times") when it matches the feed title
+func CleanTitle(title, siteName string) string {
+	cleaned := strings.Join(strings.Fields(html.UnescapeString(title)), " ")
+
+	site := normalizeSiteName(siteName)
+	if site == "" {
+		return cleaned
+	}
+
+	for _, sep := range titleSeparators {
+		idx := strings.LastIndex(cleaned, sep)
+		if idx <= 0 {
+			continue
+		}
+		if normalizeSiteName(cleaned[idx+len(sep):]) == site {
+			return strings.TrimSpace(cleaned[:idx])
+		}
+	}
+
+	return cleaned
+}
+
+// normalizeSiteName lowercases a site name and drops a leading article for comparison
+func normalizeSiteName(name string) string {
+	name = strings.ToLower(strings.Join(strings.Fields(html.UnescapeString(name)), " "))
+	return strings.TrimPrefix(name, "the ")
+}
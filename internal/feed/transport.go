@@ -0,0 +1,126 @@
+// internal/feed/transport.go
+package feed
+
+import (
+	"crypto/tls"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// TransportConfig tunes the HTTP client used to fetch feeds. Large installs
+// polling many feeds on few hosts benefit from more idle connections per
+// host and a bigger TLS session cache.
+type TransportConfig struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int // 0 means unlimited
+	IdleConnTimeout     time.Duration
+	TLSSessionCacheSize int // 0 disables TLS session resumption
+	DisableHTTP2        bool
+	Timeout             time.Duration
+}
+
+// DefaultTransportConfig returns the settings used when nothing is configured.
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+		TLSSessionCacheSize: 256,
+		Timeout:             30 * time.Second,
+	}
+}
+
+// Connection reuse counters, published with the other expvar metrics
+var (
+	connReused     = expvar.NewInt("fetch_conn_reused")
+	connNew        = expvar.NewInt("fetch_conn_new")
+	tlsResumed     = expvar.NewInt("fetch_tls_resumed")
+	http2Responses = expvar.NewInt("fetch_http2_responses")
+)
+
+// TransportStats reports how often fetches reused connections
+type TransportStats struct {
+	ConnReused     int64 `json:"connReused"`
+	ConnNew        int64 `json:"connNew"`
+	TLSResumed     int64 `json:"tlsResumed"`
+	HTTP2Responses int64 `json:"http2Responses"`
+}
+
+// GetTransportStats returns the connection counters since startup.
+func GetTransportStats() TransportStats {
+	return TransportStats{
+		ConnReused:     connReused.Value(),
+		ConnNew:        connNew.Value(),
+		TLSResumed:     tlsResumed.Value(),
+		HTTP2Responses: http2Responses.Value(),
+	}
+}
+
+// newHTTPClient builds the fetch client from cfg.
+func newHTTPClient(cfg TransportConfig) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	tlsConfig := &tls.Config{}
+	if cfg.TLSSessionCacheSize > 0 {
+		tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(cfg.TLSSessionCacheSize)
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     !cfg.DisableHTTP2,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	if cfg.DisableHTTP2 {
+		// A non-nil empty map turns off the built-in HTTP/2 support
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: &countingTransport{base: transport},
+	}
+}
+
+// countingTransport records connection reuse for every request.
+type countingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				connReused.Add(1)
+			} else {
+				connNew.Add(1)
+			}
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.TLS != nil && resp.TLS.DidResume {
+		tlsResumed.Add(1)
+	}
+	if resp.ProtoMajor == 2 {
+		http2Responses.Add(1)
+	}
+	return resp, nil
+}
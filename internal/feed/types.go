@@ -10,22 +10,73 @@ type Feed struct {
 	URL         string    `json:"url"`
 	Title       string    `json:"title"`
 	LastFetched time.Time `json:"lastFetched"`
+	Priority    bool      `json:"priority"`
+	Tags        string    `json:"tags,omitempty"`
+
+	// Rank and Interval come from tag priorities: higher ranks are started
+	// first, and a non-zero Interval fetches the feed between cycles
+	Rank     int           `json:"-"`
+	Interval time.Duration `json:"-"`
+
+	// FetchInterval is the feed's own interval. It replaces Interval, and
+	// regular cycles skip the feed until it is due.
+	FetchInterval time.Duration `json:"-"`
+
+	// AvgPostInterval and LastPost describe how often the feed posts. With
+	// adaptive polling they set Interval, and Adaptive marks feeds whose
+	// regular cycles are paced that way.
+	AvgPostInterval time.Duration `json:"-"`
+	LastPost        time.Time     `json:"-"`
+	Adaptive        bool          `json:"-"`
+
+	// FullContent replaces each entry's summary with the linked article
+	FullContent bool `json:"fullContent"`
+
+	// MaxEntries is how many entries the feed keeps; 0 follows max_posts
+	MaxEntries int `json:"-"`
+
+	// RetentionDays is the age in days past which the feed's entries are
+	// deleted; 0 follows retention_days
+	RetentionDays int `json:"-"`
 }
 
+// MaxEntriesPerFeed caps a feed's own retention limit
+const MaxEntriesPerFeed = 100000
+
 type Entry struct {
 	ID          int64     `json:"id"`
 	FeedID      int64     `json:"feedId"`
 	Title       string    `json:"title"`
+	RawTitle    string    `json:"rawTitle,omitempty"`
 	URL         string    `json:"url"`
 	Content     string    `json:"content,omitempty"`
 	GUID        string    `json:"guid,omitempty"`
 	PublishedAt time.Time `json:"publishedAt"`
 	FaviconURL  string    `json:"faviconUrl,omitempty"`
 	CreatedAt   time.Time `json:"createdAt"`
+
+	// CanonicalURL is the normalized address of the story, shared by its
+	// copies in other feeds
+	CanonicalURL string `json:"canonicalUrl,omitempty"`
+
+	// ContentExtracted marks Content as the linked article rather than
+	// the feed's summary
+	ContentExtracted bool `json:"-"`
 }
 
 type FetchResult struct {
-	Feed    Feed
-	Entries []Entry
-	Error   error
+	Feed      Feed
+	Entries   []Entry
+	Error     error
+	Requested bool   // an HTTP request was actually sent
+	Bytes     int64  // response body bytes downloaded
+	Language  string // language tag declared by the feed, if any
+	SiteURL   string // website the feed links to, used for its favicon
+
+	// Extracted are stored entries whose articles were extracted on this
+	// fetch, with their IDs and new content
+	Extracted []Entry
+
+	StatusCode int           // HTTP status, 0 when no response arrived
+	Latency    time.Duration // time until the response headers arrived
 }
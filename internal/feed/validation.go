@@ -5,8 +5,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"html"
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/mmcdole/gofeed"
@@ -18,14 +21,48 @@ var (
 	ErrNotAFeed   = errors.New("URL does not point to a valid feed")
 )
 
+// certExpiryWarning is how close to expiry a certificate must be to be flagged
+const certExpiryWarning = 14 * 24 * time.Hour
+
+// RedirectHop is one response in the chain leading to the final feed URL
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode"`
+}
+
 type FeedValidationResult struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	ItemCount   int    `json:"itemCount"`
-	LastUpdated string `json:"lastUpdated,omitempty"`
-	FeedType    string `json:"feedType,omitempty"` // RSS, Atom, etc.
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	ItemCount   int           `json:"itemCount"`
+	LastUpdated string        `json:"lastUpdated,omitempty"`
+	FeedType    string        `json:"feedType,omitempty"` // RSS, Atom, etc.
+	FeedVersion string        `json:"feedVersion,omitempty"`
+	StatusChain []RedirectHop `json:"statusChain,omitempty"`
+	FinalURL    string        `json:"finalUrl,omitempty"`
+	ContentType string        `json:"contentType,omitempty"`
+	TLSIssue    string        `json:"tlsIssue,omitempty"`
+	Warnings    []string      `json:"warnings,omitempty"`
+	Items       []PreviewItem `json:"items,omitempty"`
+	Categories  []string      `json:"categories,omitempty"` // feed and most common item categories
 }
 
+// previewItemCount is how many of the latest items validation returns
+const previewItemCount = 10
+
+// PreviewItem is one of a feed's latest items, read without storing anything
+type PreviewItem struct {
+	Title     string    `json:"title"`
+	RawTitle  string    `json:"rawTitle,omitempty"` // original title when cleaning changed it
+	URL       string    `json:"url"`
+	Published time.Time `json:"published"`
+	Undated   bool      `json:"undated,omitempty"` // no date in the feed; the fetch time is used
+	Snippet   string    `json:"snippet,omitempty"`
+	Kept      bool      `json:"kept"` // within the max_posts entries kept per feed
+}
+
+// ValidateFeedURL fetches and parses a feed URL. The returned result carries
+// whatever diagnostics were gathered and is non-nil even when err is set,
+// unless the URL itself is malformed.
 func ValidateFeedURL(feedURL string) (*FeedValidationResult, error) {
 	// Parse URL
 	u, err := url.Parse(feedURL)
@@ -42,40 +79,71 @@ func ValidateFeedURL(feedURL string) (*FeedValidationResult, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Create feed parser
-	fp := gofeed.NewParser()
+	result := &FeedValidationResult{}
 
-	// Create HTTP client with timeout
+	// Record every redirect on the way to the feed
 	client := &http.Client{
 		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.Response != nil {
+				result.StatusChain = append(result.StatusChain, RedirectHop{
+					URL:        req.Response.Request.URL.String(),
+					StatusCode: req.Response.StatusCode,
+				})
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
 	}
 
-	// Try to fetch and parse feed
-	feed, err := fp.ParseURLWithContext(feedURL, ctx)
+	resp, err := client.Do(req)
 	if err != nil {
-		// Try to determine if it's a timeout or invalid feed
 		if ctx.Err() != nil {
-			return nil, ErrTimeout
+			return result, ErrTimeout
 		}
-
-		// Check if the URL is reachable
-		resp, err := client.Get(feedURL)
-		if err != nil {
-			return nil, fmt.Errorf("could not reach URL: %v", err)
+		if ClassifyFetchError(err) == ErrorClassTLS {
+			result.TLSIssue = err.Error()
 		}
-		defer resp.Body.Close()
+		return result, fmt.Errorf("could not reach URL: %v", err)
+	}
+	defer resp.Body.Close()
+
+	result.StatusChain = append(result.StatusChain, RedirectHop{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+	})
+	if final := resp.Request.URL.String(); final != feedURL {
+		result.FinalURL = final
+	}
+	result.ContentType = resp.Header.Get("Content-Type")
+	result.Warnings = diagnoseResponse(resp, result)
 
-		return nil, ErrNotAFeed
+	if resp.StatusCode >= 400 {
+		return result, &StatusError{StatusCode: resp.StatusCode, FinalURL: result.FinalURL}
 	}
 
-	// Create validation result
-	result := &FeedValidationResult{
-		Title:       feed.Title,
-		Description: feed.Description,
-		ItemCount:   len(feed.Items),
-		FeedType:    feed.FeedType,
+	// Try to parse the body as a feed
+	feed, err := gofeed.NewParser().Parse(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return result, ErrTimeout
+		}
+		return result, ErrNotAFeed
 	}
 
+	result.Title = feed.Title
+	result.Description = feed.Description
+	result.ItemCount = len(feed.Items)
+	result.FeedType = feed.FeedType
+	result.FeedVersion = feed.FeedVersion
+
 	// Set last updated if available
 	if feed.UpdatedParsed != nil {
 		result.LastUpdated = feed.UpdatedParsed.Format("January 2, 2006")
@@ -83,5 +151,136 @@ func ValidateFeedURL(feedURL string) (*FeedValidationResult, error) {
 		result.LastUpdated = feed.Items[0].PublishedParsed.Format("January 2, 2006")
 	}
 
+	if result.ItemCount == 0 {
+		result.Warnings = append(result.Warnings, "The feed parsed but contains no items.")
+	}
+
+	result.Items = previewItems(feed.Items)
+	result.Categories = feedCategories(feed)
+
 	return result, nil
 }
+
+// diagnoseResponse collects non-fatal problems worth showing to the admin
+func diagnoseResponse(resp *http.Response, result *FeedValidationResult) []string {
+	var warnings []string
+
+	for _, hop := range result.StatusChain {
+		if hop.StatusCode == http.StatusMovedPermanently || hop.StatusCode == http.StatusPermanentRedirect {
+			warnings = append(warnings, fmt.Sprintf("Permanently redirected; consider subscribing to %s instead.", result.FinalURL))
+			break
+		}
+	}
+
+	ct := strings.ToLower(result.ContentType)
+	if strings.HasPrefix(ct, "text/html") {
+		warnings = append(warnings, "Server reports an HTML content type; this may be a web page rather than a feed.")
+	}
+
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		cert := resp.TLS.PeerCertificates[0]
+		if until := time.Until(cert.NotAfter); until < certExpiryWarning {
+			result.TLSIssue = fmt.Sprintf("certificate expires %s", cert.NotAfter.Format("2006-01-02"))
+		}
+	}
+
+	return warnings
+}
+
+// previewItems returns the latest items newest first, dated the same way
+// the fetcher dates entries.
+func previewItems(items []*gofeed.Item) []PreviewItem {
+	now := time.Now()
+	preview := make([]PreviewItem, 0, len(items))
+	for _, item := range items {
+		p := PreviewItem{
+			Title:   item.Title,
+			URL:     item.Link,
+			Snippet: TextSnippet(item.Description, 200),
+		}
+		switch {
+		case item.PublishedParsed != nil:
+			p.Published = *item.PublishedParsed
+		case item.UpdatedParsed != nil:
+			p.Published = *item.UpdatedParsed
+		default:
+			p.Published = now
+			p.Undated = true
+		}
+		preview = append(preview, p)
+	}
+
+	sort.SliceStable(preview, func(i, j int) bool {
+		return preview[i].Published.After(preview[j].Published)
+	})
+	if len(preview) > previewItemCount {
+		preview = preview[:previewItemCount]
+	}
+	return preview
+}
+
+// TextSnippet strips markup from an item body and shortens it to about
+// max characters.
+func TextSnippet(body string, max int) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range body {
+		switch {
+		case r == '<':
+			inTag = true
+			b.WriteRune(' ')
+		case r == '>':
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+
+	text := strings.Join(strings.Fields(html.UnescapeString(b.String())), " ")
+	if runes := []rune(text); len(runes) > max {
+		text = strings.TrimSpace(string(runes[:max])) + "…"
+	}
+	return text
+}
+
+// maxFeedCategories caps how many categories validation reports
+const maxFeedCategories = 8
+
+// feedCategories returns the feed's own categories followed by the item
+// categories used most often.
+func feedCategories(feed *gofeed.Feed) []string {
+	seen := make(map[string]bool)
+	var categories []string
+	add := func(c string) {
+		c = strings.TrimSpace(c)
+		key := strings.ToLower(c)
+		if c == "" || seen[key] || len(categories) == maxFeedCategories {
+			return
+		}
+		seen[key] = true
+		categories = append(categories, c)
+	}
+
+	for _, c := range feed.Categories {
+		add(c)
+	}
+
+	counts := make(map[string]int)
+	var order []string
+	for _, item := range feed.Items {
+		for _, c := range item.Categories {
+			c = strings.TrimSpace(c)
+			if counts[c] == 0 {
+				order = append(order, c)
+			}
+			counts[c]++
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return counts[order[i]] > counts[order[j]]
+	})
+	for _, c := range order {
+		add(c)
+	}
+	return categories
+}
@@ -0,0 +1,73 @@
+// Package feedurl normalizes feed addresses, so one feed reached by
+// slightly different URLs is subscribed to once.
+package feedurl
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalid is returned for addresses that aren't absolute http or https
+// URLs
+var ErrInvalid = errors.New("not an http or https URL")
+
+// Normalize returns raw with the changes every server treats as the same
+// address: the scheme and host lowercased, a default port and the fragment
+// dropped, and an empty path written as /. The path and query are kept as
+// they are, since servers may tell /feed and /feed/ apart.
+func Normalize(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", ErrInvalid
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return "", ErrInvalid
+	}
+
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+	u.Fragment, u.RawFragment = "", ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String(), nil
+}
+
+// Key identifies the feed behind an address: the normalized URL without
+// its scheme and without a trailing slash on the path. Addresses with the
+// same key are taken to be one feed. Invalid addresses are their own key.
+func Key(raw string) string {
+	normalized, err := Normalize(raw)
+	if err != nil {
+		return strings.TrimSpace(raw)
+	}
+	u, _ := url.Parse(normalized)
+	key := u.Host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
+
+// Prefer reports whether a is the better address to keep for a feed also
+// reachable at b: https over http, then the shorter of the two.
+func Prefer(a, b string) bool {
+	secureA, secureB := strings.HasPrefix(a, "https:"), strings.HasPrefix(b, "https:")
+	if secureA != secureB {
+		return secureA
+	}
+	return len(a) < len(b)
+}
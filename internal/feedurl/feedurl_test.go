@@ -0,0 +1,58 @@
+package feedurl
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"HTTPS://Example.COM/Feed.xml", "https://example.com/Feed.xml"},
+		{"  http://example.com:80/feed/#top ", "http://example.com/feed/"},
+		{"https://example.com:443", "https://example.com/"},
+		{"https://example.com:8443/rss?lang=en", "https://example.com:8443/rss?lang=en"},
+		{"http://[2001:DB8::1]:80/feed", "http://[2001:db8::1]/feed"},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "example.com/feed", "ftp://example.com/feed", "https:///feed"} {
+		if _, err := Normalize(bad); err == nil {
+			t.Errorf("Normalize(%q) accepted an invalid URL", bad)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	same := []string{
+		"http://example.com/feed",
+		"https://example.com/feed/",
+		"HTTPS://EXAMPLE.com:443/feed#latest",
+	}
+	for _, u := range same {
+		if got := Key(u); got != "example.com/feed" {
+			t.Errorf("Key(%q) = %q, want example.com/feed", u, got)
+		}
+	}
+	if Key("https://example.com/feed?page=2") == Key("https://example.com/feed") {
+		t.Error("Key ignored the query")
+	}
+	if Key("https://example.com/Feed") == Key("https://example.com/feed") {
+		t.Error("Key ignored the case of the path")
+	}
+	if Key("https://example.com/") != Key("http://example.com") {
+		t.Error("Key told the root path from an empty one")
+	}
+}
+
+func TestPrefer(t *testing.T) {
+	if !Prefer("https://example.com/feed/", "http://example.com/feed") {
+		t.Error("Prefer chose http over https")
+	}
+	if !Prefer("https://example.com/feed", "https://example.com/feed/") {
+		t.Error("Prefer chose the longer of two https addresses")
+	}
+}
@@ -0,0 +1,103 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os/exec"
+	"time"
+)
+
+// Event names passed to external scripts
+const (
+	EventEntryIngested = "entry_ingested"
+	EventFeedFetched   = "feed_fetched"
+	EventEntryClicked  = "entry_clicked"
+)
+
+const (
+	// execTimeout bounds a single run of the script
+	execTimeout = 30 * time.Second
+	// execWorkers is how many runs may be in flight at once
+	execWorkers = 4
+	// execQueueSize bounds the events waiting for a worker. Fetch events
+	// may only fill half of it, so a busy fetch cycle leaves room for the
+	// entry events integrations post from.
+	execQueueSize = 1024
+)
+
+// execPayload is written to the script's standard input
+type execPayload struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data"`
+}
+
+// execJob is an event waiting for the script
+type execJob struct {
+	event   string
+	payload []byte
+}
+
+// Exec runs an external script for every event, passing the event as JSON
+// on standard input. Events are queued and run in the background by a few
+// workers; only when the queue is full are they dropped.
+type Exec struct {
+	path   string
+	logger *slog.Logger
+	queue  chan execJob
+}
+
+func NewExec(path string, logger *slog.Logger) *Exec {
+	x := &Exec{
+		path:   path,
+		logger: logger,
+		queue:  make(chan execJob, execQueueSize),
+	}
+	for i := 0; i < execWorkers; i++ {
+		go x.work()
+	}
+	return x
+}
+
+func (x *Exec) OnEntryIngested(_ context.Context, e Entry)   { x.run(EventEntryIngested, e) }
+func (x *Exec) OnFeedFetched(_ context.Context, f FeedFetch) { x.run(EventFeedFetched, f) }
+func (x *Exec) OnEntryClicked(_ context.Context, c Click)    { x.run(EventEntryClicked, c) }
+
+func (x *Exec) run(event string, data any) {
+	payload, err := json.Marshal(execPayload{Event: event, Time: time.Now().UTC(), Data: data})
+	if err != nil {
+		x.logger.Error("Error encoding hook payload", "event", event, "error", err)
+		return
+	}
+
+	if event == EventFeedFetched && len(x.queue) >= execQueueSize/2 {
+		x.logger.Warn("Hook script queue busy, dropped event", "event", event)
+		return
+	}
+	select {
+	case x.queue <- execJob{event: event, payload: payload}:
+	default:
+		x.logger.Warn("Hook script queue full, dropped event", "event", event)
+	}
+}
+
+// work runs queued events one at a time
+func (x *Exec) work() {
+	for job := range x.queue {
+		x.exec(job)
+	}
+}
+
+func (x *Exec) exec(job execJob) {
+	// The event outlives the request or fetch that raised it
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, x.path, job.event)
+	cmd.Stdin = bytes.NewReader(job.payload)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		x.logger.Error("Hook script failed", "event", job.event, "error", err, "output", string(bytes.TrimSpace(out)))
+	}
+}
@@ -0,0 +1,49 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"infoscope/internal/logging"
+)
+
+func TestExecQueuesEntryEvents(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "events.log")
+	script := filepath.Join(dir, "hook.sh")
+	body := "#!/bin/sh\nsleep 0.05\necho \"$1\" >> " + log + "\n"
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatalf("Failed to write hook script: %v", err)
+	}
+
+	// A fetch cycle's worth of feed events arrives before the entries;
+	// every entry event must still run
+	x := NewExec(script, logging.Discard())
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		x.OnFeedFetched(ctx, FeedFetch{})
+	}
+	const entries = 20
+	for i := 0; i < entries; i++ {
+		x.OnEntryIngested(ctx, Entry{ID: int64(i)})
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		data, _ := os.ReadFile(log)
+		if n := strings.Count(string(data), EventEntryIngested); n == entries {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("Script ran for %d of %d entry events", n, entries)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,107 @@
+// Package hooks lets integrations react to what happens in the river:
+// entries arriving, feeds being fetched and readers clicking through.
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Entry is a newly ingested entry
+type Entry struct {
+	ID          int64     `json:"id"`
+	FeedID      int64     `json:"feedId"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	GUID        string    `json:"guid,omitempty"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+// FeedFetch is the outcome of fetching one feed. Error is empty on success.
+type FeedFetch struct {
+	FeedID     int64  `json:"feedId"`
+	URL        string `json:"url"`
+	NewEntries int    `json:"newEntries"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Click is a recorded click on an entry
+type Click struct {
+	EntryID int64 `json:"entryId"`
+}
+
+// Hook receives events. Calls are made synchronously from the fetcher and
+// request handlers, so a hook with slow work should hand it off.
+type Hook interface {
+	OnEntryIngested(ctx context.Context, e Entry)
+	OnFeedFetched(ctx context.Context, f FeedFetch)
+	OnEntryClicked(ctx context.Context, c Click)
+}
+
+// Base implements Hook with no-ops, for embedding in hooks that only need
+// some of the events.
+type Base struct{}
+
+func (Base) OnEntryIngested(context.Context, Entry)   {}
+func (Base) OnFeedFetched(context.Context, FeedFetch) {}
+func (Base) OnEntryClicked(context.Context, Click)    {}
+
+// Registry fans events out to the registered hooks. A nil Registry is
+// valid and drops every event.
+type Registry struct {
+	logger *slog.Logger
+
+	mu    sync.RWMutex
+	hooks []Hook
+}
+
+func NewRegistry(logger *slog.Logger) *Registry {
+	return &Registry{logger: logger}
+}
+
+// Register adds a hook. Hooks are called in the order they were registered.
+func (r *Registry) Register(h Hook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, h)
+}
+
+// EntryIngested reports a new entry to every hook.
+func (r *Registry) EntryIngested(ctx context.Context, e Entry) {
+	r.each(func(h Hook) { h.OnEntryIngested(ctx, e) })
+}
+
+// FeedFetched reports a fetched feed to every hook.
+func (r *Registry) FeedFetched(ctx context.Context, f FeedFetch) {
+	r.each(func(h Hook) { h.OnFeedFetched(ctx, f) })
+}
+
+// EntryClicked reports a click to every hook.
+func (r *Registry) EntryClicked(ctx context.Context, c Click) {
+	r.each(func(h Hook) { h.OnEntryClicked(ctx, c) })
+}
+
+// each calls fn for every hook, keeping a panicking hook from taking down
+// the fetcher or the request.
+func (r *Registry) each(fn func(Hook)) {
+	if r == nil {
+		return
+	}
+	r.mu.RLock()
+	hooks := r.hooks
+	r.mu.RUnlock()
+
+	for _, h := range hooks {
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					r.logger.Error("Hook panicked", "hook", fmt.Sprintf("%T", h), "error", p)
+				}
+			}()
+			fn(h)
+		}()
+	}
+}
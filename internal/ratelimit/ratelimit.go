@@ -0,0 +1,114 @@
+// internal/ratelimit/ratelimit.go
+
+// Package ratelimit throttles repeated requests with a token bucket per
+// key, such as a client address. Buckets live in memory: a restart forgets
+// them, so lockouts that must survive one are kept by their callers.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often buckets that have refilled are dropped
+const pruneInterval = time.Minute
+
+// Limiter allows each key a burst of requests, refilled one at a time at a
+// steady rate
+type Limiter struct {
+	burst  float64
+	refill time.Duration // time for one token to come back
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+
+	// now is the clock, replaced in tests
+	now func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// New returns a limiter allowing burst requests per key at once, with one
+// more allowed every refill after that
+func New(burst int, refill time.Duration) *Limiter {
+	return &Limiter{
+		burst:   float64(burst),
+		refill:  refill,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it
+// reports false along with how long until the next token.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	b := l.fill(key, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) * float64(l.refill))
+	return false, wait
+}
+
+// SetRate changes the burst and refill interval. Buckets keep their tokens,
+// capped at the new burst.
+func (l *Limiter) SetRate(burst int, refill time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for key := range l.buckets {
+		l.fill(key, now)
+	}
+	l.burst = float64(burst)
+	l.refill = refill
+	for _, b := range l.buckets {
+		b.tokens = math.Min(l.burst, b.tokens)
+	}
+}
+
+// Reset refills key's bucket, such as after a successful login
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.buckets, key)
+}
+
+// fill tops key's bucket up for the time since it was last used
+func (l *Limiter) fill(key string, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+		return b
+	}
+	elapsed := now.Sub(b.updated)
+	b.tokens = math.Min(l.burst, b.tokens+float64(elapsed)/float64(l.refill))
+	b.updated = now
+	return b
+}
+
+// prune drops buckets that would be full by now, since a new bucket
+// behaves the same
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = now
+	for key, b := range l.buckets {
+		if b.tokens+float64(now.Sub(b.updated))/float64(l.refill) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
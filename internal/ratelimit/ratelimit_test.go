@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	l := New(3, time.Minute)
+	l.now = func() time.Time { return now }
+
+	// The burst is allowed, then the bucket is empty
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d refused within the burst", i+1)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok || wait != time.Minute {
+		t.Fatalf("Allow after burst = %v, %v; want false, 1m", ok, wait)
+	}
+
+	// Other keys have their own bucket
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("separate key refused")
+	}
+
+	// A token comes back after the refill interval
+	now = now.Add(40 * time.Second)
+	if ok, wait := l.Allow("a"); ok || wait != 20*time.Second {
+		t.Fatalf("Allow part way through refill = %v, %v; want false, 20s", ok, wait)
+	}
+	now = now.Add(20 * time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("request refused after refill")
+	}
+
+	// Reset gives the whole burst back
+	l.Reset("a")
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d refused after reset", i+1)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	l.Allow("b")
+	l.Allow("b")
+
+	// After a minute a has refilled and is dropped; b is still short
+	now = now.Add(time.Minute)
+	l.Allow("c")
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("full bucket kept")
+	}
+	if _, ok := l.buckets["b"]; !ok {
+		t.Error("partly empty bucket dropped")
+	}
+}
+
+func TestSetRate(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	l := New(5, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	l.SetRate(2, 10*time.Second)
+
+	// The four tokens left are capped at the new burst
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d refused within the new burst", i+1)
+		}
+	}
+	if ok, wait := l.Allow("a"); ok || wait != 10*time.Second {
+		t.Fatalf("Allow after new burst = %v, %v; want false, 10s", ok, wait)
+	}
+}
@@ -0,0 +1,159 @@
+// internal/schedule/schedule.go
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec describes when a periodic job runs: either every fixed interval, or
+// once a day at a wall-clock time in a given location. The zero Spec is
+// disabled.
+type Spec struct {
+	Every  time.Duration
+	Daily  bool
+	Hour   int
+	Minute int
+	Loc    *time.Location
+}
+
+// Enabled reports whether the spec ever fires.
+func (s Spec) Enabled() bool {
+	return s.Daily || s.Every > 0
+}
+
+// Interval returns a spec firing every d.
+func Interval(d time.Duration) Spec {
+	return Spec{Every: d}
+}
+
+// Parse reads a schedule from a setting value. Accepted forms are
+// "daily HH:MM" (or just "HH:MM"), evaluated in loc, and a Go duration such
+// as "6h" or "every 6h". An empty value or "off" disables the job.
+func Parse(value string, loc *time.Location) (Spec, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" || value == "off" {
+		return Spec{}, nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	if rest, ok := strings.CutPrefix(value, "every "); ok {
+		return parseInterval(strings.TrimSpace(rest))
+	}
+	value = strings.TrimSpace(strings.TrimPrefix(value, "daily"))
+	value = strings.TrimSpace(strings.TrimPrefix(value, "at "))
+
+	if hh, mm, ok := strings.Cut(value, ":"); ok {
+		hour, err1 := strconv.Atoi(hh)
+		minute, err2 := strconv.Atoi(mm)
+		if err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+			return Spec{}, fmt.Errorf("invalid time of day %q", value)
+		}
+		return Spec{Daily: true, Hour: hour, Minute: minute, Loc: loc}, nil
+	}
+	return parseInterval(value)
+}
+
+func parseInterval(value string) (Spec, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return Spec{}, fmt.Errorf("invalid schedule %q", value)
+	}
+	if d < time.Minute {
+		return Spec{}, fmt.Errorf("schedule interval %v is shorter than a minute", d)
+	}
+	return Interval(d), nil
+}
+
+// Next returns the first time strictly after last at which the job is due.
+// Daily times are resolved in the spec's location, so a local 03:30 stays
+// at 03:30 across daylight saving changes.
+func (s Spec) Next(last time.Time) time.Time {
+	if !s.Daily {
+		return last.Add(s.Every)
+	}
+	loc := s.Loc
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := last.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), s.Hour, s.Minute, 0, 0, loc)
+	if !next.After(last) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, s.Hour, s.Minute, 0, 0, loc)
+	}
+	return next
+}
+
+// String renders the spec in the form accepted by Parse.
+func (s Spec) String() string {
+	switch {
+	case s.Daily:
+		return fmt.Sprintf("daily %02d:%02d", s.Hour, s.Minute)
+	case s.Every > 0:
+		return "every " + s.Every.String()
+	default:
+		return "off"
+	}
+}
+
+// maxWait bounds how long Run sleeps before re-reading the spec, so that
+// settings changes take effect without a restart.
+const maxWait = time.Minute
+
+// Run calls job each time spec says it is due, until ctx is cancelled. The
+// spec function is consulted before every wait so schedules can change at
+// runtime. The first run happens at the first due time after Run starts.
+func Run(ctx context.Context, spec func() Spec, job func(ctx context.Context)) {
+	last := time.Now()
+	current := spec()
+
+	for {
+		wait := maxWait
+		if current.Enabled() {
+			if d := time.Until(current.Next(last)); d < wait {
+				wait = d
+			}
+		}
+
+		timer := time.NewTimer(max(wait, 0))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		updated := spec()
+		if current.Enabled() != updated.Enabled() {
+			// A re-enabled job counts from now rather than replaying missed runs
+			last = time.Now()
+		}
+		current = updated
+		if !current.Enabled() {
+			continue
+		}
+
+		now := time.Now()
+		if next := current.Next(last); !now.Before(next) {
+			job(ctx)
+			last = now
+		}
+	}
+}
+
+// Location resolves a timezone setting, falling back to UTC.
+func Location(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,73 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		err   bool
+	}{
+		{"", "off", false},
+		{"off", "off", false},
+		{"03:30", "daily 03:30", false},
+		{"daily 03:30", "daily 03:30", false},
+		{"Daily at 23:05", "daily 23:05", false},
+		{"every 6h", "every 6h0m0s", false},
+		{"90m", "every 1h30m0s", false},
+		{"25:00", "", true},
+		{"10s", "", true},
+		{"sometimes", "", true},
+	}
+
+	for _, tt := range tests {
+		spec, err := Parse(tt.value, time.UTC)
+		if (err != nil) != tt.err {
+			t.Errorf("Parse(%q) error = %v, want error %v", tt.value, err, tt.err)
+			continue
+		}
+		if err == nil && spec.String() != tt.want {
+			t.Errorf("Parse(%q) = %q, want %q", tt.value, spec.String(), tt.want)
+		}
+	}
+}
+
+func TestNextDaily(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	spec, _ := Parse("daily 03:30", loc)
+
+	tests := []struct {
+		last time.Time
+		want time.Time
+	}{
+		// Later the same day
+		{time.Date(2024, 6, 1, 1, 0, 0, 0, loc), time.Date(2024, 6, 1, 3, 30, 0, 0, loc)},
+		// Already past today's slot
+		{time.Date(2024, 6, 1, 3, 30, 0, 0, loc), time.Date(2024, 6, 2, 3, 30, 0, 0, loc)},
+		// Across the end of daylight saving time the local time is kept
+		{time.Date(2024, 10, 26, 12, 0, 0, 0, loc), time.Date(2024, 10, 27, 3, 30, 0, 0, loc)},
+	}
+
+	for _, tt := range tests {
+		got := spec.Next(tt.last)
+		if !got.Equal(tt.want) {
+			t.Errorf("Next(%v) = %v, want %v", tt.last, got, tt.want)
+		}
+		if local := got.In(loc); local.Hour() != 3 || local.Minute() != 30 {
+			t.Errorf("Next(%v) = %v, not 03:30 local", tt.last, local)
+		}
+	}
+}
+
+func TestNextInterval(t *testing.T) {
+	last := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := Interval(time.Hour).Next(last); !got.Equal(last.Add(time.Hour)) {
+		t.Errorf("Next = %v, want %v", got, last.Add(time.Hour))
+	}
+}
@@ -0,0 +1,66 @@
+// internal/security/netutil/netutil.go
+package netutil
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrDisallowedAddress is returned for destinations outside the public
+// internet, such as loopback, private and link-local addresses.
+var ErrDisallowedAddress = errors.New("destination address not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598)
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// IsPublicAddr reports whether addr may be reached by server-side fetches
+// of URLs that came from third parties.
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// CheckURL rejects URLs that are not plain HTTP(S) or that name a
+// non-public IP address directly. Host names are checked when dialing.
+func CheckURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return errors.New("missing host")
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil && !IsPublicAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrDisallowedAddress, addr)
+	}
+	return nil
+}
+
+// SafeDialer returns a copy of d that refuses to connect to non-public
+// addresses. The check runs on the resolved address, so DNS names that
+// point inside the network are caught too.
+func SafeDialer(d *net.Dialer) *net.Dialer {
+	safe := *d
+	safe.Control = func(network, address string, _ syscall.RawConn) error {
+		addrPort, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrDisallowedAddress, address)
+		}
+		if !IsPublicAddr(addrPort.Addr()) {
+			return fmt.Errorf("%w: %s", ErrDisallowedAddress, addrPort.Addr())
+		}
+		return nil
+	}
+	return &safe
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"infoscope/internal/database"
+	"infoscope/internal/favicon"
+	"infoscope/internal/feed"
+	"infoscope/internal/logging"
+	"infoscope/internal/storage"
+)
+
+// newTestServer returns a server on a fresh database with an admin account,
+// so the river is served instead of the setup page, and one feed with two
+// entries
+func newTestServer(t *testing.T, settings map[string]string) http.Handler {
+	t.Helper()
+	h, _ := newTestServerDB(t, settings)
+	return h
+}
+
+// newTestServerDB is newTestServer that also hands back the database
+func newTestServerDB(t *testing.T, settings map[string]string) (http.Handler, *database.DB) {
+	t.Helper()
+	srv, db := newTestServerSrv(t, settings)
+	return srv.Routes(), db
+}
+
+// newTestServerSrv is newTestServerDB handing back the server itself
+func newTestServerSrv(t *testing.T, settings map[string]string) (*Server, *database.DB) {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := database.NewDB(filepath.Join(dir, "test.db"), database.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := storage.NewDir(filepath.Join(dir, "favicons"))
+	if err != nil {
+		t.Fatalf("Failed to create favicon store: %v", err)
+	}
+	logger := logging.Discard()
+	srv, err := NewServer(db.DB, logger, feed.NewService(db.DB, logger, favicon.NewService(store)), Config{WebPath: filepath.Join(dir, "web")})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	published := time.Now().UTC().Add(-time.Hour)
+	for _, stmt := range []string{
+		"INSERT INTO admin_users (username, password_hash) VALUES ('admin', 'x')",
+		"INSERT INTO feeds (id, url, title, category) VALUES (1, 'https://example.com/feed', 'Example', 'News')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed database: %v", err)
+		}
+	}
+	for i, title := range []string{"First story", "Second story"} {
+		if _, err := db.Exec(
+			"INSERT INTO entries (feed_id, title, url, published_at, favicon_url) VALUES (1, ?, ?, ?, '')",
+			title, "https://example.com/"+strings.Fields(title)[0], published.Add(-time.Duration(i)*time.Minute),
+		); err != nil {
+			t.Fatalf("Failed to insert entry: %v", err)
+		}
+	}
+	for key, value := range settings {
+		if _, err := db.Exec("INSERT OR REPLACE INTO settings (key, value, type) VALUES (?, ?, 'string')", key, value); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+	return srv, db
+}
+
+// testClient sends requests to a test server as one browser would,
+// keeping its cookies and sending its CSRF token
+type testClient struct {
+	t       *testing.T
+	h       http.Handler
+	cookies map[string]*http.Cookie
+	csrf    string
+}
+
+func newTestClient(t *testing.T, h http.Handler) *testClient {
+	t.Helper()
+	c := &testClient{t: t, h: h, cookies: make(map[string]*http.Cookie)}
+	rec := c.do(http.MethodGet, "/csrf", "")
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Token == "" {
+		t.Fatalf("Failed to get a CSRF token: %v", err)
+	}
+	c.csrf = resp.Token
+	return c
+}
+
+// do sends a request with a JSON body, if any
+func (c *testClient) do(method, path, body string) *httptest.ResponseRecorder {
+	c.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CSRF-Token", c.csrf)
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	c.h.ServeHTTP(rec, req)
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(c.cookies, cookie.Name)
+		} else {
+			c.cookies[cookie.Name] = cookie
+		}
+	}
+	return rec
+}
+
+// getRiver fetches the front page and parses it
+func getRiver(t *testing.T, h http.Handler) *html.Node {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET / = %d, want 200", rec.Code)
+	}
+	doc, err := html.Parse(rec.Body)
+	if err != nil {
+		t.Fatalf("Failed to parse the river: %v", err)
+	}
+	return doc
+}
+
+// findAll returns the elements under n that match, in document order
+func findAll(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	var found []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && match(n) {
+			found = append(found, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return found
+}
+
+func tag(name string) func(*html.Node) bool {
+	return func(n *html.Node) bool { return n.Data == name }
+}
+
+func hasClass(name string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		for _, c := range strings.Fields(attr(n, "class")) {
+			if c == name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func TestSemanticMarkup(t *testing.T) {
+	for _, layout := range []string{RiverStream, RiverGrouped} {
+		t.Run(layout, func(t *testing.T) {
+			doc := getRiver(t, newTestServer(t, map[string]string{
+				"semantic_markup": "true",
+				"river_layout":    layout,
+			}))
+
+			for _, landmark := range []string{"header", "main", "footer"} {
+				if n := len(findAll(doc, tag(landmark))); n != 1 {
+					t.Errorf("Found %d <%s> landmarks, want 1", n, landmark)
+				}
+			}
+
+			// The skip link comes first and lands on the main landmark
+			links := findAll(doc, tag("a"))
+			if len(links) == 0 || !hasClass("skip-link")(links[0]) || attr(links[0], "href") != "#river" {
+				t.Fatalf("First link is not a skip link to #river")
+			}
+			mains := findAll(doc, tag("main"))
+			if len(mains) == 0 || attr(mains[0], "id") != "river" {
+				t.Errorf("Skip link target #river is not the main landmark")
+			}
+
+			entries := findAll(doc, hasClass("entry"))
+			if len(entries) != 2 {
+				t.Fatalf("Found %d entries, want 2", len(entries))
+			}
+			for _, e := range entries {
+				if e.Data != "li" || e.Parent.Data != "ul" || attr(e.Parent, "role") != "list" || attr(e.Parent, "aria-label") == "" {
+					t.Errorf("Entry is a <%s> in a <%s>, want an <li> in a labelled list", e.Data, e.Parent.Data)
+				}
+				if layout == RiverGrouped && attr(e.Parent, "aria-label") != "News" {
+					t.Errorf("Grouped entry list is labelled %q, want its category", attr(e.Parent, "aria-label"))
+				}
+				for _, img := range findAll(e, tag("img")) {
+					if attr(img, "alt") != "" {
+						t.Errorf("Decorative favicon has alt %q, want it empty", attr(img, "alt"))
+					}
+				}
+				for _, dots := range findAll(e, hasClass("dots")) {
+					if attr(dots, "aria-hidden") != "true" {
+						t.Errorf("Leader dots are not hidden from screen readers")
+					}
+				}
+			}
+
+			// Click-tracked links say what they open
+			tracked := findAll(doc, func(n *html.Node) bool {
+				return n.Data == "a" && strings.Contains(attr(n, "onclick"), "trackClick")
+			})
+			if len(tracked) != 2 {
+				t.Fatalf("Found %d tracked links, want 2", len(tracked))
+			}
+			for _, a := range tracked {
+				label := attr(a, "aria-label")
+				if !strings.Contains(label, "story") || !strings.Contains(label, "example.com") || !strings.Contains(label, "new tab") {
+					t.Errorf("Tracked link label %q doesn't name the entry, its site and the new tab", label)
+				}
+			}
+		})
+	}
+}
+
+func TestSemanticMarkupOff(t *testing.T) {
+	doc := getRiver(t, newTestServer(t, nil))
+
+	if n := len(findAll(doc, tag("main"))); n != 0 {
+		t.Errorf("Found %d <main> landmarks with semantic markup off, want the plain layout", n)
+	}
+	if n := len(findAll(doc, hasClass("skip-link"))); n != 0 {
+		t.Errorf("Found %d skip links with semantic markup off, want none", n)
+	}
+	for _, e := range findAll(doc, hasClass("entry")) {
+		if e.Data != "div" {
+			t.Errorf("Entry is a <%s> with semantic markup off, want a <div>", e.Data)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// internal/server/api.go
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Machine-readable error codes returned by the JSON endpoints
+const (
+	codeBadRequest       = "bad_request"
+	codeValidation       = "validation_failed"
+	codeUnauthorized     = "unauthorized"
+	codeCSRF             = "csrf_failed"
+	codeForbidden        = "forbidden"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeConflict         = "conflict"
+	codeTooLarge         = "too_large"
+	codeBusy             = "busy"
+	codeRateLimited      = "rate_limited"
+	codeInternal         = "internal_error"
+)
+
+// APIError is the body of every JSON error response:
+//
+//	{"error": {"code": "validation_failed", "message": "...", "fields": {"url": "..."}}}
+//
+// Fields maps request fields to what is wrong with them, and Details
+// carries endpoint-specific context such as feed diagnostics.
+type APIError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	Details any               `json:"details,omitempty"`
+}
+
+type apiErrorEnvelope struct {
+	Error APIError `json:"error"`
+}
+
+// writeJSON sends v as a JSON response. Responses are never sniffed as
+// another type, and callers send objects rather than bare arrays so the
+// body is not a valid script.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError sends an error envelope.
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiErrorEnvelope{APIError{Code: code, Message: message}})
+}
+
+// writeValidationError reports which request fields were rejected.
+func writeValidationError(w http.ResponseWriter, message string, fields map[string]string) {
+	writeJSON(w, http.StatusBadRequest, apiErrorEnvelope{APIError{
+		Code:    codeValidation,
+		Message: message,
+		Fields:  fields,
+	}})
+}
+
+// writeMethodNotAllowed rejects a request method.
+func writeMethodNotAllowed(w http.ResponseWriter) {
+	writeAPIError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+}
+
+// writeInternalError hides the cause of a failure from the client; the
+// caller logs it.
+func writeInternalError(w http.ResponseWriter) {
+	writeAPIError(w, http.StatusInternalServerError, codeInternal, "Internal server error")
+}
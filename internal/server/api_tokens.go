@@ -0,0 +1,197 @@
+// internal/server/api_tokens.go
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// apiTokenPrefix marks infoscope tokens so they are easy to spot in
+	// scripts and secret scanners
+	apiTokenPrefix = "isc_"
+	// maxAPITokenName caps the label an admin gives a token
+	maxAPITokenName = 100
+)
+
+// APIToken is an issued API token. The token itself is only shown once,
+// when it is created; Prefix identifies it afterwards.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// newAPIToken returns a fresh random token.
+func newAPIToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashAPIToken is the form a token is stored and looked up in.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// requireAPIToken guards a /api/v1 handler with a bearer token from the
+// api_tokens table. Tokens are only accepted in the Authorization header
+// so they stay out of access logs.
+func (s *Server) requireAPIToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="infoscope"`)
+			writeAPIError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+			return
+		}
+
+		var id int64
+		err := s.db.QueryRowContext(r.Context(),
+			"SELECT id FROM api_tokens WHERE token_hash = ? AND revoked_at IS NULL",
+			hashAPIToken(token)).Scan(&id)
+		if err == sql.ErrNoRows {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="infoscope", error="invalid_token"`)
+			writeAPIError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+			return
+		}
+		if err != nil {
+			s.logger.Error("Error checking API token", "error", err)
+			writeDBError(w, err)
+			return
+		}
+
+		// A read-only mirror can't record use
+		if !s.config.ReadOnly {
+			if _, err := s.db.ExecContext(r.Context(),
+				"UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP WHERE id = ?", id); err != nil {
+				s.logger.Error("Error recording API token use", "error", err)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// getAPITokens lists the tokens that have not been revoked, newest first.
+func (s *Server) getAPITokens(ctx context.Context) ([]APIToken, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, name, prefix, created_at, last_used_at
+        FROM api_tokens
+        WHERE revoked_at IS NULL
+        ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]APIToken, 0)
+	for rows.Next() {
+		var t APIToken
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&t.ID, &t.Name, &t.Prefix, &t.CreatedAt, &lastUsed); err != nil {
+			return nil, err
+		}
+		if lastUsed.Valid {
+			t.LastUsedAt = &lastUsed.Time
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// handleAPITokens lists tokens on GET, issues one on POST and revokes one
+// on DELETE.
+func (s *Server) handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := s.getAPITokens(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting API tokens", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"tokens": tokens})
+
+	case http.MethodPost:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" || len(name) > maxAPITokenName {
+			writeValidationError(w, "Invalid token name",
+				map[string]string{"name": "required, up to 100 characters"})
+			return
+		}
+
+		token, err := newAPIToken()
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error generating API token", "error", err)
+			writeInternalError(w)
+			return
+		}
+		prefix := token[:len(apiTokenPrefix)+6]
+		res, err := s.db.ExecContext(r.Context(),
+			"INSERT INTO api_tokens (name, token_hash, prefix) VALUES (?, ?, ?)",
+			name, hashAPIToken(token), prefix)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error storing API token", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		id, _ := res.LastInsertId()
+
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"token": APIToken{ID: id, Name: name, Prefix: prefix, CreatedAt: time.Now().UTC()},
+			"value": token,
+		})
+
+	case http.MethodDelete:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+		var req struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+
+		res, err := s.db.ExecContext(r.Context(),
+			"UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL", req.ID)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error revoking API token", "id", req.ID, "error", err)
+			writeDBError(w, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			writeAPIError(w, http.StatusNotFound, codeNotFound, "Token not found")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
@@ -0,0 +1,315 @@
+// internal/server/api_v1.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAPIEntries = 50
+	maxAPIEntries     = 500
+)
+
+// APIEntry is an entry as listed by the JSON API
+type APIEntry struct {
+	ID          int64     `json:"id"`
+	FeedID      int64     `json:"feedId"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Category    string    `json:"category,omitempty"`
+	PublishedAt time.Time `json:"publishedAt"`
+	Hidden      bool      `json:"hidden"`
+}
+
+// handleAPINotFound answers unknown /api/ paths in JSON rather than with
+// the HTML 404 page.
+func (s *Server) handleAPINotFound(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, http.StatusNotFound, codeNotFound, "Not found")
+}
+
+// handleAPIFeeds lists feeds on GET and subscribes to one on POST.
+func (s *Server) handleAPIFeeds(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		feeds, err := s.getFeeds(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting feeds", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		if feeds == nil {
+			feeds = []Feed{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"feeds": feeds})
+
+	case http.MethodPost:
+		var req struct {
+			URL      string `json:"url"`
+			Category string `json:"category"`
+			Tags     string `json:"tags"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		id, err := s.addFeed(r.Context(), req.URL, req.Category, req.Tags)
+		if err != nil {
+			writeValidationError(w, err.Error(), map[string]string{"url": err.Error()})
+			return
+		}
+		f, err := s.getFeed(r.Context(), id)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting feed", "feed_id", id, "error", err)
+			writeDBError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"feed": f})
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// handleAPIFeed reads, changes or deletes the feed at /api/v1/feeds/{id}.
+// PATCH takes the same fields as the admin feed editor.
+func (s *Server) handleAPIFeed(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/v1/feeds/"), 10, 64)
+	if err != nil || id <= 0 {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "Feed not found")
+		return
+	}
+
+	f, err := s.getFeed(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "Feed not found")
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting feed", "feed_id", id, "error", err)
+		writeDBError(w, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{"feed": f})
+
+	case http.MethodPatch:
+		var u feedUpdate
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		if !s.applyFeedUpdate(w, r, id, u) {
+			return
+		}
+		if f, err = s.getFeed(r.Context(), id); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting feed", "feed_id", id, "error", err)
+			writeDBError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"feed": f})
+
+	case http.MethodDelete:
+		if err := s.feedService.DeleteFeed(id); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error deleting feed", "feed_id", id, "error", err)
+			writeDBError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// handleAPIEntries lists entries newest first. It takes limit and offset
+// for paging, feed, category or tag to narrow the list, since, an RFC 3339
+// time, to only return newer entries, and hidden=true or false to list
+// only the entries kept off the public pages, or only the others.
+func (s *Server) handleAPIEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	q := r.URL.Query()
+	fields := make(map[string]string)
+	limit, offset := defaultAPIEntries, 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxAPIEntries {
+			fields["limit"] = "must be between 1 and 500"
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			fields["offset"] = "must be zero or more"
+		}
+		offset = n
+	}
+
+	filter := RiverFilter{Category: strings.TrimSpace(q.Get("category"))}
+	if v := q.Get("tag"); v != "" {
+		if tags := normalizeTags(v); len(tags) == 1 {
+			filter.Tag = tags[0]
+		} else {
+			fields["tag"] = "must be a single tag"
+		}
+	}
+	cond, args := filter.where()
+
+	if v := q.Get("feed"); v != "" {
+		feedID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			fields["feed"] = "must be a feed ID"
+		}
+		cond += " AND e.feed_id = ?"
+		args = append(args, feedID)
+	}
+	if v := q.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			fields["since"] = "must be an RFC 3339 time"
+		}
+		cond += " AND datetime(e.published_at) > ?"
+		args = append(args, since.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if v := q.Get("hidden"); v != "" {
+		hidden, err := strconv.ParseBool(v)
+		if err != nil {
+			fields["hidden"] = "must be true or false"
+		}
+		if hidden {
+			cond += " AND e.hidden_at IS NOT NULL"
+		} else {
+			cond += " AND e.hidden_at IS NULL"
+		}
+	}
+	if len(fields) > 0 {
+		writeValidationError(w, "Invalid query", fields)
+		return
+	}
+
+	entries, err := s.queryAPIEntries(r.Context(), cond, limit, offset, args...)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error listing entries", "error", err)
+		writeDBError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"entries": entries,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// handleAPIEntry gets one entry, or with PATCH hides it from the public
+// pages and feeds, or shows it again, with {"hidden": true} or false.
+func (s *Server) handleAPIEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/v1/entries/"), 10, 64)
+	if err != nil || id <= 0 {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "Entry not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		// Answered with the entry below, as PATCH is
+	case http.MethodPatch:
+		var u struct {
+			Hidden *bool `json:"hidden"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		if u.Hidden == nil {
+			writeValidationError(w, "Nothing to change", map[string]string{"hidden": "required"})
+			return
+		}
+		_, err := s.updateEntry(r.Context(), id, entryUpdate{Hidden: u.Hidden})
+		if errors.Is(err, sql.ErrNoRows) {
+			writeAPIError(w, http.StatusNotFound, codeNotFound, "Entry not found")
+			return
+		}
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error updating entry", "id", id, "error", err)
+			writeDBError(w, err)
+			return
+		}
+		s.logger.InfoContext(r.Context(), "Changed entry visibility", "id", id, "hidden", *u.Hidden)
+	default:
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	entries, err := s.queryAPIEntries(r.Context(), " AND e.id = ?", 1, 0, id)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting entry", "id", id, "error", err)
+		writeDBError(w, err)
+		return
+	}
+	if len(entries) == 0 {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "Entry not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"entry": entries[0]})
+}
+
+// queryAPIEntries returns the entries of live feeds matching cond, newest
+// first
+func (s *Server) queryAPIEntries(ctx context.Context, cond string, limit, offset int, args ...any) ([]APIEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT e.id, e.feed_id, e.title, e.url, COALESCE(f.category, ''), e.published_at,
+               e.hidden_at IS NOT NULL
+        FROM entries e
+        JOIN feeds f ON e.feed_id = f.id
+        WHERE f.status != 'deleted'`+cond+`
+        ORDER BY e.published_at DESC, e.id DESC
+        LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]APIEntry, 0)
+	for rows.Next() {
+		var e APIEntry
+		if err := rows.Scan(&e.ID, &e.FeedID, &e.Title, &e.URL, &e.Category, &e.PublishedAt, &e.Hidden); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// handleAPISettings returns the site settings. Credentials are left out,
+// as they are from backups.
+func (s *Server) handleAPISettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+		writeDBError(w, err)
+		return
+	}
+	for key := range secretSettings {
+		delete(settings, key)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"settings": settings})
+}
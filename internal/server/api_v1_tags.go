@@ -0,0 +1,252 @@
+// internal/server/api_v1_tags.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"infoscope/internal/database"
+)
+
+// handleAPICategories lists the categories in use, most used first.
+func (s *Server) handleAPICategories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	categories, err := s.getCategories(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting categories", "error", err)
+		writeDBError(w, err)
+		return
+	}
+	if categories == nil {
+		categories = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"categories": categories})
+}
+
+// handleAPITags lists every tag in use or given a priority.
+func (s *Server) handleAPITags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	tags, err := s.getTagPriorities(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting tags", "error", err)
+		writeDBError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
+
+// handleAPITag reads the tag at /api/v1/tags/{tag}, sets its fetch
+// priority on PUT, and on DELETE removes it from every feed along with its
+// priority.
+func (s *Server) handleAPITag(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/tags/")
+	tags := normalizeTags(name)
+	if len(tags) != 1 || strings.Contains(name, ",") {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "Tag not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		tag, ok, err := s.getAPITag(r.Context(), tags[0])
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting tags", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		if !ok {
+			writeAPIError(w, http.StatusNotFound, codeNotFound, "Tag not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"tag": tag})
+
+	case http.MethodPut:
+		var req TagPriority
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		req.Tag = tags[0]
+		if !s.saveTagPriority(w, r, &req) {
+			return
+		}
+		tag, _, err := s.getAPITag(r.Context(), tags[0])
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting tags", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"tag": tag})
+
+	case http.MethodDelete:
+		if err := s.deleteTag(r.Context(), tags[0]); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error deleting tag", "tag", tags[0], "error", err)
+			writeDBError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// getAPITag looks up one tag; a tag neither on a feed nor given a priority
+// doesn't exist. A tag that only just lost both is still returned, zeroed.
+func (s *Server) getAPITag(ctx context.Context, name string) (TagPriority, bool, error) {
+	tags, err := s.getTagPriorities(ctx)
+	if err != nil {
+		return TagPriority{}, false, err
+	}
+	for _, t := range tags {
+		if t.Tag == name {
+			return t, true, nil
+		}
+	}
+	return TagPriority{Tag: name}, false, nil
+}
+
+// deleteTag takes a tag off every feed and drops its priority.
+func (s *Server) deleteTag(ctx context.Context, name string) error {
+	return database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, "SELECT id, tags FROM feeds WHERE tags LIKE ?",
+			"%"+name+"%")
+		if err != nil {
+			return err
+		}
+		updates := make(map[int64]string)
+		for rows.Next() {
+			var id int64
+			var raw string
+			if err := rows.Scan(&id, &raw); err != nil {
+				rows.Close()
+				return err
+			}
+			var kept []string
+			for _, tag := range normalizeTags(raw) {
+				if tag != name {
+					kept = append(kept, tag)
+				}
+			}
+			if joined := strings.Join(kept, ","); joined != raw {
+				updates[id] = joined
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for id, tags := range updates {
+			if _, err := tx.ExecContext(ctx,
+				"UPDATE feeds SET tags = NULLIF(?, '') WHERE id = ?", tags, id); err != nil {
+				return err
+			}
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM tag_priorities WHERE tag = ?", name)
+		return err
+	})
+}
+
+// handleAPITagRules lists tag rules on GET and adds one on POST.
+func (s *Server) handleAPITagRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rules, err := s.getTagRules(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting tag rules", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		if rules == nil {
+			rules = []TagRule{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"rules": rules})
+
+	case http.MethodPost:
+		var rule TagRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		if !s.addTagRule(w, r, &rule) {
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"rule": rule})
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// handleAPITagRule reads or deletes the rule at /api/v1/tag-rules/{id}.
+// /api/v1/tag-rules/apply starts applying the rules to every feed on POST
+// and reports the progress on GET.
+func (s *Server) handleAPITagRule(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/tag-rules/")
+	if path == "apply" {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.tagBackfill.snapshot())
+		case http.MethodPost:
+			if !s.tagBackfill.start(s) {
+				writeAPIError(w, http.StatusConflict, codeConflict, "Tag rules are already being applied")
+				return
+			}
+			writeJSON(w, http.StatusAccepted, s.tagBackfill.snapshot())
+		default:
+			writeMethodNotAllowed(w)
+		}
+		return
+	}
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil || id <= 0 {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "Tag rule not found")
+		return
+	}
+
+	rules, err := s.getTagRules(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting tag rules", "error", err)
+		writeDBError(w, err)
+		return
+	}
+	var rule *TagRule
+	for i := range rules {
+		if rules[i].ID == id {
+			rule = &rules[i]
+		}
+	}
+	if rule == nil {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "Tag rule not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{"rule": rule})
+
+	case http.MethodDelete:
+		if _, err := s.db.ExecContext(r.Context(), "DELETE FROM tag_rules WHERE id = ?", id); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error deleting tag rule", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
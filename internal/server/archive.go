@@ -0,0 +1,203 @@
+// internal/server/archive.go
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// archivePageSize is how many entries an archive page lists
+	archivePageSize = 50
+	// archiveMonthLayout names a month in archive paths
+	archiveMonthLayout = "2006-01"
+)
+
+// ArchiveMonth is a month of the archive with the entries published in it
+type ArchiveMonth struct {
+	Path  string
+	Label string
+	Count int
+}
+
+// ArchivePageData is the template data for the public archive pages
+type ArchivePageData struct {
+	SiteTitle string
+	Settings  map[string]string
+	Heading   string
+	Path      string
+	Entries   []EntryView
+	Page      int
+	PrevPage  int
+	NextPage  int
+	Months    []ArchiveMonth
+}
+
+// handleArchive serves /archive, every stored entry newest first, and
+// /archive/YYYY-MM for one month in the site timezone; both take ?page=N.
+// The pages only exist in archive mode, when entries are never deleted.
+func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if settings["archive_mode"] != "true" {
+		s.handle404(w, r)
+		return
+	}
+
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		if page, err = strconv.Atoi(p); err != nil || page < 1 {
+			s.handle404(w, r)
+			return
+		}
+	}
+
+	loc := s.siteLocation(r.Context())
+	data := ArchivePageData{
+		SiteTitle: settings["site_title"],
+		Settings:  settings,
+		Heading:   "archive",
+		Path:      "/archive",
+		Page:      page,
+	}
+
+	// Bounds are in UTC as stored, so both views walk the published_at index
+	var start, end time.Time
+	if month := strings.TrimPrefix(r.URL.Path, "/archive"); month != "" {
+		m, err := time.ParseInLocation(archiveMonthLayout, strings.TrimPrefix(month, "/"), loc)
+		if err != nil || !strings.HasPrefix(month, "/") {
+			s.handle404(w, r)
+			return
+		}
+		start, end = m, m.AddDate(0, 1, 0)
+		data.Heading = "archive: " + m.Format("January 2006")
+		data.Path = "/archive/" + m.Format(archiveMonthLayout)
+	}
+
+	entries, err := s.getArchiveEntries(r.Context(), start, end, (page-1)*archivePageSize, archivePageSize+1)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting archive entries", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(entries) == 0 && page > 1 {
+		s.handle404(w, r)
+		return
+	}
+	if len(entries) > archivePageSize {
+		entries = entries[:archivePageSize]
+		data.NextPage = page + 1
+	}
+	data.Entries = entries
+	data.PrevPage = page - 1
+
+	if data.Months, err = s.getArchiveMonths(r.Context(), loc); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting archive months", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.renderTemplate(w, r, "archive.html", data); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error rendering archive template", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// archiveVisible is the condition on entries e and feeds f for what the
+// archive shows: the river's feeds, without hidden entries or collapsed
+// copies of a story
+func archiveVisible() (string, []any) {
+	dupCond, dupArgs := RiverFilter{}.withoutDuplicates()
+	return `f.status != 'deleted' AND e.hidden_at IS NULL
+          AND (f.snoozed_until IS NULL OR f.snoozed_until <= datetime('now'))` + dupCond, dupArgs
+}
+
+// getArchiveEntries returns up to limit entries after skipping offset,
+// newest first, limited to [start, end) unless start is zero.
+func (s *Server) getArchiveEntries(ctx context.Context, start, end time.Time, offset, limit int) ([]EntryView, error) {
+	cond, args := archiveVisible()
+	if !start.IsZero() {
+		cond += " AND e.published_at >= ? AND e.published_at < ?"
+		args = append(args, start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05"))
+	}
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT e.id, e.feed_id, e.title, e.url, e.favicon_url,
+               COALESCE(f.category, ''), COALESCE(f.language, ''),
+               datetime(e.published_at)
+        FROM entries e
+        JOIN feeds f ON e.feed_id = f.id
+        WHERE `+cond+`
+        ORDER BY e.published_at DESC, e.id DESC
+        LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+	return scanEntryViews(rows, s.siteLocation(ctx))
+}
+
+// getArchiveMonths lists the months from the oldest entry to the newest,
+// newest first, leaving out months without entries. Each month is counted
+// over its own range of the published_at index, so months follow the site
+// timezone.
+func (s *Server) getArchiveMonths(ctx context.Context, loc *time.Location) ([]ArchiveMonth, error) {
+	var oldest, newest string
+	err := s.db.QueryRowContext(ctx, `
+        SELECT COALESCE(datetime(MIN(published_at)), ''), COALESCE(datetime(MAX(published_at)), '')
+        FROM entries`).Scan(&oldest, &newest)
+	if err != nil || oldest == "" {
+		return nil, err
+	}
+	first, err := time.Parse("2006-01-02 15:04:05", oldest)
+	if err != nil {
+		return nil, err
+	}
+	last, err := time.Parse("2006-01-02 15:04:05", newest)
+	if err != nil {
+		return nil, err
+	}
+
+	cond, args := archiveVisible()
+	stmt, err := s.db.PrepareContext(ctx, `
+        SELECT COUNT(*)
+        FROM entries e
+        JOIN feeds f ON e.feed_id = f.id
+        WHERE e.published_at >= ? AND e.published_at < ? AND `+cond)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var months []ArchiveMonth
+	first = first.In(loc)
+	stop := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, loc)
+	last = last.In(loc)
+	for m := time.Date(last.Year(), last.Month(), 1, 0, 0, 0, 0, loc); !m.Before(stop); m = m.AddDate(0, -1, 0) {
+		var count int
+		bounds := []any{m.UTC().Format("2006-01-02 15:04:05"), m.AddDate(0, 1, 0).UTC().Format("2006-01-02 15:04:05")}
+		if err := stmt.QueryRowContext(ctx, append(bounds, args...)...).Scan(&count); err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			months = append(months, ArchiveMonth{
+				Path:  "/archive/" + m.Format(archiveMonthLayout),
+				Label: m.Format("Jan 2006"),
+				Count: count,
+			})
+		}
+	}
+	return months, nil
+}
@@ -0,0 +1,127 @@
+// internal/server/assets.go
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"infoscope/internal/storage"
+)
+
+// assetDirs are the mutable subdirectories of static/: fetched favicons and
+// uploaded images. Everything else under static/ ships with the binary.
+var assetDirs = []string{"favicons", "images"}
+
+// assetHandler serves /static/ paths for stored assets, falling back to the
+// web directory for bundled files such as the default favicon.
+func (s *Server) assetHandler() http.Handler {
+	bundled := http.StripPrefix("/static/", http.FileServer(http.Dir(filepath.Join(s.config.WebPath, "static"))))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/static/")
+		data, obj, err := s.assets.Get(r.Context(), name)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, storage.ErrInvalidName) {
+				s.logger.ErrorContext(r.Context(), "Error reading asset", "name", name, "error", err)
+			}
+			bundled.ServeHTTP(w, r)
+			return
+		}
+		http.ServeContent(w, r, path.Base(name), obj.ModTime, bytes.NewReader(data))
+	})
+}
+
+// MigrateAssets moves favicons and uploaded images from the web directory's
+// static/ folder to dst. Files already at dst are left alone, as are files
+// bundled with the binary. When the source is read-only the files are
+// copied and the originals stay behind.
+func MigrateAssets(src, dst string, logger *slog.Logger) error {
+	if filepath.Clean(src) == filepath.Clean(dst) {
+		return nil
+	}
+
+	moved, copied := 0, 0
+	for _, dir := range assetDirs {
+		root := filepath.Join(src, dir)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(src, path)
+			if err != nil {
+				return err
+			}
+			if _, err := fs.Stat(webContent, filepath.ToSlash(filepath.Join("static", rel))); err == nil {
+				return nil
+			}
+
+			target := filepath.Join(dst, rel)
+			if _, err := os.Stat(target); err == nil {
+				return nil
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+
+			if err := os.Rename(path, target); err == nil {
+				moved++
+				return nil
+			}
+			// Renaming fails across mounts; copy instead
+			if err := copyFile(path, target); err != nil {
+				return fmt.Errorf("copying %s: %w", rel, err)
+			}
+			if os.Remove(path) == nil {
+				moved++
+			} else {
+				copied++
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("migrating %s: %w", dir, err)
+		}
+	}
+
+	if moved+copied > 0 {
+		logger.Info("Moved asset files", "moved", moved, "dst", dst)
+		if copied > 0 {
+			logger.Info("Copied asset files whose originals could not be removed", "copied", copied)
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,127 @@
+// internal/server/atom.go
+package server
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"` // entries inherit the feed's author
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID       string        `xml:"id"`
+	Title    string        `xml:"title"`
+	Updated  string        `xml:"updated"`
+	Link     atomLink      `xml:"link"`
+	Category *atomCategory `xml:"category,omitempty"`
+}
+
+// handleAtom serves the river as an Atom 1.0 feed.
+func (s *Server) handleAtom(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	entries, err := s.riverEntries(r.Context(), settings, RiverFilter{})
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting entries", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	base := siteBaseURL(r, settings["site_url"])
+	feed := atomFeed{
+		XMLNS:  atomNamespace,
+		ID:     base + "/",
+		Title:  settings["site_title"],
+		Author: atomPerson{Name: settings["site_title"], URI: base + "/"},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: base + "/atom.xml"},
+			{Rel: "alternate", Type: "text/html", Href: base + "/"},
+		},
+	}
+
+	var updated time.Time
+	for _, e := range entries {
+		link := e.URL
+		if e.Roundup {
+			link = base + e.URL
+		}
+		entry := atomEntry{
+			// Entry links are stable and unique, so they double as IDs
+			ID:      link,
+			Title:   e.Title,
+			Updated: e.PublishedAt.UTC().Format(time.RFC3339),
+			Link:    atomLink{Rel: "alternate", Href: link},
+		}
+		if e.Category != "" {
+			entry.Category = &atomCategory{Term: e.Category}
+		}
+		feed.Entries = append(feed.Entries, entry)
+		if e.PublishedAt.After(updated) {
+			updated = e.PublishedAt
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error encoding Atom feed", "error", err)
+	}
+}
+
+// siteBaseURL is the site's public address without a trailing slash. The
+// site_url setting wins; otherwise it is worked out from the request.
+func siteBaseURL(r *http.Request, siteURL string) string {
+	if u, err := url.Parse(strings.TrimSpace(siteURL)); err == nil && u.Scheme != "" && u.Host != "" {
+		return strings.TrimSuffix(u.String(), "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
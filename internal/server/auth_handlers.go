@@ -5,12 +5,20 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"infoscope/internal/auth"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
 // Helper functions for dashboard data
@@ -45,15 +53,7 @@ func (s *Server) getLastUpdateTime(ctx context.Context) (time.Time, error) {
 
 // Template rendering with CSRF
 func (s *Server) renderTemplate(w http.ResponseWriter, r *http.Request, name string, data any) error {
-	// Merge function maps
-	funcMap := template.FuncMap{
-		"safeHTML": func(s string) template.HTML {
-			return template.HTML(s)
-		},
-	}
-	for k, v := range s.registerTemplateFuncs() {
-		funcMap[k] = v
-	}
+	funcMap := s.registerTemplateFuncs(r.Context())
 
 	var wrappedData struct {
 		Data      any
@@ -82,12 +82,21 @@ func (s *Server) renderTemplate(w http.ResponseWriter, r *http.Request, name str
 			CSRFToken: s.csrf.Token(w, r),
 		}
 	case IndexData:
+		// handleIndex leaves the token out of pages meant for shared caches
 		wrappedData = struct {
 			Data      any
 			CSRFToken string
 		}{
 			Data:      v,
-			CSRFToken: s.csrf.Token(w, r),
+			CSRFToken: v.CSRFToken,
+		}
+	case RoundupPageData, FeedPageData, StarredPageData:
+		// Roundup, feed and starred pages post nothing and are shared through caches
+		wrappedData = struct {
+			Data      any
+			CSRFToken string
+		}{
+			Data: v,
 		}
 	default:
 		wrappedData = struct {
@@ -115,7 +124,7 @@ func (s *Server) renderTemplate(w http.ResponseWriter, r *http.Request, name str
 
 	tmpl, err := tmpl.ParseFiles(files...)
 	if err != nil {
-		s.logger.Printf("Error parsing template %s: %v", name, err)
+		s.logger.ErrorContext(r.Context(), "Error parsing template", "name", name, "error", err)
 		return fmt.Errorf("error parsing template: %w", err)
 	}
 
@@ -135,7 +144,7 @@ func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate session
-	session, err := s.auth.ValidateSession(s.db, cookie.Value)
+	session, err := s.auth.ValidateSession(r.Context(), cookie.Value)
 	if err != nil || session == nil || session.IsExpired() {
 		http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
 		return
@@ -144,14 +153,14 @@ func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
 	// Get settings for favicon and other UI elements
 	settings, err := s.getSettings(r.Context())
 	if err != nil {
-		s.logger.Printf("Error getting settings (user %d): %v", session.UserID, err)
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "user_id", session.UserID, "error", err)
 		settings = make(map[string]string)
 	}
 
 	// Get dashboard counts
 	feedCount, entryCount, err := s.getDashboardCounts(r.Context())
 	if err != nil {
-		s.logger.Printf("Error getting counts (user %d): %v", session.UserID, err)
+		s.logger.ErrorContext(r.Context(), "Error getting counts", "user_id", session.UserID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -159,17 +168,52 @@ func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
 	// Get last update time
 	lastUpdateTime, err := s.getLastUpdateTime(r.Context())
 	if err != nil {
-		s.logger.Printf("Error getting last update (user %d): %v", session.UserID, err)
+		s.logger.ErrorContext(r.Context(), "Error getting last update", "user_id", session.UserID, "error", err)
 		lastUpdateTime = time.Time{} // Zero time instead of "Never"
 	}
 
 	// Get click statistics
-	clickStats, err := s.getClickStats()
+	clickStats, err := s.getClickStats(r.Context(), parseClickWindow(r.URL.Query().Get("clicks")))
 	if err != nil {
-		s.logger.Printf("Error getting click stats (user %d): %v", session.UserID, err)
+		s.logger.ErrorContext(r.Context(), "Error getting click stats", "user_id", session.UserID, "error", err)
 		clickStats = &DashboardStats{}
 	}
 
+	// Get recently edited entries
+	edits, err := s.getRecentEdits(r.Context(), 10)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting recent edits", "user_id", session.UserID, "error", err)
+		edits = nil
+	}
+
+	// Get bandwidth usage
+	bandwidth, err := s.getBandwidthStats(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting bandwidth stats", "user_id", session.UserID, "error", err)
+		bandwidth = &BandwidthStats{}
+	}
+
+	// Get activity since the previous login
+	sinceLogin, err := s.getSinceLastLogin(r.Context(), session.UserID)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting activity since last login", "user_id", session.UserID, "error", err)
+		sinceLogin = nil
+	}
+
+	// Get unresolved fetch pipeline alerts
+	alerts, err := s.getFetchAlerts(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting fetch alerts", "user_id", session.UserID, "error", err)
+		alerts = nil
+	}
+
+	// Get unread notifications, such as feeds disabled after errors
+	notifications, err := s.getNotifications(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting notifications", "user_id", session.UserID, "error", err)
+		notifications = nil
+	}
+
 	data := AdminPageData{
 		Title:      "Dashboard",
 		Active:     "dashboard",
@@ -179,6 +223,13 @@ func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
 		LastUpdate: lastUpdateTime,
 		UserID:     session.UserID,
 		ClickStats: clickStats,
+		Edits:      edits,
+		Bandwidth:  bandwidth,
+		SinceLogin: sinceLogin,
+		Alerts:     alerts,
+		Update:     s.getUpdateStatus(r.Context()),
+
+		Notifications: notifications,
 	}
 
 	wrappedData := struct {
@@ -190,7 +241,7 @@ func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.renderTemplate(w, r, "admin/dashboard.html", wrappedData); err != nil {
-		s.logger.Printf("Error rendering template (user %d): %v", session.UserID, err)
+		s.logger.ErrorContext(r.Context(), "Error rendering template", "user_id", session.UserID, "error", err)
 		if !headerWritten(w) {
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		}
@@ -198,7 +249,7 @@ func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
-	s.logger.Printf("Login request received: %s", r.Method)
+	s.logger.DebugContext(r.Context(), "Login request received", "method", r.Method)
 
 	switch r.Method {
 	case http.MethodGet:
@@ -208,7 +259,7 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		// Retrieve settings
 		settings, err := s.getSettings(r.Context())
 		if err != nil {
-			s.logger.Printf("Error getting settings: %v", err)
+			s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
 			settings = make(map[string]string)
 		}
 
@@ -230,54 +281,295 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		tmplPath := filepath.Join(s.config.WebPath, "templates", "login.html")
 		tmpl, err := template.ParseFiles(tmplPath)
 		if err != nil {
-			s.logger.Printf("Error parsing login template: %v", err)
+			s.logger.ErrorContext(r.Context(), "Error parsing login template", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
 		if err := tmpl.Execute(w, data); err != nil {
-			s.logger.Printf("Error executing login template: %v", err)
+			s.logger.ErrorContext(r.Context(), "Error executing login template", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
 	case http.MethodPost:
-		s.logger.Printf("Login attempt received")
+		s.logger.DebugContext(r.Context(), "Login attempt received")
 		if !s.csrf.Validate(w, r) {
-			s.logger.Printf("CSRF validation failed")
+			s.logger.WarnContext(r.Context(), "CSRF validation failed")
 			return
 		}
 		var req loginRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			s.logger.Printf("Failed to decode login request: %v", err)
-			http.Error(w, "Invalid request", http.StatusBadRequest)
+			s.logger.WarnContext(r.Context(), "Failed to decode login request", "error", err)
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		session, err := s.auth.Authenticate(r.Context(), s.db, req.Username, req.Password)
+		var lockout *auth.LockoutError
+		if errors.As(err, &lockout) {
+			s.logger.WarnContext(r.Context(), "Login to locked account", "username", req.Username, "ip", s.clientIP(r), "until", lockout.Until)
+			writeTooManyRequests(w, time.Until(lockout.Until), "Too many failed logins, try again later")
 			return
 		}
-		session, err := s.auth.Authenticate(s.db, req.Username, req.Password)
 		if err != nil {
-			s.logger.Printf("Authentication failed: %v", err)
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+			s.logger.WarnContext(r.Context(), "Authentication failed", "username", req.Username, "ip", s.clientIP(r), "error", err)
+			writeAPIError(w, http.StatusUnauthorized, codeUnauthorized, "Invalid credentials")
 			return
 		}
-		s.logger.Printf("Authentication successful, setting session cookie")
-		// Set session cookie
-		http.SetCookie(w, &http.Cookie{
-			Name:     "session",
-			Value:    session.ID,
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   s.csrf.config.Secure,
-			SameSite: http.SameSiteStrictMode,
-			Expires:  session.ExpiresAt,
-		})
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+		if err := s.recordLogin(r.Context(), session.UserID); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error recording login time", "error", err)
+		}
+		s.logger.DebugContext(r.Context(), "Authentication successful, setting session cookie")
+		s.loginLimiter.Reset(s.clientIP(r))
+		s.setSessionCookie(w, session)
+		writeJSON(w, http.StatusOK, map[string]bool{"success": true})
 
 	default:
-		s.logger.Printf("Invalid method for login: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		s.logger.DebugContext(r.Context(), "Invalid method for login", "method", r.Method)
+		writeMethodNotAllowed(w)
+	}
+}
+
+// setSessionCookie hands a signed-in browser its session
+func (s *Server) setSessionCookie(w http.ResponseWriter, session *auth.Session) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session",
+		Value:    session.ID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   s.csrf.config.Secure,
+		SameSite: http.SameSiteStrictMode,
+		Expires:  session.ExpiresAt,
+	})
+}
+
+// handlePasskeyLoginBegin starts a passkey sign-in. No username is asked
+// for: the authenticator offers the passkeys it holds for this site and
+// names the account in its answer.
+func (s *Server) handlePasskeyLoginBegin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w)
+		return
+	}
+	if !s.csrf.Validate(w, r) {
+		return
+	}
+	wa, err := s.webAuthn(r)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error configuring passkeys", "error", err)
+		writeInternalError(w)
+		return
+	}
+	assertion, session, err := wa.BeginDiscoverableLogin()
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error starting passkey sign-in", "error", err)
+		writeInternalError(w)
+		return
+	}
+	err = s.passkeys.begin(w, s.csrf.config.Secure, passkeyCeremony{session: *session})
+	if errors.Is(err, errPasskeyCeremoniesFull) {
+		s.logger.WarnContext(r.Context(), "Refused passkey sign-in, too many in progress", "ip", s.clientIP(r))
+		writeTooManyRequests(w, time.Minute, "Too many sign-ins in progress, try again later")
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error starting passkey sign-in", "error", err)
+		writeInternalError(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, assertion)
+}
+
+// handlePasskeyLoginFinish checks the authenticator's signature and, if it
+// belongs to a registered passkey, signs its account in
+func (s *Server) handlePasskeyLoginFinish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w)
+		return
+	}
+	if !s.csrf.Validate(w, r) {
+		return
+	}
+	ceremony, ok := s.passkeys.finish(w, r)
+	if !ok {
+		writeAPIError(w, http.StatusBadRequest, codeBadRequest, "Passkey sign-in timed out, try again")
+		return
+	}
+	wa, err := s.webAuthn(r)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error configuring passkeys", "error", err)
+		writeInternalError(w)
+		return
+	}
+
+	var user *auth.PasskeyUser
+	cred, err := wa.FinishDiscoverableLogin(func(_, handle []byte) (webauthn.User, error) {
+		found, err := auth.GetPasskeyUserByHandle(r.Context(), s.db, handle)
+		user = found
+		return found, err
+	}, ceremony.session, r)
+	if err != nil {
+		s.logger.WarnContext(r.Context(), "Passkey authentication failed", "error", err)
+		writeAPIError(w, http.StatusUnauthorized, codeUnauthorized, "Passkey not recognized")
+		return
+	}
+	if cred.Authenticator.CloneWarning {
+		s.logger.WarnContext(r.Context(), "Refused passkey with a signature counter that went backwards, the authenticator may be cloned", "user_id", user.ID)
+		writeAPIError(w, http.StatusUnauthorized, codeUnauthorized, "Passkey not recognized")
+		return
+	}
+	if err := auth.UsePasskey(r.Context(), s.db, cred); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error recording passkey use", "user_id", user.ID, "error", err)
+	}
+	// The passkey proves who this is, so lift any lockout from guessed
+	// passwords
+	if err := auth.ClearLoginFailures(r.Context(), s.db, user.ID); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error clearing failed logins", "user_id", user.ID, "error", err)
+	}
+	s.loginLimiter.Reset(s.clientIP(r))
+
+	session, err := s.auth.StartSession(r.Context(), user.ID)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error starting session", "user_id", user.ID, "error", err)
+		writeInternalError(w)
+		return
+	}
+	if err := s.recordLogin(r.Context(), session.UserID); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error recording login time", "error", err)
+	}
+	s.setSessionCookie(w, session)
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// handlePasskeyRegisterBegin starts adding a passkey to the signed-in
+// account. The passkey is made discoverable so it can sign in without a
+// username.
+func (s *Server) handlePasskeyRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w)
+		return
+	}
+	if !s.csrf.Validate(w, r) {
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err, "Invalid request")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > maxPasskeyNameLength {
+		writeValidationError(w, "Invalid passkey", map[string]string{"name": "required, up to 64 characters"})
+		return
+	}
+
+	userID, _ := getUserID(r.Context())
+	user, err := auth.GetPasskeyUser(r.Context(), s.db, userID)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting user", "user_id", userID, "error", err)
+		writeDBError(w, err)
+		return
+	}
+	wa, err := s.webAuthn(r)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error configuring passkeys", "error", err)
+		writeInternalError(w)
+		return
+	}
+
+	exclude := make([]protocol.CredentialDescriptor, 0, len(user.Credentials))
+	for _, cred := range user.Credentials {
+		exclude = append(exclude, cred.Descriptor())
+	}
+	creation, session, err := wa.BeginRegistration(user,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(exclude))
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error starting passkey registration", "user_id", userID, "error", err)
+		writeInternalError(w)
+		return
+	}
+	ceremony := passkeyCeremony{session: *session, userID: userID, name: req.Name}
+	err = s.passkeys.begin(w, s.csrf.config.Secure, ceremony)
+	if errors.Is(err, errPasskeyCeremoniesFull) {
+		writeTooManyRequests(w, time.Minute, "Too many passkey requests in progress, try again later")
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error starting passkey registration", "user_id", userID, "error", err)
+		writeInternalError(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, creation)
+}
+
+// handlePasskeyRegisterFinish checks the new credential and stores it
+func (s *Server) handlePasskeyRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w)
+		return
+	}
+	if !s.csrf.Validate(w, r) {
+		return
+	}
+	userID, _ := getUserID(r.Context())
+	ceremony, ok := s.passkeys.finish(w, r)
+	if !ok || ceremony.userID != userID {
+		writeAPIError(w, http.StatusBadRequest, codeBadRequest, "Passkey registration timed out, try again")
+		return
+	}
+	user, err := auth.GetPasskeyUser(r.Context(), s.db, userID)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting user", "user_id", userID, "error", err)
+		writeDBError(w, err)
+		return
+	}
+	wa, err := s.webAuthn(r)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error configuring passkeys", "error", err)
+		writeInternalError(w)
+		return
+	}
+
+	cred, err := wa.FinishRegistration(user, ceremony.session, r)
+	if err != nil {
+		s.logger.WarnContext(r.Context(), "Passkey registration failed", "user_id", userID, "error", err)
+		writeAPIError(w, http.StatusBadRequest, codeBadRequest, "The passkey couldn't be verified")
+		return
+	}
+	id, err := auth.AddPasskey(r.Context(), s.db, userID, ceremony.name, cred)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error saving passkey", "user_id", userID, "error", err)
+		writeDBError(w, err)
+		return
+	}
+	s.logger.InfoContext(r.Context(), "Added passkey", "id", id, "user_id", userID, "name", ceremony.name)
+	writeJSON(w, http.StatusCreated, map[string]int64{"id": id})
+}
+
+// webAuthn configures the WebAuthn relying party for the site's public
+// address, which passkeys are bound to
+func (s *Server) webAuthn(r *http.Request) (*webauthn.WebAuthn, error) {
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	origin := siteBaseURL(r, settings["site_url"])
+	u, err := url.Parse(origin)
+	if err != nil {
+		return nil, err
+	}
+	name := settings["site_title"]
+	if name == "" {
+		name = "infoscope_"
 	}
+	return webauthn.New(&webauthn.Config{
+		RPID:          u.Hostname(),
+		RPDisplayName: name,
+		RPOrigins:     []string{origin},
+	})
 }
 
 func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
@@ -296,8 +588,8 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie("session")
 	if err == nil && cookie.Value != "" {
 		// Invalidate the session in the database
-		if err := s.auth.InvalidateSession(s.db, cookie.Value); err != nil {
-			s.logger.Printf("Error invalidating session: %v", err)
+		if err := s.auth.InvalidateSession(r.Context(), cookie.Value); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error invalidating session", "error", err)
 		}
 
 		// Clear the session cookie
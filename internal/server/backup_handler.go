@@ -3,8 +3,11 @@ package server
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"infoscope/internal/database"
+	"infoscope/internal/feedurl"
 	"net/http"
 	"time"
 )
@@ -14,12 +17,16 @@ type BackupData struct {
 	ExportDate time.Time         `json:"exportDate"`
 	Settings   map[string]string `json:"settings"`
 	Feeds      []Feed            `json:"feeds"` // Uses the Feed struct from types.go
+	Secrets    *BackupSecrets    `json:"secrets,omitempty"`
+
+	TagPriorities []TagPriority `json:"tagPriorities,omitempty"`
+	TagRules      []TagRule     `json:"tagRules,omitempty"`
 }
 
 func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
 	// Ensure user is authenticated
 	if _, ok := getUserID(r.Context()); !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeAPIError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -29,67 +36,158 @@ func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPost:
 		s.handleImport(w, r)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
 	}
 }
 
 func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
-	// Create backup structure
-	backup := BackupData{
-		Version:    "1.0",
+	// Credentials are only included on explicit opt-in
+	includeSecrets := r.URL.Query().Get("secrets") == "1"
+	backup, err := s.buildBackup(r.Context(), includeSecrets, r.Header.Get("X-Backup-Passphrase"))
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error building backup", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to create backup")
+		return
+	}
+
+	// Feed statistics turn the backup into a health report; imports ignore them
+	if r.URL.Query().Get("stats") == "1" {
+		stats, err := s.getFeedStats(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting feed stats", "error", err)
+			writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to create backup")
+			return
+		}
+		for i := range backup.Feeds {
+			if st, ok := stats[backup.Feeds[i].URL]; ok {
+				backup.Feeds[i].Stats = &st
+			}
+		}
+	}
+
+	// Set headers for file download
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=infoscope_backup_%s.json",
+			time.Now().Format("2006-01-02")))
+
+	// Write JSON response
+	if err := json.NewEncoder(w).Encode(backup); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error encoding backup", "error", err)
+		return
+	}
+
+	// Remember when the last backup was taken
+	if err := s.setAppState(r.Context(), "last_backup_at", backup.ExportDate.UTC().Format(time.RFC3339)); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error recording backup time", "error", err)
+	}
+}
+
+// buildBackup collects settings and feeds into a BackupData. Secret settings
+// are only included when includeSecrets is set.
+func (s *Server) buildBackup(ctx context.Context, includeSecrets bool, passphrase string) (*BackupData, error) {
+	backup := &BackupData{
+		Version:    "1.1",
 		ExportDate: time.Now(),
 		Settings:   make(map[string]string),
 		Feeds:      make([]Feed, 0),
 	}
 
 	// Get settings
-	rows, err := s.db.QueryContext(r.Context(), "SELECT key, value FROM settings")
+	rows, err := s.db.QueryContext(ctx, "SELECT key, value FROM settings")
 	if err != nil {
-		s.logger.Printf("Error getting settings for backup: %v", err)
-		http.Error(w, "Failed to export settings", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("getting settings: %w", err)
 	}
 	defer rows.Close()
 
+	secrets := make(map[string]string)
 	for rows.Next() {
 		var key, value string
 		if err := rows.Scan(&key, &value); err != nil {
-			s.logger.Printf("Error scanning setting: %v", err)
+			s.logger.ErrorContext(ctx, "Error scanning setting", "error", err)
+			continue
+		}
+		if secretSettings[key] {
+			secrets[key] = value
 			continue
 		}
 		backup.Settings[key] = value
 	}
 
+	if includeSecrets {
+		tokens, err := s.backupAPITokens(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("getting API tokens: %w", err)
+		}
+		backup.Secrets, err = sealSecrets(backupCredentials{Settings: secrets, APITokens: tokens}, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("sealing secrets: %w", err)
+		}
+	}
+
 	// Get feeds
-	rows, err = s.db.QueryContext(r.Context(), "SELECT url, title FROM feeds")
+	rows, err = s.db.QueryContext(ctx, `
+        SELECT url, title, COALESCE(language, ''), COALESCE(region, ''),
+               COALESCE(locale_manual, 0), COALESCE(category, ''), COALESCE(tags, '')
+        FROM feeds`)
 	if err != nil {
-		s.logger.Printf("Error getting feeds for backup: %v", err)
-		http.Error(w, "Failed to export feeds", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("getting feeds: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var feed Feed
-		if err := rows.Scan(&feed.URL, &feed.Title); err != nil {
-			s.logger.Printf("Error scanning feed: %v", err)
+		if err := rows.Scan(&feed.URL, &feed.Title, &feed.Language, &feed.Region, &feed.LocaleManual, &feed.Category, &feed.Tags); err != nil {
+			s.logger.ErrorContext(ctx, "Error scanning feed", "error", err)
 			continue
 		}
 		backup.Feeds = append(backup.Feeds, feed)
 	}
 
-	// Set headers for file download
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Content-Disposition",
-		fmt.Sprintf("attachment; filename=infoscope_backup_%s.json",
-			time.Now().Format("2006-01-02")))
+	// Only tags with a configured priority are worth carrying over
+	priorities, err := s.getTagPriorities(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting tag priorities: %w", err)
+	}
+	for _, p := range priorities {
+		if p.Priority != 0 || p.IntervalMinutes != 0 {
+			p.Feeds = 0
+			backup.TagPriorities = append(backup.TagPriorities, p)
+		}
+	}
 
-	// Write JSON response
-	if err := json.NewEncoder(w).Encode(backup); err != nil {
-		s.logger.Printf("Error encoding backup: %v", err)
-		http.Error(w, "Failed to create backup", http.StatusInternalServerError)
-		return
+	rules, err := s.getTagRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting tag rules: %w", err)
+	}
+	for _, rule := range rules {
+		rule.ID = 0
+		backup.TagRules = append(backup.TagRules, rule)
 	}
+
+	return backup, nil
+}
+
+// backupAPITokens lists the API tokens still in use, for the credentials
+// section of a backup
+func (s *Server) backupAPITokens(ctx context.Context) ([]BackupAPIToken, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT name, prefix, token_hash, created_at
+        FROM api_tokens WHERE revoked_at IS NULL ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []BackupAPIToken
+	for rows.Next() {
+		var t BackupAPIToken
+		if err := rows.Scan(&t.Name, &t.Prefix, &t.TokenHash, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
 }
 
 func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
@@ -98,62 +196,160 @@ func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse backup data
+	// Parse backup data; limitBodies caps its size at maxBackupSize
 	var backup BackupData
 	if err := json.NewDecoder(r.Body).Decode(&backup); err != nil {
-		http.Error(w, "Invalid backup file", http.StatusBadRequest)
+		writeDecodeError(w, err, "Invalid backup file")
 		return
 	}
 
-	// Start transaction
-	tx, err := s.db.BeginTx(r.Context(), nil)
-	if err != nil {
-		s.logger.Printf("Error starting import transaction: %v", err)
-		http.Error(w, "Failed to start import", http.StatusInternalServerError)
-		return
+	var creds backupCredentials
+	if backup.Secrets != nil {
+		var err error
+		creds, err = backup.Secrets.open(r.Header.Get("X-Backup-Passphrase"))
+		if err != nil {
+			writeValidationError(w, err.Error(), map[string]string{"passphrase": err.Error()})
+			return
+		}
 	}
-	defer tx.Rollback()
 
-	// Update settings
-	stmt, err := tx.PrepareContext(r.Context(),
-		"INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)")
-	if err != nil {
-		s.logger.Printf("Error preparing settings statement: %v", err)
-		http.Error(w, "Failed to prepare settings import", http.StatusInternalServerError)
+	// A backup goes through the same checks as the settings form, since
+	// its values are trusted just as much once imported
+	fields := make(map[string]string)
+	secrets := creds.Settings
+	for _, section := range []map[string]string{backup.Settings, secrets} {
+		for key, message := range validateImportedSettings(section) {
+			fields["settings."+key] = message
+		}
+	}
+	for key, message := range validateImportedFeeds(backup.Feeds) {
+		fields[key] = message
+	}
+	for key, message := range validateImportedAPITokens(creds.APITokens) {
+		fields["secrets."+key] = message
+	}
+	for i := range backup.TagRules {
+		for key, message := range backup.TagRules[i].validate() {
+			fields[fmt.Sprintf("tagRules.%d.%s", i, key)] = message
+		}
+	}
+	if len(fields) > 0 {
+		writeValidationError(w, "Backup contains invalid values", fields)
 		return
 	}
-	defer stmt.Close()
-
-	for key, value := range backup.Settings {
-		if _, err := stmt.ExecContext(r.Context(), key, value); err != nil {
-			s.logger.Printf("Error importing setting %s: %v", key, err)
+	if code, ok := backup.Settings["tracking_code"]; ok {
+		var removed bool
+		backup.Settings["tracking_code"], removed = sanitizeTrackingCode(code)
+		if removed {
+			s.logger.InfoContext(r.Context(), "Removed disallowed markup from imported tracking code")
 		}
 	}
 
-	// Import feeds
-	for _, feed := range backup.Feeds {
-		if feed.URL == "" {
-			continue
-		}
-		_, err := tx.ExecContext(r.Context(),
-			"INSERT OR IGNORE INTO feeds (url, title) VALUES (?, ?)",
-			feed.URL, feed.Title)
+	// Individual rows that fail are logged and skipped; lock contention
+	// retries the whole import
+	err := database.WithTx(r.Context(), s.db, func(tx *sql.Tx) error {
+		// Update settings
+		stmt, err := tx.PrepareContext(r.Context(),
+			"INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)")
 		if err != nil {
-			s.logger.Printf("Error importing feed %s: %v", feed.URL, err)
+			return fmt.Errorf("error preparing settings statement: %w", err)
 		}
-	}
+		defer stmt.Close()
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		s.logger.Printf("Error committing import: %v", err)
-		http.Error(w, "Failed to complete import", http.StatusInternalServerError)
+		for key, value := range backup.Settings {
+			if _, err := stmt.ExecContext(r.Context(), key, value); err != nil {
+				if database.IsBusy(err) {
+					return err
+				}
+				s.logger.ErrorContext(r.Context(), "Error importing setting", "key", key, "error", err)
+			}
+		}
+		for key, value := range secrets {
+			if !secretSettings[key] {
+				continue
+			}
+			if _, err := stmt.ExecContext(r.Context(), key, value); err != nil {
+				if database.IsBusy(err) {
+					return err
+				}
+				s.logger.ErrorContext(r.Context(), "Error importing secret setting", "key", key, "error", err)
+			}
+		}
+
+		// Restore API tokens, keeping any already present
+		for _, t := range creds.APITokens {
+			if t.CreatedAt.IsZero() {
+				t.CreatedAt = time.Now().UTC()
+			}
+			_, err := tx.ExecContext(r.Context(), `
+                INSERT INTO api_tokens (name, prefix, token_hash, created_at) VALUES (?, ?, ?, ?)
+                ON CONFLICT(token_hash) DO NOTHING`,
+				t.Name, t.Prefix, t.TokenHash, t.CreatedAt)
+			if err != nil {
+				if database.IsBusy(err) {
+					return err
+				}
+				s.logger.ErrorContext(r.Context(), "Error importing API token", "prefix", t.Prefix, "error", err)
+			}
+		}
+
+		// Import feeds, skipping those already present under any form of
+		// their address
+		for _, feed := range backup.Feeds {
+			feedURL, err := feedurl.Normalize(feed.URL)
+			if err != nil {
+				continue
+			}
+			_, err = tx.ExecContext(r.Context(), `
+                INSERT OR IGNORE INTO feeds (url, url_key, title, language, region, locale_manual, category, tags)
+                VALUES (?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, NULLIF(?, ''), NULLIF(?, ''))`,
+				feedURL, feedurl.Key(feedURL), feed.Title, feed.Language, feed.Region, feed.LocaleManual,
+				feed.Category, feed.Tags)
+			if err != nil {
+				if database.IsBusy(err) {
+					return err
+				}
+				s.logger.ErrorContext(r.Context(), "Error importing feed", "feed_url", feed.URL, "error", err)
+			}
+		}
+
+		// Import tag priorities
+		for _, p := range backup.TagPriorities {
+			tags := normalizeTags(p.Tag)
+			if len(tags) != 1 {
+				continue
+			}
+			_, err := tx.ExecContext(r.Context(), `
+                INSERT OR REPLACE INTO tag_priorities (tag, priority, interval_minutes)
+                VALUES (?, ?, ?)`,
+				tags[0], p.Priority, max(p.IntervalMinutes, 0))
+			if err != nil {
+				return err
+			}
+		}
+
+		// Import tag rules
+		for _, rule := range backup.TagRules {
+			_, err := tx.ExecContext(r.Context(), `
+                INSERT INTO tag_rules (field, pattern, tag) VALUES (?, ?, ?)
+                ON CONFLICT(field, pattern, tag) DO NOTHING`,
+				rule.Field, rule.Pattern, rule.Tag)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error importing backup", "error", err)
+		writeDBError(w, err)
 		return
 	}
 
 	// Trigger feed fetch for new feeds
 	go func() {
 		if err := s.feedService.UpdateFeeds(context.Background()); err != nil {
-			s.logger.Printf("Error updating feeds after import: %v", err)
+			s.logger.ErrorContext(r.Context(), "Error updating feeds after import", "error", err)
 		}
 	}()
 
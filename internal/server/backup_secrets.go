@@ -0,0 +1,135 @@
+// internal/server/backup_secrets.go
+package server
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// secretSettings are integration credentials that are kept out of the
+// regular settings section of a backup. They are only exported when the
+// admin explicitly opts in, optionally encrypted with a passphrase.
+var secretSettings = map[string]bool{
+	"stats_api_token":     true,
+	"translation_api_key": true,
+}
+
+// BackupSecrets holds the opt-in credentials section of a backup. When
+// Encrypted is set, Settings and APITokens are empty and Data carries the
+// AES-GCM sealed JSON of both.
+type BackupSecrets struct {
+	Encrypted bool              `json:"encrypted"`
+	Salt      []byte            `json:"salt,omitempty"`
+	Nonce     []byte            `json:"nonce,omitempty"`
+	Data      []byte            `json:"data,omitempty"`
+	Settings  map[string]string `json:"settings,omitempty"`
+	APITokens []BackupAPIToken  `json:"apiTokens,omitempty"`
+}
+
+// BackupAPIToken is an API token in the credentials section. Only its hash
+// is carried, which is all the server keeps, so scripts holding the token
+// keep working against the restored instance.
+type BackupAPIToken struct {
+	Name      string    `json:"name"`
+	Prefix    string    `json:"prefix"`
+	TokenHash string    `json:"tokenHash"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// backupCredentials is the content of the credentials section, sealed as a
+// whole when a passphrase is given
+type backupCredentials struct {
+	Settings  map[string]string `json:"settings,omitempty"`
+	APITokens []BackupAPIToken  `json:"apiTokens,omitempty"`
+}
+
+var errPassphraseRequired = errors.New("backup secrets are encrypted; a passphrase is required")
+
+// deriveBackupKey stretches a passphrase into an AES-256 key.
+func deriveBackupKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+// sealSecrets packages credentials for export, encrypting them when a
+// passphrase is given.
+func sealSecrets(creds backupCredentials, passphrase string) (*BackupSecrets, error) {
+	if passphrase == "" {
+		return &BackupSecrets{Settings: creds.Settings, APITokens: creds.APITokens}, nil
+	}
+
+	plain, err := json.Marshal(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := deriveBackupKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &BackupSecrets{
+		Encrypted: true,
+		Salt:      salt,
+		Nonce:     nonce,
+		Data:      gcm.Seal(nil, nonce, plain, nil),
+	}, nil
+}
+
+// open returns the credentials, decrypting them with passphrase if needed.
+func (b *BackupSecrets) open(passphrase string) (backupCredentials, error) {
+	if !b.Encrypted {
+		return backupCredentials{Settings: b.Settings, APITokens: b.APITokens}, nil
+	}
+	if passphrase == "" {
+		return backupCredentials{}, errPassphraseRequired
+	}
+
+	key, err := deriveBackupKey(passphrase, b.Salt)
+	if err != nil {
+		return backupCredentials{}, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return backupCredentials{}, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return backupCredentials{}, err
+	}
+	if len(b.Nonce) != gcm.NonceSize() {
+		return backupCredentials{}, fmt.Errorf("invalid nonce in backup secrets")
+	}
+	plain, err := gcm.Open(nil, b.Nonce, b.Data, nil)
+	if err != nil {
+		return backupCredentials{}, fmt.Errorf("wrong passphrase or corrupted backup secrets")
+	}
+
+	var creds backupCredentials
+	if err := json.Unmarshal(plain, &creds); err != nil {
+		return backupCredentials{}, err
+	}
+	return creds, nil
+}
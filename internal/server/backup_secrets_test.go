@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSealSecretsCarriesAPITokens(t *testing.T) {
+	creds := backupCredentials{
+		Settings: map[string]string{"stats_api_token": "secret"},
+		APITokens: []BackupAPIToken{{
+			Name:      "deploy",
+			Prefix:    "isc_abcdef",
+			TokenHash: hashAPIToken("isc_abcdef123"),
+			CreatedAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	for _, passphrase := range []string{"", "correct horse"} {
+		sealed, err := sealSecrets(creds, passphrase)
+		if err != nil {
+			t.Fatalf("sealSecrets failed: %v", err)
+		}
+		// Round trip through the backup file
+		data, err := json.Marshal(sealed)
+		if err != nil {
+			t.Fatalf("Failed to encode secrets: %v", err)
+		}
+		var decoded BackupSecrets
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to decode secrets: %v", err)
+		}
+
+		got, err := decoded.open(passphrase)
+		if err != nil {
+			t.Fatalf("open failed: %v", err)
+		}
+		if got.Settings["stats_api_token"] != "secret" {
+			t.Errorf("Passphrase %q: settings %v, want the stats token", passphrase, got.Settings)
+		}
+		if len(got.APITokens) != 1 || got.APITokens[0] != creds.APITokens[0] {
+			t.Errorf("Passphrase %q: API tokens %+v, want %+v", passphrase, got.APITokens, creds.APITokens)
+		}
+		if errs := validateImportedAPITokens(got.APITokens); len(errs) > 0 {
+			t.Errorf("Exported tokens fail import validation: %v", errs)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// internal/server/backup_validation.go
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"infoscope/internal/database"
+	"infoscope/internal/feed"
+	"infoscope/internal/schedule"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// settingRule checks one imported setting value
+type settingRule func(value string) error
+
+// settingRules are the limits on setting values, enforced alike when the
+// settings form is saved and when a backup is imported. Settings without a
+// rule are plain text, escaped wherever they are shown.
+var settingRules = map[string]settingRule{
+	"max_posts":                 intSetting(1, 0),
+	"retention_days":            intSetting(0, database.MaxRetentionDays),
+	"update_interval":           intSetting(60, 0),
+	"host_concurrency":          intSetting(1, 0),
+	"host_delay_ms":             intSetting(0, 0),
+	"daily_bandwidth_mb":        intSetting(0, 0),
+	"alert_cycle_minutes":       intSetting(0, 0),
+	"dead_feed_errors":          intSetting(0, 0),
+	"click_half_life_days":      intSetting(0, 0),
+	"update_check_hours":        intSetting(0, 0),
+	"roundup_size":              intSetting(1, 50),
+	"cache_index_ttl":           intSetting(0, 0),
+	"cache_static_ttl":          intSetting(0, 0),
+	"cache_api_max_age":         intSetting(0, 0),
+	"adaptive_poll_min_minutes": intSetting(1, 10080),
+	"adaptive_poll_max_minutes": intSetting(1, 10080),
+	"honor_dnt":                 boolSetting,
+	"compact_mode":              boolSetting,
+	"semantic_markup":           boolSetting,
+	"clean_titles":              boolSetting,
+	"visitor_muting":            boolSetting,
+	"weekly_roundup":            boolSetting,
+	"share_links":               boolSetting,
+	"wayback_archive":           boolSetting,
+	"archive_mode":              boolSetting,
+	"public_api":                boolSetting,
+	"local_dates":               boolSetting,
+	"adaptive_polling":          boolSetting,
+	"river_mode":                oneOf(RiverChronological, RiverShuffle),
+	"river_layout":              oneOf(RiverStream, RiverGrouped),
+	"dedup_key":                 oneOf(feed.DedupByURL, feed.DedupByGUID),
+	"cross_feed_dedup":          oneOf(feed.CrossFeedDedupOff, feed.CrossFeedDedupCollapse, feed.CrossFeedDedupFirst),
+	"entry_update_mode":         oneOf(feed.UpdateIfNewer, feed.UpdateAlways, feed.UpdateNever),
+	"translation_backend":       oneOf("", TranslateLibre, TranslateDeepL),
+	"header_link_url":           linkSetting,
+	"footer_link_url":           linkSetting,
+	"site_url":                  absoluteURLSetting,
+	"translation_url":           absoluteURLSetting,
+	"favicon_url":               uploadSetting,
+	"meta_image_url":            uploadSetting,
+	"footer_image_url":          uploadSetting,
+	"footer_image_height":       cssLengthSetting,
+	"mastodon_instance":         hostSetting,
+	"timezone":                  timezoneSetting,
+	"backup_schedule":           scheduleSetting,
+}
+
+// formFieldKeys maps the settings form's field names, as reported by its
+// cross-field validators, to setting keys
+var formFieldKeys = map[string]string{
+	"adaptivePollMinMinutes": "adaptive_poll_min_minutes",
+	"adaptivePollMaxMinutes": "adaptive_poll_max_minutes",
+	"translationBackend":     "translation_backend",
+	"translationLanguage":    "translation_language",
+	"translationURL":         "translation_url",
+}
+
+var cssLengthPattern = regexp.MustCompile(`^\d+(\.\d+)?(px|em|rem|vh|%)?$`)
+
+func intSetting(min, max int) settingRule {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be a whole number")
+		}
+		if n < min || (max > 0 && n > max) {
+			if max > 0 {
+				return fmt.Errorf("must be between %d and %d", min, max)
+			}
+			return fmt.Errorf("must be at least %d", min)
+		}
+		return nil
+	}
+}
+
+func boolSetting(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("must be true or false")
+	}
+	return nil
+}
+
+func oneOf(values ...string) settingRule {
+	return func(value string) error {
+		for _, v := range values {
+			if value == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+	}
+}
+
+// linkSetting accepts a site-relative path or an http(s) URL
+func linkSetting(value string) error {
+	if strings.HasPrefix(value, "/") && !strings.HasPrefix(value, "//") {
+		return nil
+	}
+	return absoluteURLSetting(value)
+}
+
+// absoluteURLSetting accepts an http(s) URL, or nothing
+func absoluteURLSetting(value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("must be an http or https URL")
+	}
+	return nil
+}
+
+// uploadSetting accepts the bare name of an uploaded file; anything that
+// could reach outside the upload directory is refused
+func uploadSetting(value string) error {
+	if value == "" {
+		return nil
+	}
+	if strings.ContainsAny(value, `/\`) || strings.Contains(value, "..") ||
+		filepath.Base(value) != value || strings.HasPrefix(value, ".") {
+		return fmt.Errorf("must be a file name, not a path")
+	}
+	return nil
+}
+
+func cssLengthSetting(value string) error {
+	if value != "" && !cssLengthPattern.MatchString(strings.TrimSpace(value)) {
+		return fmt.Errorf("must be a CSS length such as 50px")
+	}
+	return nil
+}
+
+// hostSetting accepts a bare host name, optionally with a port
+func hostSetting(value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse("https://" + value)
+	if err != nil || u.Host != value || u.Hostname() == "" {
+		return fmt.Errorf("must be a host name such as mastodon.social")
+	}
+	return nil
+}
+
+func timezoneSetting(value string) error {
+	if _, err := time.LoadLocation(value); err != nil {
+		return fmt.Errorf("unknown time zone")
+	}
+	return nil
+}
+
+func scheduleSetting(value string) error {
+	_, err := schedule.Parse(value, nil)
+	return err
+}
+
+// validateSettingValues checks setting values against settingRules and
+// returns the invalid ones by setting key
+func validateSettingValues(settings map[string]string) map[string]string {
+	fields := make(map[string]string)
+	for key, value := range settings {
+		if rule, ok := settingRules[key]; ok {
+			if err := rule(value); err != nil {
+				fields[key] = err.Error()
+			}
+		}
+	}
+	return fields
+}
+
+// validateImportedSettings checks imported settings against the settings
+// form's rules and returns the invalid ones by setting key.
+func validateImportedSettings(settings map[string]string) map[string]string {
+	fields := validateSettingValues(settings)
+	if len(fields) > 0 {
+		return fields
+	}
+
+	// The form's cross-field checks, on the values the import would leave
+	form := Settings{
+		TranslationBackend:  settings["translation_backend"],
+		TranslationURL:      settings["translation_url"],
+		TranslationLanguage: settings["translation_language"],
+	}
+	form.AdaptivePollMinMinutes, _ = strconv.Atoi(settings["adaptive_poll_min_minutes"])
+	form.AdaptivePollMaxMinutes, _ = strconv.Atoi(settings["adaptive_poll_max_minutes"])
+
+	var formFields []map[string]string
+	if form.AdaptivePollMinMinutes > 0 && form.AdaptivePollMaxMinutes > 0 {
+		formFields = append(formFields, validateAdaptivePolling(form))
+	}
+	if form.TranslationBackend != "" {
+		formFields = append(formFields, validateTranslationSettings(&form))
+	}
+	for _, ff := range formFields {
+		for name, message := range ff {
+			fields[formFieldKeys[name]] = message
+		}
+	}
+	return fields
+}
+
+// validateImportedFeeds returns the feeds that can't be fetched, keyed by
+// their position in the backup
+func validateImportedFeeds(feeds []Feed) map[string]string {
+	fields := make(map[string]string)
+	for i, f := range feeds {
+		if f.URL == "" {
+			continue
+		}
+		if err := absoluteURLSetting(f.URL); err != nil {
+			fields[fmt.Sprintf("feeds.%d.url", i)] = err.Error()
+		}
+	}
+	return fields
+}
+
+// apiTokenHashPattern is the form hashAPIToken stores tokens in
+var apiTokenHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// validateImportedAPITokens checks the API tokens of a backup's credentials
+// section against what issuing a token produces
+func validateImportedAPITokens(tokens []BackupAPIToken) map[string]string {
+	fields := make(map[string]string)
+	for i, t := range tokens {
+		name := strings.TrimSpace(t.Name)
+		if name == "" || len(name) > maxAPITokenName {
+			fields[fmt.Sprintf("apiTokens.%d.name", i)] = "required, up to 100 characters"
+		}
+		if !strings.HasPrefix(t.Prefix, apiTokenPrefix) || len(t.Prefix) > len(apiTokenPrefix)+6 {
+			fields[fmt.Sprintf("apiTokens.%d.prefix", i)] = "must be a token prefix"
+		}
+		if !apiTokenHashPattern.MatchString(t.TokenHash) {
+			fields[fmt.Sprintf("apiTokens.%d.tokenHash", i)] = "must be a SHA-256 hash in hex"
+		}
+	}
+	return fields
+}
+
+// trackingAttrs are the script attributes analytics snippets need
+var trackingAttrs = map[string]bool{
+	"src": true, "async": true, "defer": true, "type": true, "id": true,
+	"crossorigin": true, "integrity": true, "nonce": true, "referrerpolicy": true,
+}
+
+// sanitizeTrackingCode reduces imported tracking code to what analytics
+// snippets consist of: script elements, with their sources limited to
+// http(s), and noscript fallbacks. Everything else is dropped, and removed
+// reports whether anything was.
+func sanitizeTrackingCode(code string) (clean string, removed bool) {
+	if strings.TrimSpace(code) == "" {
+		return code, false
+	}
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(code), body)
+	if err != nil {
+		return "", true
+	}
+
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		switch {
+		case n.Type == html.TextNode && strings.TrimSpace(n.Data) == "":
+		case n.Type == html.ElementNode && n.DataAtom == atom.Script:
+			attrs := n.Attr[:0]
+			for _, a := range n.Attr {
+				name := strings.ToLower(a.Key)
+				if !trackingAttrs[name] && !strings.HasPrefix(name, "data-") {
+					removed = true
+					continue
+				}
+				if name == "src" && absoluteURLSetting(a.Val) != nil {
+					removed = true
+					continue
+				}
+				attrs = append(attrs, a)
+			}
+			n.Attr = attrs
+		case n.Type == html.ElementNode && n.DataAtom == atom.Noscript:
+		default:
+			removed = true
+			continue
+		}
+		if err := html.Render(&buf, n); err != nil {
+			return "", true
+		}
+	}
+	return buf.String(), removed
+}
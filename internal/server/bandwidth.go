@@ -0,0 +1,85 @@
+// internal/server/bandwidth.go
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// FeedBandwidth is one feed's download volume for a day
+type FeedBandwidth struct {
+	FeedID   int64  `json:"feedId"`
+	Title    string `json:"title"`
+	Bytes    int64  `json:"bytes"`
+	Requests int    `json:"requests"`
+}
+
+// BandwidthStats summarizes outbound fetch traffic for the dashboard
+type BandwidthStats struct {
+	TodayBytes  int64           `json:"todayBytes"`
+	WeekBytes   int64           `json:"weekBytes"`
+	BudgetBytes int64           `json:"budgetBytes"`
+	TopToday    []FeedBandwidth `json:"topToday"`
+}
+
+// OverBudget reports whether today's downloads have used up the daily budget
+func (b *BandwidthStats) OverBudget() bool {
+	return b.BudgetBytes > 0 && b.TodayBytes >= b.BudgetBytes
+}
+
+func (s *Server) getBandwidthStats(ctx context.Context) (*BandwidthStats, error) {
+	stats := &BandwidthStats{}
+
+	err := s.db.QueryRowContext(ctx, `
+        SELECT
+            COALESCE(SUM(CASE WHEN day = date('now') THEN bytes END), 0),
+            COALESCE(SUM(bytes), 0)
+        FROM feed_bandwidth
+        WHERE day > date('now', '-7 days')`,
+	).Scan(&stats.TodayBytes, &stats.WeekBytes)
+	if err != nil {
+		return nil, fmt.Errorf("error getting bandwidth totals: %w", err)
+	}
+
+	if mb, err := strconv.ParseInt(s.getSetting(ctx, "daily_bandwidth_mb"), 10, 64); err == nil && mb > 0 {
+		stats.BudgetBytes = mb * 1024 * 1024
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT b.feed_id, COALESCE(f.title, f.url), b.bytes, b.requests
+        FROM feed_bandwidth b
+        JOIN feeds f ON f.id = b.feed_id
+        WHERE b.day = date('now')
+        ORDER BY b.bytes DESC
+        LIMIT 5`)
+	if err != nil {
+		return nil, fmt.Errorf("error getting bandwidth by feed: %w", err)
+	}
+	defer rows.Close()
+
+	stats.TopToday = make([]FeedBandwidth, 0)
+	for rows.Next() {
+		var fb FeedBandwidth
+		if err := rows.Scan(&fb.FeedID, &fb.Title, &fb.Bytes, &fb.Requests); err != nil {
+			return nil, fmt.Errorf("error scanning bandwidth: %w", err)
+		}
+		stats.TopToday = append(stats.TopToday, fb)
+	}
+
+	return stats, rows.Err()
+}
+
+// formatBytes renders a byte count in binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
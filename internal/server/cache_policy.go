@@ -0,0 +1,154 @@
+// internal/server/cache_policy.go
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// cacheRule describes how responses for a group of routes may be cached.
+// TTLs come from the named setting so they can be tuned from the admin
+// panel; a TTL of 0 means the response must not be stored.
+type cacheRule struct {
+	prefix   string // path prefix; a rule without a trailing slash matches exactly
+	setting  string // setting holding the TTL in seconds, empty for never
+	private  bool   // only the visitor's browser may cache it
+	personal bool   // the river, which the visitor's cookies can change
+}
+
+// cacheRules is the single place where cache policy is decided. The first
+// matching rule wins; paths matching none are not stored.
+var cacheRules = []cacheRule{
+	{prefix: "/admin/api/stats", setting: "cache_api_max_age", private: true},
+	{prefix: "/admin/"},
+	{prefix: "/admin"},
+	{prefix: "/setup/"},
+	{prefix: "/setup"},
+	{prefix: "/click/"},
+	{prefix: "/click"},
+	{prefix: "/mute"},
+	{prefix: "/csrf"},
+	{prefix: "/catchup"},
+	{prefix: "/api/public/", setting: "cache_index_ttl"},
+	{prefix: "/api/"},
+	{prefix: "/static/", setting: "cache_static_ttl"},
+	{prefix: "/media/", setting: "cache_static_ttl"},
+	{prefix: "/roundup/", setting: "cache_index_ttl"},
+	{prefix: "/archive/", setting: "cache_index_ttl"},
+	{prefix: "/archive", setting: "cache_index_ttl"},
+	{prefix: "/starred/", setting: "cache_index_ttl"},
+	{prefix: "/starred", setting: "cache_index_ttl"},
+	{prefix: "/feeds/", setting: "cache_index_ttl"},
+	{prefix: "/category/", setting: "cache_index_ttl", personal: true},
+	{prefix: "/tag/", setting: "cache_index_ttl", personal: true},
+	{prefix: "/atom.xml", setting: "cache_index_ttl"},
+	{prefix: "/translate"},
+	{prefix: "/", setting: "cache_index_ttl", personal: true},
+}
+
+func matchCacheRule(path string) (cacheRule, bool) {
+	for _, rule := range cacheRules {
+		if strings.HasSuffix(rule.prefix, "/") && rule.prefix != "/" {
+			if strings.HasPrefix(path, rule.prefix) {
+				return rule, true
+			}
+		} else if path == rule.prefix {
+			return rule, true
+		}
+	}
+	return cacheRule{}, false
+}
+
+// cacheControl works out the Cache-Control value for a request.
+func (s *Server) cacheControl(r *http.Request) string {
+	rule, ok := matchCacheRule(r.URL.Path)
+	if !ok || rule.setting == "" || !isSafeMethod(r.Method) {
+		return "no-store"
+	}
+
+	ttl, err := strconv.Atoi(s.getSetting(r.Context(), rule.setting))
+	if err != nil || ttl <= 0 {
+		return "no-store"
+	}
+
+	// Visitors with muted terms or collapsed sections get their own version
+	// of the river
+	if rule.private || hasCookie(r, mutedTermsCookie) || hasCookie(r, collapsedSectionsCookie) {
+		return fmt.Sprintf("private, max-age=%d", ttl)
+	}
+	return fmt.Sprintf("public, max-age=%d", ttl)
+}
+
+func hasCookie(r *http.Request, name string) bool {
+	c, err := r.Cookie(name)
+	return err == nil && c.Value != ""
+}
+
+// cacheHeaders applies the route's cache policy to successful responses.
+// Handlers that set their own Cache-Control keep it, and errors or
+// responses setting cookies are never marked cacheable.
+func (s *Server) cacheHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule, _ := matchCacheRule(r.URL.Path)
+		cw := &cacheWriter{
+			ResponseWriter: w,
+			policy:         func() string { return s.cacheControl(r) },
+			varyCookie:     rule.personal,
+		}
+		next.ServeHTTP(cw, r)
+	})
+}
+
+type cacheWriter struct {
+	http.ResponseWriter
+	policy      func() string
+	varyCookie  bool
+	wroteHeader bool
+}
+
+func (cw *cacheWriter) WriteHeader(code int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		h := cw.Header()
+		if h.Get("Cache-Control") == "" {
+			switch {
+			case h.Get("Set-Cookie") != "":
+				h.Set("Cache-Control", "private, no-store")
+			case code == http.StatusOK || code == http.StatusNotModified:
+				h.Set("Cache-Control", cw.policy())
+				// A shared cache must not hand one visitor's muted or
+				// collapsed river to another
+				if cw.varyCookie {
+					h.Add("Vary", "Cookie")
+				}
+			default:
+				h.Set("Cache-Control", "no-store")
+			}
+		}
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cacheWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *cacheWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// handleCSRFToken hands out a CSRF token to pages served from a shared
+// cache, which can't carry a per-visitor token themselves.
+func (s *Server) handleCSRFToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]string{"token": s.csrf.Token(w, r)})
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRiverCacheVariesOnCookies(t *testing.T) {
+	h := newTestServer(t, nil)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if cc := rec.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "public") {
+		t.Fatalf("GET / Cache-Control = %q, want public", cc)
+	}
+	if vary := rec.Header().Values("Vary"); !containsValue(vary, "Cookie") {
+		t.Errorf("GET / Vary = %q, want Cookie", vary)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/translate?id=1", nil))
+	if cc := rec.Header().Get("Cache-Control"); strings.Contains(cc, "max-age") {
+		t.Errorf("GET /translate Cache-Control = %q, want it left uncached", cc)
+	}
+}
+
+func containsValue(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
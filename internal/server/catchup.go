@@ -0,0 +1,195 @@
+// internal/server/catchup.go
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// catchupCookie remembers when the visitor last opened /catchup, as
+	// Unix seconds
+	catchupCookie = "catchup_visit"
+	// catchupDefault is how far back a first visit looks
+	catchupDefault = 24 * time.Hour
+	// catchupMaxAge bounds how far back a digest reaches, keeping it quick
+	catchupMaxAge = 30 * 24 * time.Hour
+	// catchupTopItems is how many entries each feed shows
+	catchupTopItems = 5
+)
+
+// CatchupFeed is one feed's part of the catch-up digest: how many entries
+// it published since the cutoff and the most clicked of them
+type CatchupFeed struct {
+	ID      int64
+	Title   string
+	Count   int
+	Entries []EntryView
+	More    int
+}
+
+// CatchupPageData is the template data for the catch-up page
+type CatchupPageData struct {
+	SiteTitle string
+	Settings  map[string]string
+	Since     time.Time
+	LastVisit bool
+	Total     int
+	Feeds     []CatchupFeed
+}
+
+// handleCatchup serves /catchup, a digest of the entries published since
+// ?since= grouped by feed, busiest feeds first, with each feed's most
+// clicked entries. since takes an RFC 3339 time, a date in the site
+// timezone or a span back from now such as 12h or 3d. Without it the
+// digest starts at the visitor's previous visit, remembered in a cookie,
+// or a day ago.
+func (s *Server) handleCatchup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	now := time.Now().UTC()
+	loc := s.siteLocation(r.Context())
+	data := CatchupPageData{Since: now.Add(-catchupDefault)}
+	if raw := strings.TrimSpace(r.URL.Query().Get("since")); raw != "" {
+		since, err := parseSince(raw, now, loc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data.Since = since
+	} else if cookie, err := r.Cookie(catchupCookie); err == nil {
+		if unix, err := strconv.ParseInt(cookie.Value, 10, 64); err == nil && unix < now.Unix() {
+			data.Since = time.Unix(unix, 0).UTC()
+			data.LastVisit = true
+		}
+	}
+	if oldest := now.Add(-catchupMaxAge); data.Since.Before(oldest) {
+		data.Since = oldest
+	}
+
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+		settings = make(map[string]string)
+	}
+	data.SiteTitle = settings["site_title"]
+	data.Settings = settings
+
+	if data.Feeds, err = s.getCatchup(r.Context(), data.Since); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting catch-up digest", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	for _, f := range data.Feeds {
+		data.Total += f.Count
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     catchupCookie,
+		Value:    strconv.FormatInt(now.Unix(), 10),
+		Path:     "/catchup",
+		HttpOnly: true,
+		Secure:   s.csrf.config.Secure,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  now.AddDate(1, 0, 0),
+	})
+	if err := s.renderTemplate(w, r, "catchup.html", data); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error rendering catch-up template", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// parseSince reads the since parameter of /catchup
+func parseSince(raw string, now time.Time, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", raw, loc); err == nil {
+		return t.UTC(), nil
+	}
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q: use a time such as 2024-05-01T08:00:00Z, a date or a span such as 12h or 3d", raw)
+}
+
+// getCatchup counts the river's entries published since the cutoff by
+// feed, busiest first, and picks each feed's most clicked entries, newest
+// first among equals.
+func (s *Server) getCatchup(ctx context.Context, since time.Time) ([]CatchupFeed, error) {
+	cond, args := archiveVisible()
+	bounds := append([]any{since.UTC().Format("2006-01-02 15:04:05")}, args...)
+
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT f.id, COALESCE(NULLIF(f.title, ''), f.url), COUNT(*)
+        FROM entries e
+        JOIN feeds f ON e.feed_id = f.id
+        WHERE e.published_at >= ? AND `+cond+`
+        GROUP BY f.id
+        ORDER BY COUNT(*) DESC, 2`, bounds...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	var feeds []CatchupFeed
+	index := make(map[int64]int)
+	for rows.Next() {
+		var f CatchupFeed
+		if err := rows.Scan(&f.ID, &f.Title, &f.Count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[f.ID] = len(feeds)
+		feeds = append(feeds, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(feeds) == 0 {
+		return feeds, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+        SELECT id, feed_id, title, url, favicon_url, category, language, published
+        FROM (
+            SELECT e.id, e.feed_id, e.title, e.url, e.favicon_url,
+                   COALESCE(f.category, '') AS category, COALESCE(f.language, '') AS language,
+                   datetime(e.published_at) AS published,
+                   ROW_NUMBER() OVER (
+                       PARTITION BY e.feed_id
+                       ORDER BY COALESCE(c.click_count, 0) DESC, e.published_at DESC, e.id DESC
+                   ) AS rank
+            FROM entries e
+            JOIN feeds f ON e.feed_id = f.id
+            LEFT JOIN clicks c ON c.entry_id = e.id
+            WHERE e.published_at >= ? AND `+cond+`
+        )
+        WHERE rank <= ?
+        ORDER BY feed_id, rank`, append(bounds, catchupTopItems)...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+	entries, err := scanEntryViews(rows, s.siteLocation(ctx))
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if i, ok := index[e.FeedID]; ok {
+			feeds[i].Entries = append(feeds[i].Entries, e)
+		}
+	}
+	for i := range feeds {
+		feeds[i].More = feeds[i].Count - len(feeds[i].Entries)
+	}
+	return feeds, nil
+}
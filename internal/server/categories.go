@@ -0,0 +1,173 @@
+// internal/server/categories.go
+package server
+
+import (
+	"context"
+	"infoscope/internal/feed"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// maxFeedTags caps how many tags a feed carries
+const maxFeedTags = 10
+
+// CategorySuggestion is the category and tags proposed for a new feed
+type CategorySuggestion struct {
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+	Reason   string   `json:"reason,omitempty"`
+}
+
+// normalizeTags splits a comma separated tag list, lowercasing and
+// de-duplicating it.
+func normalizeTags(raw string) []string {
+	seen := make(map[string]bool)
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(raw, ",") {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+		if len(tags) == maxFeedTags {
+			break
+		}
+	}
+	return tags
+}
+
+// getCategories lists the categories already in use, most used first.
+func (s *Server) getCategories(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT category FROM feeds
+        WHERE COALESCE(category, '') != ''
+        GROUP BY category
+        ORDER BY COUNT(*) DESC, category`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]string, 0)
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// suggestCategory proposes a category and tags for a feed about to be
+// added. In order of preference it reuses the category of other feeds from
+// the same site, matches existing categories and tags against the feed's
+// categories and title, and finally falls back to the feed's own categories.
+func (s *Server) suggestCategory(ctx context.Context, feedURL string, result *feed.FeedValidationResult) CategorySuggestion {
+	var suggestion CategorySuggestion
+
+	// Words describing the feed: its categories plus title and description
+	var terms []string
+	for _, c := range result.Categories {
+		terms = append(terms, strings.ToLower(c))
+	}
+	text := " " + strings.ToLower(result.Title+" "+result.Description) + " "
+
+	mentions := func(term string) bool {
+		term = strings.ToLower(term)
+		for _, t := range terms {
+			if t == term {
+				return true
+			}
+		}
+		return strings.Contains(text, " "+term+" ")
+	}
+
+	// Existing feeds and their classification
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT url, COALESCE(category, ''), COALESCE(tags, '') FROM feeds
+        WHERE COALESCE(category, '') != '' OR COALESCE(tags, '') != ''`)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error loading feed categories", "error", err)
+		return fallbackSuggestion(result)
+	}
+	defer rows.Close()
+
+	host := hostOf(feedURL)
+	categoryScore := make(map[string]int)
+	sameHost := make(map[string]bool)
+	knownTags := make(map[string]bool)
+	for rows.Next() {
+		var u, category, tags string
+		if err := rows.Scan(&u, &category, &tags); err != nil {
+			continue
+		}
+		for _, tag := range normalizeTags(tags) {
+			knownTags[tag] = true
+		}
+		if category == "" {
+			continue
+		}
+		if host != "" && hostOf(u) == host {
+			categoryScore[category] += 10
+			sameHost[category] = true
+		}
+		if mentions(category) {
+			categoryScore[category] += 3
+		}
+		for _, tag := range normalizeTags(tags) {
+			if mentions(tag) {
+				categoryScore[category]++
+			}
+		}
+	}
+
+	// Tags already in use that fit this feed come first
+	var tags []string
+	for tag := range knownTags {
+		if mentions(tag) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+
+	best, bestScore := "", 0
+	for category, score := range categoryScore {
+		if score > bestScore || (score == bestScore && category < best) {
+			best, bestScore = category, score
+		}
+	}
+	if best == "" {
+		fallback := fallbackSuggestion(result)
+		fallback.Tags = normalizeTags(strings.Join(append(tags, fallback.Tags...), ","))
+		return fallback
+	}
+
+	suggestion.Category = best
+	suggestion.Reason = "matches existing feeds"
+	if sameHost[best] {
+		suggestion.Reason = "another feed from " + host
+	}
+	suggestion.Tags = normalizeTags(strings.Join(append(tags, result.Categories...), ","))
+	return suggestion
+}
+
+// fallbackSuggestion uses the feed's own categories when nothing matches.
+func fallbackSuggestion(result *feed.FeedValidationResult) CategorySuggestion {
+	suggestion := CategorySuggestion{Tags: normalizeTags(strings.Join(result.Categories, ","))}
+	if len(result.Categories) > 0 {
+		suggestion.Category = result.Categories[0]
+		suggestion.Reason = "the feed's own categories"
+	}
+	return suggestion
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
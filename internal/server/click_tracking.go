@@ -2,29 +2,53 @@
 package server
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"infoscope/internal/database"
+	"infoscope/internal/hooks"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 )
 
 type ClickStats struct {
-	EntryID     int64  `json:"entryId"`
-	Title       string `json:"title"`
-	URL         string
-	ClickCount  int       `json:"clickCount"`
-	LastClicked time.Time `json:"lastClicked"`
+	EntryID       int64  `json:"entryId"`
+	Title         string `json:"title"`
+	URL           string
+	ClickCount    int       `json:"clickCount"`
+	LastClicked   time.Time `json:"lastClicked"`
+	ArchiveStatus string    `json:"archiveStatus,omitempty"`
+	ArchiveURL    string    `json:"archiveUrl,omitempty"`
 }
 
 type DashboardStats struct {
-	TotalClicks int64        `json:"totalClicks"`
-	TopAllTime  []ClickStats `json:"topAllTime"`
+	TotalClicks int64 `json:"totalClicks"`
+	// Top ranks entries over the last Window days, or all time for 0
+	Window      int          `json:"window"`
+	Top         []ClickStats `json:"top"`
 	TopPastWeek []ClickStats `json:"topPastWeek"`
+	// HalfLifeDays is the click_half_life_days setting the ranking used
+	HalfLifeDays int `json:"halfLifeDays"`
+}
+
+// clickWindows are the ranking windows offered on the dashboard, in days;
+// 0 is all time
+var clickWindows = []int{7, 30, 365, 0}
+
+// parseClickWindow reads a ranking window, falling back to all time
+func parseClickWindow(raw string) int {
+	days, err := strconv.Atoi(raw)
+	if err != nil || !slices.Contains(clickWindows, days) {
+		return 0
+	}
+	return days
 }
 
 func (s *Server) handleClick(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
 		return
 	}
 
@@ -34,132 +58,150 @@ func (s *Server) handleClick(w http.ResponseWriter, r *http.Request) {
 
 	entryID := r.URL.Query().Get("id")
 	if entryID == "" {
-		http.Error(w, "Missing entry ID", http.StatusBadRequest)
+		writeValidationError(w, "Missing entry ID", map[string]string{"id": "required"})
 		return
 	}
 
 	id, err := strconv.ParseInt(entryID, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid entry ID", http.StatusBadRequest)
+		writeValidationError(w, "Invalid entry ID", map[string]string{"id": "must be a number"})
 		return
 	}
 
-	tx, err := s.db.Begin()
-	if err != nil {
-		s.logger.Printf("Error beginning transaction: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	// Respect Do-Not-Track / Global Privacy Control when enabled
+	if optedOut(r) && s.getSetting(r.Context(), "honor_dnt") == "true" {
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	defer tx.Rollback()
 
-	// First update entry-specific clicks
-	_, err = tx.Exec(`
-        INSERT INTO clicks (entry_id, click_count, last_clicked)
-        VALUES (?, 1, CURRENT_TIMESTAMP)
-        ON CONFLICT(entry_id) DO UPDATE SET
-            click_count = click_count + 1,
-            last_clicked = CURRENT_TIMESTAMP
-    `, id)
-	if err != nil {
-		s.logger.Printf("Error updating entry clicks: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+	archive := s.getSetting(r.Context(), "wayback_archive") == "true"
 
-	// Then update total clicks counter
-	_, err = tx.Exec(`
-        INSERT INTO click_stats (key, value)
-        VALUES ('total_clicks', 1)
-        ON CONFLICT(key) DO UPDATE SET 
-            value = value + 1,
-            updated_at = CURRENT_TIMESTAMP
-        WHERE key = 'total_clicks'
-    `)
-	if err != nil {
-		s.logger.Printf("Error updating total clicks: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+	err = database.WithTx(r.Context(), s.db, func(tx *sql.Tx) error {
+		// First update entry-specific clicks
+		_, err := tx.Exec(`
+            INSERT INTO clicks (entry_id, click_count, last_clicked)
+            VALUES (?, 1, CURRENT_TIMESTAMP)
+            ON CONFLICT(entry_id) DO UPDATE SET
+                click_count = click_count + 1,
+                last_clicked = CURRENT_TIMESTAMP
+        `, id)
+		if err != nil {
+			return fmt.Errorf("error updating entry clicks: %w", err)
+		}
+		_, err = tx.Exec(`
+            INSERT INTO click_daily (entry_id, day, clicks)
+            VALUES (?, date('now'), 1)
+            ON CONFLICT(entry_id, day) DO UPDATE SET clicks = clicks + 1
+        `, id)
+		if err != nil {
+			return fmt.Errorf("error updating daily clicks: %w", err)
+		}
+
+		// Then update total clicks counter
+		_, err = tx.Exec(`
+            INSERT INTO click_stats (key, value)
+            VALUES ('total_clicks', 1)
+            ON CONFLICT(key) DO UPDATE SET 
+                value = value + 1,
+                updated_at = CURRENT_TIMESTAMP
+            WHERE key = 'total_clicks'
+        `)
+		if err != nil {
+			return fmt.Errorf("error updating total clicks: %w", err)
+		}
 
-	if err := tx.Commit(); err != nil {
-		s.logger.Printf("Error committing transaction: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		// Queue the link for the Wayback Machine the first time it's clicked
+		if archive {
+			_, err = tx.Exec(
+				"UPDATE entries SET archive_status = ? WHERE id = ? AND archive_status IS NULL",
+				archiveQueued, id)
+			if err != nil {
+				return fmt.Errorf("error queueing entry for archiving: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error recording click", "error", err)
+		writeDBError(w, err)
 		return
 	}
+	s.config.Hooks.EntryClicked(r.Context(), hooks.Click{EntryID: id})
 
 	w.WriteHeader(http.StatusOK)
 }
 
-func (s *Server) getClickStats() (*DashboardStats, error) {
-	stats := &DashboardStats{}
+// getClickStats ranks the most clicked entries over window days (0 for all
+// time) and the past week. With a click_half_life_days setting, clicks
+// lose weight with age so old favourites give way to current ones: a
+// click counts half after that many days, a third after twice as many,
+// and so on.
+func (s *Server) getClickStats(ctx context.Context, window int) (*DashboardStats, error) {
+	stats := &DashboardStats{Window: window}
 	// Get total clicks
-	err := s.db.QueryRow(`
+	err := s.db.QueryRowContext(ctx, `
     	SELECT value FROM click_stats WHERE key = 'total_clicks'
     `).Scan(&stats.TotalClicks)
 	if err != nil {
 		stats.TotalClicks = 0
 	}
 
-	// Get top all time
-	rows, err := s.db.Query(`
-        SELECT e.id, e.title, e.url, c.click_count,
-               strftime('%Y-%m-%d %H:%M:%S', c.last_clicked) as last_clicked
-        FROM entries e
-        INNER JOIN clicks c ON e.id = c.entry_id 
-        ORDER BY c.click_count DESC, c.last_clicked DESC
-        LIMIT 5
-    `)
-	if err != nil {
+	stats.HalfLifeDays, _ = strconv.Atoi(s.getSetting(ctx, "click_half_life_days"))
+	stats.HalfLifeDays = max(stats.HalfLifeDays, 0)
+
+	if stats.Top, err = s.topClicked(ctx, window, stats.HalfLifeDays); err != nil {
 		return nil, fmt.Errorf("error getting top clicks: %w", err)
 	}
-	defer rows.Close()
-
-	stats.TopAllTime = make([]ClickStats, 0)
-	for rows.Next() {
-		var stat ClickStats
-		var lastClickedStr string
-		if err := rows.Scan(&stat.EntryID, &stat.Title, &stat.URL, &stat.ClickCount, &lastClickedStr); err != nil {
-			return nil, fmt.Errorf("error scanning click stats: %w", err)
-		}
-		lastClicked, err := time.ParseInLocation("2006-01-02 15:04:05", lastClickedStr, time.UTC)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing last clicked time: %w", err)
-		}
-		stat.LastClicked = lastClicked
-		stats.TopAllTime = append(stats.TopAllTime, stat)
+	if stats.TopPastWeek, err = s.topClicked(ctx, 7, stats.HalfLifeDays); err != nil {
+		return nil, fmt.Errorf("error getting weekly stats: %w", err)
 	}
+	return stats, nil
+}
 
-	// Get weekly top with same timestamp format
-	rows, err = s.db.Query(`
-        SELECT e.id, e.title, e.url, c.click_count,
-               strftime('%Y-%m-%d %H:%M:%S', c.last_clicked) as last_clicked
-        FROM entries e
-        INNER JOIN clicks c ON e.id = c.entry_id
-        WHERE c.last_clicked >= datetime('now', '-7 days')
-        ORDER BY c.click_count DESC, c.last_clicked DESC
+// topClicked returns the five entries with the most clicks over the last
+// days days, or all time for 0, weighted by age when halfLife is set. The
+// click counts returned are unweighted.
+func (s *Server) topClicked(ctx context.Context, days, halfLife int) ([]ClickStats, error) {
+	heat := "SUM(clicks)"
+	if halfLife > 0 {
+		heat = "SUM(clicks * :half / (:half + julianday('now') - julianday(day)))"
+	}
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT e.id, e.title, e.url, d.clicks,
+               strftime('%Y-%m-%d %H:%M:%S', c.last_clicked) as last_clicked,
+               COALESCE(e.archive_status, ''), COALESCE(e.archive_url, '')
+        FROM (
+            SELECT entry_id, SUM(clicks) AS clicks, `+heat+` AS heat
+            FROM click_daily
+            WHERE :days = 0 OR day > date('now', '-' || :days || ' days')
+            GROUP BY entry_id
+        ) d
+        INNER JOIN entries e ON e.id = d.entry_id
+        INNER JOIN clicks c ON c.entry_id = d.entry_id
+        ORDER BY d.heat DESC, d.clicks DESC, c.last_clicked DESC, e.id DESC
         LIMIT 5
-    `)
+    `, sql.Named("days", days), sql.Named("half", float64(halfLife)))
 	if err != nil {
-		return nil, fmt.Errorf("error getting weekly stats: %w", err)
+		return nil, err
 	}
 	defer rows.Close()
 
-	stats.TopPastWeek = make([]ClickStats, 0)
+	top := make([]ClickStats, 0)
 	for rows.Next() {
 		var stat ClickStats
 		var lastClickedStr string
-		if err := rows.Scan(&stat.EntryID, &stat.Title, &stat.URL, &stat.ClickCount, &lastClickedStr); err != nil {
-			return nil, fmt.Errorf("error scanning weekly stats: %w", err)
+		if err := rows.Scan(&stat.EntryID, &stat.Title, &stat.URL, &stat.ClickCount, &lastClickedStr,
+			&stat.ArchiveStatus, &stat.ArchiveURL); err != nil {
+			return nil, fmt.Errorf("error scanning click stats: %w", err)
 		}
 		lastClicked, err := time.ParseInLocation("2006-01-02 15:04:05", lastClickedStr, time.UTC)
 		if err != nil {
 			return nil, fmt.Errorf("error parsing last clicked time: %w", err)
 		}
 		stat.LastClicked = lastClicked
-		stats.TopPastWeek = append(stats.TopPastWeek, stat)
+		top = append(top, stat)
 	}
-
-	return stats, nil
+	return top, rows.Err()
 }
 
 func (s *Server) initializeTotalClicks() error {
@@ -196,3 +238,8 @@ func (s *Server) initializeTotalClicks() error {
 
 	return tx.Commit()
 }
+
+// optedOut reports whether the request carries a DNT or Sec-GPC opt-out signal
+func optedOut(r *http.Request) bool {
+	return r.Header.Get("DNT") == "1" || r.Header.Get("Sec-GPC") == "1"
+}
@@ -3,12 +3,14 @@ package server
 
 import (
 	"context"
+	"infoscope/internal/logging"
 )
 
 type contextKey string
 
 const (
 	contextKeyUserID       contextKey = "userID"
+	contextKeyUserRole     contextKey = "userRole"
 	contextKeyCSRFMeta     contextKey = "csrfMeta"
 	contextKeyCSRFToken    contextKey = "csrfToken"
 	contextKeyTemplateData contextKey = "templateData"
@@ -19,3 +21,14 @@ func getUserID(ctx context.Context) (int64, bool) {
 	userID, ok := ctx.Value(contextKeyUserID).(int64)
 	return userID, ok
 }
+
+// getUserRole returns the signed-in account's role, or "" outside requireRole
+func getUserRole(ctx context.Context) string {
+	role, _ := ctx.Value(contextKeyUserRole).(string)
+	return role
+}
+
+// getRequestID returns the ID assigned to the request, if any
+func getRequestID(ctx context.Context) string {
+	return logging.RequestID(ctx)
+}
@@ -107,7 +107,7 @@ func (c *CSRF) Middleware(next http.Handler) http.Handler {
 
 		// Validate token for unsafe methods
 		if err := c.validateRequest(r); err != nil {
-			http.Error(w, "CSRF validation failed", http.StatusForbidden)
+			writeAPIError(w, http.StatusForbidden, codeCSRF, "CSRF validation failed")
 			return
 		}
 
@@ -164,7 +164,7 @@ func (c *CSRF) GetMeta(token string) template.HTML {
 
 func (c *CSRF) Validate(w http.ResponseWriter, r *http.Request) bool {
 	if err := c.validateRequest(r); err != nil {
-		http.Error(w, "CSRF validation failed", http.StatusForbidden)
+		writeAPIError(w, http.StatusForbidden, codeCSRF, "CSRF validation failed")
 		return false
 	}
 	return true
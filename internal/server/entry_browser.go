@@ -0,0 +1,370 @@
+// internal/server/entry_browser.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// entryBrowserPageSize is how many entries a page of the entries
+	// browser lists
+	entryBrowserPageSize = 50
+	// maxBulkEntries caps the entries one bulk action may touch
+	maxBulkEntries = 500
+)
+
+// BrowsedEntry is one row of the admin entries browser
+type BrowsedEntry struct {
+	ID          int64      `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	FeedID      int64      `json:"feedId"`
+	Feed        string     `json:"feed"`
+	PublishedAt time.Time  `json:"publishedAt"`
+	StarredAt   *time.Time `json:"starredAt,omitempty"`
+	Pinned      bool       `json:"pinned"`
+	Hidden      bool       `json:"hidden"`
+}
+
+// EntryBrowserPageData is the data for the admin entries browser
+type EntryBrowserPageData struct {
+	Title    string
+	Active   string
+	Settings map[string]string
+	Feeds    []Feed
+	Query    string
+	FeedID   int64
+	Show     string
+	Entries  []BrowsedEntry
+	Page     int
+	PrevURL  string
+	NextURL  string
+}
+
+// entryBrowserShows are the ?show= views of the browser and the condition
+// each adds
+var entryBrowserShows = map[string]string{
+	"starred": " AND e.starred_at IS NOT NULL",
+	"pinned":  " AND e.starred_at IS NOT NULL AND e.pinned = 1",
+	"hidden":  " AND e.hidden_at IS NOT NULL",
+}
+
+// handleEntryBrowser lists entries newest first with ?page=N, narrowed by
+// ?q= on title and URL, ?feed=ID and ?show=starred, pinned or hidden.
+// PATCH stars, pins or hides one entry, or undoes that; starred entries are
+// kept whatever the retention settings say, only starred entries can be
+// pinned, and hidden entries leave every public page and feed. POST applies
+// a bulk action to a list of entries.
+func (s *Server) handleEntryBrowser(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.serveEntryBrowser(w, r)
+
+	case http.MethodPatch:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+		var req struct {
+			ID int64 `json:"id"`
+			entryUpdate
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		if req.entryUpdate.empty() {
+			writeValidationError(w, "Nothing to change",
+				map[string]string{"starred": "set starred, pinned or hidden"})
+			return
+		}
+
+		entry, err := s.updateEntry(r.Context(), req.ID, req.entryUpdate)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeAPIError(w, http.StatusNotFound, codeNotFound, "Entry not found")
+			return
+		}
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error updating entry", "id", req.ID, "error", err)
+			writeDBError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, entry)
+
+	case http.MethodPost:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+		s.handleEntryBulkAction(w, r)
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) serveEntryBrowser(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page := 1
+	if p := query.Get("page"); p != "" {
+		var err error
+		if page, err = strconv.Atoi(p); err != nil || page < 1 {
+			page = 1
+		}
+	}
+	data := EntryBrowserPageData{
+		Title:  "Entries",
+		Active: "entries",
+		Query:  strings.TrimSpace(query.Get("q")),
+		Show:   query.Get("show"),
+		Page:   page,
+	}
+	data.FeedID, _ = strconv.ParseInt(query.Get("feed"), 10, 64)
+
+	var cond string
+	var args []any
+	if data.Query != "" {
+		cond += " AND (LOWER(e.title) LIKE ? ESCAPE '\\' OR LOWER(e.url) LIKE ? ESCAPE '\\')"
+		like := "%" + escapeLike(strings.ToLower(data.Query)) + "%"
+		args = append(args, like, like)
+	}
+	if data.FeedID > 0 {
+		cond += " AND e.feed_id = ?"
+		args = append(args, data.FeedID)
+	}
+	if show, ok := entryBrowserShows[data.Show]; ok {
+		cond += show
+	} else {
+		data.Show = ""
+	}
+
+	entries, err := s.queryBrowsedEntries(r.Context(), cond, data.Show == "starred" || data.Show == "pinned",
+		(page-1)*entryBrowserPageSize, entryBrowserPageSize+1, args...)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error listing entries", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(entries) > entryBrowserPageSize {
+		entries = entries[:entryBrowserPageSize]
+		data.NextURL = entryBrowserURL(query, page+1)
+	}
+	if page > 1 {
+		data.PrevURL = entryBrowserURL(query, page-1)
+	}
+	data.Entries = entries
+
+	if data.Feeds, err = s.getFeeds(r.Context()); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting feeds", "error", err)
+	}
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+		settings = make(map[string]string)
+	}
+	data.Settings = settings
+	if err := s.renderTemplate(w, r, "admin/browse.html", data); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error rendering entries browser template", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// entryBrowserURL links to another page of the browser with the same
+// search and filters
+func entryBrowserURL(query url.Values, page int) string {
+	q := url.Values{}
+	for _, key := range []string{"q", "feed", "show"} {
+		if v := query.Get(key); v != "" {
+			q.Set(key, v)
+		}
+	}
+	if page > 1 {
+		q.Set("page", strconv.Itoa(page))
+	}
+	if len(q) == 0 {
+		return "/admin/entries"
+	}
+	return "/admin/entries?" + q.Encode()
+}
+
+// queryBrowsedEntries lists the entries matching cond, newest first or
+// most recently starred first.
+func (s *Server) queryBrowsedEntries(ctx context.Context, cond string, byStarred bool, offset, limit int, args ...any) ([]BrowsedEntry, error) {
+	order := "e.published_at DESC, e.id DESC"
+	if byStarred {
+		order = "e.starred_at DESC, e.id DESC"
+	}
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT e.id, e.title, e.url, e.published_at, f.id, COALESCE(NULLIF(f.title, ''), f.url),
+               e.starred_at, COALESCE(e.pinned, 0), e.hidden_at IS NOT NULL
+        FROM entries e
+        JOIN feeds f ON f.id = e.feed_id
+        WHERE 1 = 1`+cond+`
+        ORDER BY `+order+`
+        LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]BrowsedEntry, 0)
+	for rows.Next() {
+		var e BrowsedEntry
+		var starred sql.NullTime
+		if err := rows.Scan(&e.ID, &e.Title, &e.URL, &e.PublishedAt, &e.FeedID, &e.Feed, &starred, &e.Pinned, &e.Hidden); err != nil {
+			return nil, err
+		}
+		if starred.Valid {
+			e.StarredAt = &starred.Time
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// entryBulkActions are the changes a bulk action makes to the selected
+// entries; "favicon" is handled apart as it fetches in the background.
+// Deleted entries may come back on the next fetch while the feed still
+// carries them, so hiding is the way to keep one off the public pages.
+var entryBulkActions = map[string]string{
+	"delete": "DELETE FROM entries",
+	"hide":   "UPDATE entries SET hidden_at = COALESCE(hidden_at, CURRENT_TIMESTAMP)",
+	"unhide": "UPDATE entries SET hidden_at = NULL",
+	"star":   "UPDATE entries SET starred_at = COALESCE(starred_at, CURRENT_TIMESTAMP)",
+	"unstar": "UPDATE entries SET starred_at = NULL, pinned = 0",
+}
+
+// handleEntryBulkAction applies {"action": ..., "ids": [...]} to the
+// listed entries and reports how many changed. Re-fetching favicons starts
+// the favicon refresh for the entries' feeds and answers 202 with its
+// progress, as POST /admin/favicons/refresh does.
+func (s *Server) handleEntryBulkAction(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Action string  `json:"action"`
+		IDs    []int64 `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err, "Invalid request")
+		return
+	}
+	fields := make(map[string]string)
+	if _, ok := entryBulkActions[req.Action]; !ok && req.Action != "favicon" {
+		fields["action"] = "one of delete, hide, unhide, star, unstar or favicon"
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > maxBulkEntries {
+		fields["ids"] = fmt.Sprintf("between 1 and %d entries", maxBulkEntries)
+	}
+	if len(fields) > 0 {
+		writeValidationError(w, "Invalid bulk action", fields)
+		return
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(req.IDs)), ",")
+	args := make([]any, len(req.IDs))
+	for i, id := range req.IDs {
+		args[i] = id
+	}
+
+	if req.Action == "favicon" {
+		var feedIDs []int64
+		rows, err := s.db.QueryContext(r.Context(),
+			"SELECT DISTINCT feed_id FROM entries WHERE id IN ("+placeholders+")", args...)
+		if err == nil {
+			for rows.Next() {
+				var id int64
+				if err = rows.Scan(&id); err != nil {
+					break
+				}
+				feedIDs = append(feedIDs, id)
+			}
+			rows.Close()
+		}
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error finding feeds of entries", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		if len(feedIDs) == 0 {
+			writeAPIError(w, http.StatusNotFound, codeNotFound, "Entries not found")
+			return
+		}
+		if !s.favicons.start(s.feedService, feedIDs, s.logger) {
+			writeAPIError(w, http.StatusConflict, codeConflict, "A favicon refresh is already running")
+			return
+		}
+		writeJSON(w, http.StatusAccepted, s.favicons.snapshot())
+		return
+	}
+
+	res, err := s.db.ExecContext(r.Context(),
+		entryBulkActions[req.Action]+" WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error applying bulk action", "action", req.Action, "error", err)
+		writeDBError(w, err)
+		return
+	}
+	n, _ := res.RowsAffected()
+	s.logger.InfoContext(r.Context(), "Applied bulk action to entries", "action", req.Action, "entries", n)
+	writeJSON(w, http.StatusOK, map[string]any{"action": req.Action, "updated": n})
+}
+
+// entryUpdate is a change to one entry from the browser or the API; fields
+// left out stay as they are
+type entryUpdate struct {
+	Starred *bool `json:"starred"`
+	Pinned  *bool `json:"pinned"`
+	Hidden  *bool `json:"hidden"`
+}
+
+func (u entryUpdate) empty() bool {
+	return u.Starred == nil && u.Pinned == nil && u.Hidden == nil
+}
+
+// updateEntry applies a change and returns the entry as it now stands.
+// Pinning stars the entry too, and unstarring unpins it.
+func (s *Server) updateEntry(ctx context.Context, id int64, u entryUpdate) (BrowsedEntry, error) {
+	var set []string
+	switch {
+	case u.Starred != nil && !*u.Starred:
+		set = []string{"starred_at = NULL", "pinned = 0"}
+	case u.Pinned != nil && *u.Pinned:
+		set = []string{"starred_at = COALESCE(starred_at, CURRENT_TIMESTAMP)", "pinned = 1"}
+	default:
+		if u.Starred != nil {
+			set = append(set, "starred_at = COALESCE(starred_at, CURRENT_TIMESTAMP)")
+		}
+		if u.Pinned != nil {
+			set = append(set, "pinned = 0")
+		}
+	}
+	if u.Hidden != nil {
+		if *u.Hidden {
+			set = append(set, "hidden_at = COALESCE(hidden_at, CURRENT_TIMESTAMP)")
+		} else {
+			set = append(set, "hidden_at = NULL")
+		}
+	}
+	res, err := s.db.ExecContext(ctx, "UPDATE entries SET "+strings.Join(set, ", ")+" WHERE id = ?", id)
+	if err != nil {
+		return BrowsedEntry{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return BrowsedEntry{}, sql.ErrNoRows
+	}
+
+	entries, err := s.queryBrowsedEntries(ctx, " AND e.id = ?", false, 0, 1, id)
+	if err != nil {
+		return BrowsedEntry{}, err
+	}
+	if len(entries) == 0 {
+		return BrowsedEntry{}, sql.ErrNoRows
+	}
+	return entries[0], nil
+}
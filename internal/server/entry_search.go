@@ -0,0 +1,265 @@
+// internal/server/entry_search.go
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// entrySearchLimit caps the matches shown
+	entrySearchLimit = 200
+	// entrySearchScan caps the entries examined, newest first, so a regex
+	// over a large archive stays quick. Substring terms narrow the scan in
+	// SQL; regexes are matched on the scanned rows.
+	entrySearchScan = 10000
+	// maxSearchPattern keeps a single term to a sensible length
+	maxSearchPattern = 200
+)
+
+// entryTerm is one condition of an entry search: a case-insensitive
+// substring or regex matched against a field, or excluded from it with -
+type entryTerm struct {
+	field  string // title, url or feed
+	text   string
+	re     *regexp.Regexp
+	negate bool
+}
+
+// parseEntryQuery splits a query such as
+//
+//	feed:example title:/^(ad|sponsored):/ -url:utm_
+//
+// into terms. Bare terms match the title, "quotes" keep spaces in a
+// substring and /slashes/ make a regex, which may also contain spaces.
+func parseEntryQuery(q string) ([]entryTerm, error) {
+	var terms []entryTerm
+	for q = strings.TrimSpace(q); q != ""; q = strings.TrimSpace(q) {
+		t := entryTerm{field: "title"}
+		if strings.HasPrefix(q, "-") {
+			t.negate = true
+			q = q[1:]
+		}
+		for _, field := range []string{"title", "url", "feed"} {
+			if strings.HasPrefix(strings.ToLower(q), field+":") {
+				t.field = field
+				q = q[len(field)+1:]
+				break
+			}
+		}
+
+		var value string
+		switch {
+		case strings.HasPrefix(q, "/"):
+			end := closingSlash(q)
+			if end < 0 {
+				return nil, fmt.Errorf("regex %s is missing its closing /", q)
+			}
+			value, q = q[1:end], q[end+1:]
+			if len(value) > maxSearchPattern {
+				return nil, fmt.Errorf("regex is longer than %d characters", maxSearchPattern)
+			}
+			re, err := regexp.Compile("(?i)" + strings.ReplaceAll(value, `\/`, "/"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex /%s/: %v", value, err)
+			}
+			t.re = re
+		case strings.HasPrefix(q, `"`):
+			end := strings.Index(q[1:], `"`)
+			if end < 0 {
+				return nil, fmt.Errorf("quote %s is missing its closing \"", q)
+			}
+			value, q = q[1:end+1], q[end+2:]
+			t.text = value
+		default:
+			end := strings.IndexFunc(q, func(r rune) bool { return r == ' ' || r == '\t' })
+			if end < 0 {
+				end = len(q)
+			}
+			value, q = q[:end], q[end:]
+			t.text = value
+		}
+		if t.re == nil && strings.TrimSpace(t.text) == "" {
+			return nil, fmt.Errorf("empty %s term", t.field)
+		}
+		if len(t.text) > maxSearchPattern {
+			return nil, fmt.Errorf("term is longer than %d characters", maxSearchPattern)
+		}
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+// closingSlash finds the / ending a regex that starts q, skipping
+// escaped ones, or -1
+func closingSlash(q string) int {
+	for i := 1; i < len(q); i++ {
+		switch q[i] {
+		case '\\':
+			i++
+		case '/':
+			return i
+		}
+	}
+	return -1
+}
+
+// EntryMatch is one entry found by the admin entry search
+type EntryMatch struct {
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	FeedID      int64     `json:"feedId"`
+	Feed        string    `json:"feed"`
+	FeedURL     string    `json:"-"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+// EntrySearchResult holds the matches of an entry search. Scanned is how
+// many entries were examined; Truncated is set when the scan stopped at
+// entrySearchScan, so older entries went unsearched.
+type EntrySearchResult struct {
+	Query     string       `json:"query"`
+	Matches   []EntryMatch `json:"matches"`
+	Scanned   int          `json:"scanned"`
+	Truncated bool         `json:"truncated"`
+	Limited   bool         `json:"limited"`
+}
+
+// EntrySearchPageData is the data for the entry search page
+type EntrySearchPageData struct {
+	Title    string
+	Active   string
+	Settings map[string]string
+	Query    string
+	Result   *EntrySearchResult
+	Error    string
+}
+
+// searchEntries runs the terms over the newest entries. Substring terms
+// become LIKE conditions, walked in publish order on the published_at
+// index; regex terms are then matched on the rows that come back.
+func (s *Server) searchEntries(ctx context.Context, terms []entryTerm) (*EntrySearchResult, error) {
+	columns := map[string]string{
+		"title": "LOWER(e.title)",
+		"url":   "LOWER(e.url)",
+		"feed":  "LOWER(COALESCE(f.title, '') || ' ' || f.url)",
+	}
+	var cond strings.Builder
+	var args []any
+	for _, t := range terms {
+		if t.re != nil {
+			continue
+		}
+		not := ""
+		if t.negate {
+			not = "NOT "
+		}
+		fmt.Fprintf(&cond, " AND %s %sLIKE ? ESCAPE '\\'", columns[t.field], not)
+		args = append(args, "%"+escapeLike(strings.ToLower(t.text))+"%")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT e.id, e.title, e.url, e.published_at, f.id, COALESCE(NULLIF(f.title, ''), f.url), f.url
+        FROM entries e
+        JOIN feeds f ON f.id = e.feed_id
+        WHERE 1 = 1`+cond.String()+`
+        ORDER BY e.published_at DESC, e.id DESC
+        LIMIT ?`, append(args, entrySearchScan)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &EntrySearchResult{Matches: make([]EntryMatch, 0)}
+rows:
+	for rows.Next() {
+		var m EntryMatch
+		if err := rows.Scan(&m.ID, &m.Title, &m.URL, &m.PublishedAt, &m.FeedID, &m.Feed, &m.FeedURL); err != nil {
+			return nil, err
+		}
+		result.Scanned++
+		for _, t := range terms {
+			if t.re == nil {
+				continue
+			}
+			var value string
+			switch t.field {
+			case "title":
+				value = m.Title
+			case "url":
+				value = m.URL
+			case "feed":
+				value = m.Feed + " " + m.FeedURL
+			}
+			if t.re.MatchString(value) == t.negate {
+				continue rows
+			}
+		}
+		if len(result.Matches) == entrySearchLimit {
+			result.Limited = true
+			break
+		}
+		result.Matches = append(result.Matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	result.Truncated = !result.Limited && result.Scanned == entrySearchScan
+	return result, nil
+}
+
+// handleEntrySearch searches entries by title, URL and feed with
+// substrings or regexes, to check what a filter would catch or find
+// entries to hide. ?format=json returns the result as JSON.
+func (s *Server) handleEntrySearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	asJSON := r.URL.Query().Get("format") == "json"
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	data := EntrySearchPageData{Title: "Entry Search", Active: "entries", Query: q}
+	if q != "" {
+		terms, err := parseEntryQuery(q)
+		if err != nil {
+			if asJSON {
+				writeValidationError(w, "Invalid query", map[string]string{"q": err.Error()})
+				return
+			}
+			data.Error = err.Error()
+		} else if data.Result, err = s.searchEntries(r.Context(), terms); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error searching entries", "query", q, "error", err)
+			if asJSON {
+				writeDBError(w, err)
+				return
+			}
+			data.Error = "Search failed"
+		} else {
+			data.Result.Query = q
+		}
+	}
+	if asJSON {
+		if data.Result == nil {
+			data.Result = &EntrySearchResult{Matches: make([]EntryMatch, 0)}
+		}
+		writeJSON(w, http.StatusOK, data.Result)
+		return
+	}
+
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+		settings = make(map[string]string)
+	}
+	data.Settings = settings
+	if err := s.renderTemplate(w, r, "admin/entries.html", data); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error rendering entries template", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,194 @@
+// internal/server/error_digest.go
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"infoscope/internal/feed"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorClassCount is the number of failures of one class for a feed
+type ErrorClassCount struct {
+	Class           string `json:"class"`
+	Count           int    `json:"count"`
+	SuggestedAction string `json:"suggestedAction"`
+}
+
+// FeedErrorSummary aggregates a feed's failures over the digest window
+type FeedErrorSummary struct {
+	FeedID     int64             `json:"feedId"`
+	Title      string            `json:"title"`
+	URL        string            `json:"url"`
+	ErrorCount int               `json:"errorCount"`
+	Classes    []ErrorClassCount `json:"classes"`
+	LastError  string            `json:"lastError"`
+	LastFailed time.Time         `json:"lastFailed"`
+}
+
+// ErrorDigest is the fetch error summary for a time window
+type ErrorDigest struct {
+	Since time.Time          `json:"since"`
+	Until time.Time          `json:"until"`
+	Feeds []FeedErrorSummary `json:"feeds"`
+}
+
+type ErrorDigestPageData struct {
+	Title    string
+	Active   string
+	Settings map[string]string
+	Days     int
+	Digest   *ErrorDigest
+}
+
+// buildErrorDigest assembles per-feed error summaries from the fetch log
+func (s *Server) buildErrorDigest(ctx context.Context, days int) (*ErrorDigest, error) {
+	until := time.Now().UTC()
+	digest := &ErrorDigest{
+		Since: until.AddDate(0, 0, -days),
+		Until: until,
+		Feeds: make([]FeedErrorSummary, 0),
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT f.id, COALESCE(f.title, ''), f.url, COALESCE(l.error_class, ''), COUNT(*),
+               strftime('%Y-%m-%d %H:%M:%S', MAX(l.created_at)),
+               (SELECT l2.message FROM fetch_log l2
+                WHERE l2.feed_id = f.id AND l2.status = 'error'
+                ORDER BY l2.created_at DESC, l2.id DESC LIMIT 1)
+        FROM fetch_log l
+        JOIN feeds f ON f.id = l.feed_id
+        WHERE l.status = 'error' AND l.created_at >= ?
+        GROUP BY f.id, l.error_class`,
+		digest.Since.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, fmt.Errorf("error querying fetch log: %w", err)
+	}
+	defer rows.Close()
+
+	byFeed := make(map[int64]*FeedErrorSummary)
+	for rows.Next() {
+		var summary FeedErrorSummary
+		var class, lastFailed string
+		var count int
+		if err := rows.Scan(&summary.FeedID, &summary.Title, &summary.URL, &class, &count,
+			&lastFailed, &summary.LastError); err != nil {
+			return nil, fmt.Errorf("error scanning fetch log: %w", err)
+		}
+
+		existing, ok := byFeed[summary.FeedID]
+		if !ok {
+			existing = &summary
+			byFeed[summary.FeedID] = existing
+		}
+		failed, _ := time.ParseInLocation("2006-01-02 15:04:05", lastFailed, time.UTC)
+		if failed.After(existing.LastFailed) {
+			existing.LastFailed = failed
+		}
+		existing.ErrorCount += count
+		existing.Classes = append(existing.Classes, ErrorClassCount{
+			Class:           class,
+			Count:           count,
+			SuggestedAction: feed.SuggestedAction(class),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, summary := range byFeed {
+		sort.Slice(summary.Classes, func(i, j int) bool {
+			return summary.Classes[i].Count > summary.Classes[j].Count
+		})
+		digest.Feeds = append(digest.Feeds, *summary)
+	}
+	sort.Slice(digest.Feeds, func(i, j int) bool {
+		if digest.Feeds[i].ErrorCount != digest.Feeds[j].ErrorCount {
+			return digest.Feeds[i].ErrorCount > digest.Feeds[j].ErrorCount
+		}
+		return digest.Feeds[i].FeedID < digest.Feeds[j].FeedID
+	})
+
+	return digest, nil
+}
+
+// handleErrorDigest shows the fetch error digest, or exports it as JSON or text
+func (s *Server) handleErrorDigest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	days := 7
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 && d <= 90 {
+		days = d
+	}
+
+	digest, err := s.buildErrorDigest(r.Context(), days)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error building error digest", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("infoscope_fetch_errors_%s", time.Now().Format("2006-01-02"))
+	switch r.URL.Query().Get("format") {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename+".json")
+		if err := json.NewEncoder(w).Encode(digest); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error encoding error digest", "error", err)
+		}
+		return
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename+".txt")
+		fmt.Fprint(w, formatErrorDigest(digest))
+		return
+	}
+
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+		settings = make(map[string]string)
+	}
+
+	data := ErrorDigestPageData{
+		Title:    "Fetch Errors",
+		Active:   "errors",
+		Settings: settings,
+		Days:     days,
+		Digest:   digest,
+	}
+	if err := s.renderTemplate(w, r, "admin/errors.html", data); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error rendering errors template", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// formatErrorDigest renders a plain-text digest suitable for mailing or pasting
+func formatErrorDigest(digest *ErrorDigest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Infoscope fetch error digest\n%s to %s\n\n",
+		digest.Since.Format("2006-01-02"), digest.Until.Format("2006-01-02"))
+
+	if len(digest.Feeds) == 0 {
+		b.WriteString("No feeds failed in this period.\n")
+		return b.String()
+	}
+
+	for _, f := range digest.Feeds {
+		fmt.Fprintf(&b, "%s <%s>\n  %d failures, last %s\n  last error: %s\n",
+			f.Title, f.URL, f.ErrorCount, f.LastFailed.Format("2006-01-02 15:04"), f.LastError)
+		for _, c := range f.Classes {
+			fmt.Fprintf(&b, "  - %s x%d: %s\n", c.Class, c.Count, c.SuggestedAction)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+// internal/server/favicon_refresh.go
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"infoscope/internal/feed"
+)
+
+// FaviconRefreshStatus is the progress of the bulk favicon refresh
+type FaviconRefreshStatus struct {
+	feed.FaviconProgress
+	Running    bool       `json:"running"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// faviconRefresh runs at most one refresh at a time and remembers the
+// outcome of the last one.
+type faviconRefresh struct {
+	mu     sync.Mutex
+	status FaviconRefreshStatus
+}
+
+func (j *faviconRefresh) snapshot() FaviconRefreshStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// start begins a refresh of the given feeds' favicons, or of every feed's
+// when feedIDs is empty, in the background. It reports false if a refresh
+// is already running.
+func (j *faviconRefresh) start(feeds *feed.Service, feedIDs []int64, logger *slog.Logger) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status.Running {
+		return false
+	}
+	now := time.Now().UTC()
+	j.status = FaviconRefreshStatus{Running: true, StartedAt: &now}
+
+	go func() {
+		err := feeds.RefreshFeedFavicons(context.Background(), feedIDs, func(p feed.FaviconProgress) {
+			j.mu.Lock()
+			j.status.FaviconProgress = p
+			j.mu.Unlock()
+		})
+
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		finished := time.Now().UTC()
+		j.status.Running = false
+		j.status.FinishedAt = &finished
+		if err != nil {
+			logger.Error("Error refreshing favicons", "error", err)
+			j.status.Error = err.Error()
+		}
+	}()
+	return true
+}
+
+// handleFaviconRefresh starts a bulk favicon refresh on POST and reports its
+// progress on GET.
+func (s *Server) handleFaviconRefresh(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.favicons.snapshot())
+
+	case http.MethodPost:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+		if !s.favicons.start(s.feedService, nil, s.logger) {
+			writeAPIError(w, http.StatusConflict, codeConflict, "A favicon refresh is already running")
+			return
+		}
+		writeJSON(w, http.StatusAccepted, s.favicons.snapshot())
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
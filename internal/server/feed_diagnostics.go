@@ -0,0 +1,55 @@
+// internal/server/feed_diagnostics.go
+package server
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"infoscope/internal/feed"
+)
+
+// handleFeedDiagnostics shows whether conditional GETs work for the feed
+// given by ?feed=: the validators stored for it, the headers of its last
+// request and response, and the body hash, which tells a server that
+// ignores the validators from a feed that really changed
+func (s *Server) handleFeedDiagnostics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("feed"), 10, 64)
+	if err != nil {
+		writeValidationError(w, "Invalid feed ID", map[string]string{"feed": "must be a number"})
+		return
+	}
+
+	var title, url string
+	err = s.db.QueryRowContext(r.Context(),
+		"SELECT title, url FROM feeds WHERE id = ? AND status != 'deleted'", id,
+	).Scan(&title, &url)
+	if err == sql.ErrNoRows {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "Feed not found")
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting feed for diagnostics", "feed_id", id, "error", err)
+		writeDBError(w, err)
+		return
+	}
+
+	resp := struct {
+		ID             int64                `json:"id"`
+		Title          string               `json:"title"`
+		URL            string               `json:"url"`
+		ConditionalGet *feed.ConditionalGet `json:"conditionalGet"`
+		Note           string               `json:"note,omitempty"`
+	}{ID: id, Title: title, URL: url}
+	if diag, ok := s.feedService.ConditionalGet(id); ok {
+		resp.ConditionalGet = &diag
+	} else {
+		resp.Note = "Not fetched since the server started"
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,213 @@
+// internal/server/feed_health.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"infoscope/internal/feed"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Feed health classes, worst first
+const (
+	healthDead    = "dead"
+	healthFailing = "failing"
+	healthStale   = "stale"
+	healthQuiet   = "quiet"
+	healthOK      = "ok"
+)
+
+var healthRank = map[string]int{
+	healthDead:    0,
+	healthFailing: 1,
+	healthStale:   2,
+	healthQuiet:   3,
+	healthOK:      4,
+}
+
+// staleAfter is how long a feed may go without a successful fetch before
+// it is reported as stale
+const staleAfter = 48 * time.Hour
+
+// HTTPStatusCounts is how a feed's fetches turned out over the window
+type HTTPStatusCounts struct {
+	OK            int `json:"ok"`
+	NotModified   int `json:"notModified"`
+	ClientErrors  int `json:"clientErrors"`
+	ServerErrors  int `json:"serverErrors"`
+	NetworkErrors int `json:"networkErrors"`
+}
+
+// FeedHealth aggregates one feed's fetch metrics over the report window
+type FeedHealth struct {
+	FeedID        int64            `json:"feedId"`
+	Title         string           `json:"title"`
+	URL           string           `json:"url"`
+	Health        string           `json:"health"`
+	LastSuccess   *time.Time       `json:"lastSuccess,omitempty"`
+	ErrorStreak   int              `json:"errorStreak"`
+	LastError     string           `json:"lastError,omitempty"`
+	EntriesPerDay float64          `json:"entriesPerDay"`
+	Fetches       int              `json:"fetches"`
+	Statuses      HTTPStatusCounts `json:"statuses"`
+	AvgLatencyMS  int64            `json:"avgLatencyMs"`
+}
+
+// FeedHealthReport is the health of every feed over a window of days
+type FeedHealthReport struct {
+	Since   time.Time      `json:"since"`
+	Until   time.Time      `json:"until"`
+	Days    int            `json:"days"`
+	Summary map[string]int `json:"summary"`
+	Feeds   []FeedHealth   `json:"feeds"`
+}
+
+type FeedHealthPageData struct {
+	Title    string
+	Active   string
+	Settings map[string]string
+	Report   *FeedHealthReport
+}
+
+// buildFeedHealth assembles the health report from the feeds table and the
+// daily fetch stats, listing the least healthy feeds first.
+func (s *Server) buildFeedHealth(ctx context.Context, days int) (*FeedHealthReport, error) {
+	until := time.Now().UTC()
+	report := &FeedHealthReport{
+		Since:   until.AddDate(0, 0, -days),
+		Until:   until,
+		Days:    days,
+		Summary: make(map[string]int),
+		Feeds:   make([]FeedHealth, 0),
+	}
+	since := report.Since.Format("2006-01-02 15:04:05")
+
+	// Retained entries are a floor for activity until the daily stats have
+	// covered the whole window
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT f.id, COALESCE(f.title, ''), f.url, COALESCE(f.status, ''),
+               datetime(f.last_fetched), COALESCE(f.error_count, 0), COALESCE(f.last_error, ''),
+               COALESCE(SUM(st.fetches), 0), COALESCE(SUM(st.ok), 0), COALESCE(SUM(st.not_modified), 0),
+               COALESCE(SUM(st.client_errors), 0), COALESCE(SUM(st.server_errors), 0),
+               COALESCE(SUM(st.network_errors), 0), COALESCE(SUM(st.latency_ms), 0),
+               max(COALESCE(SUM(st.new_entries), 0),
+                   (SELECT COUNT(*) FROM entries e WHERE e.feed_id = f.id AND e.created_at >= ?))
+        FROM feeds f
+        LEFT JOIN feed_fetch_stats st ON st.feed_id = f.id AND st.day >= date(?)
+        WHERE f.status != 'deleted'
+        GROUP BY f.id`, since, since)
+	if err != nil {
+		return nil, fmt.Errorf("error querying feed health: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h FeedHealth
+		var status string
+		var lastFetched sql.NullString
+		var latencyMS int64
+		var newEntries int
+		if err := rows.Scan(&h.FeedID, &h.Title, &h.URL, &status, &lastFetched, &h.ErrorStreak, &h.LastError,
+			&h.Fetches, &h.Statuses.OK, &h.Statuses.NotModified, &h.Statuses.ClientErrors,
+			&h.Statuses.ServerErrors, &h.Statuses.NetworkErrors, &latencyMS, &newEntries); err != nil {
+			return nil, fmt.Errorf("error scanning feed health: %w", err)
+		}
+		// last_fetched is only stamped when a fetch succeeds
+		if t, err := time.Parse("2006-01-02 15:04:05", lastFetched.String); err == nil {
+			h.LastSuccess = &t
+		}
+		if responses := h.Fetches - h.Statuses.NetworkErrors; responses > 0 {
+			h.AvgLatencyMS = latencyMS / int64(responses)
+		}
+		h.EntriesPerDay = float64(newEntries) / float64(days)
+		h.Health = classifyFeedHealth(h, status, until)
+
+		report.Summary[h.Health]++
+		report.Feeds = append(report.Feeds, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(report.Feeds, func(i, j int) bool {
+		a, b := report.Feeds[i], report.Feeds[j]
+		if a.Health != b.Health {
+			return healthRank[a.Health] < healthRank[b.Health]
+		}
+		if a.ErrorStreak != b.ErrorStreak {
+			return a.ErrorStreak > b.ErrorStreak
+		}
+		return a.FeedID < b.FeedID
+	})
+	return report, nil
+}
+
+// classifyFeedHealth picks the health class of a feed: disabled, currently
+// failing, without a recent successful fetch, fetched but posting nothing,
+// or fine.
+func classifyFeedHealth(h FeedHealth, status string, now time.Time) string {
+	switch {
+	case status == feed.StatusDead:
+		return healthDead
+	case h.ErrorStreak > 0:
+		return healthFailing
+	case h.LastSuccess == nil || now.Sub(*h.LastSuccess) > staleAfter:
+		return healthStale
+	case h.EntriesPerDay == 0:
+		return healthQuiet
+	default:
+		return healthOK
+	}
+}
+
+// handleFeedHealth shows the feed health report, or exports it as JSON
+func (s *Server) handleFeedHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	days := 30
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 && d <= 90 {
+		days = d
+	}
+
+	report, err := s.buildFeedHealth(r.Context(), days)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error building feed health report", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf("attachment; filename=infoscope_feed_health_%s.json", time.Now().Format("2006-01-02")))
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error encoding feed health report", "error", err)
+		}
+		return
+	}
+
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+		settings = make(map[string]string)
+	}
+
+	data := FeedHealthPageData{
+		Title:    "Feed Health",
+		Active:   "health",
+		Settings: settings,
+		Report:   report,
+	}
+	if err := s.renderTemplate(w, r, "admin/health.html", data); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error rendering feed health template", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
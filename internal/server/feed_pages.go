@@ -0,0 +1,126 @@
+// internal/server/feed_pages.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PublicFeed is a subscribed feed as shown on its public page
+type PublicFeed struct {
+	ID       int64
+	Title    string
+	URL      string
+	SiteURL  string
+	Category string
+}
+
+// FeedPageData is the template data for a single feed's public page
+type FeedPageData struct {
+	SiteTitle string
+	Feed      PublicFeed
+	Entries   []EntryView
+	Settings  map[string]string
+}
+
+// handleFeedPage serves /feeds/{id}, listing one feed's recent entries, and
+// /feeds/{id}/rss.xml with the same entries as RSS.
+func (s *Server) handleFeedPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/feeds/")
+	idStr, suffix, _ := strings.Cut(rest, "/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 || (suffix != "" && suffix != "rss.xml") {
+		s.handle404(w, r)
+		return
+	}
+
+	f, err := s.getPublicFeed(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.handle404(w, r)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting feed", "feed_id", id, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+		settings = make(map[string]string)
+	}
+	maxPosts, err := strconv.Atoi(settings["max_posts"])
+	if err != nil || maxPosts <= 0 {
+		maxPosts = 33
+	}
+
+	entries, err := s.getFeedEntries(r.Context(), id, maxPosts)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting entries for feed", "feed_id", id, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if suffix == "rss.xml" {
+		base := siteBaseURL(r, settings["site_url"])
+		page := fmt.Sprintf("%s/feeds/%d", base, id)
+		description := fmt.Sprintf("Recent entries from %s, via %s", f.Title, settings["site_title"])
+		if err := writeRSS(w, f.Title, page, description, page+"/rss.xml", entries); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error encoding RSS for feed", "feed_id", id, "error", err)
+		}
+		return
+	}
+
+	data := FeedPageData{
+		SiteTitle: settings["site_title"],
+		Feed:      f,
+		Entries:   entries,
+		Settings:  settings,
+	}
+	if err := s.renderTemplate(w, r, "feed.html", data); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error rendering feed template", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// getPublicFeed loads a feed that is shown in the river; deleted and
+// snoozed feeds are reported as missing.
+func (s *Server) getPublicFeed(ctx context.Context, id int64) (PublicFeed, error) {
+	f := PublicFeed{ID: id}
+	err := s.db.QueryRowContext(ctx, `
+        SELECT COALESCE(NULLIF(title, ''), url), url, COALESCE(site_url, ''), COALESCE(category, '')
+        FROM feeds
+        WHERE id = ? AND status != 'deleted'
+          AND (snoozed_until IS NULL OR snoozed_until <= datetime('now'))`,
+		id).Scan(&f.Title, &f.URL, &f.SiteURL, &f.Category)
+	return f, err
+}
+
+// getFeedEntries returns a feed's most recent entries, newest first.
+func (s *Server) getFeedEntries(ctx context.Context, feedID int64, limit int) ([]EntryView, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT e.id, e.feed_id, e.title, e.url, e.favicon_url,
+               COALESCE(f.category, ''), COALESCE(f.language, ''),
+               datetime(e.published_at)
+        FROM entries e
+        JOIN feeds f ON e.feed_id = f.id
+        WHERE e.feed_id = ? AND e.hidden_at IS NULL
+        ORDER BY e.published_at DESC, e.id DESC
+        LIMIT ?`, feedID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+	return scanEntryViews(rows, s.siteLocation(ctx))
+}
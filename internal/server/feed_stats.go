@@ -0,0 +1,58 @@
+// internal/server/feed_stats.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// FeedStats summarizes a feed's health for exports. Entry and click counts
+// cover the entries still retained.
+type FeedStats struct {
+	Entries      int        `json:"entries"`
+	Entries30d   int        `json:"entries30d"`
+	Clicks       int        `json:"clicks"`
+	Errors30d    int        `json:"errors30d"`
+	ErrorCount   int        `json:"errorCount"`
+	LastPostedAt *time.Time `json:"lastPostedAt,omitempty"`
+	Status       string     `json:"status,omitempty"`
+}
+
+// getFeedStats returns the stats of every feed, keyed by feed URL.
+func (s *Server) getFeedStats(ctx context.Context) (map[string]FeedStats, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT f.url,
+               (SELECT COUNT(*) FROM entries e WHERE e.feed_id = f.id),
+               (SELECT COUNT(*) FROM entries e
+                WHERE e.feed_id = f.id AND e.created_at >= datetime('now', '-30 days')),
+               (SELECT COALESCE(SUM(c.click_count), 0) FROM clicks c
+                JOIN entries e ON e.id = c.entry_id WHERE e.feed_id = f.id),
+               (SELECT COUNT(*) FROM fetch_log l
+                WHERE l.feed_id = f.id AND l.status = 'error' AND l.created_at >= datetime('now', '-30 days')),
+               COALESCE(f.error_count, 0),
+               datetime(f.last_post_at),
+               COALESCE(f.status, '')
+        FROM feeds f
+        WHERE f.status != 'deleted'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[string]FeedStats)
+	for rows.Next() {
+		var feedURL string
+		var st FeedStats
+		var lastPost sql.NullString
+		if err := rows.Scan(&feedURL, &st.Entries, &st.Entries30d, &st.Clicks, &st.Errors30d,
+			&st.ErrorCount, &lastPost, &st.Status); err != nil {
+			return nil, err
+		}
+		if t, err := time.Parse("2006-01-02 15:04:05", lastPost.String); err == nil {
+			st.LastPostedAt = &t
+		}
+		stats[feedURL] = st
+	}
+	return stats, rows.Err()
+}
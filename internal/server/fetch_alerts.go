@@ -0,0 +1,82 @@
+// internal/server/fetch_alerts.go
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// FetchAlert is an anomaly the fetcher spotted in its cycles
+type FetchAlert struct {
+	ID          int64     `json:"id"`
+	Kind        string    `json:"kind"`
+	Message     string    `json:"message"`
+	Occurrences int       `json:"occurrences"`
+	CreatedAt   time.Time `json:"createdAt"`
+	LastSeenAt  time.Time `json:"lastSeenAt"`
+}
+
+// getFetchAlerts returns the alerts that have not been dismissed, newest first.
+func (s *Server) getFetchAlerts(ctx context.Context) ([]FetchAlert, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, kind, message, occurrences, created_at, last_seen_at
+        FROM fetch_alerts
+        WHERE dismissed_at IS NULL
+        ORDER BY last_seen_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := make([]FetchAlert, 0)
+	for rows.Next() {
+		var a FetchAlert
+		if err := rows.Scan(&a.ID, &a.Kind, &a.Message, &a.Occurrences, &a.CreatedAt, &a.LastSeenAt); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+// handleFetchAlerts lists open alerts on GET and dismisses one, or all when
+// no ID is given, on POST.
+func (s *Server) handleFetchAlerts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		alerts, err := s.getFetchAlerts(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting fetch alerts", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"alerts": alerts})
+
+	case http.MethodPost:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+		var req struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+
+		_, err := s.db.ExecContext(r.Context(), `
+            UPDATE fetch_alerts SET dismissed_at = CURRENT_TIMESTAMP
+            WHERE dismissed_at IS NULL AND (? = 0 OR id = ?)`, req.ID, req.ID)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error dismissing fetch alert", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
@@ -7,9 +7,12 @@ import (
 	"encoding/json"
 	"expvar"
 	"fmt"
+	"infoscope/internal/database"
 	"infoscope/internal/feed"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -38,57 +41,134 @@ func (s *Server) getSettings(ctx context.Context) (map[string]string, error) {
 	return settings, rows.Err()
 }
 
-func (s *Server) getRecentEntries(ctx context.Context, limit int) ([]EntryView, error) {
+// getSetting returns a single setting value, or an empty string if it is unset
+func (s *Server) getSetting(ctx context.Context, key string) string {
+	var value string
+	if err := s.db.QueryRowContext(ctx, "SELECT value FROM settings WHERE key = ?", key).Scan(&value); err != nil {
+		return ""
+	}
+	return value
+}
+
+func (s *Server) getRecentEntries(ctx context.Context, filter RiverFilter, limit int) ([]EntryView, error) {
 	// Add debug logging
-	s.logger.Printf("Getting recent entries with limit: %d", limit)
+	s.logger.DebugContext(ctx, "Getting recent entries with limit", "limit", limit)
 
+	cond, args := filter.where()
+	dupCond, dupArgs := filter.withoutDuplicates()
+	cond += dupCond
+	args = append(args, dupArgs...)
 	rows, err := s.db.QueryContext(ctx, `
         SELECT 
             e.id,
+            e.feed_id,
             e.title,
             e.url,
             e.favicon_url,
+            COALESCE(f.category, ''),
+            COALESCE(f.language, ''),
             datetime(e.published_at) as date
         FROM entries e
         JOIN feeds f ON e.feed_id = f.id
-        WHERE f.status != 'deleted' 
-        ORDER BY e.published_at DESC
+        WHERE f.status != 'deleted' AND e.hidden_at IS NULL
+          AND (f.snoozed_until IS NULL OR f.snoozed_until <= datetime('now'))`+cond+`
+        ORDER BY e.published_at DESC, e.id DESC
         LIMIT ?
-    `, limit)
+    `, append(args, limit)...)
 	if err != nil {
 		return nil, fmt.Errorf("query error: %w", err)
 	}
 	defer rows.Close()
 
+	entries, err := scanEntryViews(rows, s.siteLocation(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	// Add debug logging
+	s.logger.DebugContext(ctx, "Found entries in query", "count", len(entries))
+	if len(entries) > 0 {
+		s.logger.DebugContext(ctx, "Sample entry", "entry", entries[0])
+	}
+
+	return entries, nil
+}
+
+// scanEntryViews reads rows of id, feed id, title, URL, favicon, category,
+// language and publish date into entry views, dated in loc.
+func scanEntryViews(rows *sql.Rows, loc *time.Location) ([]EntryView, error) {
 	var entries []EntryView
 	for rows.Next() {
 		var e EntryView
 		var dateStr string
-		if err := rows.Scan(&e.ID, &e.Title, &e.URL, &e.FaviconURL, &dateStr); err != nil {
+		if err := rows.Scan(&e.ID, &e.FeedID, &e.Title, &e.URL, &e.FaviconURL, &e.Category, &e.Language, &dateStr); err != nil {
 			return nil, fmt.Errorf("scan error: %w", err)
 		}
 		// Parse the date string
 		if date, err := time.Parse("2006-01-02 15:04:05", dateStr); err == nil {
-			e.Date = date.Format("Jan 02")
+			e.Date = date.In(loc).Format(entryDateLayout)
+			e.PublishedAt = date
+		}
+		if u, err := url.Parse(e.URL); err == nil {
+			e.Host = strings.TrimPrefix(u.Hostname(), "www.")
 		}
 		entries = append(entries, e)
 	}
+	return entries, rows.Err()
+}
 
-	// Add debug logging
-	s.logger.Printf("Found %d entries in query", len(entries))
-	if len(entries) > 0 {
-		s.logger.Printf("Sample entry: %+v", entries[0])
+// riverEntries selects the entries the river shows: the latest max_posts
+// entries, with the weekly roundup slotted in when it is enabled. The
+// roundup covers every feed, so filtered rivers leave it out.
+func (s *Server) riverEntries(ctx context.Context, settings map[string]string, filter RiverFilter) ([]EntryView, error) {
+	maxPosts := 33 // default
+	if maxStr, ok := settings["max_posts"]; ok {
+		if max, err := strconv.Atoi(maxStr); err == nil {
+			maxPosts = max
+		}
 	}
 
-	return entries, rows.Err()
+	entries, err := s.getRecentEntries(ctx, filter, maxPosts)
+	if err != nil {
+		return nil, err
+	}
+	if settings["weekly_roundup"] == "true" && filter.IsZero() {
+		entries = s.withRoundup(ctx, entries, settings, maxPosts)
+	}
+	return entries, nil
 }
 
 func (s *Server) getFeeds(ctx context.Context) ([]Feed, error) {
+	return s.queryFeeds(ctx, "")
+}
+
+// getFeed loads one feed as the admin sees it, or sql.ErrNoRows.
+func (s *Server) getFeed(ctx context.Context, id int64) (Feed, error) {
+	feeds, err := s.queryFeeds(ctx, "WHERE f.id = ?", id)
+	if err != nil {
+		return Feed{}, err
+	}
+	if len(feeds) == 0 {
+		return Feed{}, sql.ErrNoRows
+	}
+	return feeds[0], nil
+}
+
+// queryFeeds lists the feeds matching the where clause, ordered by title.
+func (s *Server) queryFeeds(ctx context.Context, where string, args ...any) ([]Feed, error) {
 	rows, err := s.db.QueryContext(ctx, `
-        SELECT id, url, title, datetime(last_fetched)
-        FROM feeds
-        ORDER BY title
-    `)
+        SELECT f.id, f.url, f.title, datetime(f.last_fetched), COALESCE(f.priority, 0),
+               COALESCE(b.bytes, 0), COALESCE(f.language, ''), COALESCE(f.region, ''),
+               COALESCE(f.locale_manual, 0), COALESCE(f.category, ''), COALESCE(f.tags, ''),
+               CASE WHEN f.snoozed_until > datetime('now') THEN datetime(f.snoozed_until) END,
+               COALESCE(f.fetch_interval_seconds, 0), COALESCE(f.status, ''),
+               COALESCE(f.full_content, 0), COALESCE(f.max_entries_per_feed, 0),
+               COALESCE(f.retention_days, 0)
+        FROM feeds f
+        LEFT JOIN feed_bandwidth b ON b.feed_id = f.id AND b.day = date('now')
+        `+where+`
+        ORDER BY f.title
+    `, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -97,8 +177,8 @@ func (s *Server) getFeeds(ctx context.Context) ([]Feed, error) {
 	var feeds []Feed
 	for rows.Next() {
 		var f Feed
-		var lastFetchedStr sql.NullString
-		if err := rows.Scan(&f.ID, &f.URL, &f.Title, &lastFetchedStr); err != nil {
+		var lastFetchedStr, snoozedUntilStr sql.NullString
+		if err := rows.Scan(&f.ID, &f.URL, &f.Title, &lastFetchedStr, &f.Priority, &f.BytesToday, &f.Language, &f.Region, &f.LocaleManual, &f.Category, &f.Tags, &snoozedUntilStr, &f.FetchIntervalSeconds, &f.Status, &f.FullContent, &f.MaxEntries, &f.RetentionDays); err != nil {
 			return nil, err
 		}
 		if lastFetchedStr.Valid {
@@ -106,52 +186,99 @@ func (s *Server) getFeeds(ctx context.Context) ([]Feed, error) {
 				f.LastFetched = date
 			}
 		}
+		if snoozedUntilStr.Valid {
+			if date, err := time.Parse("2006-01-02 15:04:05", snoozedUntilStr.String); err == nil {
+				f.SnoozedUntil = date
+			}
+		}
 		feeds = append(feeds, f)
 	}
 	return feeds, rows.Err()
 }
 
-func (s *Server) updateSettings(ctx context.Context, settings Settings) error {
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
+// settingUpdate is a setting as the settings form saves it
+type settingUpdate struct {
+	field string // the form's field name
+	value string
+	type_ string
+}
+
+// settingUpdates maps the settings form onto setting keys
+func settingUpdates(settings Settings) map[string]settingUpdate {
+	return map[string]settingUpdate{
+		"site_title":                {"siteTitle", settings.SiteTitle, "string"},
+		"max_posts":                 {"maxPosts", strconv.Itoa(settings.MaxPosts), "int"},
+		"retention_days":            {"retentionDays", strconv.Itoa(settings.RetentionDays), "int"},
+		"update_interval":           {"updateInterval", strconv.Itoa(settings.UpdateInterval), "int"},
+		"header_link_text":          {"headerLinkText", settings.HeaderLinkText, "string"},
+		"header_link_url":           {"headerLinkURL", settings.HeaderLinkURL, "string"},
+		"footer_link_text":          {"footerLinkText", settings.FooterLinkText, "string"},
+		"footer_link_url":           {"footerLinkURL", settings.FooterLinkURL, "string"},
+		"footer_image_height":       {"footerImageHeight", settings.FooterImageHeight, "string"},
+		"footer_image_url":          {"footerImageURL", settings.FooterImageURL, "string"},
+		"tracking_code":             {"trackingCode", settings.TrackingCode, "string"},
+		"favicon_url":               {"faviconURL", settings.FaviconURL, "string"},
+		"timezone":                  {"timezone", settings.Timezone, "string"},
+		"meta_description":          {"metaDescription", settings.MetaDescription, "string"},
+		"meta_image_url":            {"metaImageURL", settings.MetaImageURL, "string"},
+		"honor_dnt":                 {"honorDNT", strconv.FormatBool(settings.HonorDNT), "bool"},
+		"compact_mode":              {"compactMode", strconv.FormatBool(settings.CompactMode), "bool"},
+		"semantic_markup":           {"semanticMarkup", strconv.FormatBool(settings.SemanticMarkup), "bool"},
+		"clean_titles":              {"cleanTitles", strconv.FormatBool(settings.CleanTitles), "bool"},
+		"dedup_key":                 {"dedupKey", settings.DedupKey, "string"},
+		"cross_feed_dedup":          {"crossFeedDedup", settings.CrossFeedDedup, "string"},
+		"entry_update_mode":         {"entryUpdateMode", settings.EntryUpdateMode, "string"},
+		"visitor_muting":            {"visitorMuting", strconv.FormatBool(settings.VisitorMuting), "bool"},
+		"river_mode":                {"riverMode", settings.RiverMode, "string"},
+		"stats_api_token":           {"statsAPIToken", settings.StatsAPIToken, "string"},
+		"host_concurrency":          {"hostConcurrency", strconv.Itoa(settings.HostConcurrency), "int"},
+		"host_delay_ms":             {"hostDelayMS", strconv.Itoa(settings.HostDelayMS), "int"},
+		"daily_bandwidth_mb":        {"dailyBandwidthMB", strconv.Itoa(settings.DailyBandwidthMB), "int"},
+		"backup_schedule":           {"backupSchedule", settings.BackupSchedule, "string"},
+		"cache_index_ttl":           {"cacheIndexTTL", strconv.Itoa(settings.CacheIndexTTL), "int"},
+		"cache_static_ttl":          {"cacheStaticTTL", strconv.Itoa(settings.CacheStaticTTL), "int"},
+		"cache_api_max_age":         {"cacheAPIMaxAge", strconv.Itoa(settings.CacheAPIMaxAge), "int"},
+		"weekly_roundup":            {"weeklyRoundup", strconv.FormatBool(settings.WeeklyRoundup), "bool"},
+		"roundup_size":              {"roundupSize", strconv.Itoa(settings.RoundupSize), "int"},
+		"river_layout":              {"riverLayout", settings.RiverLayout, "string"},
+		"share_links":               {"shareLinks", strconv.FormatBool(settings.ShareLinks), "bool"},
+		"mastodon_instance":         {"mastodonInstance", settings.MastodonInstance, "string"},
+		"wayback_archive":           {"waybackArchive", strconv.FormatBool(settings.WaybackArchive), "bool"},
+		"archive_mode":              {"archiveMode", strconv.FormatBool(settings.ArchiveMode), "bool"},
+		"public_api":                {"publicAPI", strconv.FormatBool(settings.PublicAPI), "bool"},
+		"local_dates":               {"localDates", strconv.FormatBool(settings.LocalDates), "bool"},
+		"translation_backend":       {"translationBackend", settings.TranslationBackend, "string"},
+		"translation_url":           {"translationURL", strings.TrimSpace(settings.TranslationURL), "string"},
+		"translation_api_key":       {"translationAPIKey", settings.TranslationAPIKey, "string"},
+		"translation_language":      {"translationLanguage", settings.TranslationLanguage, "string"},
+		"alert_cycle_minutes":       {"alertCycleMinutes", strconv.Itoa(max(settings.AlertCycleMinutes, 0)), "int"},
+		"update_check_hours":        {"updateCheckHours", strconv.Itoa(max(settings.UpdateCheckHours, 0)), "int"},
+		"adaptive_polling":          {"adaptivePolling", strconv.FormatBool(settings.AdaptivePolling), "bool"},
+		"adaptive_poll_min_minutes": {"adaptivePollMinMinutes", strconv.Itoa(settings.AdaptivePollMinMinutes), "int"},
+		"adaptive_poll_max_minutes": {"adaptivePollMaxMinutes", strconv.Itoa(settings.AdaptivePollMaxMinutes), "int"},
+		"dead_feed_errors":          {"deadFeedErrors", strconv.Itoa(max(settings.DeadFeedErrors, 0)), "int"},
+		"click_half_life_days":      {"clickHalfLifeDays", strconv.Itoa(max(settings.ClickHalfLifeDays, 0)), "int"},
 	}
-	defer tx.Rollback()
+}
 
-	stmt, err := tx.PrepareContext(ctx,
-		"INSERT OR REPLACE INTO settings (key, value, type) VALUES (?, ?, ?)")
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-
-	updates := map[string]struct {
-		value string
-		type_ string
-	}{
-		"site_title":          {settings.SiteTitle, "string"},
-		"max_posts":           {strconv.Itoa(settings.MaxPosts), "int"},
-		"update_interval":     {strconv.Itoa(settings.UpdateInterval), "int"},
-		"header_link_text":    {settings.HeaderLinkText, "string"},
-		"header_link_url":     {settings.HeaderLinkURL, "string"},
-		"footer_link_text":    {settings.FooterLinkText, "string"},
-		"footer_link_url":     {settings.FooterLinkURL, "string"},
-		"footer_image_height": {settings.FooterImageHeight, "string"},
-		"footer_image_url":    {settings.FooterImageURL, "string"},
-		"tracking_code":       {settings.TrackingCode, "string"},
-		"favicon_url":         {settings.FaviconURL, "string"},
-		"timezone":            {settings.Timezone, "string"},
-		"meta_description":    {settings.MetaDescription, "string"},
-		"meta_image_url":      {settings.MetaImageURL, "string"},
-	}
-
-	for key, setting := range updates {
-		if _, err := stmt.ExecContext(ctx, key, setting.value, setting.type_); err != nil {
+func (s *Server) updateSettings(ctx context.Context, settings Settings) error {
+	updates := settingUpdates(settings)
+
+	return database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx,
+			"INSERT OR REPLACE INTO settings (key, value, type) VALUES (?, ?, ?)")
+		if err != nil {
 			return err
 		}
-	}
+		defer stmt.Close()
 
-	return tx.Commit()
+		for key, setting := range updates {
+			if _, err := stmt.ExecContext(ctx, key, setting.value, setting.type_); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // HTTP Handlers
@@ -160,67 +287,122 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	s.serveRiver(w, r, RiverFilter{})
+}
 
+// serveRiver renders the index page with the river narrowed by filter.
+func (s *Server) serveRiver(w http.ResponseWriter, r *http.Request, filter RiverFilter) {
 	// Debug logging
-	s.logger.Printf("Starting handleIndex...")
+	s.logger.DebugContext(r.Context(), "Starting handleIndex")
 
 	// Check if setup is needed
 	isFirstRun, err := IsFirstRun(s.db)
 	if err != nil {
-		s.logger.Printf("Error checking first run: %v", err)
+		s.logger.ErrorContext(r.Context(), "Error checking first run", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if isFirstRun {
+	if isFirstRun && !s.config.ReadOnly {
 		http.Redirect(w, r, "/setup", http.StatusSeeOther)
 		return
 	}
 
-	// Get CSRF token
-	csrfToken := s.csrf.Token(w, r)
+	// A page shared through a cache can't carry a visitor's CSRF token, so
+	// the page fetches one from /csrf when it needs it
+	var csrfToken string
+	if ttl, err := strconv.Atoi(s.getSetting(r.Context(), "cache_index_ttl")); err != nil || ttl <= 0 {
+		csrfToken = s.csrf.Token(w, r)
+	}
 
 	// Get settings with debug
 	settings, err := s.getSettings(r.Context())
 	if err != nil {
-		s.logger.Printf("Error getting settings: %v", err)
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	s.logger.Printf("Retrieved settings: %+v", settings)
-
-	// Get max posts setting
-	maxPosts := 33 // default
-	if maxStr, ok := settings["max_posts"]; ok {
-		if max, err := strconv.Atoi(maxStr); err == nil {
-			maxPosts = max
-		}
-	}
-	s.logger.Printf("Using maxPosts: %d", maxPosts)
+	s.logger.DebugContext(r.Context(), "Retrieved settings", "settings", settings)
 
 	// Debug database state
 	var feedCount, entryCount int
 	err = s.db.QueryRowContext(r.Context(), "SELECT COUNT(*) FROM feeds").Scan(&feedCount)
 	if err != nil {
-		s.logger.Printf("Error counting feeds: %v", err)
+		s.logger.ErrorContext(r.Context(), "Error counting feeds", "error", err)
 	}
 	err = s.db.QueryRowContext(r.Context(), "SELECT COUNT(*) FROM entries").Scan(&entryCount)
 	if err != nil {
-		s.logger.Printf("Error counting entries: %v", err)
+		s.logger.ErrorContext(r.Context(), "Error counting entries", "error", err)
 	}
-	s.logger.Printf("Database state: %d feeds, %d entries", feedCount, entryCount)
+	s.logger.DebugContext(r.Context(), "Database state", "feeds", feedCount, "entries", entryCount)
 
 	// Get entries with debug
-	entries, err := s.getRecentEntries(r.Context(), maxPosts)
+	entries, err := s.riverEntries(r.Context(), settings, filter)
 	if err != nil {
-		s.logger.Printf("Error getting entries: %v", err)
+		s.logger.ErrorContext(r.Context(), "Error getting entries", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	s.logger.Printf("Retrieved %d entries", len(entries))
+	s.logger.DebugContext(r.Context(), "Retrieved entries", "count", len(entries))
 
 	// Sample entry logging
 	if len(entries) > 0 {
-		s.logger.Printf("Sample entry: %+v", entries[0])
+		s.logger.DebugContext(r.Context(), "Sample entry", "entry", entries[0])
+	}
+
+	// Interleave feeds so no single source dominates, if enabled
+	if settings["river_mode"] == RiverShuffle {
+		entries = interleaveByFeed(entries, shuffleBucket)
+	}
+
+	// Pinned entries lead the front page, whatever the order below them
+	if filter.IsZero() {
+		pinned, err := s.getPinnedEntries(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting pinned entries", "error", err)
+		}
+		entries = withPinned(pinned, entries)
+	}
+
+	// Apply the visitor's own muted terms, if enabled
+	visitorMuting := settings["visitor_muting"] == "true"
+	var mutedTerms []string
+	if visitorMuting {
+		mutedTerms = mutedTermsFromRequest(r)
+		entries = filterMutedEntries(entries, mutedTerms)
+	}
+
+	// Compact mode drops per-entry favicons so text-only themes make no image requests
+	compactMode := settings["compact_mode"] == "true"
+	if compactMode {
+		for i := range entries {
+			entries[i].FaviconURL = ""
+		}
+	}
+
+	if settings["share_links"] == "true" {
+		withShareLinks(entries, settings["mastodon_instance"])
+	}
+	if err := s.withSources(r.Context(), entries); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting entry sources", "error", err)
+	}
+	markTranslatable(entries, settings)
+
+	// The grouped layout splits the river into a section per feed category
+	// and pinned entries get a section of their own above the rest
+	collapsed := collapsedSectionsFromRequest(r)
+	pinned, rest := splitPinned(entries)
+	sections := []RiverSection{{Entries: rest}}
+	if settings["river_layout"] == RiverGrouped {
+		sections = groupByCategory(rest, collapsed)
+	}
+	if len(pinned) > 0 {
+		sections = append([]RiverSection{{
+			Name:      "Pinned",
+			Key:       pinnedSectionKey,
+			Link:      "/starred",
+			Entries:   pinned,
+			Collapsed: collapsed[pinnedSectionKey],
+		}}, sections...)
 	}
 
 	data := IndexData{
@@ -229,26 +411,34 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		},
 		Title:             settings["site_title"],
 		Entries:           entries,
+		Sections:          sections,
 		HeaderLinkURL:     settings["header_link_url"],
 		HeaderLinkText:    settings["header_link_text"],
 		FooterLinkURL:     settings["footer_link_url"],
 		FooterLinkText:    settings["footer_link_text"],
 		FooterImageURL:    settings["footer_image_url"],
 		FooterImageHeight: settings["footer_image_height"],
+		FooterImagePx:     footerImagePixels(settings["footer_image_height"]),
 		TrackingCode:      settings["tracking_code"],
 		Settings:          settings,
 		SiteURL:           settings["site_url"],
+		HonorDNT:          settings["honor_dnt"] == "true",
+		CompactMode:       compactMode,
+		SemanticMarkup:    settings["semantic_markup"] == "true",
+		VisitorMuting:     visitorMuting,
+		MutedTerms:        mutedTerms,
+		Filter:            filter,
 	}
 
-	s.logger.Printf("Rendering template with data: %+v", data)
+	s.logger.DebugContext(r.Context(), "Rendering template with data", "data", data)
 
 	if err := s.renderTemplate(w, r, "index.html", data); err != nil {
-		s.logger.Printf("Error rendering template: %v", err)
+		s.logger.ErrorContext(r.Context(), "Error rendering template", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	s.logger.Printf("handleIndex completed successfully")
+	s.logger.DebugContext(r.Context(), "handleIndex completed successfully")
 }
 
 func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
@@ -257,7 +447,7 @@ func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
 	case http.MethodGet:
 		settings, err := s.getSettings(r.Context())
 		if err != nil {
-			s.logger.Printf("Error getting settings: %v", err)
+			s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
@@ -272,7 +462,7 @@ func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if err := s.renderTemplate(w, r, "admin/settings.html", data); err != nil {
-			s.logger.Printf("Error rendering settings template: %v", err)
+			s.logger.ErrorContext(r.Context(), "Error rendering settings template", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
@@ -284,26 +474,71 @@ func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
 
 		var settings Settings
 		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
-			http.Error(w, "Invalid request", http.StatusBadRequest)
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		if fields := validateSettingsForm(settings); len(fields) > 0 {
+			writeValidationError(w, "Invalid settings", fields)
+			return
+		}
+		if fields := validateTranslationSettings(&settings); len(fields) > 0 {
+			writeValidationError(w, "Invalid translation settings", fields)
+			return
+		}
+		if fields := validateAdaptivePolling(settings); len(fields) > 0 {
+			writeValidationError(w, "Invalid adaptive polling bounds", fields)
 			return
 		}
-
 		if err := s.updateSettings(r.Context(), settings); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			s.logger.ErrorContext(r.Context(), "Error updating settings", "error", err)
+			writeDBError(w, err)
 			return
 		}
 
 		w.WriteHeader(http.StatusOK)
 
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
+	}
+}
+
+// validateSettingsForm holds the settings form to the rules a backup import
+// is held to, and returns the invalid fields by their form name
+func validateSettingsForm(settings Settings) map[string]string {
+	updates := settingUpdates(settings)
+	values := make(map[string]string, len(updates))
+	for key, u := range updates {
+		values[key] = u.value
+	}
+	fields := make(map[string]string)
+	for key, message := range validateSettingValues(values) {
+		fields[updates[key].field] = message
 	}
+	return fields
+}
+
+// validateAdaptivePolling returns the adaptive polling bounds that are
+// invalid. The bounds are checked even while adaptive polling is off, so
+// turning it on later can't pick up nonsense.
+func validateAdaptivePolling(settings Settings) map[string]string {
+	fields := make(map[string]string)
+	minInterval := time.Duration(settings.AdaptivePollMinMinutes) * time.Minute
+	maxInterval := time.Duration(settings.AdaptivePollMaxMinutes) * time.Minute
+	if minInterval < feed.MinFetchInterval || minInterval > feed.MaxFetchInterval {
+		fields["adaptivePollMinMinutes"] = "must be between 1 and 10080"
+	}
+	if maxInterval < feed.MinFetchInterval || maxInterval > feed.MaxFetchInterval {
+		fields["adaptivePollMaxMinutes"] = "must be between 1 and 10080"
+	} else if maxInterval < minInterval {
+		fields["adaptivePollMaxMinutes"] = "must not be below the minimum"
+	}
+	return fields
 }
 
 // handles feed validation
 func (s *Server) handleFeedValidation(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
 		return
 	}
 
@@ -317,24 +552,56 @@ func (s *Server) handleFeedValidation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		writeDecodeError(w, err, "Invalid request")
 		return
 	}
 
 	// Validate the feed URL
 	validationResult, err := feed.ValidateFeedURL(req.URL)
 	if err != nil {
-		s.logger.Printf("Feed validation failed for %s: %v", req.URL, err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.logger.WarnContext(r.Context(), "Feed validation failed", "feed_url", req.URL, "error", err)
+
+		// Send back the diagnostics so the failure can be understood
+		apiErr := APIError{
+			Code:    codeValidation,
+			Message: err.Error(),
+			Fields:  map[string]string{"url": err.Error()},
+		}
+		if validationResult != nil {
+			apiErr.Details = validationResult
+		}
+		writeJSON(w, http.StatusBadRequest, apiErrorEnvelope{apiErr})
 		return
 	}
 
-	// Return validation result
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(validationResult); err != nil {
-		s.logger.Printf("Error encoding validation response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+	s.preparePreview(r.Context(), validationResult)
+
+	// Return validation result along with a suggested classification
+	writeJSON(w, http.StatusOK, struct {
+		*feed.FeedValidationResult
+		Suggestion CategorySuggestion `json:"suggestion"`
+	}{validationResult, s.suggestCategory(r.Context(), req.URL, validationResult)})
+}
+
+// preparePreview shows preview items the way they would be stored: titles
+// cleaned when clean_titles is on, and items beyond max_posts marked as
+// trimmed.
+func (s *Server) preparePreview(ctx context.Context, result *feed.FeedValidationResult) {
+	clean := s.getSetting(ctx, "clean_titles") == "true"
+	maxPosts, err := strconv.Atoi(s.getSetting(ctx, "max_posts"))
+	if err != nil || maxPosts <= 0 {
+		maxPosts = 33
+	}
+
+	for i := range result.Items {
+		item := &result.Items[i]
+		if clean {
+			if cleaned := feed.CleanTitle(item.Title, result.Title); cleaned != item.Title {
+				item.RawTitle = item.Title
+				item.Title = cleaned
+			}
+		}
+		item.Kept = i < maxPosts
 	}
 }
 
@@ -345,29 +612,48 @@ func (s *Server) handleFeeds(w http.ResponseWriter, r *http.Request) {
 	case http.MethodGet:
 		feeds, err := s.getFeeds(r.Context())
 		if err != nil {
-			s.logger.Printf("Error getting feeds: %v", err)
+			s.logger.ErrorContext(r.Context(), "Error getting feeds", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
 		settings, err := s.getSettings(r.Context())
 		if err != nil {
-			s.logger.Printf("Error getting settings: %v", err)
+			s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
 			settings = make(map[string]string)
 		}
 
+		categories, err := s.getCategories(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting categories", "error", err)
+		}
+
+		tagPriorities, err := s.getTagPriorities(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting tag priorities", "error", err)
+		}
+
+		tagRules, err := s.getTagRules(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting tag rules", "error", err)
+		}
+
 		data := AdminPageData{
 			BaseTemplateData: BaseTemplateData{
 				CSRFToken: csrfToken,
 			},
-			Title:    "Manage Feeds",
-			Active:   "feeds",
-			Settings: settings,
-			Feeds:    feeds,
+			Title:      "Manage Feeds",
+			Active:     "feeds",
+			Settings:   settings,
+			Feeds:      feeds,
+			Categories: categories,
+
+			TagPriorities: tagPriorities,
+			TagRules:      tagRules,
 		}
 
 		if err := s.renderTemplate(w, r, "admin/feeds.html", data); err != nil {
-			s.logger.Printf("Error rendering feeds template: %v", err)
+			s.logger.ErrorContext(r.Context(), "Error rendering feeds template", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
@@ -378,15 +664,17 @@ func (s *Server) handleFeeds(w http.ResponseWriter, r *http.Request) {
 		}
 
 		var req struct {
-			URL string `json:"url"`
+			URL      string `json:"url"`
+			Category string `json:"category"`
+			Tags     string `json:"tags"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request", http.StatusBadRequest)
+			writeDecodeError(w, err, "Invalid request")
 			return
 		}
 
-		if err := s.feedService.AddFeed(req.URL); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if _, err := s.addFeed(r.Context(), req.URL, req.Category, req.Tags); err != nil {
+			writeValidationError(w, err.Error(), map[string]string{"url": err.Error()})
 			return
 		}
 
@@ -401,47 +689,268 @@ func (s *Server) handleFeeds(w http.ResponseWriter, r *http.Request) {
 			ID int64 `json:"id"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request", http.StatusBadRequest)
+			writeDecodeError(w, err, "Invalid request")
 			return
 		}
 
 		if err := s.feedService.DeleteFeed(req.ID); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			s.logger.ErrorContext(r.Context(), "Error deleting feed", "feed_id", req.ID, "error", err)
+			writeInternalError(w)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodPatch:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+
+		var req struct {
+			ID int64 `json:"id"`
+			feedUpdate
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		if !s.applyFeedUpdate(w, r, req.ID, req.feedUpdate) {
 			return
 		}
 
 		w.WriteHeader(http.StatusOK)
 
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
+	}
+}
+
+// feedUpdate holds the feed fields a request may change; only the fields
+// present are applied
+type feedUpdate struct {
+	Priority *bool   `json:"priority"`
+	Language *string `json:"language"`
+	Region   *string `json:"region"`
+	Category *string `json:"category"`
+	Tags     *string `json:"tags"`
+
+	// SnoozedUntil is a date in the site timezone; empty wakes the feed
+	SnoozedUntil *string `json:"snoozedUntil"`
+
+	// FetchIntervalSeconds sets the feed's own fetch interval; 0 clears it
+	FetchIntervalSeconds *int `json:"fetchIntervalSeconds"`
+
+	// FullContent downloads each new entry's article in place of the
+	// feed's summary
+	FullContent *bool `json:"fullContent"`
+
+	// MaxEntries sets how many entries the feed keeps; 0 follows max_posts
+	MaxEntries *int `json:"maxEntries"`
+
+	// RetentionDays deletes the feed's entries past this age; 0 follows
+	// retention_days
+	RetentionDays *int `json:"retentionDays"`
+
+	// Revive re-enables a dead feed and clears its error count
+	Revive bool `json:"revive"`
+}
+
+// addFeed subscribes to a feed and files it under category and tags,
+// returning its ID. The returned error describes why the URL was rejected.
+func (s *Server) addFeed(ctx context.Context, feedURL, category, tags string) (int64, error) {
+	id, err := s.feedService.AddFeed(feedURL)
+	if err != nil {
+		return 0, err
+	}
+
+	// The stored address may differ from the one given
+	if err := s.db.QueryRowContext(ctx, "SELECT url FROM feeds WHERE id = ?", id).Scan(&feedURL); err != nil {
+		s.logger.ErrorContext(ctx, "Error reading added feed", "feed_id", id, "error", err)
+		return id, nil
 	}
+	category = strings.TrimSpace(category)
+	tags = strings.Join(normalizeTags(tags), ",")
+	if category != "" || tags != "" {
+		if _, err := s.db.ExecContext(ctx,
+			"UPDATE feeds SET category = NULLIF(?, ''), tags = NULLIF(?, '') WHERE id = ?",
+			category, tags, id); err != nil {
+			s.logger.ErrorContext(ctx, "Error saving category", "feed_url", feedURL, "error", err)
+		}
+	}
+	s.tagNewFeeds(ctx, feedURL)
+	return id, nil
+}
+
+// applyFeedUpdate saves the changes in u to a feed. On failure it writes
+// the error response and returns false.
+func (s *Server) applyFeedUpdate(w http.ResponseWriter, r *http.Request, id int64, u feedUpdate) bool {
+	if u.Priority != nil {
+		if _, err := s.db.ExecContext(r.Context(),
+			"UPDATE feeds SET priority = ? WHERE id = ?", *u.Priority, id); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error updating feed priority", "error", err)
+			writeDBError(w, err)
+			return false
+		}
+	}
+
+	if u.Language != nil {
+		// A manual language sticks; clearing it hands control back to auto-detection
+		tag := *u.Language
+		if u.Region != nil && *u.Region != "" {
+			tag += "-" + *u.Region
+		}
+		language, region := feed.ParseLocale(tag)
+		if language == "" && strings.TrimSpace(*u.Language) != "" {
+			writeValidationError(w, "Invalid language code",
+				map[string]string{"language": "not a recognised language code"})
+			return false
+		}
+		if _, err := s.db.ExecContext(r.Context(),
+			"UPDATE feeds SET language = ?, region = ?, locale_manual = ? WHERE id = ?",
+			language, region, language != "", id); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error updating feed language", "error", err)
+			writeDBError(w, err)
+			return false
+		}
+	}
+
+	if u.Category != nil || u.Tags != nil {
+		var category, tags sql.NullString
+		if u.Category != nil {
+			category = sql.NullString{String: strings.TrimSpace(*u.Category), Valid: true}
+		}
+		if u.Tags != nil {
+			tags = sql.NullString{String: strings.Join(normalizeTags(*u.Tags), ","), Valid: true}
+		}
+		if _, err := s.db.ExecContext(r.Context(), `
+            UPDATE feeds SET
+                category = CASE WHEN ? THEN NULLIF(?, '') ELSE category END,
+                tags = CASE WHEN ? THEN NULLIF(?, '') ELSE tags END
+            WHERE id = ?`,
+			category.Valid, category.String, tags.Valid, tags.String, id); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error updating feed category", "error", err)
+			writeDBError(w, err)
+			return false
+		}
+	}
+
+	if u.SnoozedUntil != nil {
+		var until sql.NullString
+		if date := strings.TrimSpace(*u.SnoozedUntil); date != "" {
+			t, err := time.ParseInLocation("2006-01-02", date, s.siteLocation(r.Context()))
+			if err != nil || !t.After(time.Now()) {
+				writeValidationError(w, "Snooze date must be in the future",
+					map[string]string{"snoozedUntil": "must be a future date"})
+				return false
+			}
+			until = sql.NullString{String: t.UTC().Format("2006-01-02 15:04:05"), Valid: true}
+		}
+		if _, err := s.db.ExecContext(r.Context(),
+			"UPDATE feeds SET snoozed_until = ? WHERE id = ?", until, id); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error snoozing feed", "error", err)
+			writeDBError(w, err)
+			return false
+		}
+	}
+
+	if u.FetchIntervalSeconds != nil {
+		interval := time.Duration(*u.FetchIntervalSeconds) * time.Second
+		if interval != 0 && (interval < feed.MinFetchInterval || interval > feed.MaxFetchInterval) {
+			writeValidationError(w, "Fetch interval must be between a minute and a week",
+				map[string]string{"fetchIntervalSeconds": "must be 0 or between 60 and 604800"})
+			return false
+		}
+		if _, err := s.db.ExecContext(r.Context(),
+			"UPDATE feeds SET fetch_interval_seconds = NULLIF(?, 0) WHERE id = ?",
+			*u.FetchIntervalSeconds, id); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error updating feed fetch interval", "error", err)
+			writeDBError(w, err)
+			return false
+		}
+	}
+
+	if u.FullContent != nil {
+		if _, err := s.db.ExecContext(r.Context(),
+			"UPDATE feeds SET full_content = ? WHERE id = ?", *u.FullContent, id); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error updating feed full content", "error", err)
+			writeDBError(w, err)
+			return false
+		}
+	}
+
+	if u.MaxEntries != nil {
+		if *u.MaxEntries < 0 || *u.MaxEntries > feed.MaxEntriesPerFeed {
+			writeValidationError(w, "Entries kept must be between 1 and 100000",
+				map[string]string{"maxEntries": "must be 0 or between 1 and 100000"})
+			return false
+		}
+		if _, err := s.db.ExecContext(r.Context(),
+			"UPDATE feeds SET max_entries_per_feed = NULLIF(?, 0) WHERE id = ?",
+			*u.MaxEntries, id); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error updating feed retention", "error", err)
+			writeDBError(w, err)
+			return false
+		}
+	}
+
+	if u.RetentionDays != nil {
+		if *u.RetentionDays < 0 || *u.RetentionDays > database.MaxRetentionDays {
+			writeValidationError(w, "Retention must be between 1 and 36500 days",
+				map[string]string{"retentionDays": "must be 0 or between 1 and 36500"})
+			return false
+		}
+		if _, err := s.db.ExecContext(r.Context(),
+			"UPDATE feeds SET retention_days = NULLIF(?, 0) WHERE id = ?",
+			*u.RetentionDays, id); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error updating feed retention window", "error", err)
+			writeDBError(w, err)
+			return false
+		}
+	}
+
+	if u.Revive {
+		err := database.WithTx(r.Context(), s.db, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(r.Context(), `
+                UPDATE feeds SET status = 'active', error_count = 0, last_error = NULL, next_retry_at = NULL
+                WHERE id = ? AND status = ?`, id, feed.StatusDead); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(r.Context(), `
+                UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+                WHERE feed_id = ? AND kind = ? AND read_at IS NULL`, id, feed.NotifyFeedDead)
+			return err
+		})
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error reviving feed", "error", err)
+			writeDBError(w, err)
+			return false
+		}
+	}
+	return true
 }
 
 // Handle Metrics
 
 func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
 		return
 	}
 
 	// Only allow authenticated users
 	if _, ok := getUserID(r.Context()); !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeAPIError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
 	metrics := map[string]interface{}{
 		"query_count":       dbQueryCount.String(),
 		"query_duration_ms": dbQueryDuration.String(),
+		"fetch_transport":   feed.GetTransportStats(),
+		"db_contention":     database.GetContentionStats(),
+		"db_pool":           poolStats(s.db.Stats()),
+		"http_panics":       httpPanics.Value(),
 	}
 
-	if err := json.NewEncoder(w).Encode(metrics); err != nil {
-		s.logger.Printf("Error encoding metrics: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+	writeJSON(w, http.StatusOK, metrics)
 }
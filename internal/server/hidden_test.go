@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHiddenEntryLeavesPublicPages(t *testing.T) {
+	h, db := newTestServerDB(t, nil)
+	if _, err := db.Exec("UPDATE entries SET hidden_at = CURRENT_TIMESTAMP WHERE title = 'First story'"); err != nil {
+		t.Fatalf("Failed to hide entry: %v", err)
+	}
+
+	for _, path := range []string{"/", "/atom.xml", "/feeds/1", "/feeds/1/rss.xml", "/category/News"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, rec.Code)
+			continue
+		}
+		body := rec.Body.String()
+		if strings.Contains(body, "First story") {
+			t.Errorf("GET %s shows the hidden entry", path)
+		}
+		if !strings.Contains(body, "Second story") {
+			t.Errorf("GET %s dropped the visible entry too", path)
+		}
+	}
+}
@@ -2,46 +2,45 @@
 package server
 
 import (
+	"context"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"sort"
 	"strings"
-	"time"
+	"sync"
+
+	"infoscope/internal/storage"
 )
 
 var ErrInvalidFileType = errors.New("invalid file type")
 
 const (
 	maxUploadSize = 5 << 20 // 5 MB
-	imagesDir     = "web/static/images"
 )
 
+// ImageHandler keeps uploaded images in store, favicons under favicon/ and
+// resized copies under variants/
 type ImageHandler struct {
 	db        *sql.DB
-	logger    *log.Logger
-	uploadDir string
+	logger    *slog.Logger
+	store     storage.Store
+	variantMu sync.Mutex
 }
 
-func NewImageHandler(db *sql.DB, logger *log.Logger) (*ImageHandler, error) {
-	// Ensure upload directory exists
-	if err := os.MkdirAll(imagesDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create image directory: %w", err)
-	}
-
+func NewImageHandler(db *sql.DB, logger *slog.Logger, store storage.Store) *ImageHandler {
 	return &ImageHandler{
-		db:        db,
-		logger:    logger,
-		uploadDir: imagesDir,
-	}, nil
+		db:     db,
+		logger: logger,
+		store:  store,
+	}
 }
 
 // ValidateFile checks if an uploaded file is allowed
@@ -82,15 +81,12 @@ func (h *ImageHandler) validateFile(file *multipart.FileHeader) error {
 
 func (h *ImageHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
 		return
 	}
 
-	// Parse multipart form with size limit
-	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
-		h.logger.Printf("File size error: %v", err)
-		http.Error(w, fmt.Sprintf("File too large (max %d MB)", maxUploadSize/(1<<20)),
-			http.StatusBadRequest)
+	// The body cap comes from limitBodies
+	if !parseUpload(w, r) {
 		return
 	}
 	defer r.MultipartForm.RemoveAll()
@@ -98,32 +94,32 @@ func (h *ImageHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 	// Get the file
 	file, header, err := r.FormFile("image")
 	if err != nil {
-		h.logger.Printf("Error getting file: %v", err)
-		http.Error(w, "Invalid file upload", http.StatusBadRequest)
+		h.logger.ErrorContext(r.Context(), "Error getting file", "error", err)
+		writeValidationError(w, "Invalid file upload", map[string]string{"image": "missing or unreadable"})
 		return
 	}
 	defer file.Close()
 
 	// Validate the file
 	if err := h.validateFile(header); err != nil {
-		h.logger.Printf("File validation error: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "File validation error", "error", err)
+		writeValidationError(w, err.Error(), map[string]string{"image": err.Error()})
 		return
 	}
 
 	// Save the file
-	filename, err := h.saveImage(file, header)
+	filename, err := h.saveImage(r.Context(), file, header)
 	if err != nil {
-		h.logger.Printf("Error saving file: %v", err)
-		http.Error(w, "Failed to save image", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Error saving file", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to save image")
 		return
 	}
 
 	// Start transaction to update settings
 	tx, err := h.db.Begin()
 	if err != nil {
-		h.logger.Printf("Error starting transaction: %v", err)
-		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Error starting transaction", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to update settings")
 		return
 	}
 	defer tx.Rollback()
@@ -134,14 +130,14 @@ func (h *ImageHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 		filename,
 	)
 	if err != nil {
-		h.logger.Printf("Error updating settings: %v", err)
-		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Error updating settings", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to update settings")
 		return
 	}
 
 	if err := tx.Commit(); err != nil {
-		h.logger.Printf("Error committing transaction: %v", err)
-		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Error committing transaction", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to update settings")
 		return
 	}
 
@@ -149,11 +145,10 @@ func (h *ImageHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 	go h.cleanupOldImages()
 
 	// Return the filename
-	w.Header().Set("Content-Type", "text/plain")
-	fmt.Fprint(w, filename)
+	writeJSON(w, http.StatusOK, map[string]string{"filename": filename})
 }
 
-func (h *ImageHandler) saveImage(file multipart.File, header *multipart.FileHeader) (string, error) {
+func (h *ImageHandler) saveImage(ctx context.Context, file multipart.File, header *multipart.FileHeader) (string, error) {
 	// Read file content
 	content, err := io.ReadAll(file)
 	if err != nil {
@@ -166,8 +161,7 @@ func (h *ImageHandler) saveImage(file multipart.File, header *multipart.FileHead
 	filename := hex.EncodeToString(hash[:8]) + ext
 
 	// Save file
-	path := filepath.Join(h.uploadDir, filename)
-	if err := os.WriteFile(path, content, 0644); err != nil {
+	if err := h.store.Put(ctx, filename, content); err != nil {
 		return "", fmt.Errorf("error writing file: %w", err)
 	}
 
@@ -175,10 +169,12 @@ func (h *ImageHandler) saveImage(file multipart.File, header *multipart.FileHead
 }
 
 func (h *ImageHandler) cleanupOldImages() {
+	ctx := context.Background()
+
 	// Get list of images ordered by modification time
-	files, err := filepath.Glob(filepath.Join(h.uploadDir, "*"))
+	files, err := h.store.List(ctx, "")
 	if err != nil {
-		h.logger.Printf("Error listing images during cleanup: %v", err)
+		h.logger.Error("Error listing images during cleanup", "error", err)
 		return
 	}
 
@@ -187,49 +183,70 @@ func (h *ImageHandler) cleanupOldImages() {
 		return
 	}
 
-	// Sort files by modification time
-	type fileInfo struct {
-		path    string
-		modTime time.Time
+	// Never remove an image a setting still points at
+	refs, err := h.referencedImages()
+	if err != nil {
+		h.logger.Error("Error reading image references during cleanup", "error", err)
+		return
 	}
-	fileInfos := make([]fileInfo, 0, len(files))
+
+	// Sort files by modification time, skipping default.ico and images in use
+	candidates := make([]storage.Object, 0, len(files))
 	for _, file := range files {
-		info, err := os.Stat(file)
-		if err != nil {
-			continue
-		}
-		// Skip default.ico
-		if filepath.Base(file) == "default.ico" {
+		if file.Name == "default.ico" || len(refs[file.Name]) > 0 {
 			continue
 		}
-		fileInfos = append(fileInfos, fileInfo{file, info.ModTime()})
+		candidates = append(candidates, file)
 	}
 
-	sort.Slice(fileInfos, func(i, j int) bool {
-		return fileInfos[i].modTime.After(fileInfos[j].modTime)
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].ModTime.After(candidates[j].ModTime)
 	})
 
-	// Remove old files
-	for _, fi := range fileInfos[10:] {
-		if err := os.Remove(fi.path); err != nil {
-			h.logger.Printf("Error removing old image %s: %v", fi.path, err)
+	if len(candidates) <= 10 {
+		return
+	}
+
+	// Remove old files and their cached variants
+	for _, file := range candidates[10:] {
+		if err := h.store.Delete(ctx, file.Name); err != nil {
+			h.logger.Error("Error removing old image", "name", file.Name, "error", err)
+		}
+		h.deleteVariants(ctx, file.Name)
+	}
+}
+
+// deleteVariants removes the cached resized copies of an image
+func (h *ImageHandler) deleteVariants(ctx context.Context, name string) {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	variants, err := h.store.List(ctx, variantsDir)
+	if err != nil {
+		h.logger.Error("Error listing image variants", "name", name, "error", err)
+		return
+	}
+	for _, v := range variants {
+		if strings.HasPrefix(v.Name, base+"-") {
+			h.store.Delete(ctx, variantsDir+"/"+v.Name)
 		}
 	}
 }
 
 func (h *ImageHandler) HandleFaviconUpload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
 		return
 	}
 
-	// Max file size 1MB
-	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	// The default body cap of 1MB applies
+	if !parseUpload(w, r) {
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
 
 	file, header, err := r.FormFile("favicon")
 	if err != nil {
-		h.logger.Printf("Error getting uploaded file: %v", err)
-		http.Error(w, "Failed to get uploaded file", http.StatusBadRequest)
+		h.logger.ErrorContext(r.Context(), "Error getting uploaded file", "error", err)
+		writeValidationError(w, "Failed to get uploaded file", map[string]string{"favicon": "missing or unreadable"})
 		return
 	}
 	defer file.Close()
@@ -237,8 +254,8 @@ func (h *ImageHandler) HandleFaviconUpload(w http.ResponseWriter, r *http.Reques
 	// Validate file type
 	contentType := header.Header.Get("Content-Type")
 	if !isValidFaviconType(contentType) {
-		h.logger.Printf("Invalid favicon type: %s", contentType)
-		http.Error(w, "Invalid file type. Must be ICO, PNG", http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "Invalid favicon type", "content_type", contentType)
+		writeValidationError(w, "Invalid file type. Must be ICO, PNG", map[string]string{"favicon": "must be ICO or PNG"})
 		return
 	}
 
@@ -251,27 +268,26 @@ func (h *ImageHandler) HandleFaviconUpload(w http.ResponseWriter, r *http.Reques
 	// Read and hash file
 	content, err := io.ReadAll(file)
 	if err != nil {
-		h.logger.Printf("Error reading file: %v", err)
-		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Error reading file", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to read file")
 		return
 	}
 
 	hash := sha256.Sum256(content)
 	filename := fmt.Sprintf("favicon_%s%s", hex.EncodeToString(hash[:8]), ext)
-	filepath := filepath.Join(h.uploadDir, "favicon", filename)
 
 	// Save file
-	if err := os.WriteFile(filepath, content, 0644); err != nil {
-		h.logger.Printf("Error saving file: %v", err)
-		http.Error(w, "Failed to save file", http.StatusInternalServerError)
+	if err := h.store.Put(r.Context(), "favicon/"+filename, content); err != nil {
+		h.logger.ErrorContext(r.Context(), "Error saving file", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to save file")
 		return
 	}
 
 	// Update database
 	tx, err := h.db.Begin()
 	if err != nil {
-		h.logger.Printf("Error starting transaction: %v", err)
-		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Error starting transaction", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to update settings")
 		return
 	}
 	defer tx.Rollback()
@@ -281,19 +297,18 @@ func (h *ImageHandler) HandleFaviconUpload(w http.ResponseWriter, r *http.Reques
 		filename,
 	)
 	if err != nil {
-		h.logger.Printf("Error updating settings: %v", err)
-		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Error updating settings", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to update settings")
 		return
 	}
 
 	if err := tx.Commit(); err != nil {
-		h.logger.Printf("Error committing transaction: %v", err)
-		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Error committing transaction", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to update settings")
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/plain")
-	fmt.Fprint(w, filename)
+	writeJSON(w, http.StatusOK, map[string]string{"filename": filename})
 }
 
 func isValidFaviconType(contentType string) bool {
@@ -305,17 +320,14 @@ func isValidFaviconType(contentType string) bool {
 // handle meta image upload
 
 func (h *ImageHandler) HandleMetaImageUpload(w http.ResponseWriter, r *http.Request) {
-	h.logger.Printf("Content-Type: %s", r.Header.Get("Content-Type"))
+	h.logger.DebugContext(r.Context(), "Meta image upload", "content_type", r.Header.Get("Content-Type"))
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
 		return
 	}
 
-	// Parse multipart form with size limit
-	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
-		h.logger.Printf("File size error: %v", err)
-		http.Error(w, fmt.Sprintf("File too large (max %d MB)", maxUploadSize/(1<<20)),
-			http.StatusBadRequest)
+	// The body cap comes from limitBodies
+	if !parseUpload(w, r) {
 		return
 	}
 	defer r.MultipartForm.RemoveAll()
@@ -323,32 +335,32 @@ func (h *ImageHandler) HandleMetaImageUpload(w http.ResponseWriter, r *http.Requ
 	// Get the file
 	file, header, err := r.FormFile("image")
 	if err != nil {
-		h.logger.Printf("Error getting file: %v", err)
-		http.Error(w, "Invalid file upload", http.StatusBadRequest)
+		h.logger.ErrorContext(r.Context(), "Error getting file", "error", err)
+		writeValidationError(w, "Invalid file upload", map[string]string{"image": "missing or unreadable"})
 		return
 	}
 	defer file.Close()
 
 	// Validate the file
 	if err := h.validateFile(header); err != nil {
-		h.logger.Printf("File validation error: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "File validation error", "error", err)
+		writeValidationError(w, err.Error(), map[string]string{"image": err.Error()})
 		return
 	}
 
 	// Save the file
-	filename, err := h.saveImage(file, header)
+	filename, err := h.saveImage(r.Context(), file, header)
 	if err != nil {
-		h.logger.Printf("Error saving file: %v", err)
-		http.Error(w, "Failed to save image", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Error saving file", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to save image")
 		return
 	}
 
 	// Update settings in database
 	tx, err := h.db.Begin()
 	if err != nil {
-		h.logger.Printf("Error starting transaction: %v", err)
-		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Error starting transaction", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to update settings")
 		return
 	}
 	defer tx.Rollback()
@@ -358,17 +370,16 @@ func (h *ImageHandler) HandleMetaImageUpload(w http.ResponseWriter, r *http.Requ
 		filename,
 	)
 	if err != nil {
-		h.logger.Printf("Error updating settings: %v", err)
-		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Error updating settings", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to update settings")
 		return
 	}
 
 	if err := tx.Commit(); err != nil {
-		h.logger.Printf("Error committing transaction: %v", err)
-		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Error committing transaction", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to update settings")
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/plain")
-	fmt.Fprint(w, filename)
+	writeJSON(w, http.StatusOK, map[string]string{"filename": filename})
 }
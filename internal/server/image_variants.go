@@ -0,0 +1,187 @@
+// internal/server/image_variants.go
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"infoscope/internal/storage"
+	"infoscope/internal/webp"
+
+	"golang.org/x/image/draw"
+)
+
+// variantHeights are the heights images are resized to; requests are
+// rounded up to the next entry so the cache stays small
+var variantHeights = []int{32, 48, 64, 96, 128, 160, 192, 256, 320, 384, 512, 768, 1024}
+
+const variantsDir = "variants"
+
+// ServeImage serves an uploaded image, resized to ?h= pixels high and as
+// WebP when the client accepts it and it is smaller than the original format.
+// Variants are generated on first request and kept in the image store.
+func (h *ImageHandler) ServeImage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/media/")
+	if name == "" || name != path.Base(name) || strings.HasPrefix(name, ".") {
+		http.NotFound(w, r)
+		return
+	}
+	source, err := h.store.Stat(r.Context(), name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	height := 0
+	if v, err := strconv.Atoi(r.URL.Query().Get("h")); err == nil && v > 0 {
+		height = snapVariantHeight(v)
+	}
+
+	w.Header().Set("Vary", "Accept")
+	w.Header().Set("Cache-Control", "public, max-age=604800")
+
+	serve := name
+	ext := strings.ToLower(path.Ext(name))
+	if ext == ".png" || ext == ".jpg" || ext == ".jpeg" {
+		wantWebP := strings.Contains(r.Header.Get("Accept"), "image/webp")
+		if variant, err := h.imageVariant(r.Context(), name, source.ModTime, height, wantWebP); err != nil {
+			h.logger.ErrorContext(r.Context(), "Error generating variant", "name", name, "error", err)
+		} else {
+			serve = variant
+		}
+	}
+
+	data, obj, err := h.store.Get(r.Context(), serve)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if strings.HasSuffix(serve, ".webp") {
+		w.Header().Set("Content-Type", "image/webp")
+	}
+	http.ServeContent(w, r, path.Base(serve), obj.ModTime, bytes.NewReader(data))
+}
+
+// imageVariant returns the name of the best stored variant, generating the
+// resized original-format and WebP files if they are missing or stale
+func (h *ImageHandler) imageVariant(ctx context.Context, source string, modTime time.Time, height int, wantWebP bool) (string, error) {
+	h.variantMu.Lock()
+	defer h.variantMu.Unlock()
+
+	ext := strings.ToLower(path.Ext(source))
+	base := strings.TrimSuffix(source, path.Ext(source))
+	size := "orig"
+	if height > 0 {
+		size = strconv.Itoa(height)
+	}
+
+	fallback := fmt.Sprintf("%s/%s-%s%s", variantsDir, base, size, ext)
+	webpName := fmt.Sprintf("%s/%s-%s.webp", variantsDir, base, size)
+	if height == 0 {
+		fallback = source
+	}
+
+	fallbackObj, fallbackFresh := h.freshVariant(ctx, fallback, modTime)
+	webpObj, webpFresh := h.freshVariant(ctx, webpName, modTime)
+	if fallbackFresh && (!wantWebP || webpFresh) {
+		return pickSmaller(fallback, webpName, fallbackObj, webpObj, wantWebP && webpFresh), nil
+	}
+
+	data, _, err := h.store.Get(ctx, source)
+	if err != nil {
+		return "", err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("error decoding image: %w", err)
+	}
+
+	if b := img.Bounds(); height > 0 && height < b.Dy() {
+		width := b.Dx() * height / b.Dy()
+		if width < 1 {
+			width = 1
+		}
+		resized := image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(resized, resized.Bounds(), img, b, draw.Src, nil)
+		img = resized
+	}
+
+	if fallback != source {
+		var buf bytes.Buffer
+		if ext == ".png" {
+			err = png.Encode(&buf, img)
+		} else {
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := h.store.Put(ctx, fallback, buf.Bytes()); err != nil {
+			return "", err
+		}
+		fallbackObj.Size = int64(buf.Len())
+	}
+
+	if wantWebP {
+		var buf bytes.Buffer
+		if err := webp.Encode(&buf, img); err != nil {
+			return "", err
+		}
+		if err := h.store.Put(ctx, webpName, buf.Bytes()); err != nil {
+			return "", err
+		}
+		webpObj.Size = int64(buf.Len())
+	}
+
+	return pickSmaller(fallback, webpName, fallbackObj, webpObj, wantWebP), nil
+}
+
+// freshVariant reports a stored file and whether it is at least as new as
+// its source
+func (h *ImageHandler) freshVariant(ctx context.Context, name string, sourceModTime time.Time) (storage.Object, bool) {
+	obj, err := h.store.Stat(ctx, name)
+	return obj, err == nil && !obj.ModTime.Before(sourceModTime)
+}
+
+// snapVariantHeight rounds a requested height up to a supported variant
+func snapVariantHeight(height int) int {
+	for _, v := range variantHeights {
+		if height <= v {
+			return v
+		}
+	}
+	return variantHeights[len(variantHeights)-1]
+}
+
+// pickSmaller prefers the WebP variant only when it actually saves bytes
+func pickSmaller(fallback, webpName string, fallbackObj, webpObj storage.Object, wantWebP bool) string {
+	if !wantWebP || webpObj.Size >= fallbackObj.Size {
+		return fallback
+	}
+	return webpName
+}
+
+// footerImagePixels extracts a pixel height from the footer image height
+// setting, or 0 when it is not expressed in pixels
+func footerImagePixels(setting string) int {
+	px, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(setting), "px"))
+	if err != nil || px <= 0 {
+		return 0
+	}
+	return px
+}
@@ -0,0 +1,70 @@
+// internal/server/markdown.go
+package server
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// Inline markdown, matched after the text has been HTML escaped
+var (
+	mdCode   = regexp.MustCompile("`([^`]+)`")
+	mdLink   = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^\s)]+)\)`)
+	mdStrong = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	mdEm     = regexp.MustCompile(`\*([^*]+)\*`)
+)
+
+// renderMarkdown renders the small markdown subset used for notes:
+// paragraphs, "- " lists, **bold**, *italics*, `code` and http(s) links.
+// Any HTML in the source is escaped, so the result is safe to embed.
+func renderMarkdown(src string) template.HTML {
+	var b strings.Builder
+	var paragraph []string
+	inList := false
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + strings.Join(paragraph, "<br>") + "</p>\n")
+			paragraph = nil
+		}
+		if inList {
+			b.WriteString("</ul>\n")
+			inList = false
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* "):
+			if len(paragraph) > 0 {
+				flush()
+			}
+			if !inList {
+				b.WriteString("<ul>\n")
+				inList = true
+			}
+			b.WriteString("<li>" + renderInlineMarkdown(line[2:]) + "</li>\n")
+		default:
+			if inList {
+				flush()
+			}
+			paragraph = append(paragraph, renderInlineMarkdown(line))
+		}
+	}
+	flush()
+
+	return template.HTML(b.String())
+}
+
+func renderInlineMarkdown(text string) string {
+	text = html.EscapeString(text)
+	text = mdCode.ReplaceAllString(text, "<code>$1</code>")
+	text = mdLink.ReplaceAllString(text, `<a href="$2" rel="nofollow noopener">$1</a>`)
+	text = mdStrong.ReplaceAllString(text, "<strong>$1</strong>")
+	return mdEm.ReplaceAllString(text, "<em>$1</em>")
+}
@@ -0,0 +1,167 @@
+// internal/server/media.go
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// mediaSettings are the settings that can reference an uploaded image, and
+// the directory of the image store their files live in
+var mediaSettings = []struct {
+	key, dir string
+}{
+	{"footer_image_url", ""},
+	{"meta_image_url", ""},
+	{"favicon_url", "favicon"},
+}
+
+// MediaFile is an uploaded image shown on the media page
+type MediaFile struct {
+	Name    string
+	Dir     string
+	URL     string
+	Size    int64
+	ModTime time.Time
+	UsedBy  []string
+}
+
+type MediaPageData struct {
+	Title    string
+	Active   string
+	Settings map[string]string
+	Files    []MediaFile
+}
+
+// referencedImages maps names in the image store to the settings that
+// currently point at them
+func (h *ImageHandler) referencedImages() (map[string][]string, error) {
+	refs := make(map[string][]string)
+	for _, ms := range mediaSettings {
+		var value string
+		err := h.db.QueryRow("SELECT value FROM settings WHERE key = ?", ms.key).Scan(&value)
+		if err != nil || value == "" {
+			continue
+		}
+		rel := path.Join(ms.dir, value)
+		refs[rel] = append(refs[rel], ms.key)
+	}
+	return refs, nil
+}
+
+// listMedia returns uploaded images, newest first
+func (h *ImageHandler) listMedia(ctx context.Context) ([]MediaFile, error) {
+	refs, err := h.referencedImages()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []MediaFile
+	for _, dir := range []string{"", "favicon"} {
+		objects, err := h.store.List(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objects {
+			rel := path.Join(dir, obj.Name)
+			files = append(files, MediaFile{
+				Name:    obj.Name,
+				Dir:     dir,
+				URL:     "/static/images/" + rel,
+				Size:    obj.Size,
+				ModTime: obj.ModTime,
+				UsedBy:  refs[rel],
+			})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime.After(files[j].ModTime)
+	})
+	return files, nil
+}
+
+// deleteMedia removes an unreferenced upload and its cached variants
+func (h *ImageHandler) deleteMedia(ctx context.Context, dir, name string) error {
+	if name == "" || name != path.Base(name) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid file name")
+	}
+	if dir != "" && dir != "favicon" {
+		return fmt.Errorf("invalid directory")
+	}
+
+	refs, err := h.referencedImages()
+	if err != nil {
+		return err
+	}
+	rel := path.Join(dir, name)
+	if used := refs[rel]; len(used) > 0 {
+		return fmt.Errorf("image is in use by %s", strings.Join(used, ", "))
+	}
+
+	if err := h.store.Delete(ctx, rel); err != nil {
+		return err
+	}
+	h.deleteVariants(ctx, name)
+	return nil
+}
+
+func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		files, err := s.imageHandler.listMedia(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error listing media", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		settings, err := s.getSettings(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+			settings = make(map[string]string)
+		}
+
+		data := MediaPageData{
+			Title:    "Media",
+			Active:   "media",
+			Settings: settings,
+			Files:    files,
+		}
+		if err := s.renderTemplate(w, r, "admin/media.html", data); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error rendering media template", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+
+	case http.MethodDelete:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+
+		var req struct {
+			Name string `json:"name"`
+			Dir  string `json:"dir"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+
+		if err := s.imageHandler.deleteMedia(r.Context(), req.Dir, req.Name); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error deleting media", "name", req.Name, "error", err)
+			writeValidationError(w, err.Error(), map[string]string{"name": err.Error()})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
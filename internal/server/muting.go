@@ -0,0 +1,116 @@
+// internal/server/muting.go
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	mutedTermsCookie = "muted_terms"
+	maxMutedTerms    = 20
+	maxMutedTermLen  = 40
+)
+
+// parseMutedTerms normalizes a comma separated list of visitor muted terms,
+// dropping empty, duplicate and oversized terms
+func parseMutedTerms(raw string) []string {
+	seen := make(map[string]bool)
+	terms := make([]string, 0)
+	for _, term := range strings.Split(raw, ",") {
+		term = strings.ToLower(strings.Join(strings.Fields(term), " "))
+		if term == "" || len([]rune(term)) > maxMutedTermLen || seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+		if len(terms) == maxMutedTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// mutedTermsFromRequest reads and re-validates the visitor's muted terms cookie
+func mutedTermsFromRequest(r *http.Request) []string {
+	cookie, err := r.Cookie(mutedTermsCookie)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+	raw, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return nil
+	}
+	return parseMutedTerms(raw)
+}
+
+// filterMutedEntries removes entries whose title contains any muted term
+func filterMutedEntries(entries []EntryView, terms []string) []EntryView {
+	if len(terms) == 0 {
+		return entries
+	}
+	filtered := entries[:0]
+	for _, e := range entries {
+		title := strings.ToLower(e.Title)
+		muted := false
+		for _, term := range terms {
+			if strings.Contains(title, term) {
+				muted = true
+				break
+			}
+		}
+		if !muted {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// handleMute stores the visitor's muted terms in a cookie. It only affects the
+// requesting visitor's view of the river and never touches global settings.
+func (s *Server) handleMute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	if !s.csrf.Validate(w, r) {
+		return
+	}
+
+	if s.getSetting(r.Context(), "visitor_muting") != "true" {
+		writeAPIError(w, http.StatusForbidden, codeForbidden, "Muting is disabled")
+		return
+	}
+
+	var req struct {
+		Terms string `json:"terms"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil {
+		writeDecodeError(w, err, "Invalid request")
+		return
+	}
+
+	terms := parseMutedTerms(req.Terms)
+	cookie := &http.Cookie{
+		Name:     mutedTermsCookie,
+		Value:    url.QueryEscape(strings.Join(terms, ",")),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   s.csrf.config.Secure,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now().AddDate(1, 0, 0),
+	}
+	if len(terms) == 0 {
+		cookie.Value = ""
+		cookie.MaxAge = -1
+		cookie.Expires = time.Time{}
+	}
+	http.SetCookie(w, cookie)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"terms": terms})
+}
@@ -0,0 +1,85 @@
+// internal/server/notifications.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Notification is an event queued for the admin, such as a feed being
+// disabled after repeated errors
+type Notification struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"`
+	FeedID    int64     `json:"feedId,omitempty"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// getNotifications returns the unread notifications, newest first.
+func (s *Server) getNotifications(ctx context.Context) ([]Notification, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, kind, feed_id, message, created_at
+        FROM notifications
+        WHERE read_at IS NULL
+        ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]Notification, 0)
+	for rows.Next() {
+		var n Notification
+		var feedID sql.NullInt64
+		if err := rows.Scan(&n.ID, &n.Kind, &feedID, &n.Message, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		n.FeedID = feedID.Int64
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// handleNotifications lists unread notifications on GET and marks one, or
+// all when no ID is given, as read on POST.
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		notifications, err := s.getNotifications(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting notifications", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"notifications": notifications})
+
+	case http.MethodPost:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+		var req struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+
+		_, err := s.db.ExecContext(r.Context(), `
+            UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+            WHERE read_at IS NULL AND (? = 0 OR id = ?)`, req.ID, req.ID)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error marking notification read", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
@@ -0,0 +1,329 @@
+// internal/server/openapi.go
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiSchemas are the types described under components/schemas, by name.
+// Schemas are generated from the Go types, so the document can't drift
+// from what the handlers encode.
+var apiSchemas = []struct {
+	name string
+	v    any
+}{
+	{"Error", apiErrorEnvelope{}},
+	{"Feed", Feed{}},
+	{"FeedStats", FeedStats{}},
+	{"FeedUpdate", feedUpdate{}},
+	{"Entry", APIEntry{}},
+	{"Tag", TagPriority{}},
+	{"TagRule", TagRule{}},
+	{"TagRuleBackfill", TagRuleBackfillStatus{}},
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  map[string]any
+)
+
+// handleOpenAPI serves the OpenAPI 3 description of /api/v1. It needs no
+// token, so clients can be generated before one is issued.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeMethodNotAllowed(w)
+		return
+	}
+	openAPIOnce.Do(func() { openAPIDoc = openAPISpec() })
+	writeJSON(w, http.StatusOK, openAPIDoc)
+}
+
+// openAPISpec builds the API description
+func openAPISpec() map[string]any {
+	refs := make(map[reflect.Type]string)
+	for _, s := range apiSchemas {
+		refs[reflect.TypeOf(s.v)] = s.name
+	}
+	schemas := make(map[string]any)
+	for _, s := range apiSchemas {
+		schemas[s.name] = structSchema(reflect.TypeOf(s.v), refs)
+	}
+	// A feed update only carries the fields being changed
+	delete(schemas["FeedUpdate"].(map[string]any), "required")
+
+	idParam := apiPathParam("id", "integer")
+	tagParam := apiPathParam("tag", "string")
+	feed := schemaWrapper("feed", schemaRef("Feed"))
+	entry := schemaWrapper("entry", schemaRef("Entry"))
+	tag := schemaWrapper("tag", schemaRef("Tag"))
+	rule := schemaWrapper("rule", schemaRef("TagRule"))
+
+	paths := map[string]any{
+		"/feeds": map[string]any{
+			"get": apiOperation("listFeeds", "List feeds", nil, nil,
+				apiOK(schemaWrapper("feeds", schemaArray(schemaRef("Feed"))))),
+			"post": apiOperation("addFeed", "Subscribe to a feed", nil,
+				apiBody(map[string]any{
+					"type":     "object",
+					"required": []string{"url"},
+					"properties": map[string]any{
+						"url":      map[string]any{"type": "string", "format": "uri"},
+						"category": map[string]any{"type": "string"},
+						"tags":     map[string]any{"type": "string", "description": "Comma separated"},
+					},
+				}),
+				apiCreated(feed)),
+		},
+		"/feeds/{id}": map[string]any{
+			"parameters": []any{idParam},
+			"get":        apiOperation("getFeed", "Get a feed", nil, nil, apiOK(feed)),
+			"patch": apiOperation("updateFeed", "Change a feed; only the fields given are applied", nil,
+				apiBody(schemaRef("FeedUpdate")), apiOK(feed)),
+			"delete": apiOperation("deleteFeed", "Unsubscribe from a feed and delete its entries", nil, nil, apiNoContent()),
+		},
+		"/entries": map[string]any{
+			"get": apiOperation("listEntries", "List entries, newest first", []any{
+				apiQueryParam("limit", "integer", "Entries to return, 1 to 500 (default 50)"),
+				apiQueryParam("offset", "integer", "Entries to skip"),
+				apiQueryParam("feed", "integer", "Only entries from this feed"),
+				apiQueryParam("category", "string", "Only entries from feeds in this category"),
+				apiQueryParam("tag", "string", "Only entries from feeds with this tag"),
+				apiQueryParam("since", "string", "Only entries published after this RFC 3339 time"),
+				apiQueryParam("hidden", "boolean", "Only hidden entries when true, only visible ones when false"),
+			}, nil, apiOK(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"entries": schemaArray(schemaRef("Entry")),
+					"limit":   map[string]any{"type": "integer"},
+					"offset":  map[string]any{"type": "integer"},
+				},
+			})),
+		},
+		"/entries/{id}": map[string]any{
+			"parameters": []any{idParam},
+			"get":        apiOperation("getEntry", "Get an entry", nil, nil, apiOK(entry)),
+			"patch": apiOperation("updateEntry", "Hide an entry from the public pages and feeds, or show it again", nil,
+				apiBody(map[string]any{
+					"type":     "object",
+					"required": []string{"hidden"},
+					"properties": map[string]any{
+						"hidden": map[string]any{"type": "boolean"},
+					},
+				}),
+				apiOK(entry)),
+		},
+		"/settings": map[string]any{
+			"get": apiOperation("getSettings", "Get the site settings, without credentials", nil, nil,
+				apiOK(schemaWrapper("settings", map[string]any{
+					"type":                 "object",
+					"additionalProperties": map[string]any{"type": "string"},
+				}))),
+		},
+		"/categories": map[string]any{
+			"get": apiOperation("listCategories", "List categories in use, most used first", nil, nil,
+				apiOK(schemaWrapper("categories", schemaArray(map[string]any{"type": "string"})))),
+		},
+		"/tags": map[string]any{
+			"get": apiOperation("listTags", "List tags in use or given a fetch priority", nil, nil,
+				apiOK(schemaWrapper("tags", schemaArray(schemaRef("Tag"))))),
+		},
+		"/tags/{tag}": map[string]any{
+			"parameters": []any{tagParam},
+			"get":        apiOperation("getTag", "Get a tag", nil, nil, apiOK(tag)),
+			"put": apiOperation("setTagPriority", "Set a tag's fetch priority; zero priority and interval clear it", nil,
+				apiBody(schemaRef("Tag")), apiOK(tag)),
+			"delete": apiOperation("deleteTag", "Remove a tag from every feed and clear its priority", nil, nil, apiNoContent()),
+		},
+		"/tag-rules": map[string]any{
+			"get": apiOperation("listTagRules", "List tag rules", nil, nil,
+				apiOK(schemaWrapper("rules", schemaArray(schemaRef("TagRule"))))),
+			"post": apiOperation("addTagRule", "Add a tag rule", nil, apiBody(schemaRef("TagRule")), apiCreated(rule)),
+		},
+		"/tag-rules/{id}": map[string]any{
+			"parameters": []any{idParam},
+			"get":        apiOperation("getTagRule", "Get a tag rule", nil, nil, apiOK(rule)),
+			"delete":     apiOperation("deleteTagRule", "Delete a tag rule, keeping the tags it applied", nil, nil, apiNoContent()),
+		},
+		"/tag-rules/apply": map[string]any{
+			"get": apiOperation("getTagRuleBackfill", "Progress of applying the tag rules to every feed", nil, nil,
+				apiOK(schemaRef("TagRuleBackfill"))),
+			"post": apiOperation("startTagRuleBackfill", "Start applying the tag rules to every feed", nil, nil,
+				map[string]any{"202": apiResponse("Started", schemaRef("TagRuleBackfill"))}),
+		},
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Infoscope API",
+			"version":     "1",
+			"description": "Feeds, entries and taxonomy of an Infoscope install. Tokens are created in the admin settings and sent as a bearer token.",
+		},
+		"servers":  []any{map[string]any{"url": "/api/v1"}},
+		"security": []any{map[string]any{"bearerAuth": []string{}}},
+		"paths":    paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+			"responses": map[string]any{
+				"Error": apiResponse("Error", schemaRef("Error")),
+			},
+		},
+	}
+}
+
+// apiOperation describes one method on a path; every operation can fail
+// with the standard error envelope
+func apiOperation(id, summary string, params []any, requestBody map[string]any, responses map[string]any) map[string]any {
+	responses["default"] = map[string]any{"$ref": "#/components/responses/Error"}
+	op := map[string]any{
+		"operationId": id,
+		"summary":     summary,
+		"responses":   responses,
+	}
+	if params != nil {
+		op["parameters"] = params
+	}
+	if requestBody != nil {
+		op["requestBody"] = requestBody
+	}
+	return op
+}
+
+func apiResponse(description string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content":     map[string]any{"application/json": map[string]any{"schema": schema}},
+	}
+}
+
+func apiOK(schema map[string]any) map[string]any {
+	return map[string]any{"200": apiResponse("OK", schema)}
+}
+
+func apiCreated(schema map[string]any) map[string]any {
+	return map[string]any{"201": apiResponse("Created", schema)}
+}
+
+func apiNoContent() map[string]any {
+	return map[string]any{"204": map[string]any{"description": "Done"}}
+}
+
+func apiBody(schema map[string]any) map[string]any {
+	return map[string]any{
+		"required": true,
+		"content":  map[string]any{"application/json": map[string]any{"schema": schema}},
+	}
+}
+
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func schemaArray(items map[string]any) map[string]any {
+	return map[string]any{"type": "array", "items": items}
+}
+
+// object is the {"key": value} wrapper the handlers send single results in
+func schemaWrapper(key string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{key: schema},
+	}
+}
+
+func apiPathParam(name, typ string) map[string]any {
+	return map[string]any{
+		"name": name, "in": "path", "required": true,
+		"schema": map[string]any{"type": typ},
+	}
+}
+
+func apiQueryParam(name, typ, description string) map[string]any {
+	return map[string]any{
+		"name": name, "in": "query", "description": description,
+		"schema": map[string]any{"type": typ},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeSchema describes t, referring to the named schemas in refs
+func typeSchema(t reflect.Type, refs map[reflect.Type]string) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		s := typeSchema(t.Elem(), refs)
+		if _, isRef := s["$ref"]; !isRef {
+			s["nullable"] = true
+		}
+		return s
+	}
+	if name, ok := refs[t]; ok {
+		return schemaRef(name)
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		if t.Kind() == reflect.Int64 {
+			return map[string]any{"type": "integer", "format": "int64"}
+		}
+		return map[string]any{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]any{"type": "number"}
+	case t.Kind() == reflect.Slice:
+		return schemaArray(typeSchema(t.Elem(), refs))
+	case t.Kind() == reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), refs)}
+	case t.Kind() == reflect.Struct:
+		return structSchema(t, refs)
+	}
+	// Interface fields such as error details can hold anything
+	return map[string]any{}
+}
+
+// structSchema describes a struct by its JSON encoding: fields without
+// omitempty are required, and embedded structs are flattened.
+func structSchema(t reflect.Type, refs map[reflect.Type]string) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+				walk(f.Type)
+				continue
+			}
+			if !f.IsExported() || tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = typeSchema(f.Type, refs)
+			if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+				required = append(required, name)
+			}
+		}
+	}
+	walk(t)
+
+	s := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
@@ -0,0 +1,282 @@
+// internal/server/opml.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"infoscope/internal/database"
+	"infoscope/internal/feedurl"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxOPMLSize caps the size of an uploaded subscription list
+const maxOPMLSize = 5 << 20
+
+// opmlStatsNamespace identifies the feed statistics attributes added to
+// exports on request
+const opmlStatsNamespace = "https://github.com/disinfo-zone/infoscope/opml"
+
+type opmlDocument struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	StatsNS string        `xml:"xmlns:infoscope,attr,omitempty"`
+	Title   string        `xml:"head>title"`
+	Created string        `xml:"head>dateCreated,omitempty"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+// opmlOutline is a feed when XMLURL is set and a folder otherwise. Tags
+// travel in the standard comma-separated category attribute.
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Category string        `xml:"category,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+
+	// Feed statistics, only written when requested. Other readers ignore
+	// attributes in a foreign namespace.
+	Entries      string `xml:"infoscope:entries,attr,omitempty"`
+	Entries30d   string `xml:"infoscope:entries30d,attr,omitempty"`
+	Clicks       string `xml:"infoscope:clicks,attr,omitempty"`
+	Errors30d    string `xml:"infoscope:errors30d,attr,omitempty"`
+	LastPostedAt string `xml:"infoscope:lastPostedAt,attr,omitempty"`
+	Status       string `xml:"infoscope:status,attr,omitempty"`
+}
+
+// setStats fills in the statistics attributes of a feed outline
+func (o *opmlOutline) setStats(st FeedStats) {
+	o.Entries = strconv.Itoa(st.Entries)
+	o.Entries30d = strconv.Itoa(st.Entries30d)
+	o.Clicks = strconv.Itoa(st.Clicks)
+	o.Errors30d = strconv.Itoa(st.Errors30d)
+	if st.LastPostedAt != nil {
+		o.LastPostedAt = st.LastPostedAt.UTC().Format(time.RFC3339)
+	}
+	o.Status = st.Status
+}
+
+// opmlFeed is a subscription read from an OPML file
+type opmlFeed struct {
+	URL      string
+	Title    string
+	Category string
+	Tags     string
+}
+
+// OPMLImportResult reports what an OPML upload changed
+type OPMLImportResult struct {
+	Added   int `json:"added"`
+	Skipped int `json:"skipped"`
+}
+
+// handleOPML exports subscriptions as OPML on GET and imports them on POST.
+func (s *Server) handleOPML(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleOPMLExport(w, r)
+	case http.MethodPost:
+		s.handleOPMLImport(w, r)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) handleOPMLExport(w http.ResponseWriter, r *http.Request) {
+	// Feed statistics are only included on request
+	var stats map[string]FeedStats
+	if r.URL.Query().Get("stats") == "1" {
+		var err error
+		if stats, err = s.getFeedStats(r.Context()); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting feed stats", "error", err)
+			writeDBError(w, err)
+			return
+		}
+	}
+
+	doc, err := s.buildOPML(r.Context(), stats)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error building OPML", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to export feeds")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=infoscope_feeds_%s.opml",
+			time.Now().Format("2006-01-02")))
+
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error encoding OPML", "error", err)
+	}
+}
+
+// buildOPML lists the active feeds, nested in a folder per category. Feeds
+// found in stats carry their statistics.
+func (s *Server) buildOPML(ctx context.Context, stats map[string]FeedStats) (*opmlDocument, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT url, COALESCE(title, ''), COALESCE(site_url, ''),
+               COALESCE(category, ''), COALESCE(tags, '')
+        FROM feeds
+        WHERE status != 'deleted'
+        ORDER BY COALESCE(category, ''), title COLLATE NOCASE`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	doc := &opmlDocument{
+		Version: "2.0",
+		Title:   "Infoscope subscriptions",
+		Created: time.Now().UTC().Format(time.RFC1123Z),
+	}
+	if stats != nil {
+		doc.StatsNS = opmlStatsNamespace
+	}
+	folders := make(map[string]int)
+	for rows.Next() {
+		var feedURL, title, siteURL, category, tags string
+		if err := rows.Scan(&feedURL, &title, &siteURL, &category, &tags); err != nil {
+			return nil, err
+		}
+		if title == "" {
+			title = feedURL
+		}
+		outline := opmlOutline{
+			Text:     title,
+			Title:    title,
+			Type:     "rss",
+			XMLURL:   feedURL,
+			HTMLURL:  siteURL,
+			Category: tags,
+		}
+		if st, ok := stats[feedURL]; ok {
+			outline.setStats(st)
+		}
+
+		if category == "" {
+			doc.Body = append(doc.Body, outline)
+			continue
+		}
+		i, ok := folders[category]
+		if !ok {
+			i = len(doc.Body)
+			folders[category] = i
+			doc.Body = append(doc.Body, opmlOutline{Text: category, Title: category})
+		}
+		doc.Body[i].Outlines = append(doc.Body[i].Outlines, outline)
+	}
+	return doc, rows.Err()
+}
+
+func (s *Server) handleOPMLImport(w http.ResponseWriter, r *http.Request) {
+	if !s.csrf.Validate(w, r) {
+		return
+	}
+
+	var doc opmlDocument
+	if err := xml.NewDecoder(r.Body).Decode(&doc); err != nil {
+		writeDecodeError(w, err, "Invalid OPML file")
+		return
+	}
+	feeds := collectOPMLFeeds(doc.Body, "")
+	if len(feeds) == 0 {
+		writeValidationError(w, "The file contains no feeds", nil)
+		return
+	}
+
+	// Subscriptions already present, under any form of their address, are
+	// left as they are
+	var result OPMLImportResult
+	var added []string
+	err := database.WithTx(r.Context(), s.db, func(tx *sql.Tx) error {
+		result = OPMLImportResult{}
+		added = added[:0]
+		for _, f := range feeds {
+			res, err := tx.ExecContext(r.Context(), `
+                INSERT OR IGNORE INTO feeds (url, url_key, title, category, tags)
+                VALUES (?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))`,
+				f.URL, feedurl.Key(f.URL), f.Title, f.Category, f.Tags)
+			if err != nil {
+				if database.IsBusy(err) {
+					return err
+				}
+				s.logger.ErrorContext(r.Context(), "Error importing feed", "feed_url", f.URL, "error", err)
+				result.Skipped++
+				continue
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				result.Added++
+				added = append(added, f.URL)
+			} else {
+				result.Skipped++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error importing OPML", "error", err)
+		writeDBError(w, err)
+		return
+	}
+
+	if result.Added > 0 {
+		s.tagNewFeeds(r.Context(), added...)
+		go func() {
+			if err := s.feedService.UpdateFeeds(context.Background()); err != nil {
+				s.logger.ErrorContext(r.Context(), "Error updating feeds after OPML import", "error", err)
+			}
+		}()
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// collectOPMLFeeds flattens an outline tree into feeds, which take the name
+// of the outermost folder they sit in as their category.
+func collectOPMLFeeds(outlines []opmlOutline, folder string) []opmlFeed {
+	var feeds []opmlFeed
+	for _, o := range outlines {
+		name := strings.TrimSpace(o.Title)
+		if name == "" {
+			name = strings.TrimSpace(o.Text)
+		}
+
+		if o.XMLURL == "" {
+			if folder == "" {
+				feeds = append(feeds, collectOPMLFeeds(o.Outlines, name)...)
+			} else {
+				feeds = append(feeds, collectOPMLFeeds(o.Outlines, folder)...)
+			}
+			continue
+		}
+
+		feedURL, err := feedurl.Normalize(o.XMLURL)
+		if err != nil {
+			continue
+		}
+		// Other readers write categories as slash paths, e.g. "/News/World"
+		var tags []string
+		for _, c := range strings.Split(o.Category, ",") {
+			tags = append(tags, strings.Trim(strings.TrimSpace(c), "/"))
+		}
+		feeds = append(feeds, opmlFeed{
+			URL:      feedURL,
+			Title:    name,
+			Category: folder,
+			Tags:     strings.Join(normalizeTags(strings.Join(tags, ",")), ","),
+		})
+	}
+	return feeds
+}
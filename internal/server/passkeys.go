@@ -0,0 +1,146 @@
+// internal/server/passkeys.go
+package server
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"infoscope/internal/auth"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	// passkeyCeremonyTimeout is how long a browser has to answer a
+	// passkey challenge
+	passkeyCeremonyTimeout = 5 * time.Minute
+
+	// passkeyCeremonyCookie ties the finish request to its challenge
+	passkeyCeremonyCookie = "passkey_ceremony"
+
+	// maxPasskeyNameLength caps the label an account gives a passkey
+	maxPasskeyNameLength = 64
+
+	// maxPendingPasskeyCeremonies caps the challenges waiting for an
+	// answer, since anyone may start a sign-in
+	maxPendingPasskeyCeremonies = 256
+)
+
+var errPasskeyCeremoniesFull = errors.New("too many passkey ceremonies in progress")
+
+// passkeyCeremony is a WebAuthn challenge waiting for its answer, along
+// with the account and label a registration is for
+type passkeyCeremony struct {
+	session webauthn.SessionData
+	userID  int64
+	name    string
+	expires time.Time
+}
+
+// passkeyCeremonies holds challenges between the begin and finish requests
+// of a passkey sign-in or registration. Each is answered at most once.
+type passkeyCeremonies struct {
+	mu      sync.Mutex
+	pending map[string]passkeyCeremony
+}
+
+// begin stores a challenge and hands the browser a cookie naming it. It
+// fails with errPasskeyCeremoniesFull while too many are waiting.
+func (c *passkeyCeremonies) begin(w http.ResponseWriter, secure bool, ceremony passkeyCeremony) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	id := base64.RawURLEncoding.EncodeToString(b)
+	ceremony.expires = time.Now().Add(passkeyCeremonyTimeout)
+
+	c.mu.Lock()
+	if c.pending == nil {
+		c.pending = make(map[string]passkeyCeremony)
+	}
+	for key, pending := range c.pending {
+		if pending.expires.Before(time.Now()) {
+			delete(c.pending, key)
+		}
+	}
+	if len(c.pending) >= maxPendingPasskeyCeremonies {
+		c.mu.Unlock()
+		return errPasskeyCeremoniesFull
+	}
+	c.pending[id] = ceremony
+	c.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     passkeyCeremonyCookie,
+		Value:    id,
+		Path:     "/admin",
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   int(passkeyCeremonyTimeout / time.Second),
+	})
+	return nil
+}
+
+// finish takes the challenge the request's cookie names, reporting false
+// when there is none or it has expired
+func (c *passkeyCeremonies) finish(w http.ResponseWriter, r *http.Request) (passkeyCeremony, bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     passkeyCeremonyCookie,
+		Path:     "/admin",
+		HttpOnly: true,
+		MaxAge:   -1,
+	})
+	cookie, err := r.Cookie(passkeyCeremonyCookie)
+	if err != nil {
+		return passkeyCeremony{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ceremony, ok := c.pending[cookie.Value]
+	delete(c.pending, cookie.Value)
+	if !ok || ceremony.expires.Before(time.Now()) {
+		return passkeyCeremony{}, false
+	}
+	return ceremony, true
+}
+
+// handleAccountPasskeys removes one of the signed-in account's passkeys.
+// Passkeys are added through handlePasskeyRegisterBegin and
+// handlePasskeyRegisterFinish.
+func (s *Server) handleAccountPasskeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeMethodNotAllowed(w)
+		return
+	}
+	if !s.csrf.Validate(w, r) {
+		return
+	}
+	var req struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err, "Invalid request")
+		return
+	}
+
+	userID, _ := getUserID(r.Context())
+	err := auth.DeletePasskey(r.Context(), s.db, userID, req.ID)
+	if errors.Is(err, auth.ErrPasskeyNotFound) {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "Passkey not found")
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error deleting passkey", "id", req.ID, "user_id", userID, "error", err)
+		writeDBError(w, err)
+		return
+	}
+	s.logger.InfoContext(r.Context(), "Deleted passkey", "id", req.ID, "user_id", userID)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPasskeyLogin(t *testing.T) {
+	srv, _ := newTestServerSrv(t, nil)
+	c := newTestClient(t, srv.Routes())
+
+	rec := c.do(http.MethodPost, "/admin/login/passkey/begin", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Begin = %d, want 200", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "challenge") {
+		t.Errorf("Begin answered %s, want a challenge", rec.Body.String())
+	}
+	if c.cookies[passkeyCeremonyCookie] == nil {
+		t.Fatal("Begin set no ceremony cookie")
+	}
+
+	// An answer that doesn't verify is refused, and uses up the challenge
+	if rec := c.do(http.MethodPost, "/admin/login/passkey/finish", "{}"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Finish with a bad answer = %d, want 401", rec.Code)
+	}
+	if rec := c.do(http.MethodPost, "/admin/login/passkey/finish", "{}"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Second finish = %d, want 400", rec.Code)
+	}
+	if c.cookies["session"] != nil {
+		t.Error("Signed in without a valid passkey")
+	}
+}
+
+func TestPasskeyLoginUnknownChallenge(t *testing.T) {
+	srv, _ := newTestServerSrv(t, nil)
+	c := newTestClient(t, srv.Routes())
+	c.cookies[passkeyCeremonyCookie] = &http.Cookie{Name: passkeyCeremonyCookie, Value: "made-up"}
+	if rec := c.do(http.MethodPost, "/admin/login/passkey/finish", "{}"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Finish with an unknown challenge = %d, want 400", rec.Code)
+	}
+}
+
+func TestPasskeyLoginExpiredChallenge(t *testing.T) {
+	srv, _ := newTestServerSrv(t, nil)
+	c := newTestClient(t, srv.Routes())
+	if rec := c.do(http.MethodPost, "/admin/login/passkey/begin", ""); rec.Code != http.StatusOK {
+		t.Fatalf("Begin = %d, want 200", rec.Code)
+	}
+
+	srv.passkeys.mu.Lock()
+	for id, ceremony := range srv.passkeys.pending {
+		ceremony.expires = time.Now().Add(-time.Second)
+		srv.passkeys.pending[id] = ceremony
+	}
+	srv.passkeys.mu.Unlock()
+
+	if rec := c.do(http.MethodPost, "/admin/login/passkey/finish", "{}"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Finish after the challenge expired = %d, want 400", rec.Code)
+	}
+}
+
+func TestPasskeyCeremoniesCapped(t *testing.T) {
+	srv, _ := newTestServerSrv(t, nil)
+	c := newTestClient(t, srv.Routes())
+
+	srv.passkeys.mu.Lock()
+	srv.passkeys.pending = make(map[string]passkeyCeremony)
+	for i := 0; i < maxPendingPasskeyCeremonies; i++ {
+		srv.passkeys.pending[strings.Repeat("x", i+1)] = passkeyCeremony{expires: time.Now().Add(time.Minute)}
+	}
+	srv.passkeys.mu.Unlock()
+
+	if rec := c.do(http.MethodPost, "/admin/login/passkey/begin", ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Begin with the ceremonies full = %d, want 429", rec.Code)
+	}
+	if n := len(srv.passkeys.pending); n != maxPendingPasskeyCeremonies {
+		t.Errorf("%d ceremonies pending, want the cap of %d", n, maxPendingPasskeyCeremonies)
+	}
+}
+
+func TestPasskeyLoginBeginThrottled(t *testing.T) {
+	srv, _ := newTestServerSrv(t, nil)
+	c := newTestClient(t, srv.Routes())
+	for i := 0; i < 20; i++ {
+		rec := c.do(http.MethodPost, "/admin/login/passkey/begin", "")
+		if rec.Code == http.StatusTooManyRequests {
+			return
+		}
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Begin %d = %d, want 200 until throttled", i+1, rec.Code)
+		}
+	}
+	t.Error("Begin never throttled")
+}
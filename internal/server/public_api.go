@@ -0,0 +1,153 @@
+// internal/server/public_api.go
+package server
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPublicEntries = 20
+	maxPublicEntries     = 100
+)
+
+// publicCursor marks where a page of the public entries API ended: the
+// last entry's publish time and ID. Entries are ordered by both, so the
+// next page starts cleanly even while new entries arrive.
+type publicCursor struct {
+	PublishedAt time.Time
+	ID          int64
+}
+
+func (c publicCursor) String() string {
+	raw := c.PublishedAt.UTC().Format("2006-01-02 15:04:05") + "," + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parsePublicCursor(s string) (publicCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return publicCursor{}, err
+	}
+	date, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return publicCursor{}, fmt.Errorf("malformed cursor")
+	}
+	var c publicCursor
+	if c.PublishedAt, err = time.Parse("2006-01-02 15:04:05", date); err != nil {
+		return publicCursor{}, err
+	}
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return publicCursor{}, err
+	}
+	return c, nil
+}
+
+// handlePublicEntries lists the river's entries, newest first, without a
+// login so other sites can fetch them from the browser. It takes limit,
+// category or tag, since (an RFC 3339 time), and page, the nextPage
+// cursor of the previous response. The public_api setting turns it off.
+func (s *Server) handlePublicEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeMethodNotAllowed(w)
+		return
+	}
+	if s.getSetting(r.Context(), "public_api") != "true" {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "Not found")
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	q := r.URL.Query()
+	fields := make(map[string]string)
+	limit := defaultPublicEntries
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxPublicEntries {
+			fields["limit"] = "must be between 1 and 100"
+		}
+		limit = n
+	}
+
+	filter := RiverFilter{Category: strings.TrimSpace(q.Get("category"))}
+	if v := q.Get("tag"); v != "" {
+		if tags := normalizeTags(v); len(tags) == 1 {
+			filter.Tag = tags[0]
+		} else {
+			fields["tag"] = "must be a single tag"
+		}
+	}
+	cond, args := filter.where()
+	dupCond, dupArgs := filter.withoutDuplicates()
+	cond += dupCond
+	args = append(args, dupArgs...)
+
+	if v := q.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			fields["since"] = "must be an RFC 3339 time"
+		}
+		cond += " AND datetime(e.published_at) > ?"
+		args = append(args, since.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if v := q.Get("page"); v != "" {
+		cursor, err := parsePublicCursor(v)
+		if err != nil {
+			fields["page"] = "must be the nextPage of an earlier response"
+		}
+		date := cursor.PublishedAt.Format("2006-01-02 15:04:05")
+		cond += " AND (datetime(e.published_at) < ? OR (datetime(e.published_at) = ? AND e.id < ?))"
+		args = append(args, date, date, cursor.ID)
+	}
+	if len(fields) > 0 {
+		writeValidationError(w, "Invalid query", fields)
+		return
+	}
+
+	// One more than asked for tells whether there is another page
+	rows, err := s.db.QueryContext(r.Context(), `
+        SELECT e.id, e.feed_id, e.title, e.url, COALESCE(f.category, ''), datetime(e.published_at)
+        FROM entries e
+        JOIN feeds f ON e.feed_id = f.id
+        WHERE f.status != 'deleted' AND e.hidden_at IS NULL
+          AND (f.snoozed_until IS NULL OR f.snoozed_until <= datetime('now'))`+cond+`
+        ORDER BY datetime(e.published_at) DESC, e.id DESC
+        LIMIT ?`, append(args, limit+1)...)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error listing public entries", "error", err)
+		writeDBError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	entries := make([]APIEntry, 0, limit)
+	for rows.Next() {
+		var e APIEntry
+		var date string
+		if err := rows.Scan(&e.ID, &e.FeedID, &e.Title, &e.URL, &e.Category, &date); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error scanning entry", "error", err)
+			writeInternalError(w)
+			return
+		}
+		e.PublishedAt, _ = time.Parse("2006-01-02 15:04:05", date)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error listing public entries", "error", err)
+		writeDBError(w, err)
+		return
+	}
+
+	resp := map[string]any{"entries": entries}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[limit-1]
+		resp["entries"] = entries
+		resp["nextPage"] = publicCursor{PublishedAt: last.PublishedAt, ID: last.ID}.String()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,42 @@
+// internal/server/readonly.go
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// readOnlyGuard restricts a mirror to serving the public river. Admin and
+// setup pages are unavailable, click tracking is accepted but not recorded,
+// and any other mutating request is refused. The stats API stays available
+// since it only reads.
+func (s *Server) readOnlyGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+
+		switch {
+		case path == "/admin/api/stats":
+			// Token-protected and read-only
+
+		case path == "/admin" || strings.HasPrefix(path, "/admin/") ||
+			path == "/setup" || strings.HasPrefix(path, "/setup/"):
+			http.Error(w, "This instance is a read-only mirror", http.StatusForbidden)
+			return
+
+		case path == "/click" || strings.HasPrefix(path, "/click/"):
+			// Let visitors' links open without counting the click
+			w.WriteHeader(http.StatusNoContent)
+			return
+
+		case path == "/mute":
+			// Muted terms live in a visitor cookie, nothing is written
+
+		case !isSafeMethod(r.Method):
+			w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+			http.Error(w, "This instance is a read-only mirror", http.StatusMethodNotAllowed)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
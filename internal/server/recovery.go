@@ -0,0 +1,121 @@
+// internal/server/recovery.go
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"expvar"
+	"infoscope/internal/logging"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+)
+
+// httpPanics counts handler panics caught by recoverPanics
+var httpPanics = expvar.NewInt("http_panics")
+
+// validRequestID limits which incoming X-Request-ID values are trusted,
+// so IDs from a proxy can be correlated without letting clients inject
+// arbitrary text into the logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder remembers whether the response has started, so a panic
+// after the first write doesn't try to send a second set of headers, and
+// which status was sent for the request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	written bool
+	status  int
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	if !w.written {
+		w.status = code
+	}
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if !w.written {
+		w.status = http.StatusOK
+	}
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Written reports whether headers have been sent; see headerWritten.
+func (w *statusRecorder) Written() bool {
+	return w.written
+}
+
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recoverPanics tags every request with an ID, which every record logged
+// with its context carries, and turns a handler panic into a logged stack
+// trace and a 500 page instead of a dropped connection.
+func (s *Server) recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		r = r.WithContext(logging.WithRequestID(r.Context(), id))
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				// Deliberate abort; let net/http handle it quietly
+				panic(err)
+			}
+
+			httpPanics.Add(1)
+			s.logger.ErrorContext(r.Context(), "Panic serving request",
+				"method", r.Method, "path", r.URL.Path, "error", err, "stack", string(debug.Stack()))
+
+			if rec.written {
+				return
+			}
+			s.renderServerError(rec, r)
+		}()
+
+		next.ServeHTTP(rec, r)
+		s.logger.DebugContext(r.Context(), "Request served",
+			"method", r.Method, "path", r.URL.Path, "status", rec.status, "duration", time.Since(start))
+	})
+}
+
+// renderServerError writes the themed 500 page, falling back to plain text.
+func (s *Server) renderServerError(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		RequestID string
+	}{
+		RequestID: getRequestID(r.Context()),
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusInternalServerError)
+
+	if err := s.renderTemplate(w, r, "500.html", data); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error rendering 500 template", "error", err)
+		w.Write([]byte("500 Internal Server Error"))
+	}
+}
@@ -0,0 +1,189 @@
+// internal/server/revisions.go
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDiffWords bounds the word-level diff so huge content edits stay cheap
+const maxDiffWords = 2000
+
+// DiffSegment is one run of a word-level diff: "eq", "del" or "ins"
+type DiffSegment struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// EntryRevision is a previous version of an entry with its diff to the next version
+type EntryRevision struct {
+	ID          int64         `json:"id"`
+	Title       string        `json:"title"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	TitleDiff   []DiffSegment `json:"titleDiff"`
+	ContentDiff []DiffSegment `json:"contentDiff"`
+}
+
+// EditedEntry summarizes an entry that has been edited upstream
+type EditedEntry struct {
+	ID            int64         `json:"id"`
+	Title         string        `json:"title"`
+	URL           string        `json:"url"`
+	RevisionCount int           `json:"revisionCount"`
+	LastEdited    time.Time     `json:"lastEdited"`
+	TitleDiff     []DiffSegment `json:"titleDiff"`
+}
+
+// getRecentEdits returns the most recently edited entries with a diff of their latest title change
+func (s *Server) getRecentEdits(ctx context.Context, limit int) ([]EditedEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT e.id, e.title, e.url, COUNT(r.id),
+               strftime('%Y-%m-%d %H:%M:%S', MAX(r.created_at)),
+               (SELECT r2.title FROM entry_revisions r2
+                WHERE r2.entry_id = e.id
+                ORDER BY r2.created_at DESC, r2.id DESC LIMIT 1)
+        FROM entries e
+        JOIN entry_revisions r ON r.entry_id = e.id
+        GROUP BY e.id
+        ORDER BY MAX(r.created_at) DESC, e.id DESC
+        LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying edited entries: %w", err)
+	}
+	defer rows.Close()
+
+	edits := make([]EditedEntry, 0)
+	for rows.Next() {
+		var e EditedEntry
+		var lastEdited, previousTitle string
+		if err := rows.Scan(&e.ID, &e.Title, &e.URL, &e.RevisionCount, &lastEdited, &previousTitle); err != nil {
+			return nil, fmt.Errorf("error scanning edited entry: %w", err)
+		}
+		e.LastEdited, _ = time.ParseInLocation("2006-01-02 15:04:05", lastEdited, time.UTC)
+		e.TitleDiff = diffWords(previousTitle, e.Title)
+		edits = append(edits, e)
+	}
+	return edits, rows.Err()
+}
+
+// getEntryRevisions returns all revisions of an entry, newest first, each diffed
+// against the version that replaced it
+func (s *Server) getEntryRevisions(ctx context.Context, entryID int64) ([]EntryRevision, error) {
+	var currentTitle, currentContent string
+	err := s.db.QueryRowContext(ctx,
+		"SELECT title, COALESCE(content, '') FROM entries WHERE id = ?", entryID,
+	).Scan(&currentTitle, &currentContent)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, title, COALESCE(content, ''), strftime('%Y-%m-%d %H:%M:%S', created_at)
+        FROM entry_revisions
+        WHERE entry_id = ?
+        ORDER BY created_at DESC, id DESC`, entryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]EntryRevision, 0)
+	nextTitle, nextContent := currentTitle, currentContent
+	for rows.Next() {
+		var rev EntryRevision
+		var content, createdAt string
+		if err := rows.Scan(&rev.ID, &rev.Title, &content, &createdAt); err != nil {
+			return nil, err
+		}
+		rev.CreatedAt, _ = time.ParseInLocation("2006-01-02 15:04:05", createdAt, time.UTC)
+		rev.TitleDiff = diffWords(rev.Title, nextTitle)
+		rev.ContentDiff = diffWords(content, nextContent)
+		revisions = append(revisions, rev)
+		nextTitle, nextContent = rev.Title, content
+	}
+	return revisions, rows.Err()
+}
+
+// handleEntryRevisions returns the revision history of an entry as JSON
+func (s *Server) handleEntryRevisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		writeValidationError(w, "Invalid entry ID", map[string]string{"id": "must be a number"})
+		return
+	}
+
+	revisions, err := s.getEntryRevisions(r.Context(), id)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting revisions for entry", "entry_id", id, "error", err)
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "Entry not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Revisions []EntryRevision `json:"revisions"`
+	}{revisions})
+}
+
+// diffWords computes a word-level diff between two strings using an LCS table
+func diffWords(oldText, newText string) []DiffSegment {
+	a, b := strings.Fields(oldText), strings.Fields(newText)
+	if len(a) > maxDiffWords || len(b) > maxDiffWords {
+		return []DiffSegment{{Op: "del", Text: oldText}, {Op: "ins", Text: newText}}
+	}
+
+	// lcs[i][j] holds the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var segments []DiffSegment
+	appendWord := func(op, word string) {
+		if n := len(segments); n > 0 && segments[n-1].Op == op {
+			segments[n-1].Text += " " + word
+			return
+		}
+		segments = append(segments, DiffSegment{Op: op, Text: word})
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			appendWord("eq", a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			appendWord("del", a[i])
+			i++
+		default:
+			appendWord("ins", b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		appendWord("del", a[i])
+	}
+	for ; j < len(b); j++ {
+		appendWord("ins", b[j])
+	}
+	return segments
+}
@@ -0,0 +1,235 @@
+// internal/server/river.go
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// River display modes, stored in the river_mode setting
+const (
+	RiverChronological = "chronological"
+	RiverShuffle       = "shuffle"
+)
+
+// River layouts, stored in the river_layout setting
+const (
+	RiverStream  = "stream"
+	RiverGrouped = "grouped"
+)
+
+const (
+	// collapsedSectionsCookie lists the grouped river sections a visitor
+	// has collapsed
+	collapsedSectionsCookie = "collapsed_sections"
+	// uncategorizedSection holds entries from feeds without a category
+	uncategorizedSection = "Other"
+)
+
+// RiverFilter narrows the river to the feeds in one category or carrying
+// one tag. The zero value is the whole river.
+type RiverFilter struct {
+	Category string
+	Tag      string
+}
+
+// IsZero reports whether the filter lets every feed through
+func (f RiverFilter) IsZero() bool {
+	return f.Category == "" && f.Tag == ""
+}
+
+// Label describes the filter for page headings
+func (f RiverFilter) Label() string {
+	switch {
+	case f.Category != "":
+		return "category: " + f.Category
+	case f.Tag != "":
+		return "tag: " + f.Tag
+	}
+	return ""
+}
+
+// Path is the public page of the filtered river
+func (f RiverFilter) Path() string {
+	switch {
+	case f.Category != "":
+		return "/category/" + url.PathEscape(f.Category)
+	case f.Tag != "":
+		return "/tag/" + url.PathEscape(f.Tag)
+	}
+	return "/"
+}
+
+// where returns the SQL condition on feeds f that applies the filter
+func (f RiverFilter) where() (string, []any) {
+	switch {
+	case f.Category != "":
+		return " AND f.category = ? COLLATE NOCASE", []any{f.Category}
+	case f.Tag != "":
+		return " AND instr(',' || COALESCE(f.tags, '') || ',', ',' || ? || ',') > 0", []any{f.Tag}
+	}
+	return "", nil
+}
+
+// withoutDuplicates returns the SQL condition on entries e that leaves out
+// copies of a story collapsed into an original the same river shows. A copy
+// whose original is filtered out, snoozed or gone stands in for it.
+func (f RiverFilter) withoutDuplicates() (string, []any) {
+	cond, args := f.where()
+	return ` AND (e.duplicate_of IS NULL OR NOT EXISTS (
+            SELECT 1 FROM entries o JOIN feeds f ON o.feed_id = f.id
+            WHERE o.id = e.duplicate_of AND f.status != 'deleted'
+              AND (f.snoozed_until IS NULL OR f.snoozed_until <= datetime('now'))` + cond + `))`, args
+}
+
+// withSources lists, on each entry, the other feeds whose copies of the
+// story were collapsed into it
+func (s *Server) withSources(ctx context.Context, entries []EntryView) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	index := make(map[int64]int, len(entries))
+	args := make([]any, 0, len(entries))
+	for i, e := range entries {
+		index[e.ID] = i
+		args = append(args, e.ID)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT e.duplicate_of, f.title, e.url
+        FROM entries e
+        JOIN feeds f ON e.feed_id = f.id
+        WHERE f.status != 'deleted' AND e.hidden_at IS NULL
+          AND e.duplicate_of IN (?`+strings.Repeat(", ?", len(args)-1)+`)
+        ORDER BY e.published_at, e.id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var src EntrySource
+		if err := rows.Scan(&id, &src.Feed, &src.URL); err != nil {
+			return err
+		}
+		if i, ok := index[id]; ok {
+			entries[i].AlsoIn = append(entries[i].AlsoIn, src)
+		}
+	}
+	return rows.Err()
+}
+
+// RiverSection is one collapsible group of the river. The stream layout
+// renders a single section without a name.
+type RiverSection struct {
+	Name      string
+	Key       string
+	Link      string
+	Entries   []EntryView
+	Collapsed bool
+}
+
+// shuffleBucket is the time window within which entries are interleaved by feed
+const shuffleBucket = 6 * time.Hour
+
+// interleaveByFeed reorders entries (newest first) so no single feed dominates
+// consecutive slots. Entries are grouped into time buckets and each bucket is
+// emitted round-robin across feeds, keeping each feed's own order intact.
+func interleaveByFeed(entries []EntryView, bucket time.Duration) []EntryView {
+	result := make([]EntryView, 0, len(entries))
+
+	for start := 0; start < len(entries); {
+		// Collect the bucket anchored at the newest remaining entry
+		end := start + 1
+		for end < len(entries) && entries[start].PublishedAt.Sub(entries[end].PublishedAt) < bucket {
+			end++
+		}
+
+		// Group by feed in order of first appearance
+		var feedOrder []int64
+		byFeed := make(map[int64][]EntryView)
+		for _, e := range entries[start:end] {
+			if _, ok := byFeed[e.FeedID]; !ok {
+				feedOrder = append(feedOrder, e.FeedID)
+			}
+			byFeed[e.FeedID] = append(byFeed[e.FeedID], e)
+		}
+
+		// Round-robin across feeds until the bucket is drained
+		for remaining := end - start; remaining > 0; {
+			for _, feedID := range feedOrder {
+				if queue := byFeed[feedID]; len(queue) > 0 {
+					result = append(result, queue[0])
+					byFeed[feedID] = queue[1:]
+					remaining--
+				}
+			}
+		}
+
+		start = end
+	}
+
+	return result
+}
+
+// groupByCategory splits entries into a section per feed category. Sections
+// are ordered by their newest entry, with uncategorized entries last, and
+// entries keep their order within a section.
+func groupByCategory(entries []EntryView, collapsed map[string]bool) []RiverSection {
+	var sections []RiverSection
+	index := make(map[string]int)
+	var other []EntryView
+
+	for _, e := range entries {
+		key := strings.ToLower(e.Category)
+		if key == "" {
+			other = append(other, e)
+			continue
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(sections)
+			index[key] = i
+			sections = append(sections, RiverSection{
+				Name: e.Category,
+				Key:  key,
+				Link: RiverFilter{Category: e.Category}.Path(),
+			})
+		}
+		sections[i].Entries = append(sections[i].Entries, e)
+	}
+	if len(other) > 0 {
+		sections = append(sections, RiverSection{
+			Name:    uncategorizedSection,
+			Key:     strings.ToLower(uncategorizedSection),
+			Entries: other,
+		})
+	}
+
+	for i := range sections {
+		sections[i].Collapsed = collapsed[sections[i].Key]
+	}
+	return sections
+}
+
+// collapsedSectionsFromRequest reads the keys of the sections the visitor
+// has collapsed. The page's script maintains the cookie.
+func collapsedSectionsFromRequest(r *http.Request) map[string]bool {
+	cookie, err := r.Cookie(collapsedSectionsCookie)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+	raw, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return nil
+	}
+	collapsed := make(map[string]bool)
+	for _, key := range strings.Split(raw, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			collapsed[key] = true
+		}
+	}
+	return collapsed
+}
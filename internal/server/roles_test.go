@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"infoscope/internal/auth"
+)
+
+// signedInClient returns a client signed in to the test server as the
+// account with the given role, adding the account if needed
+func signedInClient(t *testing.T, srv *Server, id int64, role string) *testClient {
+	t.Helper()
+	if _, err := srv.db.Exec(
+		"INSERT OR IGNORE INTO admin_users (id, username, password_hash, role) VALUES (?, ?, 'x', ?)",
+		id, role, role); err != nil {
+		t.Fatalf("Failed to add %s: %v", role, err)
+	}
+	session, err := srv.auth.StartSession(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Failed to sign in as %s: %v", role, err)
+	}
+	c := newTestClient(t, srv.Routes())
+	c.cookies["session"] = &http.Cookie{Name: "session", Value: session.ID}
+	return c
+}
+
+func TestRoleEnforcement(t *testing.T) {
+	srv, _ := newTestServerSrv(t, nil)
+	clients := map[string]*testClient{
+		auth.RoleAdmin:  signedInClient(t, srv, 1, auth.RoleAdmin),
+		auth.RoleEditor: signedInClient(t, srv, 2, auth.RoleEditor),
+		auth.RoleViewer: signedInClient(t, srv, 3, auth.RoleViewer),
+	}
+	anonymous := newTestClient(t, srv.Routes())
+
+	// Writes carry an empty body, which the handlers refuse once the role
+	// check lets them through
+	routes := []struct {
+		method, path string
+		required     string
+	}{
+		{http.MethodGet, "/admin", auth.RoleViewer},
+		{http.MethodGet, "/admin/feeds", auth.RoleViewer},
+		{http.MethodDelete, "/admin/feeds", auth.RoleEditor},
+		{http.MethodGet, "/admin/entries", auth.RoleViewer},
+		{http.MethodDelete, "/admin/entries", auth.RoleEditor},
+		{http.MethodGet, "/admin/tags", auth.RoleViewer},
+		{http.MethodDelete, "/admin/tags", auth.RoleEditor},
+		{http.MethodGet, "/admin/tag-rules", auth.RoleViewer},
+		{http.MethodDelete, "/admin/tag-rules", auth.RoleEditor},
+		{http.MethodGet, "/admin/opml", auth.RoleViewer},
+		{http.MethodGet, "/admin/notifications", auth.RoleViewer},
+		{http.MethodGet, "/admin/metrics", auth.RoleViewer},
+		{http.MethodGet, "/admin/media", auth.RoleViewer},
+		{http.MethodDelete, "/admin/media", auth.RoleAdmin},
+		{http.MethodGet, "/admin/account", auth.RoleViewer},
+		{http.MethodPost, "/admin/account", auth.RoleViewer},
+		{http.MethodGet, "/admin/settings", auth.RoleAdmin},
+		{http.MethodPost, "/admin/settings", auth.RoleAdmin},
+		{http.MethodGet, "/admin/backup", auth.RoleAdmin},
+		{http.MethodPost, "/admin/backup", auth.RoleAdmin},
+		{http.MethodGet, "/admin/users", auth.RoleAdmin},
+		{http.MethodPatch, "/admin/users", auth.RoleAdmin},
+		{http.MethodDelete, "/admin/users", auth.RoleAdmin},
+		{http.MethodGet, "/admin/api-tokens", auth.RoleAdmin},
+		{http.MethodPost, "/admin/api-tokens", auth.RoleAdmin},
+	}
+	for _, route := range routes {
+		for role, c := range clients {
+			rec := c.do(route.method, route.path, "")
+			allowed := auth.RoleAllows(role, route.required)
+			switch {
+			case rec.Code == http.StatusSeeOther:
+				t.Errorf("%s %s as %s was sent to sign in", route.method, route.path, role)
+			case allowed && rec.Code == http.StatusForbidden:
+				t.Errorf("%s %s as %s = 403, want it allowed", route.method, route.path, role)
+			case !allowed && rec.Code != http.StatusForbidden:
+				t.Errorf("%s %s as %s = %d, want 403", route.method, route.path, role, rec.Code)
+			}
+		}
+		if rec := anonymous.do(route.method, route.path, ""); rec.Code != http.StatusSeeOther {
+			t.Errorf("%s %s signed out = %d, want a redirect to sign in", route.method, route.path, rec.Code)
+		}
+	}
+}
+
+func TestPasswordChangeRevokesSessions(t *testing.T) {
+	srv, _ := newTestServerSrv(t, nil)
+	ctx := context.Background()
+	admin := signedInClient(t, srv, 1, auth.RoleAdmin)
+	editor := signedInClient(t, srv, 2, auth.RoleEditor)
+	editorElsewhere := signedInClient(t, srv, 2, auth.RoleEditor)
+	viewer := signedInClient(t, srv, 3, auth.RoleViewer)
+	if err := auth.SetPassword(ctx, srv.db, 2, "old-password"); err != nil {
+		t.Fatalf("Failed to set password: %v", err)
+	}
+
+	// Changing one's own password keeps this browser signed in and signs
+	// out the others
+	rec := editor.do(http.MethodPost, "/admin/account", `{"currentPassword":"old-password","newPassword":"new-password"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Changing password = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if rec := editor.do(http.MethodGet, "/admin", ""); rec.Code != http.StatusOK {
+		t.Errorf("Browser that changed the password: GET /admin = %d, want 200", rec.Code)
+	}
+	if rec := editorElsewhere.do(http.MethodGet, "/admin", ""); rec.Code != http.StatusSeeOther {
+		t.Errorf("Other browser after the change: GET /admin = %d, want a redirect to sign in", rec.Code)
+	}
+
+	// An admin resetting a password signs the account out everywhere
+	rec = admin.do(http.MethodPatch, "/admin/users", `{"id":3,"password":"reset-password"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Resetting password = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if rec := viewer.do(http.MethodGet, "/admin", ""); rec.Code != http.StatusSeeOther {
+		t.Errorf("Account after a reset: GET /admin = %d, want a redirect to sign in", rec.Code)
+	}
+	if rec := admin.do(http.MethodGet, "/admin", ""); rec.Code != http.StatusOK {
+		t.Errorf("Admin after resetting another password: GET /admin = %d, want 200", rec.Code)
+	}
+}
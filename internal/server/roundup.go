@@ -0,0 +1,239 @@
+// internal/server/roundup.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"infoscope/internal/schedule"
+)
+
+// defaultRoundupSize is how many entries a weekly roundup lists when the
+// roundup_size setting is unset.
+const defaultRoundupSize = 10
+
+// RoundupItem is one of the week's most clicked entries
+type RoundupItem struct {
+	EntryID int64  `json:"entryId"`
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Host    string `json:"host"`
+	Clicks  int    `json:"clicks"`
+}
+
+// Roundup is a weekly summary of the most clicked entries, shown in the
+// river as a synthetic entry linking to its own page.
+type Roundup struct {
+	ID          int64
+	WeekStart   time.Time
+	Title       string
+	Items       []RoundupItem
+	PublishedAt time.Time
+}
+
+// RoundupPageData is the template data for a roundup page
+type RoundupPageData struct {
+	BaseTemplateData
+	SiteTitle string
+	Roundup   *Roundup
+	Settings  map[string]string
+}
+
+// weekStart returns midnight on the Monday starting the week containing t.
+func weekStart(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+}
+
+// roundupLoop publishes the previous week's roundup once it has ended.
+// Checking hourly keeps it simple to catch up after downtime, and the
+// unique week keeps it from being published twice.
+func (s *Server) roundupLoop(ctx context.Context) {
+	schedule.Run(ctx, func() schedule.Spec {
+		if s.getSetting(ctx, "weekly_roundup") != "true" {
+			return schedule.Spec{}
+		}
+		return schedule.Interval(time.Hour)
+	}, func(ctx context.Context) {
+		if err := s.publishRoundup(ctx, time.Now()); err != nil {
+			s.logger.ErrorContext(ctx, "Error publishing weekly roundup", "error", err)
+		}
+	})
+}
+
+// publishRoundup builds the roundup for the last full week before now,
+// unless it already exists or nothing was clicked.
+func (s *Server) publishRoundup(ctx context.Context, now time.Time) error {
+	loc := s.siteLocation(ctx)
+	end := weekStart(now, loc)
+	start := end.AddDate(0, 0, -7)
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM roundups WHERE week_start = ?)",
+		start.Format("2006-01-02")).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	size, err := strconv.Atoi(s.getSetting(ctx, "roundup_size"))
+	if err != nil || size <= 0 {
+		size = defaultRoundupSize
+	}
+
+	// Clicks are kept as running totals, so rank the week's entries by them
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT e.id, e.title, e.url, c.click_count
+        FROM entries e
+        JOIN clicks c ON c.entry_id = e.id
+        WHERE datetime(e.published_at) >= ? AND datetime(e.published_at) < ?
+          AND c.click_count > 0 AND e.hidden_at IS NULL
+        ORDER BY c.click_count DESC, e.published_at DESC, e.id DESC
+        LIMIT ?`,
+		start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05"), size)
+	if err != nil {
+		return fmt.Errorf("error ranking entries: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]RoundupItem, 0, size)
+	for rows.Next() {
+		var item RoundupItem
+		if err := rows.Scan(&item.EntryID, &item.Title, &item.URL, &item.Clicks); err != nil {
+			return fmt.Errorf("error scanning entry: %w", err)
+		}
+		if u, err := url.Parse(item.URL); err == nil {
+			item.Host = strings.TrimPrefix(u.Hostname(), "www.")
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	title := fmt.Sprintf("Most clicked, week of %s", start.Format("Jan 2"))
+	_, err = s.db.ExecContext(ctx, `
+        INSERT OR IGNORE INTO roundups (week_start, title, items, published_at)
+        VALUES (?, ?, ?, ?)`,
+		start.Format("2006-01-02"), title, string(encoded), end.UTC())
+	if err != nil {
+		return fmt.Errorf("error saving roundup: %w", err)
+	}
+	s.logger.InfoContext(ctx, "Published weekly roundup", "week", start.Format("2006-01-02"), "entries", len(items))
+	return nil
+}
+
+// getRoundup loads a roundup by ID, or the latest one when id is 0.
+func (s *Server) getRoundup(ctx context.Context, id int64) (*Roundup, error) {
+	query := "SELECT id, week_start, title, items, published_at FROM roundups WHERE id = ?"
+	args := []any{id}
+	if id == 0 {
+		query = "SELECT id, week_start, title, items, published_at FROM roundups ORDER BY week_start DESC LIMIT 1"
+		args = nil
+	}
+
+	var r Roundup
+	var items string
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&r.ID, &r.WeekStart, &r.Title, &items, &r.PublishedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(items), &r.Items); err != nil {
+		return nil, fmt.Errorf("error decoding roundup items: %w", err)
+	}
+	return &r, nil
+}
+
+// withRoundup places the latest roundup in the river at its publish time,
+// as long as it is recent enough to fall among the entries shown.
+func (s *Server) withRoundup(ctx context.Context, entries []EntryView, settings map[string]string, limit int) []EntryView {
+	roundup, err := s.getRoundup(ctx, 0)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logger.ErrorContext(ctx, "Error getting roundup", "error", err)
+		}
+		return entries
+	}
+
+	view := EntryView{
+		Title:       roundup.Title,
+		URL:         fmt.Sprintf("/roundup/%d", roundup.ID),
+		Date:        roundup.PublishedAt.In(s.siteLocation(ctx)).Format(entryDateLayout),
+		PublishedAt: roundup.PublishedAt,
+		Roundup:     true,
+	}
+	if favicon := settings["favicon_url"]; favicon != "" {
+		view.FaviconURL = "/static/images/favicon/" + favicon
+	}
+
+	for i, e := range entries {
+		if view.PublishedAt.After(e.PublishedAt) {
+			entries = append(entries[:i], append([]EntryView{view}, entries[i:]...)...)
+			if len(entries) > limit {
+				entries = entries[:limit]
+			}
+			return entries
+		}
+	}
+	if len(entries) < limit {
+		entries = append(entries, view)
+	}
+	return entries
+}
+
+// handleRoundup shows a published weekly roundup.
+func (s *Server) handleRoundup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/roundup/"), 10, 64)
+	if err != nil || id <= 0 {
+		s.handle404(w, r)
+		return
+	}
+
+	roundup, err := s.getRoundup(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.handle404(w, r)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting roundup", "id", id, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+		settings = make(map[string]string)
+	}
+
+	data := RoundupPageData{
+		SiteTitle: settings["site_title"],
+		Roundup:   roundup,
+		Settings:  settings,
+	}
+	if err := s.renderTemplate(w, r, "roundup.html", data); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error rendering roundup template", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
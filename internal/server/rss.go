@@ -0,0 +1,79 @@
+// internal/server/rss.go
+package server
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"time"
+)
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Self          atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title    string  `xml:"title"`
+	Link     string  `xml:"link"`
+	GUID     rssGUID `xml:"guid"`
+	PubDate  string  `xml:"pubDate,omitempty"`
+	Category string  `xml:"category,omitempty"`
+}
+
+// writeRSS renders entries as an RSS 2.0 channel. selfURL is the channel's
+// own address and link the page it mirrors.
+func writeRSS(w http.ResponseWriter, title, link, description, selfURL string, entries []EntryView) error {
+	channel := rssChannel{
+		Title:       title,
+		Link:        link,
+		Description: description,
+		Self:        atomLink{Rel: "self", Type: "application/rss+xml", Href: selfURL},
+	}
+
+	var updated time.Time
+	for _, e := range entries {
+		item := rssItem{
+			Title:    e.Title,
+			Link:     e.URL,
+			GUID:     rssGUID{IsPermaLink: true, Value: e.URL},
+			Category: e.Category,
+		}
+		if !e.PublishedAt.IsZero() {
+			item.PubDate = e.PublishedAt.UTC().Format(time.RFC1123Z)
+		}
+		channel.Items = append(channel.Items, item)
+		if e.PublishedAt.After(updated) {
+			updated = e.PublishedAt
+		}
+	}
+	if !updated.IsZero() {
+		channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(rssDocument{
+		Version: "2.0",
+		Atom:    atomNamespace,
+		Channel: channel,
+	})
+}
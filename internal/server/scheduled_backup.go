@@ -0,0 +1,112 @@
+// internal/server/scheduled_backup.go
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"infoscope/internal/schedule"
+)
+
+// scheduledBackupKeep is how many scheduled backup files are retained.
+const scheduledBackupKeep = 7
+
+// backupDir is where scheduled backups are written.
+func (s *Server) backupDir() string {
+	dataPath := s.config.DataPath
+	if dataPath == "" {
+		dataPath = "data"
+	}
+	return filepath.Join(dataPath, "backups")
+}
+
+// siteLocation returns the location for the timezone setting.
+func (s *Server) siteLocation(ctx context.Context) *time.Location {
+	return schedule.Location(s.getSetting(ctx, "timezone"))
+}
+
+// backupSchedule reads the backup_schedule setting in the site timezone.
+func (s *Server) backupSchedule(ctx context.Context) schedule.Spec {
+	spec, err := schedule.Parse(s.getSetting(ctx, "backup_schedule"), s.siteLocation(ctx))
+	if err != nil {
+		s.logger.WarnContext(ctx, "Invalid backup schedule, scheduled backups disabled", "error", err)
+		return schedule.Spec{}
+	}
+	return spec
+}
+
+// backupLoop writes scheduled backups until ctx is cancelled.
+func (s *Server) backupLoop(ctx context.Context) {
+	schedule.Run(ctx, func() schedule.Spec {
+		return s.backupSchedule(ctx)
+	}, func(ctx context.Context) {
+		path, err := s.writeScheduledBackup(ctx)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "Scheduled backup failed", "error", err)
+			return
+		}
+		s.logger.InfoContext(ctx, "Scheduled backup written", "path", path)
+	})
+}
+
+// writeScheduledBackup saves a backup without credentials to the backup
+// directory and prunes old scheduled backups.
+func (s *Server) writeScheduledBackup(ctx context.Context) (string, error) {
+	backup, err := s.buildBackup(ctx, false, "")
+	if err != nil {
+		return "", err
+	}
+
+	dir := s.backupDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating backup directory: %w", err)
+	}
+
+	data, err := json.Marshal(backup)
+	if err != nil {
+		return "", fmt.Errorf("encoding backup: %w", err)
+	}
+
+	name := fmt.Sprintf("infoscope_backup_%s.json",
+		backup.ExportDate.In(s.siteLocation(ctx)).Format("2006-01-02_1504"))
+	path := filepath.Join(dir, name)
+	if err := writeFileAtomic(path, data); err != nil {
+		return "", fmt.Errorf("writing backup: %w", err)
+	}
+
+	if err := s.setAppState(ctx, "last_backup_at", backup.ExportDate.UTC().Format(time.RFC3339)); err != nil {
+		s.logger.ErrorContext(ctx, "Error recording backup time", "error", err)
+	}
+
+	s.pruneScheduledBackups(dir)
+	return path, nil
+}
+
+// pruneScheduledBackups keeps only the newest scheduledBackupKeep files.
+func (s *Server) pruneScheduledBackups(dir string) {
+	files, err := filepath.Glob(filepath.Join(dir, "infoscope_backup_*.json"))
+	if err != nil || len(files) <= scheduledBackupKeep {
+		return
+	}
+
+	// Names embed the timestamp, so lexical order is chronological
+	sort.Strings(files)
+	for _, f := range files[:len(files)-scheduledBackupKeep] {
+		if err := os.Remove(f); err != nil {
+			s.logger.Error("Error removing old backup", "file", f, "error", err)
+		}
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,115 @@
+// internal/server/search.go
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// searchLimit caps the results of each kind
+	searchLimit = 8
+	// searchEntryDays is how far back entry titles are searched
+	searchEntryDays = 30
+)
+
+// SearchResult is one hit for the admin quick switcher
+type SearchResult struct {
+	Kind   string `json:"kind"`
+	Title  string `json:"title"`
+	Detail string `json:"detail,omitempty"`
+	URL    string `json:"url"`
+}
+
+// adminPages are matched by name so the switcher can also navigate
+var adminPages = []SearchResult{
+	{Kind: "page", Title: "Dashboard", URL: "/admin"},
+	{Kind: "page", Title: "Manage Feeds", URL: "/admin/feeds"},
+	{Kind: "page", Title: "Fetch Errors", URL: "/admin/fetch-errors"},
+	{Kind: "page", Title: "Media", URL: "/admin/media"},
+	{Kind: "page", Title: "Settings", URL: "/admin/settings"},
+}
+
+// escapeLike escapes LIKE wildcards so the query matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// search looks the query up across feeds, feed tags and categories,
+// setting keys and recent entry titles in a single query.
+func (s *Server) search(ctx context.Context, q string) ([]SearchResult, error) {
+	results := make([]SearchResult, 0)
+	lower := strings.ToLower(q)
+	for _, page := range adminPages {
+		if strings.Contains(strings.ToLower(page.Title), lower) {
+			results = append(results, page)
+		}
+	}
+
+	pattern := "%" + escapeLike(lower) + "%"
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT * FROM (
+            SELECT 'feed', COALESCE(NULLIF(title, ''), url), url, '/admin/feeds#feed-' || id
+            FROM feeds
+            WHERE LOWER(title) LIKE ?1 ESCAPE '\' OR LOWER(url) LIKE ?1 ESCAPE '\'
+               OR LOWER(COALESCE(category, '')) LIKE ?1 ESCAPE '\'
+               OR LOWER(COALESCE(tags, '')) LIKE ?1 ESCAPE '\'
+            ORDER BY title
+            LIMIT ?2)
+        UNION ALL
+        SELECT * FROM (
+            SELECT 'setting', key, '', '/admin/settings'
+            FROM settings
+            WHERE LOWER(key) LIKE ?1 ESCAPE '\'
+            ORDER BY key
+            LIMIT ?2)
+        UNION ALL
+        SELECT * FROM (
+            SELECT 'entry', e.title, COALESCE(NULLIF(f.title, ''), f.url), e.url
+            FROM entries e
+            JOIN feeds f ON f.id = e.feed_id
+            WHERE LOWER(e.title) LIKE ?1 ESCAPE '\'
+              AND datetime(e.published_at) > datetime('now', ?3)
+            ORDER BY e.published_at DESC, e.id DESC
+            LIMIT ?2)`,
+		pattern, searchLimit, fmt.Sprintf("-%d days", searchEntryDays))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.Kind, &r.Title, &r.Detail, &r.URL); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// handleSearch powers the admin quick switcher at /admin/api/search?q=
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	results := make([]SearchResult, 0)
+	if q != "" {
+		var err error
+		results, err = s.search(r.Context(), q)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error searching", "query", q, "error", err)
+			writeDBError(w, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Results []SearchResult `json:"results"`
+	}{results})
+}
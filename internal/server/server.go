@@ -7,49 +7,115 @@ import (
 	"fmt"
 	"infoscope/internal/auth"
 	"infoscope/internal/feed"
-	"log"
+	"infoscope/internal/hooks"
+	"infoscope/internal/ratelimit"
+	"infoscope/internal/statsd"
+	"infoscope/internal/storage"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
 	"path/filepath"
+	"time"
 )
 
 type Config struct {
 	UseHTTPS               bool
 	DisableTemplateUpdates bool
 	WebPath                string
+	DataPath               string
+	ReadOnly               bool
+
+	// AssetsPath holds fetched favicons and uploaded images; empty keeps
+	// them under WebPath's static directory
+	AssetsPath string
+
+	// Assets stores fetched favicons and uploaded images in place of
+	// AssetsPath, such as in an S3 bucket; nil keeps them on disk
+	Assets storage.Store
+
+	// StatsD, when set, receives the metrics every StatsDInterval
+	StatsD         *statsd.Client
+	StatsDInterval time.Duration
+
+	// Hooks hears about clicks; nil drops them
+	Hooks *hooks.Registry
+
+	// Version is the running build, compared against the latest release
+	Version string
+
+	// Sessions holds admin sessions; nil keeps them in the database
+	Sessions auth.SessionStore
+
+	// TLS, when enabled, has Start serve HTTPS itself
+	TLS TLSConfig
+
+	// TrustedProxies are reverse proxies whose X-Forwarded-For is believed
+	// when working out a client's address
+	TrustedProxies []netip.Prefix
+
+	// LoginBurst and LoginRefill throttle sign-in attempts per client
+	// address; zero values keep ten at once and one a minute after that
+	LoginBurst  int
+	LoginRefill time.Duration
 }
 
 type Server struct {
 	db           *sql.DB
-	logger       *log.Logger
+	logger       *slog.Logger
 	auth         *auth.Service
 	settings     *SettingsManager
 	feedService  *feed.Service
 	imageHandler *ImageHandler
+	assets       storage.Store
 	csrf         *CSRF
 	config       Config
+	favicons     faviconRefresh
+	tagBackfill  tagRuleBackfill
+	passkeys     passkeyCeremonies
+	loginLimiter *ratelimit.Limiter
 }
 
-func NewServer(db *sql.DB, logger *log.Logger, feedService *feed.Service, config Config) (*Server, error) {
-	// Initialize image handler
-	imageHandler, err := NewImageHandler(db, logger)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize image handler: %w", err)
+func NewServer(db *sql.DB, logger *slog.Logger, feedService *feed.Service, config Config) (*Server, error) {
+	// Initialize asset storage and the image handler on top of it
+	assets := config.Assets
+	if assets == nil {
+		assetsPath := config.AssetsPath
+		if assetsPath == "" {
+			assetsPath = filepath.Join(config.WebPath, "static")
+		}
+		dir, err := storage.NewDir(assetsPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create asset directory: %w", err)
+		}
+		assets = dir
 	}
+	imageHandler := NewImageHandler(db, logger.With("component", "images"), storage.Sub(assets, "images"))
 
 	// Initialize CSRF with configuration
 	csrfConfig := DefaultConfig()
 	csrfConfig.Secure = config.UseHTTPS
 
+	sessions := config.Sessions
+	if sessions == nil {
+		sessions = auth.NewSQLStore(db)
+	}
+
 	// Create server instance
 	s := &Server{
 		db:           db,
 		logger:       logger,
-		auth:         auth.NewService(),
+		auth:         auth.NewService(sessions),
 		settings:     NewSettingsManager(),
 		feedService:  feedService,
 		imageHandler: imageHandler,
+		assets:       assets,
 		csrf:         NewCSRF(csrfConfig),
 		config:       config,
+		loginLimiter: ratelimit.New(loginBurst, loginRefill),
+	}
+	if config.LoginBurst > 0 && config.LoginRefill > 0 {
+		s.loginLimiter.SetRate(config.LoginBurst, config.LoginRefill)
 	}
 
 	// Extract web content if needed, force update if not disabled
@@ -57,12 +123,32 @@ func NewServer(db *sql.DB, logger *log.Logger, feedService *feed.Service, config
 		return nil, fmt.Errorf("failed to extract web content: %w", err)
 	}
 
-	// Initialize total click counts
-	if err := s.initializeTotalClicks(); err != nil {
-		return nil, fmt.Errorf("error initializing click counts: %w", err)
+	// A read-only mirror never writes, so it neither fixes up click counts
+	// nor runs scheduled jobs
+	if !config.ReadOnly {
+		// Initialize total click counts
+		if err := s.initializeTotalClicks(); err != nil {
+			return nil, fmt.Errorf("error initializing click counts: %w", err)
+		}
+
+		// Run scheduled backups in the background
+		go s.backupLoop(context.Background())
+
+		// Publish weekly roundups of the most clicked entries
+		go s.roundupLoop(context.Background())
+
+		// Submit clicked links to the Wayback Machine
+		go s.waybackLoop(context.Background())
+
+		// Look for newer releases, when enabled
+		go s.updateCheckLoop(context.Background())
+	}
+
+	if config.StatsD != nil {
+		go s.statsdLoop(context.Background(), config.StatsD, config.StatsDInterval)
 	}
 
-	s.logger.Printf("Server initialized successfully")
+	s.logger.Info("Server initialized successfully")
 	return s, nil
 }
 
@@ -72,36 +158,114 @@ func (s *Server) Routes() http.Handler {
 	// Serve static files
 	fileServer := http.FileServer(http.Dir(filepath.Join(s.config.WebPath, "static")))
 	mux.Handle("/static/", http.StripPrefix("/static/", fileServer))
+	for _, dir := range assetDirs {
+		mux.Handle("/static/"+dir+"/", s.assetHandler())
+	}
 
 	// Setup endpoints
-	mux.HandleFunc("/setup", s.handleSetup)
-	mux.HandleFunc("/setup/", s.handleSetup)
+	mux.HandleFunc("/setup", s.throttle(s.handleSetup))
+	mux.HandleFunc("/setup/", s.throttle(s.handleSetup))
 
 	// Admin routes
-	mux.HandleFunc("/admin/login", s.handleLogin)
-	mux.HandleFunc("/admin/login/", s.handleLogin)
-	mux.HandleFunc("/admin/logout", s.requireAuth(s.handleLogout))
-	mux.HandleFunc("/admin/logout/", s.requireAuth(s.handleLogout))
-	mux.HandleFunc("/admin/settings", s.requireAuth(s.handleSettings))
-	mux.HandleFunc("/admin/settings/", s.requireAuth(s.handleSettings))
+	mux.HandleFunc("/admin/login", s.throttle(s.handleLogin))
+	mux.HandleFunc("/admin/login/", s.throttle(s.handleLogin))
+	mux.HandleFunc("/admin/login/passkey/begin", s.throttle(s.handlePasskeyLoginBegin))
+	mux.HandleFunc("/admin/login/passkey/finish", s.throttle(s.handlePasskeyLoginFinish))
+	mux.HandleFunc("/admin/logout", s.requireRole(auth.RoleViewer, auth.RoleViewer, s.handleLogout))
+	mux.HandleFunc("/admin/logout/", s.requireRole(auth.RoleViewer, auth.RoleViewer, s.handleLogout))
+	mux.HandleFunc("/admin/settings", s.requireAdmin(s.handleSettings))
+	mux.HandleFunc("/admin/settings/", s.requireAdmin(s.handleSettings))
 	mux.HandleFunc("/admin/feeds", s.requireAuth(s.handleFeeds))
 	mux.HandleFunc("/admin/feeds/", s.requireAuth(s.handleFeeds))
 	mux.HandleFunc("/admin/feeds/validate", s.requireAuth(s.handleFeedValidation))
 	mux.HandleFunc("/admin/feeds/validate/", s.requireAuth(s.handleFeedValidation))
-	mux.HandleFunc("/admin/backup", s.requireAuth(s.handleBackup))
-	mux.HandleFunc("/admin/backup/", s.requireAuth(s.handleBackup))
+	mux.HandleFunc("/admin/tags", s.requireAuth(s.handleTagPriorities))
+	mux.HandleFunc("/admin/tag-rules", s.requireAuth(s.handleTagRules))
+	mux.HandleFunc("/admin/tag-rules/apply", s.requireAuth(s.handleTagRuleBackfill))
+	mux.HandleFunc("/admin/favicons/refresh", s.requireAuth(s.handleFaviconRefresh))
+	mux.HandleFunc("/admin/opml", s.requireAuth(s.handleOPML))
+	mux.HandleFunc("/admin/backup", s.requireAdmin(s.handleBackup))
+	mux.HandleFunc("/admin/backup/", s.requireAdmin(s.handleBackup))
+	mux.HandleFunc("/admin/api/stats", s.requireStatsToken(s.handleQuickStats))
+	mux.HandleFunc("/admin/api/search", s.requireAuth(s.handleSearch))
+	mux.HandleFunc("/admin/entries", s.requireAuth(s.handleEntryBrowser))
+	mux.HandleFunc("/admin/entries/search", s.requireAuth(s.handleEntrySearch))
+	mux.HandleFunc("/admin/entries/revisions", s.requireAuth(s.handleEntryRevisions))
+	mux.HandleFunc("/admin/media", s.requireRole(auth.RoleViewer, auth.RoleAdmin, s.handleMedia))
+	mux.HandleFunc("/admin/fetch-errors", s.requireAuth(s.handleErrorDigest))
+	mux.HandleFunc("/admin/feed-health", s.requireAuth(s.handleFeedHealth))
+	mux.HandleFunc("/admin/feed-diagnostics", s.requireAuth(s.handleFeedDiagnostics))
+	mux.HandleFunc("/admin/alerts", s.requireAuth(s.handleFetchAlerts))
+	mux.HandleFunc("/admin/notifications", s.requireAuth(s.handleNotifications))
+	mux.HandleFunc("/admin/api-tokens", s.requireAdmin(s.handleAPITokens))
+	mux.HandleFunc("/admin/users", s.requireAdmin(s.handleUsers))
+	mux.HandleFunc("/admin/account", s.requireRole(auth.RoleViewer, auth.RoleViewer, s.throttle(s.handleAccount)))
+	mux.HandleFunc("/admin/account/passkeys", s.requireRole(auth.RoleViewer, auth.RoleViewer, s.handleAccountPasskeys))
+	mux.HandleFunc("/admin/account/passkeys/begin", s.requireRole(auth.RoleViewer, auth.RoleViewer, s.handlePasskeyRegisterBegin))
+	mux.HandleFunc("/admin/account/passkeys/finish", s.requireRole(auth.RoleViewer, auth.RoleViewer, s.handlePasskeyRegisterFinish))
 	mux.HandleFunc("/admin/metrics", s.requireAuth(s.handleMetrics))
 	mux.HandleFunc("/admin/metrics/", s.requireAuth(s.handleMetrics))
 	mux.HandleFunc("/admin", s.requireAuth(s.handleAdmin))
 	mux.HandleFunc("/admin/", s.requireAuth(s.handleAdmin))
 
+	// Token-authenticated JSON API
+	mux.HandleFunc("/api/v1/feeds", s.requireAPIToken(s.handleAPIFeeds))
+	mux.HandleFunc("/api/v1/feeds/", s.requireAPIToken(s.handleAPIFeed))
+	mux.HandleFunc("/api/v1/entries", s.requireAPIToken(s.handleAPIEntries))
+	mux.HandleFunc("/api/v1/entries/", s.requireAPIToken(s.handleAPIEntry))
+	mux.HandleFunc("/api/v1/settings", s.requireAPIToken(s.handleAPISettings))
+	mux.HandleFunc("/api/v1/categories", s.requireAPIToken(s.handleAPICategories))
+	mux.HandleFunc("/api/v1/tags", s.requireAPIToken(s.handleAPITags))
+	mux.HandleFunc("/api/v1/tags/", s.requireAPIToken(s.handleAPITag))
+	mux.HandleFunc("/api/v1/tag-rules", s.requireAPIToken(s.handleAPITagRules))
+	mux.HandleFunc("/api/v1/tag-rules/", s.requireAPIToken(s.handleAPITagRule))
+	mux.HandleFunc("/api/v1/openapi.json", s.handleOpenAPI)
+	mux.HandleFunc("/api/", s.handleAPINotFound)
+
+	// Entries for embedding the river on other sites, without a login
+	mux.HandleFunc("/api/public/entries", s.handlePublicEntries)
+
+	// Visitor keyword muting
+	mux.HandleFunc("/mute", s.handleMute)
+
+	// Atom feed of the river
+	mux.HandleFunc("/atom.xml", s.handleAtom)
+
+	// On-demand entry translation
+	mux.HandleFunc("/translate", s.handleTranslate)
+
+	// Public pages and RSS for single feeds
+	mux.HandleFunc("/feeds/", s.handleFeedPage)
+
+	// Category and tag rivers, with RSS
+	mux.HandleFunc("/category/", s.handleCategoryPage)
+	mux.HandleFunc("/tag/", s.handleTagPage)
+
+	// Weekly roundups
+	mux.HandleFunc("/roundup/", s.handleRoundup)
+
+	// Paged archive of every entry, when entries are never deleted
+	mux.HandleFunc("/archive", s.handleArchive)
+	mux.HandleFunc("/archive/", s.handleArchive)
+
+	// Digest of what came in since a visitor's last visit
+	mux.HandleFunc("/catchup", s.handleCatchup)
+
+	// Entries starred by the admin, with RSS
+	mux.HandleFunc("/starred", s.handleStarred)
+	mux.HandleFunc("/starred/", s.handleStarred)
+
+	// CSRF tokens for cached pages
+	mux.HandleFunc("/csrf", s.handleCSRFToken)
+
 	// Click tracking
 	mux.HandleFunc("/click", s.handleClick)
 	mux.HandleFunc("/click/", s.handleClick)
 
 	// image upload support
-	mux.HandleFunc("/admin/upload-favicon", s.requireAuth(s.imageHandler.HandleFaviconUpload))
-	mux.HandleFunc("/admin/upload-meta-image", s.requireAuth(s.imageHandler.HandleMetaImageUpload))
+	mux.HandleFunc("/media/", s.imageHandler.ServeImage)
+	mux.HandleFunc("/admin/upload-favicon", s.requireAdmin(s.imageHandler.HandleFaviconUpload))
+	mux.HandleFunc("/admin/upload-meta-image", s.requireAdmin(s.imageHandler.HandleMetaImageUpload))
 
 	// Handle root and all unmatched paths
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -112,12 +276,18 @@ func (s *Server) Routes() http.Handler {
 		s.handleIndex(w, r)
 	})
 
-	return mux
+	var handler http.Handler = mux
+	handler = s.limitBodies(handler)
+	if s.config.ReadOnly {
+		handler = s.readOnlyGuard(handler)
+	}
+	handler = s.cacheHeaders(handler)
+	return s.recoverPanics(handler)
 }
 
 // handle 404 pages for unspecified html routes
 func (s *Server) handle404(w http.ResponseWriter, r *http.Request) {
-	s.logger.Printf("404 error for path: %s", r.URL.Path)
+	s.logger.DebugContext(r.Context(), "404 error for path", "path", r.URL.Path)
 
 	// Create template data
 	data := struct {
@@ -132,13 +302,26 @@ func (s *Server) handle404(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotFound)
 
 	if err := s.renderTemplate(w, r, "404.html", data); err != nil {
-		s.logger.Printf("Error rendering 404 template: %v", err)
+		s.logger.ErrorContext(r.Context(), "Error rendering 404 template", "error", err)
 		http.Error(w, "404 Page Not Found", http.StatusNotFound)
 	}
 }
 
-// requireAuth wraps handlers with authentication and CSRF token injection
+// requireAuth wraps handlers with authentication and CSRF token injection.
+// Any account may read; changes need an editor.
 func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return s.requireRole(auth.RoleViewer, auth.RoleEditor, next)
+}
+
+// requireAdmin wraps handlers only admins may use at all
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return s.requireRole(auth.RoleAdmin, auth.RoleAdmin, next)
+}
+
+// requireRole wraps handlers with authentication, allowing safe methods to
+// accounts with the read role and anything else to those with the write
+// role. The role is looked up on every request so changes apply at once.
+func (s *Server) requireRole(read, write string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Check authentication
 		cookie, err := r.Cookie("session")
@@ -148,14 +331,42 @@ func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
 		}
 
 		// Validate session and get user ID
-		session, err := s.auth.ValidateSession(s.db, cookie.Value)
+		session, err := s.auth.ValidateSession(r.Context(), cookie.Value)
 		if err != nil {
 			http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
 			return
 		}
 
-		// Create new context with user ID
+		// A deleted account's sessions may outlive it in Redis
+		user, err := auth.GetUser(r.Context(), s.db, session.UserID)
+		if err == auth.ErrUserNotFound {
+			http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting user", "user_id", session.UserID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		required := write
+		if isSafeMethod(r.Method) {
+			required = read
+		}
+		if !auth.RoleAllows(user.Role, required) {
+			s.logger.WarnContext(r.Context(), "Permission denied",
+				"user_id", user.ID, "role", user.Role, "method", r.Method, "path", r.URL.Path)
+			if isSafeMethod(r.Method) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+			} else {
+				writeAPIError(w, http.StatusForbidden, codeForbidden, "Your role doesn't allow this")
+			}
+			return
+		}
+
+		// Create new context with user ID and role
 		ctx := context.WithValue(r.Context(), contextKeyUserID, session.UserID)
+		ctx = context.WithValue(ctx, contextKeyUserRole, user.Role)
 
 		// Get CSRF token
 		token := s.csrf.Token(w, r)
@@ -178,6 +389,18 @@ func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
 }
 
 func (s *Server) Start(addr string) error {
-	s.logger.Printf("Starting server on %s", addr)
-	return http.ListenAndServe(addr, s.Routes())
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln, such as a socket passed in by systemd
+func (s *Server) Serve(ln net.Listener) error {
+	if s.config.TLS.Enabled() {
+		return s.serveTLS(ln)
+	}
+	s.logger.Info("Starting server", "addr", ln.Addr().String())
+	return http.Serve(ln, s.Routes())
 }
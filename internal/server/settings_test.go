@@ -0,0 +1,54 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestDefaultSettingsPassTheRules(t *testing.T) {
+	_, db := newTestServerDB(t, nil)
+	rows, err := db.Query("SELECT key, value FROM settings")
+	if err != nil {
+		t.Fatalf("Failed to read settings: %v", err)
+	}
+	defer rows.Close()
+	settings := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			t.Fatalf("Failed to scan setting: %v", err)
+		}
+		settings[key] = value
+	}
+	if fields := validateSettingValues(settings); len(fields) > 0 {
+		t.Errorf("Default settings break the rules: %v", fields)
+	}
+}
+
+func TestValidateSettingsForm(t *testing.T) {
+	form := Settings{
+		MaxPosts:               100,
+		UpdateInterval:         30,
+		RetentionDays:          -1,
+		HostConcurrency:        1,
+		RoundupSize:            10,
+		FooterImageHeight:      "50px",
+		FaviconURL:             "../../etc/passwd",
+		Timezone:               "UTC",
+		DedupKey:               "url",
+		CrossFeedDedup:         "off",
+		EntryUpdateMode:        "newer",
+		RiverMode:              RiverChronological,
+		RiverLayout:            RiverStream,
+		AdaptivePollMinMinutes: 5,
+		AdaptivePollMaxMinutes: 60,
+	}
+	fields := validateSettingsForm(form)
+	for _, field := range []string{"updateInterval", "retentionDays", "faviconURL"} {
+		if fields[field] == "" {
+			t.Errorf("%s accepted, want it refused", field)
+		}
+	}
+	if len(fields) != 3 {
+		t.Errorf("Invalid fields %v, want only updateInterval, retentionDays and faviconURL", fields)
+	}
+}
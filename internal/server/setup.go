@@ -33,7 +33,7 @@ type setupRequest struct {
 }
 
 func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
-	s.logger.Printf("Setup handler called: %s %s", r.Method, r.URL.Path)
+	s.logger.DebugContext(r.Context(), "Setup handler called", "method", r.Method, "path", r.URL.Path)
 	switch r.Method {
 	case http.MethodGet:
 		// Get CSRF token
@@ -42,7 +42,7 @@ func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
 		// Get settings
 		settings, err := s.getSettings(r.Context())
 		if err != nil {
-			s.logger.Printf("Error getting settings: %v", err)
+			s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
 			settings = make(map[string]string)
 		}
 
@@ -64,13 +64,13 @@ func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
 		tmplPath := filepath.Join(s.config.WebPath, "templates", "setup.html")
 		tmpl, err := template.ParseFiles(tmplPath)
 		if err != nil {
-			s.logger.Printf("Error parsing setup template: %v", err)
+			s.logger.ErrorContext(r.Context(), "Error parsing setup template", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
 		if err := tmpl.Execute(w, data); err != nil {
-			s.logger.Printf("Error executing setup template: %v", err)
+			s.logger.ErrorContext(r.Context(), "Error executing setup template", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
@@ -82,28 +82,31 @@ func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
 
 		var req setupRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request", http.StatusBadRequest)
+			writeDecodeError(w, err, "Invalid request")
 			return
 		}
 
 		// Validate input
 		if req.Username == "" || req.Password == "" {
-			http.Error(w, "Username and password are required", http.StatusBadRequest)
+			writeValidationError(w, "Username and password are required",
+				map[string]string{"username": "required", "password": "required"})
 			return
 		}
 		if req.Password != req.ConfirmPassword {
-			http.Error(w, "Passwords do not match", http.StatusBadRequest)
+			writeValidationError(w, "Passwords do not match",
+				map[string]string{"confirmPassword": "does not match the password"})
 			return
 		}
-		if len(req.Password) < 8 {
-			http.Error(w, "Password must be at least 8 characters", http.StatusBadRequest)
+		if len(req.Password) < auth.MinPasswordLength {
+			writeValidationError(w, "Password must be at least 8 characters",
+				map[string]string{"password": "must be at least 8 characters"})
 			return
 		}
 
 		// Create admin user
-		if err := auth.CreateUser(s.db, req.Username, req.Password); err != nil {
-			s.logger.Printf("Failed to create user: %v", err)
-			http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		if _, err := auth.CreateUser(s.db, req.Username, req.Password, auth.RoleAdmin); err != nil {
+			s.logger.ErrorContext(r.Context(), "Failed to create user", "error", err)
+			writeAPIError(w, http.StatusInternalServerError, codeInternal, "Failed to create user")
 			return
 		}
 
@@ -114,7 +117,7 @@ func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
 				"site_title", req.SiteTitle,
 			)
 			if err != nil {
-				s.logger.Printf("Failed to set site title: %v", err)
+				s.logger.ErrorContext(r.Context(), "Failed to set site title", "error", err)
 			}
 		}
 
@@ -122,6 +125,6 @@ func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
 	}
 }
@@ -0,0 +1,78 @@
+// internal/server/share.go
+package server
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ShareLinks are ready-made share targets for an entry, so themes can
+// render share and copy buttons without knowing each service's URL format.
+type ShareLinks struct {
+	Permalink string `json:"permalink"`
+	Text      string `json:"text"`
+	Mastodon  string `json:"mastodon"`
+	Bluesky   string `json:"bluesky"`
+	Email     string `json:"email"`
+}
+
+// trackingParams are query parameters dropped from permalinks
+var trackingParams = []string{"fbclid", "gclid", "mc_cid", "mc_eid", "ref"}
+
+// canonicalURL strips fragments and tracking parameters from an entry URL.
+func canonicalURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	u.Fragment = ""
+
+	q := u.Query()
+	for key := range q {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") {
+			q.Del(key)
+		}
+	}
+	for _, key := range trackingParams {
+		q.Del(key)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// shareLinksFor builds the share targets for an entry. Mastodon has no
+// central share page, so links go to the configured instance.
+func shareLinksFor(e EntryView, mastodonInstance string) *ShareLinks {
+	permalink := canonicalURL(e.URL)
+	text := e.Title + " " + permalink
+
+	instance := strings.TrimSuffix(strings.TrimSpace(mastodonInstance), "/")
+	instance = strings.TrimPrefix(strings.TrimPrefix(instance, "https://"), "http://")
+	if instance == "" {
+		instance = "mastodon.social"
+	}
+
+	return &ShareLinks{
+		Permalink: permalink,
+		Text:      text,
+		Mastodon:  "https://" + instance + "/share?text=" + url.QueryEscape(text),
+		Bluesky:   "https://bsky.app/intent/compose?text=" + url.QueryEscape(text),
+		Email:     "mailto:?subject=" + mailtoEscape(e.Title) + "&body=" + mailtoEscape(permalink),
+	}
+}
+
+// mailtoEscape escapes a mailto header value. Mail clients don't read "+"
+// as a space, so spaces are percent-encoded.
+func mailtoEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// withShareLinks attaches share links to every entry except roundups,
+// which point at a local page.
+func withShareLinks(entries []EntryView, mastodonInstance string) {
+	for i := range entries {
+		if !entries[i].Roundup {
+			entries[i].Share = shareLinksFor(entries[i], mastodonInstance)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// internal/server/since_login.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FeedErrorCount is a feed that failed one or more fetches in a period
+type FeedErrorCount struct {
+	FeedID int64  `json:"feedId"`
+	Title  string `json:"title"`
+	Errors int    `json:"errors"`
+}
+
+// SinceLastLogin summarizes activity since the admin's previous login
+type SinceLastLogin struct {
+	Since        time.Time        `json:"since"`
+	NewEntries   int              `json:"newEntries"`
+	NewFeeds     int              `json:"newFeeds"`
+	ErroredFeeds []FeedErrorCount `json:"erroredFeeds"`
+	Backups      int              `json:"backups"`
+	LastBackup   time.Time        `json:"lastBackup"`
+}
+
+// recordLogin shifts the stored last login into previous_login and stamps
+// the new one, so the dashboard can report what happened in between.
+func (s *Server) recordLogin(ctx context.Context, userID int64) error {
+	_, err := s.db.ExecContext(ctx, `
+        UPDATE admin_users
+        SET previous_login = last_login, last_login = CURRENT_TIMESTAMP
+        WHERE id = ?`, userID)
+	return err
+}
+
+// getSinceLastLogin returns nil when the user has not logged in before.
+func (s *Server) getSinceLastLogin(ctx context.Context, userID int64) (*SinceLastLogin, error) {
+	var previous sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		"SELECT previous_login FROM admin_users WHERE id = ?", userID).Scan(&previous)
+	if err != nil {
+		return nil, fmt.Errorf("error getting previous login: %w", err)
+	}
+	if !previous.Valid {
+		return nil, nil
+	}
+
+	summary := &SinceLastLogin{Since: previous.Time.UTC()}
+	since := summary.Since.Format("2006-01-02 15:04:05")
+
+	err = s.db.QueryRowContext(ctx, `
+        SELECT
+            (SELECT COUNT(*) FROM entries WHERE created_at > ?),
+            (SELECT COUNT(*) FROM feeds WHERE created_at > ?)`,
+		since, since).Scan(&summary.NewEntries, &summary.NewFeeds)
+	if err != nil {
+		return nil, fmt.Errorf("error counting new items: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT l.feed_id, COALESCE(NULLIF(f.title, ''), f.url), COUNT(*) AS errors
+        FROM fetch_log l
+        JOIN feeds f ON f.id = l.feed_id
+        WHERE l.status = 'error' AND l.created_at > ?
+        GROUP BY l.feed_id
+        ORDER BY errors DESC
+        LIMIT 10`, since)
+	if err != nil {
+		return nil, fmt.Errorf("error getting feed errors: %w", err)
+	}
+	defer rows.Close()
+
+	summary.ErroredFeeds = make([]FeedErrorCount, 0)
+	for rows.Next() {
+		var fe FeedErrorCount
+		if err := rows.Scan(&fe.FeedID, &fe.Title, &fe.Errors); err != nil {
+			return nil, fmt.Errorf("error scanning feed errors: %w", err)
+		}
+		summary.ErroredFeeds = append(summary.ErroredFeeds, fe)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Scheduled backups are counted from the files on disk; manual exports
+	// only leave the last_backup_at timestamp behind.
+	files, _ := filepath.Glob(filepath.Join(s.backupDir(), "infoscope_backup_*.json"))
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil && info.ModTime().After(summary.Since) {
+			summary.Backups++
+		}
+	}
+	if lastBackup := s.getAppState(ctx, "last_backup_at"); lastBackup != "" {
+		if t, err := time.Parse(time.RFC3339, lastBackup); err == nil && t.After(summary.Since) {
+			summary.LastBackup = t
+			if summary.Backups == 0 {
+				summary.Backups = 1
+			}
+		}
+	}
+
+	return summary, nil
+}
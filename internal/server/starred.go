@@ -0,0 +1,160 @@
+// internal/server/starred.go
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// starredPageSize is how many entries a /starred page lists, and the
+	// RSS feed carries
+	starredPageSize = 50
+	// pinnedSectionKey names the pinned section in the collapsed sections
+	// cookie; the ~ keeps it apart from category keys
+	pinnedSectionKey = "~pinned"
+)
+
+// StarredPageData is the template data for the public starred page
+type StarredPageData struct {
+	SiteTitle string
+	Settings  map[string]string
+	Entries   []EntryView
+	Page      int
+	PrevPage  int
+	NextPage  int
+}
+
+// handleStarred serves /starred, the entries starred from the admin
+// entries browser, most recently starred first with ?page=N, and
+// /starred/rss.xml with the newest of them as RSS.
+func (s *Server) handleStarred(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path != "/starred" && r.URL.Path != "/starred/rss.xml" {
+		s.handle404(w, r)
+		return
+	}
+
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+		settings = make(map[string]string)
+	}
+
+	if r.URL.Path == "/starred/rss.xml" {
+		entries, err := s.getStarredEntries(r.Context(), 0, starredPageSize)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting starred entries", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		page := siteBaseURL(r, settings["site_url"]) + "/starred"
+		title := "Starred - " + settings["site_title"]
+		description := fmt.Sprintf("Entries starred on %s", settings["site_title"])
+		if err := writeRSS(w, title, page, description, page+"/rss.xml", entries); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error encoding RSS for starred entries", "error", err)
+		}
+		return
+	}
+
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		if page, err = strconv.Atoi(p); err != nil || page < 1 {
+			s.handle404(w, r)
+			return
+		}
+	}
+	entries, err := s.getStarredEntries(r.Context(), (page-1)*starredPageSize, starredPageSize+1)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting starred entries", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(entries) == 0 && page > 1 {
+		s.handle404(w, r)
+		return
+	}
+
+	data := StarredPageData{
+		SiteTitle: settings["site_title"],
+		Settings:  settings,
+		Page:      page,
+		PrevPage:  page - 1,
+	}
+	if len(entries) > starredPageSize {
+		entries = entries[:starredPageSize]
+		data.NextPage = page + 1
+	}
+	data.Entries = entries
+
+	if err := s.renderTemplate(w, r, "starred.html", data); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error rendering starred template", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// getStarredEntries returns up to limit starred entries after skipping
+// offset, most recently starred first.
+func (s *Server) getStarredEntries(ctx context.Context, offset, limit int) ([]EntryView, error) {
+	return s.queryStarredEntries(ctx, "", limit, offset)
+}
+
+// getPinnedEntries returns the starred entries pinned to the top of the
+// river, most recently starred first.
+func (s *Server) getPinnedEntries(ctx context.Context) ([]EntryView, error) {
+	entries, err := s.queryStarredEntries(ctx, " AND e.pinned = 1", -1, 0)
+	for i := range entries {
+		entries[i].Pinned = true
+	}
+	return entries, err
+}
+
+func (s *Server) queryStarredEntries(ctx context.Context, cond string, limit, offset int) ([]EntryView, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT e.id, e.feed_id, e.title, e.url, e.favicon_url,
+               COALESCE(f.category, ''), COALESCE(f.language, ''),
+               datetime(e.published_at)
+        FROM entries e
+        JOIN feeds f ON e.feed_id = f.id
+        WHERE e.starred_at IS NOT NULL AND e.hidden_at IS NULL AND f.status != 'deleted'`+cond+`
+        ORDER BY e.starred_at DESC, e.id DESC
+        LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+	return scanEntryViews(rows, s.siteLocation(ctx))
+}
+
+// withPinned puts the pinned entries first, dropping their copies from
+// the river below
+func withPinned(pinned, entries []EntryView) []EntryView {
+	if len(pinned) == 0 {
+		return entries
+	}
+	ids := make(map[int64]bool, len(pinned))
+	for _, e := range pinned {
+		ids[e.ID] = true
+	}
+	result := append(make([]EntryView, 0, len(pinned)+len(entries)), pinned...)
+	for _, e := range entries {
+		if !ids[e.ID] {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// splitPinned separates the pinned entries leading the river from the rest
+func splitPinned(entries []EntryView) (pinned, rest []EntryView) {
+	n := 0
+	for n < len(entries) && entries[n].Pinned {
+		n++
+	}
+	return entries[:n], entries[n:]
+}
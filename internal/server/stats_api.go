@@ -0,0 +1,146 @@
+// internal/server/stats_api.go
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// FetchCycleStats describes the most recent feed update cycle
+type FetchCycleStats struct {
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	FeedCount  int       `json:"feedCount"`
+	EntryCount int       `json:"entryCount"`
+	ErrorCount int       `json:"errorCount"`
+}
+
+// QuickStats is the payload served to external dashboards
+type QuickStats struct {
+	FeedCount       int              `json:"feedCount"`
+	EntryCount      int              `json:"entryCount"`
+	EntriesToday    int              `json:"entriesToday"`
+	EntriesThisWeek int              `json:"entriesThisWeek"`
+	ErrorFeeds      int              `json:"errorFeeds"`
+	LastFetchCycle  *FetchCycleStats `json:"lastFetchCycle"`
+	DBSizeBytes     int64            `json:"dbSizeBytes"`
+	LastBackup      *time.Time       `json:"lastBackup"`
+	Update          *UpdateStatus    `json:"update,omitempty"`
+	GeneratedAt     time.Time        `json:"generatedAt"`
+}
+
+// requireStatsToken guards a handler with the bearer token from the stats_api_token
+// setting. The endpoint is disabled while no token is configured.
+func (s *Server) requireStatsToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expected := s.getSetting(r.Context(), "stats_api_token")
+		if expected == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		// Only the header is read, so the token stays out of access logs,
+		// proxy logs and browser history
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="infoscope"`)
+			writeAPIError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+func (s *Server) handleQuickStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	stats, err := s.getQuickStats(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting quick stats", "error", err)
+		writeDBError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) getQuickStats(ctx context.Context) (*QuickStats, error) {
+	stats := &QuickStats{GeneratedAt: time.Now().UTC()}
+
+	err := s.db.QueryRowContext(ctx, `
+        SELECT
+            (SELECT COUNT(*) FROM feeds),
+            (SELECT COUNT(*) FROM entries),
+            (SELECT COUNT(*) FROM entries WHERE created_at >= datetime('now', 'start of day')),
+            (SELECT COUNT(*) FROM entries WHERE created_at >= datetime('now', '-7 days')),
+            (SELECT COUNT(*) FROM feeds WHERE error_count > 0)`,
+	).Scan(&stats.FeedCount, &stats.EntryCount, &stats.EntriesToday,
+		&stats.EntriesThisWeek, &stats.ErrorFeeds)
+	if err != nil {
+		return nil, err
+	}
+
+	// Last fetch cycle
+	var cycle FetchCycleStats
+	var startedAt string
+	err = s.db.QueryRowContext(ctx, `
+        SELECT strftime('%Y-%m-%d %H:%M:%S', started_at), duration_ms, feed_count, entry_count, error_count
+        FROM fetch_cycles
+        ORDER BY started_at DESC, id DESC
+        LIMIT 1`,
+	).Scan(&startedAt, &cycle.DurationMs, &cycle.FeedCount, &cycle.EntryCount, &cycle.ErrorCount)
+	if err == nil {
+		cycle.StartedAt, _ = time.ParseInLocation("2006-01-02 15:04:05", startedAt, time.UTC)
+		stats.LastFetchCycle = &cycle
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// Database size
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()",
+	).Scan(&stats.DBSizeBytes); err != nil {
+		s.logger.ErrorContext(ctx, "Error getting database size", "error", err)
+	}
+
+	// Backup status
+	if lastBackup := s.getAppState(ctx, "last_backup_at"); lastBackup != "" {
+		if t, err := time.Parse(time.RFC3339, lastBackup); err == nil {
+			stats.LastBackup = &t
+		}
+	}
+
+	// Release check, when enabled
+	stats.Update = s.getUpdateStatus(ctx)
+
+	return stats, nil
+}
+
+// getAppState reads an internal state value, or an empty string if unset
+func (s *Server) getAppState(ctx context.Context, key string) string {
+	var value string
+	if err := s.db.QueryRowContext(ctx, "SELECT value FROM app_state WHERE key = ?", key).Scan(&value); err != nil {
+		return ""
+	}
+	return value
+}
+
+// setAppState stores an internal state value
+func (s *Server) setAppState(ctx context.Context, key, value string) error {
+	_, err := s.db.ExecContext(ctx, `
+        INSERT INTO app_state (key, value, updated_at)
+        VALUES (?, ?, CURRENT_TIMESTAMP)
+        ON CONFLICT(key) DO UPDATE SET
+            value = excluded.value,
+            updated_at = CURRENT_TIMESTAMP`,
+		key, value)
+	return err
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatsTokenOnlyInHeader(t *testing.T) {
+	h := newTestServer(t, map[string]string{"stats_api_token": "s3cret-token"})
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"bearer", "/admin/api/stats", "Bearer s3cret-token", http.StatusOK},
+		{"wrong token", "/admin/api/stats", "Bearer guess", http.StatusUnauthorized},
+		{"bare token", "/admin/api/stats", "s3cret-token", http.StatusUnauthorized},
+		{"query", "/admin/api/stats?token=s3cret-token", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: GET %s = %d, want %d", tt.name, tt.path, rec.Code, tt.want)
+		}
+	}
+}
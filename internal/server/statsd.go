@@ -0,0 +1,126 @@
+// internal/server/statsd.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"infoscope/internal/database"
+	"infoscope/internal/feed"
+	"infoscope/internal/statsd"
+)
+
+// PoolStats is the database connection pool as shown on the metrics
+// endpoint. InUse near MaxOpen with a climbing WaitCount means requests are
+// queueing for a connection.
+type PoolStats struct {
+	MaxOpen           int   `json:"max_open"`
+	Open              int   `json:"open"`
+	InUse             int   `json:"in_use"`
+	Idle              int   `json:"idle"`
+	WaitCount         int64 `json:"wait_count"`
+	WaitDurationMS    int64 `json:"wait_duration_ms"`
+	MaxIdleClosed     int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+}
+
+func poolStats(st sql.DBStats) PoolStats {
+	return PoolStats{
+		MaxOpen:           st.MaxOpenConnections,
+		Open:              st.OpenConnections,
+		InUse:             st.InUse,
+		Idle:              st.Idle,
+		WaitCount:         st.WaitCount,
+		WaitDurationMS:    st.WaitDuration.Milliseconds(),
+		MaxIdleClosed:     st.MaxIdleClosed,
+		MaxIdleTimeClosed: st.MaxIdleTimeClosed,
+		MaxLifetimeClosed: st.MaxLifetimeClosed,
+	}
+}
+
+// metricCounters flattens the cumulative counters shown on the metrics
+// endpoint.
+func (s *Server) metricCounters() map[string]int64 {
+	transport := feed.GetTransportStats()
+	contention := database.GetContentionStats()
+	pool := s.db.Stats()
+	return map[string]int64{
+		"query_count":                     dbQueryCount.Value(),
+		"fetch_transport.conn_reused":     transport.ConnReused,
+		"fetch_transport.conn_new":        transport.ConnNew,
+		"fetch_transport.tls_resumed":     transport.TLSResumed,
+		"fetch_transport.http2_responses": transport.HTTP2Responses,
+		"db_contention.retries":           contention.Retries,
+		"db_contention.failures":          contention.Failures,
+		"db_pool.wait_count":              pool.WaitCount,
+		"db_pool.wait_duration_ms":        pool.WaitDuration.Milliseconds(),
+		"http_panics":                     httpPanics.Value(),
+	}
+}
+
+// statsdLoop publishes the metrics endpoint's counters and the duration of
+// each fetch cycle to a StatsD agent every interval until ctx is cancelled.
+func (s *Server) statsdLoop(ctx context.Context, client *statsd.Client, interval time.Duration) {
+	defer client.Close()
+
+	// Counters go out as deltas since the previous flush
+	previous := make(map[string]int64)
+	var lastCycle int64
+	s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM fetch_cycles").Scan(&lastCycle)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for name, value := range s.metricCounters() {
+			if delta := value - previous[name]; delta != 0 {
+				client.Count(name, delta)
+			}
+			previous[name] = value
+		}
+		client.Gauge("query_duration_ms", dbQueryDuration.Value())
+		pool := s.db.Stats()
+		client.Gauge("db_pool.open", float64(pool.OpenConnections))
+		client.Gauge("db_pool.in_use", float64(pool.InUse))
+		client.Gauge("db_pool.idle", float64(pool.Idle))
+
+		lastCycle = s.emitFetchCycles(ctx, client, lastCycle)
+
+		if err := client.Flush(); err != nil {
+			s.logger.ErrorContext(ctx, "Error sending metrics to statsd", "error", err)
+		}
+	}
+}
+
+// emitFetchCycles sends timings for fetch cycles recorded after the cycle
+// with ID after, and returns the newest ID seen.
+func (s *Server) emitFetchCycles(ctx context.Context, client *statsd.Client, after int64) int64 {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, duration_ms, feed_count, entry_count, error_count
+        FROM fetch_cycles WHERE id > ? ORDER BY id`, after)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error reading fetch cycles for statsd", "error", err)
+		return after
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, durationMS, feeds, entries, errors int64
+		if err := rows.Scan(&id, &durationMS, &feeds, &entries, &errors); err != nil {
+			break
+		}
+		client.Timing("fetch_cycle.duration", time.Duration(durationMS)*time.Millisecond)
+		client.Count("fetch_cycle.feeds", feeds)
+		client.Count("fetch_cycle.entries", entries)
+		client.Count("fetch_cycle.errors", errors)
+		after = id
+	}
+	return after
+}
@@ -0,0 +1,148 @@
+// internal/server/tag_priorities.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"infoscope/internal/database"
+)
+
+// TagPriority is how the fetcher treats feeds carrying a tag. Feeds with a
+// higher priority are fetched ahead of others, and a non-zero interval
+// fetches them that often between regular update cycles.
+type TagPriority struct {
+	Tag             string `json:"tag"`
+	Priority        int    `json:"priority"`
+	IntervalMinutes int    `json:"intervalMinutes"`
+	Feeds           int    `json:"feeds,omitempty"`
+}
+
+// getTagPriorities lists every tag in use or configured, with how many
+// feeds carry it.
+func (s *Server) getTagPriorities(ctx context.Context) ([]TagPriority, error) {
+	byTag := make(map[string]*TagPriority)
+
+	rows, err := s.db.QueryContext(ctx, "SELECT tag, priority, interval_minutes FROM tag_priorities")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var p TagPriority
+		if err := rows.Scan(&p.Tag, &p.Priority, &p.IntervalMinutes); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byTag[p.Tag] = &p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, "SELECT tags FROM feeds WHERE COALESCE(tags, '') != ''")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tags string
+		if err := rows.Scan(&tags); err != nil {
+			return nil, err
+		}
+		for _, tag := range normalizeTags(tags) {
+			if byTag[tag] == nil {
+				byTag[tag] = &TagPriority{Tag: tag}
+			}
+			byTag[tag].Feeds++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	priorities := make([]TagPriority, 0, len(byTag))
+	for _, p := range byTag {
+		priorities = append(priorities, *p)
+	}
+	sort.Slice(priorities, func(i, j int) bool {
+		if priorities[i].Priority != priorities[j].Priority {
+			return priorities[i].Priority > priorities[j].Priority
+		}
+		return priorities[i].Tag < priorities[j].Tag
+	})
+	return priorities, nil
+}
+
+// handleTagPriorities saves a tag's fetch priority. Setting both the
+// priority and interval to zero removes it.
+func (s *Server) handleTagPriorities(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		priorities, err := s.getTagPriorities(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting tag priorities", "error", err)
+			writeInternalError(w)
+			return
+		}
+		writeJSON(w, http.StatusOK, struct {
+			Tags []TagPriority `json:"tags"`
+		}{priorities})
+
+	case http.MethodPost:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+
+		var req TagPriority
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		if !s.saveTagPriority(w, r, &req) {
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// saveTagPriority validates and stores p, normalizing its tag. On failure
+// it writes the error response and returns false.
+func (s *Server) saveTagPriority(w http.ResponseWriter, r *http.Request, p *TagPriority) bool {
+	tags := normalizeTags(p.Tag)
+	if len(tags) != 1 || strings.Contains(p.Tag, ",") {
+		writeValidationError(w, "A single tag is required", map[string]string{"tag": "must be a single tag"})
+		return false
+	}
+	if p.IntervalMinutes < 0 {
+		writeValidationError(w, "Interval can't be negative", map[string]string{"intervalMinutes": "can't be negative"})
+		return false
+	}
+	p.Tag = tags[0]
+
+	err := database.WithTx(r.Context(), s.db, func(tx *sql.Tx) error {
+		if p.Priority == 0 && p.IntervalMinutes == 0 {
+			_, err := tx.ExecContext(r.Context(), "DELETE FROM tag_priorities WHERE tag = ?", p.Tag)
+			return err
+		}
+		_, err := tx.ExecContext(r.Context(), `
+            INSERT INTO tag_priorities (tag, priority, interval_minutes) VALUES (?, ?, ?)
+            ON CONFLICT(tag) DO UPDATE SET priority = excluded.priority,
+                interval_minutes = excluded.interval_minutes`,
+			p.Tag, p.Priority, p.IntervalMinutes)
+		return err
+	})
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error saving tag priority", "error", err)
+		writeDBError(w, err)
+		return false
+	}
+	return true
+}
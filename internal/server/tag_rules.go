@@ -0,0 +1,330 @@
+// internal/server/tag_rules.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"infoscope/internal/database"
+)
+
+// Fields a tag rule can match against
+const (
+	TagRuleURL   = "url"
+	TagRuleHost  = "host"
+	TagRuleTitle = "title"
+)
+
+// maxTagRulePattern bounds a rule's pattern length
+const maxTagRulePattern = 200
+
+// TagRule applies Tag to every feed whose URL, host or title matches
+// Pattern, a case-insensitive glob in which * matches anything.
+type TagRule struct {
+	ID      int64  `json:"id,omitempty"`
+	Field   string `json:"field"`
+	Pattern string `json:"pattern"`
+	Tag     string `json:"tag"`
+
+	match *regexp.Regexp
+}
+
+// compile prepares the rule's pattern for matching
+func (r *TagRule) compile() {
+	expr := strings.ReplaceAll(regexp.QuoteMeta(r.Pattern), `\*`, ".*")
+	r.match = regexp.MustCompile("(?i)^" + expr + "$")
+}
+
+// matches reports whether a feed with this URL and title matches the rule
+func (r *TagRule) matches(feedURL, title string) bool {
+	switch r.Field {
+	case TagRuleURL:
+		return r.match.MatchString(feedURL)
+	case TagRuleHost:
+		u, err := url.Parse(feedURL)
+		return err == nil && r.match.MatchString(u.Hostname())
+	case TagRuleTitle:
+		return r.match.MatchString(title)
+	}
+	return false
+}
+
+// validate normalizes the rule and returns its invalid fields
+func (r *TagRule) validate() map[string]string {
+	fields := make(map[string]string)
+	switch r.Field {
+	case TagRuleURL, TagRuleHost, TagRuleTitle:
+	default:
+		fields["field"] = "must be url, host or title"
+	}
+	r.Pattern = strings.TrimSpace(r.Pattern)
+	if r.Pattern == "" || len(r.Pattern) > maxTagRulePattern {
+		fields["pattern"] = "must be between 1 and 200 characters"
+	}
+	tags := normalizeTags(r.Tag)
+	if len(tags) != 1 || strings.Contains(r.Tag, ",") {
+		fields["tag"] = "must be a single tag"
+	} else {
+		r.Tag = tags[0]
+	}
+	return fields
+}
+
+// getTagRules lists the tag rules in the order they were added
+func (s *Server) getTagRules(ctx context.Context) ([]TagRule, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, field, pattern, tag FROM tag_rules ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []TagRule
+	for rows.Next() {
+		var rule TagRule
+		if err := rows.Scan(&rule.ID, &rule.Field, &rule.Pattern, &rule.Tag); err != nil {
+			return nil, err
+		}
+		rule.compile()
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// applyTagRules adds the tags of every matching rule to the feeds with the
+// given URLs, or to all feeds when none are given. Rules only ever add tags,
+// so running them again is harmless. It returns how many feeds were checked
+// and how many gained tags.
+func (s *Server) applyTagRules(ctx context.Context, feedURLs ...string) (checked, tagged int, err error) {
+	rules, err := s.getTagRules(ctx)
+	if err != nil || len(rules) == 0 {
+		return 0, 0, err
+	}
+
+	// An OPML import can name more feeds than SQLite takes parameters, so
+	// the URLs are filtered here rather than in the query
+	only := make(map[string]bool, len(feedURLs))
+	for _, u := range feedURLs {
+		only[u] = true
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, url, COALESCE(title, ''), COALESCE(tags, '')
+        FROM feeds WHERE status != 'deleted'`)
+	if err != nil {
+		return 0, 0, err
+	}
+	updates := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var feedURL, title, tags string
+		if err := rows.Scan(&id, &feedURL, &title, &tags); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		if len(only) > 0 && !only[feedURL] {
+			continue
+		}
+		checked++
+
+		merged := tags
+		for i := range rules {
+			if rules[i].matches(feedURL, title) {
+				merged += "," + rules[i].Tag
+			}
+		}
+		if merged = strings.Join(normalizeTags(merged), ","); merged != tags {
+			updates[id] = merged
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+	if len(updates) == 0 {
+		return checked, 0, nil
+	}
+
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		for id, tags := range updates {
+			if _, err := tx.ExecContext(ctx, "UPDATE feeds SET tags = ? WHERE id = ?", tags, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return checked, 0, err
+	}
+	return checked, len(updates), nil
+}
+
+// tagNewFeeds applies the tag rules to feeds that were just added. Failures
+// only cost the automatic tags, so they are logged rather than returned.
+func (s *Server) tagNewFeeds(ctx context.Context, feedURLs ...string) {
+	if _, tagged, err := s.applyTagRules(ctx, feedURLs...); err != nil {
+		s.logger.ErrorContext(ctx, "Error applying tag rules", "error", err)
+	} else if tagged > 0 {
+		s.logger.InfoContext(ctx, "Tagged new feeds by rule", "count", tagged)
+	}
+}
+
+// addTagRule validates and stores rule, filling in its ID. On failure it
+// writes the error response and returns false.
+func (s *Server) addTagRule(w http.ResponseWriter, r *http.Request, rule *TagRule) bool {
+	if fields := rule.validate(); len(fields) > 0 {
+		writeValidationError(w, "Invalid tag rule", fields)
+		return false
+	}
+
+	res, err := s.db.ExecContext(r.Context(), `
+        INSERT INTO tag_rules (field, pattern, tag) VALUES (?, ?, ?)
+        ON CONFLICT(field, pattern, tag) DO NOTHING`,
+		rule.Field, rule.Pattern, rule.Tag)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error saving tag rule", "error", err)
+		writeDBError(w, err)
+		return false
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeAPIError(w, http.StatusConflict, codeConflict, "That rule already exists")
+		return false
+	}
+	rule.ID, _ = res.LastInsertId()
+	return true
+}
+
+// TagRuleBackfillStatus is the progress of applying the rules to all feeds
+type TagRuleBackfillStatus struct {
+	Running    bool       `json:"running"`
+	Checked    int        `json:"checked"`
+	Tagged     int        `json:"tagged"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// tagRuleBackfill runs at most one backfill at a time and remembers the
+// outcome of the last one.
+type tagRuleBackfill struct {
+	mu     sync.Mutex
+	status TagRuleBackfillStatus
+}
+
+func (j *tagRuleBackfill) snapshot() TagRuleBackfillStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// start begins a backfill in the background, or reports false if one is
+// already running.
+func (j *tagRuleBackfill) start(s *Server) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status.Running {
+		return false
+	}
+	now := time.Now().UTC()
+	j.status = TagRuleBackfillStatus{Running: true, StartedAt: &now}
+
+	go func() {
+		checked, tagged, err := s.applyTagRules(context.Background())
+
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		finished := time.Now().UTC()
+		j.status.Running = false
+		j.status.FinishedAt = &finished
+		j.status.Checked, j.status.Tagged = checked, tagged
+		if err != nil {
+			s.logger.Error("Error applying tag rules", "error", err)
+			j.status.Error = err.Error()
+			return
+		}
+		s.logger.Info("Applied tag rules", "checked", checked, "tagged", tagged)
+	}()
+	return true
+}
+
+// handleTagRules lists rules on GET, adds one on POST and removes one on
+// DELETE. Removing a rule leaves the tags it already applied in place.
+func (s *Server) handleTagRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rules, err := s.getTagRules(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting tag rules", "error", err)
+			writeInternalError(w)
+			return
+		}
+		writeJSON(w, http.StatusOK, struct {
+			Rules []TagRule `json:"rules"`
+		}{rules})
+
+	case http.MethodPost:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+
+		var rule TagRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		if !s.addTagRule(w, r, &rule) {
+			return
+		}
+		writeJSON(w, http.StatusCreated, rule)
+
+	case http.MethodDelete:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+
+		var req struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		if _, err := s.db.ExecContext(r.Context(), "DELETE FROM tag_rules WHERE id = ?", req.ID); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error deleting tag rule", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// handleTagRuleBackfill starts applying the rules to every feed on POST and
+// reports the progress on GET.
+func (s *Server) handleTagRuleBackfill(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.tagBackfill.snapshot())
+
+	case http.MethodPost:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+		if !s.tagBackfill.start(s) {
+			writeAPIError(w, http.StatusConflict, codeConflict, "Tag rules are already being applied")
+			return
+		}
+		writeJSON(w, http.StatusAccepted, s.tagBackfill.snapshot())
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
@@ -0,0 +1,105 @@
+// internal/server/taxonomy.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// handleCategoryPage serves /category/{name}, the river restricted to one
+// category, and /category/{name}/rss.xml with the same entries as RSS.
+func (s *Server) handleCategoryPage(w http.ResponseWriter, r *http.Request) {
+	name, rss, ok := taxonomyPath(r, "/category/")
+	if !ok {
+		s.handle404(w, r)
+		return
+	}
+	s.serveFilteredRiver(w, r, RiverFilter{Category: strings.Join(strings.Fields(name), " ")}, rss)
+}
+
+// handleTagPage serves /tag/{name} and /tag/{name}/rss.xml.
+func (s *Server) handleTagPage(w http.ResponseWriter, r *http.Request) {
+	name, rss, ok := taxonomyPath(r, "/tag/")
+	if !ok {
+		s.handle404(w, r)
+		return
+	}
+	tags := normalizeTags(name)
+	if len(tags) != 1 {
+		s.handle404(w, r)
+		return
+	}
+	s.serveFilteredRiver(w, r, RiverFilter{Tag: tags[0]}, rss)
+}
+
+// taxonomyPath splits a category or tag URL into the name and whether the
+// RSS version was asked for.
+func taxonomyPath(r *http.Request, prefix string) (name string, rss bool, ok bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return "", false, false
+	}
+	name = strings.TrimPrefix(r.URL.Path, prefix)
+	name, rss = strings.CutSuffix(name, "/rss.xml")
+	name = strings.TrimSuffix(name, "/")
+	return name, rss, strings.TrimSpace(name) != ""
+}
+
+// serveFilteredRiver renders a category or tag page, or its RSS feed. Names
+// no visible feed uses are reported as missing.
+func (s *Server) serveFilteredRiver(w http.ResponseWriter, r *http.Request, filter RiverFilter, rss bool) {
+	filter, err := s.resolveRiverFilter(r.Context(), filter)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.handle404(w, r)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error checking", "filter", filter.Label(), "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !rss {
+		s.serveRiver(w, r, filter)
+		return
+	}
+
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+		settings = make(map[string]string)
+	}
+	entries, err := s.riverEntries(r.Context(), settings, filter)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting entries", "filter", filter.Label(), "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	page := siteBaseURL(r, settings["site_url"]) + filter.Path()
+	title := settings["site_title"] + " (" + filter.Label() + ")"
+	description := "Recent entries filed under " + filter.Label()
+	if err := writeRSS(w, title, page, description, page+"/rss.xml", entries); err != nil {
+		s.logger.ErrorContext(r.Context(), "Error encoding RSS", "filter", filter.Label(), "error", err)
+	}
+}
+
+// resolveRiverFilter checks that a feed shown in the river matches filter
+// and spells its category the way the feeds do. It returns sql.ErrNoRows
+// when none does.
+func (s *Server) resolveRiverFilter(ctx context.Context, filter RiverFilter) (RiverFilter, error) {
+	cond, args := filter.where()
+	var category string
+	err := s.db.QueryRowContext(ctx, `
+        SELECT COALESCE(f.category, '') FROM feeds f
+        WHERE f.status != 'deleted'
+          AND (f.snoozed_until IS NULL OR f.snoozed_until <= datetime('now'))`+cond+`
+        ORDER BY f.id
+        LIMIT 1`, args...).Scan(&category)
+	if err == nil && filter.Category != "" {
+		filter.Category = category
+	}
+	return filter, err
+}
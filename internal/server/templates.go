@@ -2,12 +2,17 @@
 package server
 
 import (
+	"context"
 	"embed"
 	"fmt"
 	"html/template"
+	"infoscope/internal/auth"
 	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -27,7 +32,7 @@ func init() {
 
 // extractWebContent extracts all embedded web content to disk
 func (s *Server) extractWebContent(forceUpdate bool) error {
-	s.logger.Printf("Checking web content...")
+	s.logger.Debug("Checking web content")
 
 	// Use configured web path
 	dirs := []string{
@@ -79,13 +84,26 @@ func (s *Server) extractWebContent(forceUpdate bool) error {
 			if err := os.WriteFile(localPath, content, 0644); err != nil {
 				return fmt.Errorf("failed to write file %s: %w", localPath, err)
 			}
-			s.logger.Printf("Updated: %s", localPath)
+			s.logger.Debug("Updated web file", "path", localPath)
 		}
 		return nil
 	})
 }
 
-func (s *Server) registerTemplateFuncs() template.FuncMap {
+// registerTemplateFuncs is the FuncMap every page and theme template can
+// use. Besides the helpers the bundled pages rely on it offers:
+//
+//	formatDate LAYOUT TIME   time formatted with a Go layout in the site timezone
+//	truncate N TEXT          TEXT cut to N characters, ending in "…" when cut
+//	host URL                 the URL's host name without a leading "www."
+//	plural N ONE MANY        "1 entry", "3 entries"
+//	markdown TEXT            TEXT rendered from basic markdown, with HTML escaped
+//	safeHTML TEXT            TEXT inserted as is; only for trusted admin input
+//
+// Arguments are ordered so the value can be piped in, as in
+// {{ .PublishedAt | formatDate "Jan 2" }}.
+func (s *Server) registerTemplateFuncs(ctx context.Context) template.FuncMap {
+	loc := s.siteLocation(ctx)
 	return template.FuncMap{
 		"formatTimeInZone": func(tz string, t time.Time) string {
 			loc, err := time.LoadLocation(tz)
@@ -94,6 +112,47 @@ func (s *Server) registerTemplateFuncs() template.FuncMap {
 			}
 			return t.In(loc).Format("02/01/06 15:04")
 		},
+		// formatDateInZone renders a date for <input type="date">
+		"formatDateInZone": func(tz string, t time.Time) string {
+			loc, err := time.LoadLocation(tz)
+			if err != nil {
+				return t.UTC().Format("2006-01-02")
+			}
+			return t.In(loc).Format("2006-01-02")
+		},
+		"formatDate": func(layout string, t time.Time) string {
+			if t.IsZero() {
+				return ""
+			}
+			return t.In(loc).Format(layout)
+		},
+		"truncate": truncateText,
+		"host": func(raw string) string {
+			u, err := url.Parse(raw)
+			if err != nil {
+				return ""
+			}
+			return strings.TrimPrefix(u.Hostname(), "www.")
+		},
+		"plural": func(n int, one, many string) string {
+			if n == 1 {
+				return "1 " + one
+			}
+			return strconv.Itoa(n) + " " + many
+		},
+		"markdown": renderMarkdown,
+		"safeHTML": func(s string) template.HTML {
+			return template.HTML(s)
+		},
+		"formatBytes": formatBytes,
+		"mul":         func(a, b int) int { return a * b },
+		"add":         func(a, b int) int { return a + b },
+		"div": func(a, b int) int {
+			if b == 0 {
+				return 0
+			}
+			return a / b
+		},
 		"time": func(layout, value string) time.Time {
 			t, err := time.Parse(layout, value)
 			if err != nil {
@@ -101,5 +160,23 @@ func (s *Server) registerTemplateFuncs() template.FuncMap {
 			}
 			return t.UTC()
 		},
+		// can reports whether the signed-in account holds at least role
+		"can": func(role string) bool {
+			return auth.RoleAllows(getUserRole(ctx), role)
+		},
+	}
+}
+
+// truncateText shortens text to at most n characters, marking the cut with
+// an ellipsis and preferring to break between words.
+func truncateText(n int, text string) string {
+	runes := []rune(strings.TrimSpace(text))
+	if n <= 0 || len(runes) <= n {
+		return string(runes)
+	}
+	cut := string(runes[:n-1])
+	if i := strings.LastIndexByte(cut, ' '); i > len(cut)/2 {
+		cut = cut[:i]
 	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
 }
@@ -0,0 +1,85 @@
+// internal/server/throttle.go
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// loginBurst and loginRefill limit password and passkey checks from
+	// one client address by default: ten at once, then one a minute
+	loginBurst  = 10
+	loginRefill = time.Minute
+)
+
+// clientIP is the address a request came from. Behind a trusted reverse
+// proxy it is the nearest address in X-Forwarded-For that no trusted
+// proxy claims, since anything further left may be made up by the client.
+func (s *Server) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	addr = addr.Unmap()
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0 && s.trustedProxy(addr); i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+	}
+	return addr.String()
+}
+
+// trustedProxy reports whether addr is one of the configured proxies
+func (s *Server) trustedProxy(addr netip.Addr) bool {
+	for _, prefix := range s.config.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLoginLimits changes how many sign-in attempts each client address
+// gets, such as when the configuration is reloaded
+func (s *Server) SetLoginLimits(burst int, refill time.Duration) {
+	s.loginLimiter.SetRate(burst, refill)
+}
+
+// throttle limits how often one client address may POST to next, which
+// checks a password or other secret. Once the address has used up its
+// attempts it gets 429 with a Retry-After until one comes back. Other
+// methods pass untouched.
+func (s *Server) throttle(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next(w, r)
+			return
+		}
+		ip := s.clientIP(r)
+		if ok, wait := s.loginLimiter.Allow(ip); !ok {
+			s.logger.WarnContext(r.Context(), "Throttled repeated attempts", "ip", ip, "path", r.URL.Path)
+			writeTooManyRequests(w, wait, "Too many attempts, try again later")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// writeTooManyRequests answers a throttled request, saying when to retry
+func writeTooManyRequests(w http.ResponseWriter, wait time.Duration, msg string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+	writeAPIError(w, http.StatusTooManyRequests, codeRateLimited, msg)
+}
@@ -0,0 +1,83 @@
+// internal/server/tls.go
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// TLSConfig enables serving HTTPS directly, from certificate files or with
+// certificates obtained from Let's Encrypt. The zero value serves plain HTTP.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+
+	// AutocertDomains are the host names to obtain certificates for, which
+	// are cached in AutocertCacheDir. AutocertEmail is given to Let's Encrypt
+	// for expiry notices.
+	AutocertDomains  []string
+	AutocertEmail    string
+	AutocertCacheDir string
+
+	// RedirectAddr, when set, is a plain HTTP listener that sends every
+	// request to HTTPS and answers Let's Encrypt challenges
+	RedirectAddr string
+}
+
+// Enabled reports whether the server should terminate TLS itself
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || len(c.AutocertDomains) > 0
+}
+
+// serveTLS serves HTTPS on ln, along with the redirect listener if one is
+// configured.
+func (s *Server) serveTLS(ln net.Listener) error {
+	cfg := s.config.TLS
+	addr := ln.Addr().String()
+	srv := &http.Server{Addr: addr, Handler: s.Routes()}
+
+	redirect := httpsRedirect(addr)
+	if len(cfg.AutocertDomains) > 0 {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.AutocertDomains...),
+			Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+			Email:      cfg.AutocertEmail,
+		}
+		srv.TLSConfig = m.TLSConfig()
+		redirect = m.HTTPHandler(redirect)
+		s.logger.Info("Obtaining certificates from Let's Encrypt", "domains", strings.Join(cfg.AutocertDomains, ","))
+	}
+
+	if cfg.RedirectAddr != "" {
+		go func() {
+			s.logger.Info("Redirecting HTTP to HTTPS", "addr", cfg.RedirectAddr)
+			if err := http.ListenAndServe(cfg.RedirectAddr, redirect); err != nil {
+				s.logger.Error("HTTP redirect listener stopped", "addr", cfg.RedirectAddr, "error", err)
+			}
+		}()
+	}
+
+	s.logger.Info("Starting server with TLS", "addr", addr)
+	// With autocert the certificate comes from srv.TLSConfig
+	return srv.ServeTLS(ln, cfg.CertFile, cfg.KeyFile)
+}
+
+// httpsRedirect permanently redirects requests to the same URL over HTTPS
+// on the port of tlsAddr.
+func httpsRedirect(tlsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
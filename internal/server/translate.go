@@ -0,0 +1,269 @@
+// internal/server/translate.go
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"infoscope/internal/feed"
+)
+
+// Translation backends selectable in settings
+const (
+	TranslateLibre = "libretranslate"
+	TranslateDeepL = "deepl"
+)
+
+const (
+	deeplDefaultURL = "https://api-free.deepl.com/v2/translate"
+
+	// maxTranslatedBody caps how much of an entry body is sent for
+	// translation, since paid backends bill per character
+	maxTranslatedBody = 1500
+)
+
+var translateClient = &http.Client{Timeout: 20 * time.Second}
+
+// EntryTranslation is an entry's title and body in the site's reading language
+type EntryTranslation struct {
+	Language string `json:"language"`
+	Title    string `json:"title"`
+	Content  string `json:"content,omitempty"`
+}
+
+// translationConfig is the translation settings, or nil when disabled
+type translationConfig struct {
+	backend  string
+	endpoint string
+	apiKey   string
+	language string
+}
+
+func translationConfigFrom(settings map[string]string) *translationConfig {
+	backend := settings["translation_backend"]
+	if backend != TranslateLibre && backend != TranslateDeepL {
+		return nil
+	}
+	language, _ := feed.ParseLocale(settings["translation_language"])
+	if language == "" {
+		language = "en"
+	}
+	endpoint := strings.TrimSpace(settings["translation_url"])
+	if endpoint == "" && backend == TranslateDeepL {
+		endpoint = deeplDefaultURL
+	}
+	return &translationConfig{
+		backend:  backend,
+		endpoint: endpoint,
+		apiKey:   settings["translation_api_key"],
+		language: language,
+	}
+}
+
+// validateTranslationSettings normalizes the translation settings and
+// returns the fields that are invalid.
+func validateTranslationSettings(settings *Settings) map[string]string {
+	fields := make(map[string]string)
+	switch settings.TranslationBackend {
+	case "":
+		return nil
+	case TranslateLibre, TranslateDeepL:
+	default:
+		fields["translationBackend"] = "unknown translation backend"
+	}
+
+	language, _ := feed.ParseLocale(settings.TranslationLanguage)
+	if language == "" {
+		fields["translationLanguage"] = "not a recognised language code"
+	}
+	settings.TranslationLanguage = language
+
+	endpoint := strings.TrimSpace(settings.TranslationURL)
+	if endpoint == "" {
+		if settings.TranslationBackend == TranslateLibre {
+			fields["translationURL"] = "required for LibreTranslate"
+		}
+	} else if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fields["translationURL"] = "must be an http or https URL"
+	}
+	return fields
+}
+
+// markTranslatable offers translation on entries from feeds that aren't
+// known to be in the reading language already.
+func markTranslatable(entries []EntryView, settings map[string]string) {
+	cfg := translationConfigFrom(settings)
+	if cfg == nil {
+		return
+	}
+	for i := range entries {
+		if !entries[i].Roundup && entries[i].Language != cfg.language {
+			entries[i].Translatable = true
+		}
+	}
+}
+
+// handleTranslate returns an entry's translation, asking the backend the
+// first time and serving the stored copy afterwards.
+func (s *Server) handleTranslate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	settings, err := s.getSettings(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+		writeInternalError(w)
+		return
+	}
+	cfg := translationConfigFrom(settings)
+	if cfg == nil {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "Translation is not enabled")
+		return
+	}
+
+	entryID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		writeValidationError(w, "Invalid entry ID", map[string]string{"id": "must be a number"})
+		return
+	}
+
+	t, err := s.translateEntry(r.Context(), cfg, entryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "Entry not found")
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Error translating entry", "entry_id", entryID, "error", err)
+		writeAPIError(w, http.StatusBadGateway, codeInternal, "Translation failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// translateEntry returns the stored translation of an entry, translating it
+// again when there is none or the entry's title has changed since.
+func (s *Server) translateEntry(ctx context.Context, cfg *translationConfig, entryID int64) (*EntryTranslation, error) {
+	var title, content string
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT title, COALESCE(content, '') FROM entries WHERE id = ? AND hidden_at IS NULL", entryID,
+	).Scan(&title, &content); err != nil {
+		return nil, err
+	}
+
+	t := &EntryTranslation{Language: cfg.language}
+	err := s.db.QueryRowContext(ctx, `
+        SELECT title, COALESCE(content, '') FROM entry_translations
+        WHERE entry_id = ? AND language = ? AND source_title = ?`,
+		entryID, cfg.language, title).Scan(&t.Title, &t.Content)
+	if err == nil {
+		return t, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	texts := []string{title}
+	if body := feed.TextSnippet(content, maxTranslatedBody); body != "" {
+		texts = append(texts, body)
+	}
+	translated, err := cfg.translate(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	t.Title = translated[0]
+	if len(translated) > 1 {
+		t.Content = translated[1]
+	}
+
+	// A read-only mirror can't store it, and serves the ones the primary
+	// stored instead
+	if s.config.ReadOnly {
+		return t, nil
+	}
+	if _, err := s.db.ExecContext(ctx, `
+        INSERT OR REPLACE INTO entry_translations (entry_id, language, source_title, title, content)
+        VALUES (?, ?, ?, ?, NULLIF(?, ''))`,
+		entryID, cfg.language, title, t.Title, t.Content); err != nil {
+		s.logger.ErrorContext(ctx, "Error storing translation for entry", "entry_id", entryID, "error", err)
+	}
+	return t, nil
+}
+
+// translate sends texts to the configured backend and returns the
+// translations in the same order.
+func (c *translationConfig) translate(ctx context.Context, texts []string) ([]string, error) {
+	var payload any
+	header := http.Header{"Content-Type": {"application/json"}}
+	switch c.backend {
+	case TranslateDeepL:
+		payload = map[string]any{"text": texts, "target_lang": strings.ToUpper(c.language)}
+		header.Set("Authorization", "DeepL-Auth-Key "+c.apiKey)
+	default:
+		payload = map[string]any{"q": texts, "source": "auto", "target": c.language,
+			"format": "text", "api_key": c.apiKey}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+
+	resp, err := translateClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", c.backend, resp.Status)
+	}
+
+	var out []string
+	switch c.backend {
+	case TranslateDeepL:
+		var result struct {
+			Translations []struct {
+				Text string `json:"text"`
+			} `json:"translations"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("decoding %s response: %w", c.backend, err)
+		}
+		for _, t := range result.Translations {
+			out = append(out, t.Text)
+		}
+	default:
+		var result struct {
+			TranslatedText []string `json:"translatedText"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("decoding %s response: %w", c.backend, err)
+		}
+		out = result.TranslatedText
+	}
+
+	if len(out) != len(texts) {
+		return nil, fmt.Errorf("%s returned %d translations for %d texts", c.backend, len(out), len(texts))
+	}
+	return out, nil
+}
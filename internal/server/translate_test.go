@@ -0,0 +1,58 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"infoscope/internal/database"
+)
+
+func TestTranslateEntryOnReadOnlyMirror(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := database.NewDB(path, database.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO feeds (id, url, title) VALUES (1, 'https://example.com/feed', 'Example')",
+		"INSERT INTO entries (id, feed_id, title, url, published_at, favicon_url) VALUES (1, 1, 'Hallo', 'https://example.com/a', CURRENT_TIMESTAMP, '')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed database: %v", err)
+		}
+	}
+	db.Close()
+
+	mirrorConfig := database.DefaultConfig()
+	mirrorConfig.ReadOnly = true
+	mirror, err := database.NewDB(path, mirrorConfig)
+	if err != nil {
+		t.Fatalf("Failed to open read-only database: %v", err)
+	}
+	defer mirror.Close()
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"translatedText": []string{"Hello"}})
+	}))
+	defer backend.Close()
+
+	var logged bytes.Buffer
+	s := &Server{db: mirror.DB, logger: slog.New(slog.NewTextHandler(&logged, nil)), config: Config{ReadOnly: true}}
+	cfg := &translationConfig{backend: TranslateLibre, endpoint: backend.URL, language: "en"}
+	got, err := s.translateEntry(context.Background(), cfg, 1)
+	if err != nil {
+		t.Fatalf("translateEntry failed: %v", err)
+	}
+	if got.Title != "Hello" {
+		t.Errorf("Translated title %q, want Hello", got.Title)
+	}
+	// The mirror doesn't try to store it
+	if logged.Len() > 0 {
+		t.Errorf("Translating on a mirror logged: %s", logged.String())
+	}
+}
@@ -18,26 +18,74 @@ const (
 )
 
 type EntryView struct {
-	ID         int64  `json:"id"`
-	Title      string `json:"title"`
-	URL        string `json:"url"`
-	FaviconURL string `json:"faviconUrl"`
-	Date       string `json:"date"`
+	ID          int64     `json:"id"`
+	FeedID      int64     `json:"feedId"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	FaviconURL  string    `json:"faviconUrl"`
+	Host        string    `json:"host"`
+	Category    string    `json:"category,omitempty"`
+	Date        string    `json:"date"`
+	PublishedAt time.Time `json:"-"`
+
+	// Roundup marks the synthetic weekly roundup entry, which links to a
+	// local page and isn't click tracked
+	Roundup bool `json:"roundup,omitempty"`
+
+	// Pinned marks a starred entry pinned to the top of the river
+	Pinned bool `json:"pinned,omitempty"`
+
+	// Share is set when share_links is on
+	Share *ShareLinks `json:"share,omitempty"`
+
+	// Language is the feed's language; Translatable is set when a
+	// translation backend is configured and the feed may need one
+	Language     string `json:"language,omitempty"`
+	Translatable bool   `json:"translatable,omitempty"`
+
+	// AlsoIn lists the other feeds that carried the same story, when
+	// cross-feed dedup collapses copies
+	AlsoIn []EntrySource `json:"alsoIn,omitempty"`
+}
+
+// entryDateLayout is how public pages date entries, in the site timezone
+// or, with local_dates, the visitor's
+const entryDateLayout = "Jan 02"
+
+// Timestamp is the entry's publish time in UTC for a <time datetime>
+// attribute, which the local dates script reformats in the visitor's
+// timezone
+func (e EntryView) Timestamp() string {
+	return e.PublishedAt.UTC().Format(time.RFC3339)
+}
+
+// EntrySource is another feed's copy of an entry
+type EntrySource struct {
+	Feed string `json:"feed"`
+	URL  string `json:"url"`
 }
 
 type IndexData struct {
 	BaseTemplateData
 	Title             string
 	Entries           []EntryView
+	Sections          []RiverSection
 	HeaderLinkURL     string
 	HeaderLinkText    string
 	FooterLinkURL     string
 	FooterLinkText    string
 	FooterImageURL    string
 	FooterImageHeight string
+	FooterImagePx     int
 	TrackingCode      string
 	Settings          map[string]string
 	SiteURL           string
+	HonorDNT          bool
+	CompactMode       bool
+	SemanticMarkup    bool
+	VisitorMuting     bool
+	MutedTerms        []string
+	Filter            RiverFilter
 }
 
 type BaseTemplateData struct {
@@ -55,6 +103,16 @@ type AdminPageData struct {
 	UserID     int64
 	ClickStats *DashboardStats
 	Feeds      []Feed
+	Edits      []EditedEntry
+	Bandwidth  *BandwidthStats
+	SinceLogin *SinceLastLogin
+	Alerts     []FetchAlert
+	Update     *UpdateStatus
+	Categories []string
+
+	TagPriorities []TagPriority
+	TagRules      []TagRule
+	Notifications []Notification
 }
 
 type SettingsTemplateData struct {
@@ -67,6 +125,7 @@ type SettingsTemplateData struct {
 type Settings struct {
 	SiteTitle         string `json:"siteTitle"`
 	MaxPosts          int    `json:"maxPosts"`
+	RetentionDays     int    `json:"retentionDays"`
 	UpdateInterval    int    `json:"updateInterval"`
 	HeaderLinkText    string `json:"headerLinkText"`
 	HeaderLinkURL     string `json:"headerLinkURL"`
@@ -79,6 +138,48 @@ type Settings struct {
 	Timezone          string `json:"timezone"`
 	MetaDescription   string `json:"metaDescription"`
 	MetaImageURL      string `json:"metaImageURL"`
+	HonorDNT          bool   `json:"honorDNT"`
+	CompactMode       bool   `json:"compactMode"`
+	SemanticMarkup    bool   `json:"semanticMarkup"`
+	CleanTitles       bool   `json:"cleanTitles"`
+	DedupKey          string `json:"dedupKey"`
+	CrossFeedDedup    string `json:"crossFeedDedup"`
+	EntryUpdateMode   string `json:"entryUpdateMode"`
+	VisitorMuting     bool   `json:"visitorMuting"`
+	RiverMode         string `json:"riverMode"`
+	StatsAPIToken     string `json:"statsAPIToken"`
+	HostConcurrency   int    `json:"hostConcurrency"`
+	HostDelayMS       int    `json:"hostDelayMS"`
+	DailyBandwidthMB  int    `json:"dailyBandwidthMB"`
+	BackupSchedule    string `json:"backupSchedule"`
+	CacheIndexTTL     int    `json:"cacheIndexTTL"`
+	CacheStaticTTL    int    `json:"cacheStaticTTL"`
+	CacheAPIMaxAge    int    `json:"cacheAPIMaxAge"`
+	WeeklyRoundup     bool   `json:"weeklyRoundup"`
+	RoundupSize       int    `json:"roundupSize"`
+	RiverLayout       string `json:"riverLayout"`
+	ShareLinks        bool   `json:"shareLinks"`
+	MastodonInstance  string `json:"mastodonInstance"`
+	WaybackArchive    bool   `json:"waybackArchive"`
+	ArchiveMode       bool   `json:"archiveMode"`
+	PublicAPI         bool   `json:"publicAPI"`
+	LocalDates        bool   `json:"localDates"`
+
+	TranslationBackend  string `json:"translationBackend"`
+	TranslationURL      string `json:"translationURL"`
+	TranslationAPIKey   string `json:"translationAPIKey"`
+	TranslationLanguage string `json:"translationLanguage"`
+
+	AlertCycleMinutes int `json:"alertCycleMinutes"`
+	UpdateCheckHours  int `json:"updateCheckHours"`
+
+	AdaptivePolling        bool `json:"adaptivePolling"`
+	AdaptivePollMinMinutes int  `json:"adaptivePollMinMinutes"`
+	AdaptivePollMaxMinutes int  `json:"adaptivePollMaxMinutes"`
+
+	DeadFeedErrors int `json:"deadFeedErrors"`
+
+	ClickHalfLifeDays int `json:"clickHalfLifeDays"`
 }
 
 type Feed struct {
@@ -86,6 +187,39 @@ type Feed struct {
 	URL         string    `json:"url"`
 	Title       string    `json:"title"`
 	LastFetched time.Time `json:"lastFetched,omitempty"`
+	Priority    bool      `json:"priority"`
+	BytesToday  int64     `json:"bytesToday"`
+	Language    string    `json:"language,omitempty"`
+	Region      string    `json:"region,omitempty"`
+
+	// LocaleManual is set when an admin chose the language, so feed
+	// metadata no longer overrides it
+	LocaleManual bool `json:"localeManual,omitempty"`
+
+	// Category groups feeds; Tags is a comma separated list
+	Category string `json:"category,omitempty"`
+	Tags     string `json:"tags,omitempty"`
+
+	// SnoozedUntil is set while the feed is neither fetched nor shown
+	SnoozedUntil time.Time `json:"snoozedUntil,omitempty"`
+
+	// FetchIntervalSeconds overrides update_interval for this feed; 0 uses it
+	FetchIntervalSeconds int `json:"fetchIntervalSeconds,omitempty"`
+
+	// Status is "dead" once the feed was disabled after repeated errors
+	Status string `json:"status,omitempty"`
+
+	// FullContent replaces each entry's summary with the linked article
+	FullContent bool `json:"fullContent"`
+
+	// MaxEntries overrides max_posts as the entries this feed keeps; 0 uses it
+	MaxEntries int `json:"maxEntries,omitempty"`
+
+	// RetentionDays overrides retention_days for this feed; 0 uses it
+	RetentionDays int `json:"retentionDays,omitempty"`
+
+	// Stats is only filled in for exports that ask for feed statistics
+	Stats *FeedStats `json:"stats,omitempty"`
 }
 
 type LoginTemplateData struct {
@@ -0,0 +1,183 @@
+// internal/server/update_check.go
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"infoscope/internal/schedule"
+)
+
+// updateReleaseURL is the GitHub API endpoint for the latest release
+const updateReleaseURL = "https://api.github.com/repos/disinfo-zone/infoscope/releases/latest"
+
+// updateStatusKey is the app_state key holding the last check's result
+const updateStatusKey = "update_status"
+
+var updateClient = &http.Client{Timeout: 30 * time.Second}
+
+// UpdateStatus is the outcome of the last release check
+type UpdateStatus struct {
+	Current   string    `json:"current"`
+	Latest    string    `json:"latest"`
+	Available bool      `json:"available"`
+	URL       string    `json:"url,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// updateCheckInterval reads the update_check_hours setting; 0 disables
+// the check.
+func (s *Server) updateCheckInterval(ctx context.Context) time.Duration {
+	hours, err := strconv.Atoi(s.getSetting(ctx, "update_check_hours"))
+	if err != nil || hours <= 0 {
+		return 0
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// updateCheckLoop compares the running version with the latest release
+// on the configured interval until ctx is cancelled. It only reports;
+// nothing is downloaded or installed.
+func (s *Server) updateCheckLoop(ctx context.Context) {
+	// Catch up at startup when the last result is older than the interval
+	if interval := s.updateCheckInterval(ctx); interval > 0 {
+		if status := s.getUpdateStatus(ctx); status == nil || time.Since(status.CheckedAt) >= interval {
+			s.runUpdateCheck(ctx)
+		}
+	}
+
+	schedule.Run(ctx, func() schedule.Spec {
+		return schedule.Interval(s.updateCheckInterval(ctx))
+	}, s.runUpdateCheck)
+}
+
+func (s *Server) runUpdateCheck(ctx context.Context) {
+	status, err := s.checkForUpdate(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Update check failed", "error", err)
+		return
+	}
+	if status.Available {
+		s.logger.InfoContext(ctx, "Newer release available", "latest", status.Latest, "current", status.Current, "url", status.URL)
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error encoding update status", "error", err)
+		return
+	}
+	if err := s.setAppState(ctx, updateStatusKey, string(data)); err != nil {
+		s.logger.ErrorContext(ctx, "Error saving update status", "error", err)
+	}
+}
+
+// checkForUpdate asks GitHub for the latest release.
+func (s *Server) checkForUpdate(ctx context.Context) (*UpdateStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, updateReleaseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "infoscope/"+s.version())
+
+	resp, err := updateClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub returned %s", resp.Status)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("decoding release: %w", err)
+	}
+
+	return &UpdateStatus{
+		Current:   s.version(),
+		Latest:    release.TagName,
+		Available: newerVersion(release.TagName, s.version()),
+		URL:       release.HTMLURL,
+		CheckedAt: time.Now().UTC(),
+	}, nil
+}
+
+// getUpdateStatus returns the last check's result, or nil when checks are
+// off or none has run. A result from an older build is not reported.
+func (s *Server) getUpdateStatus(ctx context.Context) *UpdateStatus {
+	if s.updateCheckInterval(ctx) == 0 {
+		return nil
+	}
+	raw := s.getAppState(ctx, updateStatusKey)
+	if raw == "" {
+		return nil
+	}
+	var status UpdateStatus
+	if err := json.Unmarshal([]byte(raw), &status); err != nil || status.Current != s.version() {
+		return nil
+	}
+	return &status
+}
+
+func (s *Server) version() string {
+	if s.config.Version == "" {
+		return "dev"
+	}
+	return s.config.Version
+}
+
+// newerVersion reports whether release is a later version than current.
+// Both are dotted numbers with an optional "v" prefix; development builds
+// and anything else unparsable are never reported as outdated.
+func newerVersion(release, current string) bool {
+	r, ok := parseVersion(release)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := 0; i < max(len(r), len(c)); i++ {
+		var a, b int
+		if i < len(r) {
+			a = r[i]
+		}
+		if i < len(c) {
+			b = c[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return false
+}
+
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	// Drop pre-release and build suffixes such as "-rc1" or "+abc"
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, false
+	}
+	var parts []int
+	for _, p := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
@@ -0,0 +1,299 @@
+// internal/server/users.go
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"infoscope/internal/auth"
+)
+
+// maxUsernameLength caps account names
+const maxUsernameLength = 64
+
+// UsersPageData is the template data for the account management page
+type UsersPageData struct {
+	Title    string
+	Active   string
+	Settings map[string]string
+	Users    []auth.User
+	Roles    []string
+	UserID   int64
+}
+
+// AccountPageData is the template data for a user's own account page
+type AccountPageData struct {
+	Title    string
+	Active   string
+	Settings map[string]string
+	User     *auth.User
+	Passkeys []auth.Passkey
+}
+
+// userRequest is the body of account changes. Fields left empty on PATCH
+// are not changed.
+type userRequest struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// validate checks the fields being set and returns the invalid ones
+func (req *userRequest) validate(creating bool) map[string]string {
+	fields := make(map[string]string)
+	req.Username = strings.TrimSpace(req.Username)
+	if creating && (req.Username == "" || utf8.RuneCountInString(req.Username) > maxUsernameLength) {
+		fields["username"] = "required, up to 64 characters"
+	}
+	if (creating || req.Password != "") && len(req.Password) < auth.MinPasswordLength {
+		fields["password"] = "must be at least 8 characters"
+	}
+	if (creating || req.Role != "") && !auth.ValidRole(req.Role) {
+		fields["role"] = "must be viewer, editor or admin"
+	}
+	return fields
+}
+
+// handleUsers serves the account management page on GET, adds an account
+// on POST, changes one's role or password on PATCH and removes one on
+// DELETE. The last admin can't be demoted or removed.
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		users, err := auth.ListUsers(r.Context(), s.db)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error listing users", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		settings, err := s.getSettings(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+			settings = make(map[string]string)
+		}
+		userID, _ := getUserID(r.Context())
+
+		data := UsersPageData{
+			Title:    "Users",
+			Active:   "users",
+			Settings: settings,
+			Users:    users,
+			Roles:    auth.Roles,
+			UserID:   userID,
+		}
+		if err := s.renderTemplate(w, r, "admin/users.html", data); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error rendering users template", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+
+	case http.MethodPost:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+		var req userRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		if fields := req.validate(true); len(fields) > 0 {
+			writeValidationError(w, "Invalid user", fields)
+			return
+		}
+
+		id, err := auth.CreateUser(s.db, req.Username, req.Password, req.Role)
+		if errors.Is(err, auth.ErrUsernameTaken) {
+			writeAPIError(w, http.StatusConflict, codeConflict, "That username is taken")
+			return
+		}
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error creating user", "error", err)
+			writeDBError(w, err)
+			return
+		}
+		s.logUserChange(r, "Created user", id, "username", req.Username, "role", req.Role)
+
+		user, err := auth.GetUser(r.Context(), s.db, id)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting user", "id", id, "error", err)
+			writeDBError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"user": user})
+
+	case http.MethodPatch:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+		var req userRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		if fields := req.validate(false); len(fields) > 0 {
+			writeValidationError(w, "Invalid user", fields)
+			return
+		}
+
+		if req.Role != "" {
+			if err := auth.SetRole(r.Context(), s.db, req.ID, req.Role); err != nil {
+				s.writeUserError(w, r, "Error changing role", req.ID, err)
+				return
+			}
+			s.logUserChange(r, "Changed user role", req.ID, "role", req.Role)
+		}
+		if req.Password != "" {
+			if err := auth.SetPassword(r.Context(), s.db, req.ID, req.Password); err != nil {
+				s.writeUserError(w, r, "Error resetting password", req.ID, err)
+				return
+			}
+			// Whoever knew the old password is signed out with it
+			if err := s.auth.InvalidateUserSessions(r.Context(), req.ID); err != nil {
+				s.writeUserError(w, r, "Error revoking sessions", req.ID, err)
+				return
+			}
+			s.logUserChange(r, "Reset user password", req.ID)
+		}
+
+		user, err := auth.GetUser(r.Context(), s.db, req.ID)
+		if err != nil {
+			s.writeUserError(w, r, "Error getting user", req.ID, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"user": user})
+
+	case http.MethodDelete:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+		var req struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		if userID, _ := getUserID(r.Context()); userID == req.ID {
+			writeAPIError(w, http.StatusConflict, codeConflict, "You can't delete your own account")
+			return
+		}
+
+		if err := auth.DeleteUser(r.Context(), s.db, req.ID); err != nil {
+			s.writeUserError(w, r, "Error deleting user", req.ID, err)
+			return
+		}
+		s.logUserChange(r, "Deleted user", req.ID)
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// handleAccount shows the signed-in account on GET and changes its
+// password on POST, given the current one.
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	userID, _ := getUserID(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		user, err := auth.GetUser(r.Context(), s.db, userID)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting user", "user_id", userID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		passkeys, err := auth.ListPasskeys(r.Context(), s.db, userID)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error listing passkeys", "user_id", userID, "error", err)
+			passkeys = nil
+		}
+		settings, err := s.getSettings(r.Context())
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error getting settings", "error", err)
+			settings = make(map[string]string)
+		}
+
+		data := AccountPageData{
+			Title:    "Account",
+			Active:   "account",
+			Settings: settings,
+			User:     user,
+			Passkeys: passkeys,
+		}
+		if err := s.renderTemplate(w, r, "admin/account.html", data); err != nil {
+			s.logger.ErrorContext(r.Context(), "Error rendering account template", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+
+	case http.MethodPost:
+		if !s.csrf.Validate(w, r) {
+			return
+		}
+		var req struct {
+			CurrentPassword string `json:"currentPassword"`
+			NewPassword     string `json:"newPassword"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err, "Invalid request")
+			return
+		}
+		if len(req.NewPassword) < auth.MinPasswordLength {
+			writeValidationError(w, "Password must be at least 8 characters",
+				map[string]string{"newPassword": "must be at least 8 characters"})
+			return
+		}
+
+		err := auth.CheckPassword(r.Context(), s.db, userID, req.CurrentPassword)
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			writeValidationError(w, "Current password is incorrect",
+				map[string]string{"currentPassword": "is incorrect"})
+			return
+		}
+		if err == nil {
+			err = auth.SetPassword(r.Context(), s.db, userID, req.NewPassword)
+		}
+		// Sign out every other browser, keeping this one signed in on a
+		// fresh session
+		var session *auth.Session
+		if err == nil {
+			err = s.auth.InvalidateUserSessions(r.Context(), userID)
+		}
+		if err == nil {
+			session, err = s.auth.StartSession(r.Context(), userID)
+		}
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Error changing password", "user_id", userID, "error", err)
+			writeDBError(w, err)
+			return
+		}
+		s.setSessionCookie(w, session)
+		s.logger.InfoContext(r.Context(), "Changed own password", "user_id", userID)
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// writeUserError answers a failed account change
+func (s *Server) writeUserError(w http.ResponseWriter, r *http.Request, msg string, id int64, err error) {
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "User not found")
+	case errors.Is(err, auth.ErrLastAdmin):
+		writeAPIError(w, http.StatusConflict, codeConflict, "There must be at least one admin")
+	default:
+		s.logger.ErrorContext(r.Context(), msg, "id", id, "error", err)
+		writeDBError(w, err)
+	}
+}
+
+// logUserChange records an account change along with who made it
+func (s *Server) logUserChange(r *http.Request, msg string, id int64, args ...any) {
+	by, _ := getUserID(r.Context())
+	s.logger.InfoContext(r.Context(), msg, append([]any{"id", id, "by_user_id", by}, args...)...)
+}
@@ -1,6 +1,9 @@
 package server
 
-import "net/http"
+import (
+	"infoscope/internal/database"
+	"net/http"
+)
 
 // headerWritten checks if response headers have already been written
 func headerWritten(w http.ResponseWriter) bool {
@@ -9,3 +12,14 @@ func headerWritten(w http.ResponseWriter) bool {
 	}
 	return false
 }
+
+// writeDBError reports a failed write, telling the client to retry when the
+// database stayed locked rather than returning a bare 500.
+func writeDBError(w http.ResponseWriter, err error) {
+	if database.IsBusy(err) {
+		w.Header().Set("Retry-After", "1")
+		writeAPIError(w, http.StatusServiceUnavailable, codeBusy, "Server busy, please retry")
+		return
+	}
+	writeInternalError(w)
+}
@@ -0,0 +1,139 @@
+// internal/server/wayback.go
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Archive states stored in entries.archive_status
+const (
+	archiveQueued = "queued"
+	archiveSaved  = "saved"
+	archiveFailed = "failed"
+)
+
+const (
+	waybackSaveURL = "https://web.archive.org/save/"
+	waybackBaseURL = "https://web.archive.org"
+
+	// waybackInterval spaces out save requests; the anonymous save API
+	// allows only a few captures a minute
+	waybackInterval = 30 * time.Second
+	// waybackBackoff is how long to wait after the API asks us to slow down
+	waybackBackoff = 10 * time.Minute
+	// waybackMaxAttempts is how often a URL is tried before giving up
+	waybackMaxAttempts = 3
+)
+
+// errWaybackRateLimited means the save API refused the request for now
+var errWaybackRateLimited = errors.New("wayback machine rate limit reached")
+
+var waybackClient = &http.Client{Timeout: 2 * time.Minute}
+
+// waybackLoop submits clicked entries queued for archiving to the Wayback
+// Machine, one at a time, until ctx is cancelled.
+func (s *Server) waybackLoop(ctx context.Context) {
+	ticker := time.NewTicker(waybackInterval)
+	defer ticker.Stop()
+
+	var pausedUntil time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if time.Now().Before(pausedUntil) || s.getSetting(ctx, "wayback_archive") != "true" {
+			continue
+		}
+		if err := s.archiveNext(ctx); errors.Is(err, errWaybackRateLimited) {
+			s.logger.WarnContext(ctx, "Wayback Machine asked us to slow down, pausing archiving", "pause", waybackBackoff)
+			pausedUntil = time.Now().Add(waybackBackoff)
+		} else if err != nil {
+			s.logger.ErrorContext(ctx, "Error archiving entry", "error", err)
+		}
+	}
+}
+
+// archiveNext submits the oldest queued entry and records the outcome.
+func (s *Server) archiveNext(ctx context.Context) error {
+	var id int64
+	var entryURL string
+	var attempts int
+	err := s.db.QueryRowContext(ctx, `
+        SELECT id, url, COALESCE(archive_attempts, 0)
+        FROM entries
+        WHERE archive_status = ?
+        ORDER BY id
+        LIMIT 1`, archiveQueued).Scan(&id, &entryURL, &attempts)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := saveToWayback(ctx, entryURL)
+	if errors.Is(err, errWaybackRateLimited) {
+		return err
+	}
+	if err != nil {
+		attempts++
+		status := archiveQueued
+		if attempts >= waybackMaxAttempts {
+			status = archiveFailed
+		}
+		s.logger.ErrorContext(ctx, "Error archiving entry", "url", entryURL, "attempt", attempts, "error", err)
+		_, err = s.db.ExecContext(ctx,
+			"UPDATE entries SET archive_status = ?, archive_attempts = ? WHERE id = ?",
+			status, attempts, id)
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		"UPDATE entries SET archive_status = ?, archive_url = ?, archive_attempts = ? WHERE id = ?",
+		archiveSaved, snapshot, attempts+1, id)
+	return err
+}
+
+// saveToWayback asks the Wayback Machine to capture pageURL and returns the
+// snapshot's address.
+func saveToWayback(ctx context.Context, pageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, waybackSaveURL+pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "Infoscope (+https://github.com/disinfo-zone/infoscope)")
+
+	resp, err := waybackClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "", errWaybackRateLimited
+	case resp.StatusCode >= 400:
+		return "", fmt.Errorf("save request returned %s", resp.Status)
+	}
+
+	// The capture is named in Content-Location; otherwise the redirect
+	// followed to it is the snapshot
+	if loc := resp.Header.Get("Content-Location"); strings.HasPrefix(loc, "/web/") {
+		return waybackBaseURL + loc, nil
+	}
+	if final := resp.Request.URL; strings.HasPrefix(final.Path, "/web/") {
+		return final.String(), nil
+	}
+	return waybackBaseURL + "/web/" + pageURL, nil
+}
@@ -0,0 +1,27 @@
+// Shows entry dates in the visitor's timezone. Pages carry each date in the
+// site timezone, with the UTC time in the datetime attribute of its <time>
+// element; this rewrites the text and adds the full local time as a tooltip.
+(function () {
+    'use strict';
+
+    var day = new Intl.DateTimeFormat(undefined, { month: 'short', day: '2-digit' });
+    var full = new Intl.DateTimeFormat(undefined, { dateStyle: 'medium', timeStyle: 'short' });
+
+    function localize(root) {
+        var stamps = root.querySelectorAll('time[datetime]');
+        for (var i = 0; i < stamps.length; i++) {
+            var when = new Date(stamps[i].getAttribute('datetime'));
+            if (isNaN(when.getTime())) {
+                continue;
+            }
+            stamps[i].textContent = day.format(when);
+            stamps[i].title = full.format(when);
+        }
+    }
+
+    if (document.readyState === 'loading') {
+        document.addEventListener('DOMContentLoaded', function () { localize(document); });
+    } else {
+        localize(document);
+    }
+})();
@@ -0,0 +1,89 @@
+// internal/statsd/statsd.go
+
+// Package statsd sends metrics to a StatsD or DogStatsD agent over UDP.
+package statsd
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPacketSize keeps packets under the usual network MTU so they aren't
+// fragmented.
+const maxPacketSize = 1432
+
+// Client buffers metrics and writes them to the agent in batches. It is not
+// safe for concurrent use.
+type Client struct {
+	conn   net.Conn
+	prefix string
+	tags   string
+	buf    bytes.Buffer
+}
+
+// Dial connects to the agent at addr. Every metric name is prefixed with
+// prefix. When tags are given, lines use the DogStatsD tag extension.
+func Dial(addr, prefix string, tags []string) (*Client, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to statsd at %s: %w", addr, err)
+	}
+	c := &Client{conn: conn, prefix: prefix}
+	if len(tags) > 0 {
+		c.tags = "|#" + strings.Join(tags, ",")
+	}
+	return c, nil
+}
+
+// Gauge records the current value of name.
+func (c *Client) Gauge(name string, value float64) error {
+	return c.write(name, strconv.FormatFloat(value, 'f', -1, 64), "g")
+}
+
+// Count adds delta to the counter name.
+func (c *Client) Count(name string, delta int64) error {
+	return c.write(name, strconv.FormatInt(delta, 10), "c")
+}
+
+// Timing records a duration for name in milliseconds.
+func (c *Client) Timing(name string, d time.Duration) error {
+	ms := float64(d) / float64(time.Millisecond)
+	return c.write(name, strconv.FormatFloat(ms, 'f', -1, 64), "ms")
+}
+
+func (c *Client) write(name, value, kind string) error {
+	line := c.prefix + name + ":" + value + "|" + kind + c.tags
+	if c.buf.Len() > 0 && c.buf.Len()+1+len(line) > maxPacketSize {
+		if err := c.Flush(); err != nil {
+			return err
+		}
+	}
+	if c.buf.Len() > 0 {
+		c.buf.WriteByte('\n')
+	}
+	c.buf.WriteString(line)
+	return nil
+}
+
+// Flush sends any buffered metrics.
+func (c *Client) Flush() error {
+	if c.buf.Len() == 0 {
+		return nil
+	}
+	defer c.buf.Reset()
+	_, err := c.conn.Write(c.buf.Bytes())
+	return err
+}
+
+// Close flushes and closes the connection.
+func (c *Client) Close() error {
+	flushErr := c.Flush()
+	if err := c.conn.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dir keeps files in a directory on the local disk
+type Dir struct {
+	root string
+}
+
+// NewDir returns a Store rooted at root, creating the directory if needed
+func NewDir(root string) (*Dir, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &Dir{root: root}, nil
+}
+
+func (d *Dir) path(name string) string {
+	return filepath.Join(d.root, filepath.FromSlash(name))
+}
+
+// Put writes to a hidden temporary file first, so readers never see a
+// partly written file
+func (d *Dir) Put(ctx context.Context, name string, data []byte) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	target := d.path(name)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (d *Dir) Get(ctx context.Context, name string) ([]byte, Object, error) {
+	obj, err := d.Stat(ctx, name)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	data, err := os.ReadFile(d.path(name))
+	if err != nil {
+		return nil, Object{}, err
+	}
+	return data, obj, nil
+}
+
+func (d *Dir) Stat(ctx context.Context, name string) (Object, error) {
+	if err := checkName(name); err != nil {
+		return Object{}, err
+	}
+	info, err := os.Stat(d.path(name))
+	if err != nil {
+		return Object{}, err
+	}
+	if info.IsDir() {
+		return Object{}, &os.PathError{Op: "stat", Path: d.path(name), Err: os.ErrNotExist}
+	}
+	return Object{Name: name, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (d *Dir) Delete(ctx context.Context, name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	return os.Remove(d.path(name))
+}
+
+// List skips subdirectories and hidden files. A missing directory is
+// empty.
+func (d *Dir) List(ctx context.Context, dir string) ([]Object, error) {
+	if err := checkDir(dir); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(d.path(dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var objects []Object
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		objects = append(objects, Object{Name: e.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return objects, nil
+}
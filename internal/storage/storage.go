@@ -0,0 +1,108 @@
+// Package storage keeps fetched favicons and uploaded images, on the local
+// disk or in an S3-compatible bucket, so instances with ephemeral disks can
+// share them.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrInvalidName is returned for names that are empty, absolute, climb out
+// of the store or have a hidden element
+var ErrInvalidName = errors.New("invalid storage name")
+
+// Store holds files under slash-separated names such as
+// "images/favicon/favicon_ab12.ico". Files that don't exist are reported
+// with errors matching fs.ErrNotExist.
+type Store interface {
+	Put(ctx context.Context, name string, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, Object, error)
+	Stat(ctx context.Context, name string) (Object, error)
+	Delete(ctx context.Context, name string) error
+	// List returns the files directly inside dir, "" being the top level;
+	// their names are relative to dir
+	List(ctx context.Context, dir string) ([]Object, error)
+}
+
+// Object describes a stored file
+type Object struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// checkName rejects names that could reach outside the store. Hidden
+// elements are refused too, which keeps temporary files private.
+func checkName(name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return ErrInvalidName
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if strings.HasPrefix(elem, ".") {
+			return ErrInvalidName
+		}
+	}
+	return nil
+}
+
+// checkDir is checkName allowing "" for the top level
+func checkDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	return checkName(dir)
+}
+
+// Sub returns a Store for the files under dir in store
+func Sub(store Store, dir string) Store {
+	return sub{store: store, dir: dir}
+}
+
+type sub struct {
+	store Store
+	dir   string
+}
+
+func (s sub) Put(ctx context.Context, name string, data []byte) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	return s.store.Put(ctx, path.Join(s.dir, name), data)
+}
+
+func (s sub) Get(ctx context.Context, name string) ([]byte, Object, error) {
+	if err := checkName(name); err != nil {
+		return nil, Object{}, err
+	}
+	data, obj, err := s.store.Get(ctx, path.Join(s.dir, name))
+	obj.Name = name
+	return data, obj, err
+}
+
+func (s sub) Stat(ctx context.Context, name string) (Object, error) {
+	if err := checkName(name); err != nil {
+		return Object{}, err
+	}
+	obj, err := s.store.Stat(ctx, path.Join(s.dir, name))
+	obj.Name = name
+	return obj, err
+}
+
+func (s sub) Delete(ctx context.Context, name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	return s.store.Delete(ctx, path.Join(s.dir, name))
+}
+
+func (s sub) List(ctx context.Context, dir string) ([]Object, error) {
+	if err := checkDir(dir); err != nil {
+		return nil, err
+	}
+	return s.store.List(ctx, path.Join(s.dir, dir))
+}
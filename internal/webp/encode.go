@@ -0,0 +1,506 @@
+// internal/webp/encode.go
+
+// Package webp implements a small lossless WebP (VP8L) encoder. It applies
+// the subtract-green and predictor transforms, then codes pixels with greedy
+// LZ77 backward references and a single group of prefix codes. Colour caches
+// and the 2D distance map are not used.
+package webp
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+)
+
+const (
+	maxDimension      = 1 << 14
+	greenAlphabetSize = 256 + 24 // literals + length prefixes, no colour cache
+	maxCodeLength     = 15
+	maxCodeLenCodeLen = 7
+
+	transformPredictor     = 0
+	transformSubtractGreen = 2
+
+	predictorBlockBits = 5
+)
+
+// Predictor modes tried for each block
+const (
+	predictLeft   = 1
+	predictTop    = 2
+	predictSelect = 11
+)
+
+// codeLengthCodeOrder is the order in which code length code lengths are stored
+var codeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// Encode writes img to w as a lossless WebP file
+func Encode(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width <= 0 || height <= 0 || width > maxDimension || height > maxDimension {
+		return errors.New("webp: invalid image size")
+	}
+
+	// Collect pixels as non-premultiplied ARGB channels
+	pixels := make([]color.NRGBA, 0, width*height)
+	hasAlpha := false
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			hasAlpha = hasAlpha || c.A != 0xff
+			pixels = append(pixels, c)
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.write(0x2f, 8) // VP8L signature
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	if hasAlpha {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3) // version
+
+	// Subtract-green, then spatial prediction
+	subtractGreen(pixels)
+	bw.write(1, 1)
+	bw.write(transformSubtractGreen, 2)
+
+	modes, blocksWide := choosePredictors(pixels, width, height)
+	bw.write(1, 1)
+	bw.write(transformPredictor, 2)
+	bw.write(predictorBlockBits-2, 3)
+	bw.writeImageData(modes, false)
+	residuals := predict(pixels, width, height, modes, blocksWide)
+
+	bw.write(0, 1) // no more transforms
+	bw.writeImageData(residuals, true)
+
+	data := bw.bytes()
+	pad := len(data) & 1
+
+	var header [20]byte
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(4+8+len(data)+pad))
+	copy(header[8:12], "WEBP")
+	copy(header[12:16], "VP8L")
+	binary.LittleEndian.PutUint32(header[16:20], uint32(len(data)))
+
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if pad == 1 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// writeImageData writes an entropy-coded image: a single prefix code group
+// followed by literal pixels and backward references. Only the main image
+// carries the meta code bit.
+func (bw *bitWriter) writeImageData(pixels []color.NRGBA, main bool) {
+	bw.write(0, 1) // no colour cache
+	if main {
+		bw.write(0, 1) // single prefix code group
+	}
+
+	tokens := findMatches(pixels)
+
+	green := make([]int, greenAlphabetSize)
+	var red, blue, alpha [256]int
+	distance := make([]int, 40)
+	for _, t := range tokens {
+		if t.length == 0 {
+			green[t.pixel.G]++
+			red[t.pixel.R]++
+			blue[t.pixel.B]++
+			alpha[t.pixel.A]++
+			continue
+		}
+		lengthSymbol, _, _ := prefixEncode(t.length)
+		distSymbol, _, _ := prefixEncode(t.distance + distanceCodeOffset)
+		green[256+lengthSymbol]++
+		distance[distSymbol]++
+	}
+
+	codes := []*prefixCode{
+		bw.writePrefixCode(green),
+		bw.writePrefixCode(red[:]),
+		bw.writePrefixCode(blue[:]),
+		bw.writePrefixCode(alpha[:]),
+		bw.writePrefixCode(distance),
+	}
+
+	for _, t := range tokens {
+		if t.length == 0 {
+			codes[0].emit(bw, int(t.pixel.G))
+			codes[1].emit(bw, int(t.pixel.R))
+			codes[2].emit(bw, int(t.pixel.B))
+			codes[3].emit(bw, int(t.pixel.A))
+			continue
+		}
+		symbol, n, extra := prefixEncode(t.length)
+		codes[0].emit(bw, 256+symbol)
+		bw.write(extra, n)
+		symbol, n, extra = prefixEncode(t.distance + distanceCodeOffset)
+		codes[4].emit(bw, symbol)
+		bw.write(extra, n)
+	}
+}
+
+func subtractGreen(pixels []color.NRGBA) {
+	for i := range pixels {
+		pixels[i].R -= pixels[i].G
+		pixels[i].B -= pixels[i].G
+	}
+}
+
+// choosePredictors picks, per block, the predictor with the smallest residuals
+func choosePredictors(pixels []color.NRGBA, width, height int) ([]color.NRGBA, int) {
+	size := 1 << predictorBlockBits
+	blocksWide := (width + size - 1) / size
+	blocksHigh := (height + size - 1) / size
+
+	modes := make([]color.NRGBA, blocksWide*blocksHigh)
+	for by := 0; by < blocksHigh; by++ {
+		for bx := 0; bx < blocksWide; bx++ {
+			best, bestCost := predictLeft, -1
+			for _, mode := range []int{predictLeft, predictTop, predictSelect} {
+				cost := 0
+				for y := by * size; y < (by+1)*size && y < height; y++ {
+					for x := bx * size; x < (bx+1)*size && x < width; x++ {
+						cost += residualCost(pixels[y*width+x], predictPixel(pixels, width, x, y, mode))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			modes[by*blocksWide+bx] = color.NRGBA{G: uint8(best), A: 0xff}
+		}
+	}
+	return modes, blocksWide
+}
+
+// predict replaces pixels with their residuals against the chosen predictors
+func predict(pixels []color.NRGBA, width, height int, modes []color.NRGBA, blocksWide int) []color.NRGBA {
+	residuals := make([]color.NRGBA, len(pixels))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			mode := int(modes[(y>>predictorBlockBits)*blocksWide+(x>>predictorBlockBits)].G)
+			p, q := pixels[y*width+x], predictPixel(pixels, width, x, y, mode)
+			residuals[y*width+x] = color.NRGBA{R: p.R - q.R, G: p.G - q.G, B: p.B - q.B, A: p.A - q.A}
+		}
+	}
+	return residuals
+}
+
+// predictPixel applies the VP8L prediction rules, including the fixed
+// predictors used along the top row and left column
+func predictPixel(pixels []color.NRGBA, width, x, y, mode int) color.NRGBA {
+	switch {
+	case x == 0 && y == 0:
+		return color.NRGBA{A: 0xff}
+	case y == 0:
+		return pixels[x-1]
+	case x == 0:
+		return pixels[(y-1)*width]
+	}
+
+	left, top := pixels[y*width+x-1], pixels[(y-1)*width+x]
+	switch mode {
+	case predictTop:
+		return top
+	case predictSelect:
+		topLeft := pixels[(y-1)*width+x-1]
+		pL, pT := 0, 0
+		for _, c := range [][3]uint8{
+			{left.A, top.A, topLeft.A}, {left.R, top.R, topLeft.R},
+			{left.G, top.G, topLeft.G}, {left.B, top.B, topLeft.B},
+		} {
+			estimate := int(c[0]) + int(c[1]) - int(c[2])
+			pL += abs(estimate - int(c[0]))
+			pT += abs(estimate - int(c[1]))
+		}
+		if pL < pT {
+			return left
+		}
+		return top
+	default:
+		return left
+	}
+}
+
+func residualCost(p, q color.NRGBA) int {
+	return abs(int(int8(p.R-q.R))) + abs(int(int8(p.G-q.G))) +
+		abs(int(int8(p.B-q.B))) + abs(int(int8(p.A-q.A)))
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// bitWriter packs values least-significant bit first
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (bw *bitWriter) write(v uint32, n uint) {
+	bw.acc |= uint64(v) << bw.nbits
+	bw.nbits += n
+	for bw.nbits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nbits -= 8
+	}
+}
+
+func (bw *bitWriter) bytes() []byte {
+	if bw.nbits > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.nbits = 0, 0
+	}
+	return bw.buf
+}
+
+// prefixCode maps symbols to bit-reversed canonical codes
+type prefixCode struct {
+	lengths []int
+	codes   []uint32
+}
+
+func (pc *prefixCode) emit(bw *bitWriter, symbol int) {
+	if n := pc.lengths[symbol]; n > 0 {
+		bw.write(pc.codes[symbol], uint(n))
+	}
+}
+
+// writePrefixCode chooses a prefix code for the given symbol frequencies,
+// writes its description and returns it for encoding symbols
+func (bw *bitWriter) writePrefixCode(freq []int) *prefixCode {
+	var used []int
+	for sym, f := range freq {
+		if f > 0 {
+			used = append(used, sym)
+		}
+	}
+	if len(used) == 0 {
+		used = []int{0}
+	}
+
+	// One or two small symbols fit the "simple" code form
+	if len(used) <= 2 && used[len(used)-1] < 256 {
+		bw.write(1, 1)
+		bw.write(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(used[0]), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			bw.write(uint32(used[1]), 8)
+		}
+
+		pc := &prefixCode{lengths: make([]int, len(freq)), codes: make([]uint32, len(freq))}
+		if len(used) == 2 {
+			pc.lengths[used[0]], pc.codes[used[0]] = 1, 0
+			pc.lengths[used[1]], pc.codes[used[1]] = 1, 1
+		}
+		return pc
+	}
+
+	lengths := huffmanLengths(freq, maxCodeLength)
+	ensureTwoCodes(lengths)
+
+	// Describe the code lengths with a code length code
+	clFreq := make([]int, 19)
+	for _, l := range lengths {
+		clFreq[l]++
+	}
+	clLengths := huffmanLengths(clFreq, maxCodeLenCodeLen)
+	ensureTwoCodes(clLengths)
+	clCodes := canonicalCodes(clLengths)
+
+	numCodes := 4
+	for i, sym := range codeLengthCodeOrder {
+		if clLengths[sym] > 0 && i+1 > numCodes {
+			numCodes = i + 1
+		}
+	}
+
+	bw.write(0, 1) // normal code
+	bw.write(uint32(numCodes-4), 4)
+	for _, sym := range codeLengthCodeOrder[:numCodes] {
+		bw.write(uint32(clLengths[sym]), 3)
+	}
+	bw.write(0, 1) // code lengths cover the whole alphabet
+	for _, l := range lengths {
+		bw.write(clCodes[l], uint(clLengths[l]))
+	}
+
+	return &prefixCode{lengths: lengths, codes: canonicalCodes(lengths)}
+}
+
+// ensureTwoCodes gives a lone used symbol a partner so the code is complete
+func ensureTwoCodes(lengths []int) {
+	used := -1
+	for sym, l := range lengths {
+		if l > 0 {
+			if used >= 0 {
+				return
+			}
+			used = sym
+		}
+	}
+	if used < 0 {
+		used = 0
+	}
+	lengths[used] = 1
+	if used == 0 {
+		lengths[1] = 1
+	} else {
+		lengths[0] = 1
+	}
+}
+
+// canonicalCodes assigns canonical prefix codes, bit-reversed for LSB-first output
+func canonicalCodes(lengths []int) []uint32 {
+	var count [maxCodeLength + 2]uint32
+	for _, l := range lengths {
+		if l > 0 {
+			count[l]++
+		}
+	}
+
+	var next [maxCodeLength + 2]uint32
+	code := uint32(0)
+	for l := 1; l <= maxCodeLength; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+
+	codes := make([]uint32, len(lengths))
+	for sym, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		c := next[l]
+		next[l]++
+		var rev uint32
+		for i := 0; i < l; i++ {
+			rev = rev<<1 | (c>>i)&1
+		}
+		codes[sym] = rev
+	}
+	return codes
+}
+
+// huffmanLengths builds Huffman code lengths no longer than maxLen. When the
+// tree is too deep the frequencies are flattened and the tree rebuilt.
+func huffmanLengths(freq []int, maxLen int) []int {
+	f := make([]int, len(freq))
+	copy(f, freq)
+
+	for {
+		lengths := buildLengths(f)
+		longest := 0
+		for _, l := range lengths {
+			if l > longest {
+				longest = l
+			}
+		}
+		if longest <= maxLen {
+			return lengths
+		}
+		for i := range f {
+			if f[i] > 0 {
+				f[i] = (f[i] + 1) / 2
+			}
+		}
+	}
+}
+
+type huffNode struct {
+	freq   int
+	parent int
+}
+
+type nodeHeap struct {
+	nodes []huffNode
+	order []int
+}
+
+func (h *nodeHeap) Len() int { return len(h.order) }
+func (h *nodeHeap) Less(i, j int) bool {
+	a, b := h.order[i], h.order[j]
+	if h.nodes[a].freq != h.nodes[b].freq {
+		return h.nodes[a].freq < h.nodes[b].freq
+	}
+	return a < b
+}
+func (h *nodeHeap) Swap(i, j int) { h.order[i], h.order[j] = h.order[j], h.order[i] }
+func (h *nodeHeap) Push(x any)    { h.order = append(h.order, x.(int)) }
+func (h *nodeHeap) Pop() any {
+	n := h.order[len(h.order)-1]
+	h.order = h.order[:len(h.order)-1]
+	return n
+}
+
+func buildLengths(freq []int) []int {
+	lengths := make([]int, len(freq))
+	h := &nodeHeap{}
+	leaf := make(map[int]int) // symbol -> node index
+	for sym, f := range freq {
+		if f > 0 {
+			leaf[sym] = len(h.nodes)
+			h.order = append(h.order, len(h.nodes))
+			h.nodes = append(h.nodes, huffNode{freq: f, parent: -1})
+		}
+	}
+	if len(h.nodes) == 0 {
+		return lengths
+	}
+	if len(h.nodes) == 1 {
+		for sym := range leaf {
+			lengths[sym] = 1
+		}
+		return lengths
+	}
+
+	heap.Init(h)
+	for h.Len() > 1 {
+		a := heap.Pop(h).(int)
+		b := heap.Pop(h).(int)
+		parent := len(h.nodes)
+		h.nodes = append(h.nodes, huffNode{freq: h.nodes[a].freq + h.nodes[b].freq, parent: -1})
+		h.nodes[a].parent = parent
+		h.nodes[b].parent = parent
+		heap.Push(h, parent)
+	}
+
+	for sym, idx := range leaf {
+		depth := 0
+		for n := idx; h.nodes[n].parent >= 0; n = h.nodes[n].parent {
+			depth++
+		}
+		lengths[sym] = depth
+	}
+	return lengths
+}
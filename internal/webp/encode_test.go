@@ -0,0 +1,63 @@
+// internal/webp/encode_test.go
+package webp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	xwebp "golang.org/x/image/webp"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name  string
+		w, h  int
+		pixel func(x, y int) color.NRGBA
+	}{
+		{"single pixel", 1, 1, func(x, y int) color.NRGBA { return color.NRGBA{10, 20, 30, 255} }},
+		{"two colours", 7, 5, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x % 2 * 200), 5, 9, 255} }},
+		{"checkerboard", 400, 120, func(x, y int) color.NRGBA {
+			if (x/40+y/40)%2 == 0 {
+				return color.NRGBA{200, 220, 240, 255}
+			}
+			return color.NRGBA{30, 60, 90, 255}
+		}},
+		{"gradient", 300, 200, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x), uint8(y), uint8(x + y), 255} }},
+		{"noise with alpha", 64, 40, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(1 + rng.Intn(255))}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, tt.w, tt.h))
+			for y := 0; y < tt.h; y++ {
+				for x := 0; x < tt.w; x++ {
+					img.SetNRGBA(x, y, tt.pixel(x, y))
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := Encode(&buf, img); err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			out, err := xwebp.Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			for y := 0; y < tt.h; y++ {
+				for x := 0; x < tt.w; x++ {
+					got := color.NRGBAModel.Convert(out.At(x, y)).(color.NRGBA)
+					if want := img.NRGBAAt(x, y); got != want {
+						t.Fatalf("pixel (%d,%d) = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// internal/webp/lz77.go
+package webp
+
+import "image/color"
+
+const (
+	minMatch       = 3
+	maxMatch       = 4096
+	maxDistance    = 1<<20 - 120
+	hashBits       = 15
+	maxChainProbes = 32
+
+	// distanceCodeOffset is added to linear distances; smaller distance codes
+	// refer to the 2D neighbourhood map, which we don't use
+	distanceCodeOffset = 120
+)
+
+// token is either a literal pixel or a backward reference
+type token struct {
+	pixel    color.NRGBA
+	length   int // 0 for literals
+	distance int
+}
+
+// findMatches greedily replaces repeated pixel runs with backward references
+// using hash chains over three-pixel prefixes
+func findMatches(pixels []color.NRGBA) []token {
+	tokens := make([]token, 0, len(pixels)/2)
+	head := make([]int32, 1<<hashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int32, len(pixels))
+
+	insert := func(i int) {
+		if i+minMatch > len(pixels) {
+			return
+		}
+		h := hashPixels(pixels[i:])
+		prev[i] = head[h]
+		head[h] = int32(i)
+	}
+
+	for i := 0; i < len(pixels); {
+		bestLen, bestDist := 0, 0
+		if i+minMatch <= len(pixels) {
+			candidate := head[hashPixels(pixels[i:])]
+			for probes := 0; candidate >= 0 && probes < maxChainProbes; probes++ {
+				dist := i - int(candidate)
+				if dist > maxDistance {
+					break
+				}
+				n := matchLength(pixels, int(candidate), i)
+				if n > bestLen {
+					bestLen, bestDist = n, dist
+					if n == maxMatch {
+						break
+					}
+				}
+				candidate = prev[candidate]
+			}
+		}
+
+		if bestLen >= minMatch {
+			tokens = append(tokens, token{length: bestLen, distance: bestDist})
+			for j := i; j < i+bestLen; j++ {
+				insert(j)
+			}
+			i += bestLen
+			continue
+		}
+
+		tokens = append(tokens, token{pixel: pixels[i]})
+		insert(i)
+		i++
+	}
+	return tokens
+}
+
+func hashPixels(p []color.NRGBA) uint32 {
+	var h uint32
+	for _, c := range p[:minMatch] {
+		h = h*0x9e3779b1 + (uint32(c.A)<<24 | uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B))
+	}
+	return h >> (32 - hashBits)
+}
+
+func matchLength(pixels []color.NRGBA, from, at int) int {
+	n := 0
+	for at+n < len(pixels) && n < maxMatch && pixels[from+n] == pixels[at+n] {
+		n++
+	}
+	return n
+}
+
+// prefixEncode splits a length or distance code into its prefix symbol and
+// extra bits, as defined by the VP8L LZ77 prefix coding
+func prefixEncode(value int) (symbol int, extraBits uint, extra uint32) {
+	x := value - 1
+	if x < 4 {
+		return x, 0, 0
+	}
+	hb := 0
+	for v := x; v > 1; v >>= 1 {
+		hb++
+	}
+	second := (x >> (hb - 1)) & 1
+	extraBits = uint(hb - 1)
+	return 2*hb + second, extraBits, uint32(x) & (1<<extraBits - 1)
+}